@@ -39,6 +39,10 @@ var (
 	// This error is returned by WaitDeployed if contract creation leaves an
 	// empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
+
+	// This error is raised when attempting to wait for confirmations on a backend
+	// that doesn't implement ChainHeadReader.
+	ErrNoChainHead = errors.New("backend does not support following the chain head")
 )
 
 // ContractCaller defines the methods needed to allow operating with contract on a read
@@ -104,6 +108,23 @@ type DeployBackend interface {
 	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
 }
 
+// ChainHeadReader defines the methods needed to follow the chain head, to wait
+// for blocks to become final. WatchLogsConfirmed will try to discover this
+// interface on the filterer, returning ErrNoChainHead if it's not implemented.
+type ChainHeadReader interface {
+	// HeaderByNumber returns a header of the canonical chain, or the head if
+	// number is nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	// SubscribeNewHead subscribes to notifications about the canonical chain head.
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// ConfirmBackend wraps the operations needed by WaitMinedConfirmed.
+type ConfirmBackend interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
 // ContractBackend defines the methods needed to work with contracts on a read-write basis.
 type ContractBackend interface {
 	ContractCaller
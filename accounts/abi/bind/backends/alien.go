@@ -0,0 +1,193 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/internal/blocksim"
+	"github.com/eeefan/dpeth/params"
+)
+
+var errNotAlien = errors.New("SimulatedBackend is not sealed by the alien engine")
+
+// alienSealer creates the blocks of an alien simulated backend. It runs them
+// through the engine's real Prepare, Finalize and Seal path, signing each with
+// the in-memory key of the signer in turn, against a simulated clock which only
+// moves forward when a new block is due.
+type alienSealer struct {
+	engine *alien.Alien
+	keys   map[common.Address]*ecdsa.PrivateKey
+	now    int64 // Simulated unix time the engine reads as the current time
+}
+
+// newAlienSealer creates a sealer for the given chain configuration, starting the
+// simulated clock at the genesis timestamp.
+func newAlienSealer(config *params.ChainConfig, signers []*ecdsa.PrivateKey) (*alienSealer, error) {
+	if config.Alien.Period == 0 || config.Alien.MaxSignerCount == 0 {
+		return nil, errors.New("simulated alien chains need an explicit period and signer count")
+	}
+	s := &alienSealer{
+		keys: make(map[common.Address]*ecdsa.PrivateKey),
+		now:  int64(config.Alien.GenesisTimestamp),
+	}
+	for _, key := range signers {
+		s.keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	for _, signer := range config.Alien.SelfVoteSigners {
+		if _, ok := s.keys[common.Address(signer)]; !ok {
+			return nil, fmt.Errorf("missing key of genesis signer %x", common.Address(signer))
+		}
+	}
+	return s, nil
+}
+
+// clock returns the simulated time, it is the clock the engine is created with.
+func (s *alienSealer) clock() time.Time {
+	return time.Unix(atomic.LoadInt64(&s.now), 0)
+}
+
+// snapshot retrieves the alien snapshot at the given block.
+func (s *alienSealer) snapshot(chain *core.BlockChain, block *types.Block) (*alien.Snapshot, error) {
+	for _, api := range s.engine.APIs(chain) {
+		if api, ok := api.Service.(*alien.API); ok {
+			return api.GetSnapshotAtHash(block.Hash())
+		}
+	}
+	return nil, errNotAlien
+}
+
+// build creates a sealed block on top of the chain head containing txs. The
+// clock is moved to the next slot, skipping offset more seconds.
+func (s *alienSealer) build(chain *core.BlockChain, txs types.Transactions, offset int64) (*types.Block, error) {
+	parent := chain.CurrentBlock()
+
+	now := parent.Time().Int64() + int64(chain.Config().Alien.Period)
+	if current := atomic.LoadInt64(&s.now); current > now {
+		now = current
+	}
+	now += offset
+	atomic.StoreInt64(&s.now, now)
+
+	signer, err := s.engine.SignerAt(parent.Header(), uint64(now))
+	if err != nil {
+		return nil, err
+	}
+	key, ok := s.keys[signer]
+	if !ok {
+		return nil, fmt.Errorf("missing key of signer %x", signer)
+	}
+	s.engine.Authorize(signer, blocksim.SignFn(key), blocksim.SignTxFn(key))
+	return blocksim.Seal(s.engine, chain, parent, signer, uint64(now), txs)
+}
+
+// NewAlienSimulatedBackend creates a new binding backend using a simulated
+// blockchain sealed by the alien engine with the given signer keys. If config
+// is nil, every signer self votes in the genesis block, the first of them is
+// the signer admin and blocks are produced every 3 seconds in loops with one
+// slot per signer.
+func NewAlienSimulatedBackend(alloc core.GenesisAlloc, config *params.ChainConfig, signers ...*ecdsa.PrivateKey) (*SimulatedBackend, error) {
+	if config == nil {
+		config = DefaultAlienChainConfig(signers...)
+	}
+	if config.Alien == nil {
+		return nil, errNotAlien
+	}
+	// Copy the configs so the genesis timestamp can be filled in
+	chainConfig, alienConfig := *config, *config.Alien
+	chainConfig.Alien = &alienConfig
+	if alienConfig.GenesisTimestamp == 0 {
+		alienConfig.GenesisTimestamp = uint64(time.Now().Unix())
+	}
+	sealer, err := newAlienSealer(&chainConfig, signers)
+	if err != nil {
+		return nil, err
+	}
+	database := ethdb.NewMemDatabase()
+	sealer.engine = alien.NewSimulated(&alienConfig, database, sealer.clock)
+
+	backend := newSimulatedBackend(database, &core.Genesis{
+		Config:    &chainConfig,
+		Timestamp: alienConfig.GenesisTimestamp,
+		ExtraData: make([]byte, 32+65), // Empty vanity and seal of the alien genesis
+		Alloc:     alloc,
+	}, sealer.engine, sealer)
+	return backend, nil
+}
+
+// DefaultAlienChainConfig returns the chain configuration of an alien simulated
+// backend sealed by the given signers.
+func DefaultAlienChainConfig(signers ...*ecdsa.PrivateKey) *params.ChainConfig {
+	config := *params.AllEthashProtocolChanges
+	config.Ethash = nil
+	config.Alien = &params.AlienConfig{
+		Period:            3,
+		Epoch:             30000,
+		MaxSignerCount:    uint64(len(signers)),
+		MinVoterBalance:   new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether)),
+		MaxRewardOutBlock: new(big.Int).SetUint64(1e10),
+		PerBlockReward:    big.NewInt(params.Ether),
+		MinerRewardRatio:  50,
+		LuckyDrawAddress:  params.MainnetChainConfig.Alien.LuckyDrawAddress,
+	}
+	for i, key := range signers {
+		signer := crypto.PubkeyToAddress(key.PublicKey)
+		if i == 0 {
+			config.Alien.AdminAddress = signer
+		}
+		config.Alien.SelfVoteSigners = append(config.Alien.SelfVoteSigners, common.UnprefixedAddress(signer))
+	}
+	return &config
+}
+
+// Snapshot returns the alien snapshot at the head of the simulated chain.
+func (b *SimulatedBackend) Snapshot() (*alien.Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sealer == nil {
+		return nil, errNotAlien
+	}
+	return b.sealer.snapshot(b.blockchain, b.blockchain.CurrentBlock())
+}
+
+// AdvanceLoops commits the pending block and then empty blocks until n signer
+// loops have started, leaving the head at the first block of the last loop.
+func (b *SimulatedBackend) AdvanceLoops(n int) error {
+	if b.sealer == nil {
+		return errNotAlien
+	}
+	loop := b.config.Alien.MaxSignerCount
+	for i := 0; i < n; i++ {
+		b.Commit()
+		for b.blockchain.CurrentBlock().NumberU64()%loop != 0 {
+			b.Commit()
+		}
+	}
+	return nil
+}
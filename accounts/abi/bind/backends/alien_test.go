@@ -0,0 +1,128 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/params"
+)
+
+// Tests that the alien simulated backend seals blocks by the signers in turn,
+// splits the rewards and applies admin transactions to the signer queue.
+func TestAlienSimulatedBackend(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(core.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = core.GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	admin, removed := crypto.PubkeyToAddress(keys[0].PublicKey), crypto.PubkeyToAddress(keys[1].PublicKey)
+
+	sim, err := NewAlienSimulatedBackend(alloc, nil, keys...)
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	config := sim.config.Alien
+
+	// The signer admin is only set up by the first block
+	sim.Commit()
+	parent := sim.blockchain.CurrentBlock()
+
+	// Remove the second signer from the candidates with an admin transaction
+	tx, _ := types.SignTx(types.NewTransaction(0, removed, new(big.Int), 100000, big.NewInt(1), []byte("dpos:1:admin:dels")), types.HomesteadSigner{}, keys[0])
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+
+	block := sim.blockchain.CurrentBlock()
+	if have, want := block.Time().Uint64(), parent.Time().Uint64()+config.Period; have != want {
+		t.Errorf("block time mismatch: have %d, want %d", have, want)
+	}
+	if receipt, _ := sim.TransactionReceipt(context.Background(), tx.Hash()); receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("admin transaction failed: %v", receipt)
+	}
+	// Both blocks paid half of their reward to the lucky draw pool
+	lucky, _ := sim.BalanceAt(context.Background(), config.LuckyDrawAddress, nil)
+	if want := config.PerBlockReward; lucky.Cmp(want) != 0 {
+		t.Errorf("lucky draw reward mismatch: have %v, want %v", lucky, want)
+	}
+	// Skipping slots leaves a gap in the timestamps
+	if err := sim.AdjustTime(2 * time.Duration(config.Period) * time.Second); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	sim.Commit()
+	if have, want := sim.blockchain.CurrentBlock().Time().Uint64(), block.Time().Uint64()+3*config.Period; have != want {
+		t.Errorf("adjusted block time mismatch: have %d, want %d", have, want)
+	}
+	// The next loop is sealed without the removed signer
+	if err := sim.AdvanceLoops(1); err != nil {
+		t.Fatalf("failed to advance loop: %v", err)
+	}
+	head := sim.blockchain.CurrentBlock()
+	if head.NumberU64()%config.MaxSignerCount != 0 {
+		t.Fatalf("head %d is not the start of a loop", head.NumberU64())
+	}
+	snap, err := sim.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if snap.Hash != head.Hash() || snap.LoopStartTime != head.Time().Uint64() {
+		t.Errorf("snapshot mismatch: have %x/%d, want %x/%d", snap.Hash, snap.LoopStartTime, head.Hash(), head.Time())
+	}
+	if snap.SignerAdmin != admin || len(snap.CandidateSigners) != 2 {
+		t.Errorf("candidate signers mismatch: admin %x, candidates %x", snap.SignerAdmin, snap.CandidateSigners)
+	}
+	for _, signer := range snap.Signers {
+		if *signer == removed {
+			t.Errorf("removed signer %x still in signer queue", removed)
+		}
+	}
+	if len(snap.Signers) != int(config.MaxSignerCount) {
+		t.Errorf("signer queue length mismatch: have %d, want %d", len(snap.Signers), config.MaxSignerCount)
+	}
+	// Blocks keep being sealed by the signers in the new queue
+	sim.Commit()
+	if coinbase := sim.blockchain.CurrentBlock().Coinbase(); coinbase == removed || coinbase == (common.Address{}) {
+		t.Errorf("block sealed by unexpected signer %x", coinbase)
+	}
+}
+
+// Tests that alien backends refuse to start without the keys of the genesis
+// signers.
+func TestAlienSimulatedBackendMissingKey(t *testing.T) {
+	keys := []*ecdsa.PrivateKey{newKey(), newKey()}
+	config := DefaultAlienChainConfig(keys...)
+
+	if _, err := NewAlienSimulatedBackend(nil, config, keys[0]); err == nil {
+		t.Fatalf("backend created without all signer keys")
+	}
+}
+
+func newKey() *ecdsa.PrivateKey {
+	key, _ := crypto.GenerateKey()
+	return key
+}
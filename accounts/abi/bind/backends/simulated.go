@@ -28,6 +28,7 @@ import (
 	"github.com/eeefan/dpeth/accounts/abi/bind"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/math"
+	"github.com/eeefan/dpeth/consensus"
 	"github.com/eeefan/dpeth/consensus/ethash"
 	"github.com/eeefan/dpeth/core"
 	"github.com/eeefan/dpeth/core/bloombits"
@@ -44,6 +45,7 @@ import (
 
 // This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
 var _ bind.ContractBackend = (*SimulatedBackend)(nil)
+var _ bind.ChainHeadReader = (*SimulatedBackend)(nil)
 
 var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
 var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
@@ -53,6 +55,8 @@ var errGasEstimationFailed = errors.New("gas required exceeds allowance or alway
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	engine     consensus.Engine // Consensus engine the simulated blocks are created for
+	sealer     *alienSealer     // In-memory signers sealing alien blocks, nil for other engines
 
 	mu           sync.Mutex
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
@@ -66,14 +70,27 @@ type SimulatedBackend struct {
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
 // for testing purposes.
 func NewSimulatedBackend(alloc core.GenesisAlloc) *SimulatedBackend {
-	database := ethdb.NewMemDatabase()
-	genesis := core.Genesis{Config: params.AllEthashProtocolChanges, Alloc: alloc}
+	return NewSimulatedBackendWithConfig(alloc, params.AllEthashProtocolChanges, ethash.NewFaker())
+}
+
+// NewSimulatedBackendWithConfig creates a new binding backend using a simulated
+// blockchain with the given chain configuration and consensus engine. The engine
+// must accept unsealed blocks, use NewAlienSimulatedBackend for alien chains.
+func NewSimulatedBackendWithConfig(alloc core.GenesisAlloc, config *params.ChainConfig, engine consensus.Engine) *SimulatedBackend {
+	return newSimulatedBackend(ethdb.NewMemDatabase(), &core.Genesis{Config: config, Alloc: alloc}, engine, nil)
+}
+
+// newSimulatedBackend creates a simulated backend on top of the given genesis,
+// sealing blocks with sealer if it's set.
+func newSimulatedBackend(database ethdb.Database, genesis *core.Genesis, engine consensus.Engine, sealer *alienSealer) *SimulatedBackend {
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{})
+	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, engine, vm.Config{})
 
 	backend := &SimulatedBackend{
 		database:   database,
 		blockchain: blockchain,
+		engine:     engine,
+		sealer:     sealer,
 		config:     genesis.Config,
 		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
 	}
@@ -90,7 +107,8 @@ func (b *SimulatedBackend) Commit() {
 	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
 		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
 	}
-	b.rollback()
+	// Keep building on the committed block, even if it's on a side chain
+	b.generate(b.pendingBlock, nil, 0)
 }
 
 // Rollback aborts all pending transactions, reverting to the last committed state.
@@ -98,14 +116,59 @@ func (b *SimulatedBackend) Rollback() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.rollback()
+	b.generate(b.pendingParent(), nil, 0)
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	b.generate(b.blockchain.CurrentBlock(), nil, 0)
+}
+
+// Fork replaces the pending block with an empty one on top of the given parent.
+// Committing it, and the blocks after it, builds a side chain which replaces
+// the canonical one once heavier, reorganising the chain. It's not supported on
+// alien chains.
+func (b *SimulatedBackend) Fork(ctx context.Context, parent common.Hash) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sealer != nil {
+		return errors.New("simulated alien chains can't be forked")
+	}
+	block := b.blockchain.GetBlockByHash(parent)
+	if block == nil {
+		return errors.New("parent block not found")
+	}
+	b.generate(block, nil, 0)
+	return nil
+}
+
+// pendingParent returns the block the pending block is built on.
+func (b *SimulatedBackend) pendingParent() *types.Block {
+	return b.blockchain.GetBlockByHash(b.pendingBlock.ParentHash())
+}
+
+// generate creates a new pending block on top of parent containing txs,
+// timestamped offset seconds later than due, and resets the pending state to
+// match it. Alien blocks are always built on top of the current head.
+func (b *SimulatedBackend) generate(parent *types.Block, txs types.Transactions, offset int64) {
+	var block *types.Block
+	if b.sealer != nil {
+		var err error
+		if block, err = b.sealer.build(b.blockchain, txs, offset); err != nil {
+			panic(err) // This cannot happen unless the simulator is wrong, fail in that case
+		}
+	} else {
+		blocks, _ := core.GenerateChain(b.config, parent, b.engine, b.database, 1, func(number int, block *core.BlockGen) {
+			for _, tx := range txs {
+				block.AddTxWithChain(b.blockchain, tx)
+			}
+			block.OffsetTime(offset)
+		})
+		block = blocks[0]
+	}
 	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock = block
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 }
 
@@ -160,10 +223,31 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 
 // TransactionReceipt returns the receipt of a transaction.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	receipt, _, _, _ := rawdb.ReadReceipt(b.database, txHash)
+	receipt, blockHash, blockNumber, index := rawdb.ReadReceipt(b.database, txHash)
+	if receipt != nil {
+		receipt.BlockHash = blockHash
+		receipt.BlockNumber = new(big.Int).SetUint64(blockNumber)
+		receipt.TransactionIndex = uint(index)
+	}
 	return receipt, nil
 }
 
+// HeaderByNumber returns a block header from the current canonical chain. If
+// number is nil, the latest known header is returned.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if number == nil {
+		return b.blockchain.CurrentHeader(), nil
+	}
+	header := b.blockchain.GetHeaderByNumber(number.Uint64())
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
 // PendingCodeAt returns the code associated with an account in the pending state.
 func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
 	b.mu.Lock()
@@ -306,16 +390,8 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTxWithChain(b.blockchain, tx)
-		}
-		block.AddTxWithChain(b.blockchain, tx)
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	txs := append(types.Transactions{}, b.pendingBlock.Transactions()...)
+	b.generate(b.pendingParent(), append(txs, tx), 0)
 	return nil
 }
 
@@ -381,21 +457,40 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query ethere
 	}), nil
 }
 
-// AdjustTime adds a time shift to the simulated clock.
+// SubscribeNewHead returns an event subscription for a new header imported as
+// the head of the canonical chain.
+func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	sink := make(chan *types.Header)
+	sub := b.events.SubscribeNewHeads(sink)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case head := <-sink:
+				select {
+				case ch <- head:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// AdjustTime adds a time shift to the simulated clock. On alien chains the
+// skipped slots are missed by their signers.
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.OffsetTime(int64(adjustment.Seconds()))
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 
+	b.generate(b.pendingParent(), b.pendingBlock.Transactions(), int64(adjustment.Seconds()))
 	return nil
 }
 
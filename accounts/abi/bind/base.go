@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/eeefan/dpeth"
 	"github.com/eeefan/dpeth/accounts/abi"
@@ -324,6 +325,149 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 	return logs, sub, nil
 }
 
+// WatchLogsConfirmed subscribes to the contract logs like WatchLogs, but only
+// delivers them once their block is final according to the given rule. Logs of
+// blocks reorganised out of the chain before are dropped, while logs delivered
+// already are delivered again, with Removed set, if a deeper reorg drops them.
+// The filterer must implement ChainHeadReader.
+func (c *BoundContract) WatchLogsConfirmed(opts *WatchOpts, finality Finality, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
+	chain, ok := c.filterer.(ChainHeadReader)
+	if !ok {
+		return nil, nil, ErrNoChainHead
+	}
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(WatchOpts)
+	}
+	ctx := ensureContext(opts.Context)
+
+	// Follow the chain head before subscribing to the logs, not to miss any head
+	heads := make(chan *types.Header, 16)
+	headSub, err := chain.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return nil, nil, err
+	}
+	logs, logSub, err := c.WatchLogs(opts, name, query...)
+	if err != nil {
+		headSub.Unsubscribe()
+		return nil, nil, err
+	}
+	head, err := chain.HeaderByNumber(ctx, nil)
+	if err == nil {
+		_, err = finality.Finalized(head)
+	}
+	if err != nil {
+		logSub.Unsubscribe()
+		headSub.Unsubscribe()
+		return nil, nil, err
+	}
+	confirmed := make(chan types.Log, 128)
+
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		defer headSub.Unsubscribe()
+		defer logSub.Unsubscribe()
+
+		var (
+			pending []types.Log              // Logs waiting for their block to become final
+			dropped = make(map[logID]uint64) // Logs dropped as reorganised out, with the final block at the time
+		)
+		for {
+			select {
+			case log := <-logs:
+				if log.Removed {
+					if i := indexOfLog(pending, log); i >= 0 {
+						pending = append(pending[:i], pending[i+1:]...)
+						continue
+					}
+					if _, ok := dropped[idOfLog(log)]; ok {
+						delete(dropped, idOfLog(log))
+						continue
+					}
+					// The log was delivered already, report its removal
+					select {
+					case confirmed <- log:
+					case <-quit:
+						return nil
+					}
+					continue
+				}
+				pending = append(pending, log)
+
+			case head = <-heads:
+			case err := <-headSub.Err():
+				return err
+			case err := <-logSub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+			// Deliver the logs which became final, dropping the reorganised ones
+			final, err := finality.Finalized(head)
+			if err != nil {
+				return err
+			}
+			// The removal of a dropped log arrives with the reorg, before later blocks
+			// become final, so forget the dropped logs once the chain moved past them
+			for id, number := range dropped {
+				if number < final {
+					delete(dropped, id)
+				}
+			}
+			sort.SliceStable(pending, func(i, j int) bool {
+				if pending[i].BlockNumber != pending[j].BlockNumber {
+					return pending[i].BlockNumber < pending[j].BlockNumber
+				}
+				return pending[i].Index < pending[j].Index
+			})
+			var (
+				done    int
+				checked = make(map[common.Hash]bool) // Canonical status of the checked blocks
+			)
+			for ; done < len(pending) && pending[done].BlockNumber <= final; done++ {
+				log := pending[done]
+				ok, known := checked[log.BlockHash]
+				if !known {
+					if ok, err = canonical(ctx, chain, log.BlockNumber, log.BlockHash); err != nil {
+						return err
+					}
+					checked[log.BlockHash] = ok
+				}
+				if !ok {
+					dropped[idOfLog(log)] = final
+					continue
+				}
+				select {
+				case confirmed <- log:
+				case <-quit:
+					return nil
+				}
+			}
+			pending = pending[done:]
+		}
+	})
+	return confirmed, sub, nil
+}
+
+// logID identifies a log in a block.
+type logID struct {
+	block common.Hash
+	index uint
+}
+
+func idOfLog(log types.Log) logID {
+	return logID{log.BlockHash, log.Index}
+}
+
+// indexOfLog returns the position of a log in the list, or -1 if it's missing.
+func indexOfLog(logs []types.Log, log types.Log) int {
+	for i := range logs {
+		if idOfLog(logs[i]) == idOfLog(log) {
+			return i
+		}
+	}
+	return -1
+}
+
 // UnpackLog unpacks a retrieved log into the provided output structure.
 func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log) error {
 	if len(log.Data) > 0 {
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"unicode"
@@ -47,14 +48,20 @@ const (
 // manually maintain hard coded strings that break on runtime.
 func Bind(types []string, abis []string, bytecodes []string, pkg string, lang Lang) (string, error) {
 	// Process each individual contract requested binding
-	contracts := make(map[string]*tmplContract)
-
+	var (
+		contracts = make(map[string]*tmplContract)
+		structs   = make(map[string]*tmplStruct)
+	)
 	for i := 0; i < len(types); i++ {
 		// Parse the actual ABI to generate the binding for
 		evmABI, err := abi.JSON(strings.NewReader(abis[i]))
 		if err != nil {
 			return "", err
 		}
+		// Declare the types of the tuples used by the contract
+		if err := bindStructs(evmABI, lang, structs); err != nil {
+			return "", err
+		}
 		// Strip any whitespace from the JSON ABI
 		strippedABI := strings.Map(func(r rune) rune {
 			if unicode.IsSpace(r) {
@@ -131,12 +138,13 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 	data := &tmplData{
 		Package:   pkg,
 		Contracts: contracts,
+		Structs:   structs,
 	}
 	buffer := new(bytes.Buffer)
 
 	funcs := map[string]interface{}{
-		"bindtype":      bindType[lang],
-		"bindtopictype": bindTopicType[lang],
+		"bindtype":      func(kind abi.Type) string { return bindType[lang](kind, structs) },
+		"bindtopictype": func(kind abi.Type) string { return bindTopicType[lang](kind, structs) },
 		"namedtype":     namedType[lang],
 		"capitalise":    capitalise,
 		"decapitalise":  decapitalise,
@@ -157,9 +165,122 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 	return buffer.String(), nil
 }
 
+// bindStructs declares a struct for each distinct tuple used by the methods and
+// events of a contract. Tuples are visited in a fixed order so the names given
+// to the tuples without a source struct name are stable.
+func bindStructs(evmABI abi.ABI, lang Lang, structs map[string]*tmplStruct) error {
+	var args []abi.Argument
+
+	args = append(args, evmABI.Constructor.Inputs...)
+	methods := make([]string, 0, len(evmABI.Methods))
+	for name := range evmABI.Methods {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	for _, name := range methods {
+		args = append(args, evmABI.Methods[name].Inputs...)
+		args = append(args, evmABI.Methods[name].Outputs...)
+	}
+	events := make([]string, 0, len(evmABI.Events))
+	for name := range evmABI.Events {
+		events = append(events, name)
+	}
+	sort.Strings(events)
+	for _, name := range events {
+		args = append(args, evmABI.Events[name].Inputs...)
+	}
+	for _, arg := range args {
+		if err := bindStruct(arg.Type, lang, structs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindStruct declares the struct of a tuple type, or of the tuples it contains,
+// after the structs of any nested tuples.
+func bindStruct(kind abi.Type, lang Lang, structs map[string]*tmplStruct) error {
+	for kind.T == abi.SliceTy || kind.T == abi.ArrayTy {
+		kind = *kind.Elem
+	}
+	if kind.T != abi.TupleTy {
+		return nil
+	}
+	if lang != LangGo {
+		return fmt.Errorf("tuple %s is only supported in Go bindings", kind)
+	}
+	id := structID(kind)
+	if structs[id] != nil {
+		return nil
+	}
+	var fields []*tmplField
+	for i, elem := range kind.TupleElems {
+		if err := bindStruct(*elem, lang, structs); err != nil {
+			return err
+		}
+		field := &tmplField{
+			Type:    bindTypeGo(*elem, structs),
+			Name:    capitalise(kind.TupleRawNames[i]),
+			SolKind: *elem,
+		}
+		// The abi package maps fields to struct fields by capitalising them,
+		// tag the fields the camel case naming diverges from
+		if name := strings.TrimLeft(kind.TupleRawNames[i], "_"); strings.ToUpper(name[:1])+name[1:] != field.Name {
+			field.Tag = fmt.Sprintf("`abi:\"%s\"`", kind.TupleRawNames[i])
+		}
+		fields = append(fields, field)
+	}
+	// Name the struct after its source struct, making sure distinct tuples don't
+	// end up with the same name
+	base := capitalise(kind.TupleRawName)
+	if base == "" {
+		base = "Struct"
+	}
+	name := base
+	for i := 0; ; i++ {
+		if kind.TupleRawName == "" || i > 0 {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+		taken := false
+		for _, s := range structs {
+			if s.Name == name {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			break
+		}
+	}
+	structs[id] = &tmplStruct{Name: name, Fields: fields}
+	return nil
+}
+
+// structID returns an identifier of a tuple type which is distinct for tuples
+// differing in field names, types or source struct name.
+func structID(kind abi.Type) string {
+	id := kind.TupleRawName + "("
+	for i, elem := range kind.TupleElems {
+		if i > 0 {
+			id += ","
+		}
+		inner := *elem
+		for inner.T == abi.SliceTy || inner.T == abi.ArrayTy {
+			inner = *inner.Elem
+		}
+		if inner.T == abi.TupleTy {
+			id += structID(inner) + elem.String()[len(inner.String()):]
+		} else {
+			id += elem.String()
+		}
+		id += " " + kind.TupleRawNames[i]
+	}
+	return id + ")"
+}
+
 // bindType is a set of type binders that convert Solidity types to some supported
 // programming language types.
-var bindType = map[Lang]func(kind abi.Type) string{
+var bindType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTypeGo,
 	LangJava: bindTypeJava,
 }
@@ -199,8 +320,17 @@ func arrayBindingGo(inner string, arraySizes []string) string {
 // bindTypeGo converts a Solidity type to a Go one. Since there is no clear mapping
 // from all Solidity types to Go ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. *big.Int).
-func bindTypeGo(kind abi.Type) string {
+func bindTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
 	stringKind := kind.String()
+
+	// Tuples are bound to the structs declared for them
+	inner := kind
+	for inner.T == abi.SliceTy || inner.T == abi.ArrayTy {
+		inner = *inner.Elem
+	}
+	if inner.T == abi.TupleTy {
+		return arrayBindingGo(wrapArray(stringKind, len(inner.String()), structs[structID(inner)].Name))
+	}
 	innerLen, innerMapping := bindUnnestedTypeGo(stringKind)
 	return arrayBindingGo(wrapArray(stringKind, innerLen, innerMapping))
 }
@@ -247,7 +377,7 @@ func arrayBindingJava(inner string, arraySizes []string) string {
 // bindTypeJava converts a Solidity type to a Java one. Since there is no clear mapping
 // from all Solidity types to Java ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. BigDecimal).
-func bindTypeJava(kind abi.Type) string {
+func bindTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
 	stringKind := kind.String()
 	innerLen, innerMapping := bindUnnestedTypeJava(stringKind)
 	return arrayBindingJava(wrapArray(stringKind, innerLen, innerMapping))
@@ -310,16 +440,16 @@ func bindUnnestedTypeJava(stringKind string) (int, string) {
 
 // bindTopicType is a set of type binders that convert Solidity types to some
 // supported programming language topic types.
-var bindTopicType = map[Lang]func(kind abi.Type) string{
+var bindTopicType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTopicTypeGo,
 	LangJava: bindTopicTypeJava,
 }
 
 // bindTypeGo converts a Solidity topic type to a Go one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeGo(kind abi.Type) string {
-	bound := bindTypeGo(kind)
-	if bound == "string" || bound == "[]byte" {
+func bindTopicTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	bound := bindTypeGo(kind, structs)
+	if bound == "string" || bound == "[]byte" || kind.T == abi.TupleTy {
 		bound = "common.Hash"
 	}
 	return bound
@@ -327,8 +457,8 @@ func bindTopicTypeGo(kind abi.Type) string {
 
 // bindTypeGo converts a Solidity topic type to a Java one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeJava(kind abi.Type) string {
-	bound := bindTypeJava(kind)
+func bindTopicTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
+	bound := bindTypeJava(kind, structs)
 	if bound == "String" || bound == "Bytes" {
 		bound = "Hash"
 	}
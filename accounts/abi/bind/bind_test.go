@@ -809,6 +809,64 @@ var bindTests = []struct {
 			}
 		`,
 	},
+	// Tests that tuples and nested dynamic arrays of them bind to generated structs
+	{
+		`Tuple`,
+		`
+		pragma solidity ^0.5.11;
+		pragma experimental ABIEncoderV2;
+
+		contract Tuple {
+			struct S { uint a; uint[] b; T[] c; }
+			struct T { uint x; uint y; }
+
+			event TupleEvent(S a, T[2][] b, T[][2] c);
+
+			function func1(S memory a, T[2][] memory b, T[][2] memory c) public pure returns (S memory, T[2][] memory, T[][2] memory) {
+				return (a, b, c);
+			}
+			function func2(S memory a, T[2][] memory b, T[][2] memory c) public {
+				emit TupleEvent(a, b, c);
+			}
+		}
+		`,
+		``,
+		`[{"anonymous":false,"inputs":[{"components":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256[]","name":"b","type":"uint256[]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[]","name":"c","type":"tuple[]"}],"internalType":"struct Tuple.S","name":"a","type":"tuple","indexed":false},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[2][]","name":"b","type":"tuple[2][]","indexed":false},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[][2]","name":"c","type":"tuple[][2]","indexed":false}],"name":"TupleEvent","type":"event"},{"constant":true,"inputs":[{"components":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256[]","name":"b","type":"uint256[]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[]","name":"c","type":"tuple[]"}],"internalType":"struct Tuple.S","name":"a","type":"tuple"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[2][]","name":"b","type":"tuple[2][]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[][2]","name":"c","type":"tuple[][2]"}],"name":"func1","outputs":[{"components":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256[]","name":"b","type":"uint256[]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[]","name":"c","type":"tuple[]"}],"internalType":"struct Tuple.S","name":"","type":"tuple"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[2][]","name":"","type":"tuple[2][]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[][2]","name":"","type":"tuple[][2]"}],"payable":false,"stateMutability":"pure","type":"function"},{"constant":false,"inputs":[{"components":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256[]","name":"b","type":"uint256[]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[]","name":"c","type":"tuple[]"}],"internalType":"struct Tuple.S","name":"a","type":"tuple"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[2][]","name":"b","type":"tuple[2][]"},{"components":[{"internalType":"uint256","name":"x","type":"uint256"},{"internalType":"uint256","name":"y","type":"uint256"}],"internalType":"struct Tuple.T[][2]","name":"c","type":"tuple[][2]"}],"name":"func2","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"}]`,
+		`
+			parsed, err := abi.JSON(strings.NewReader(TupleABI))
+			if err != nil {
+				t.Fatalf("Failed to parse binding ABI: %v", err)
+			}
+			tuple, err := NewTuple(common.Address{}, nil)
+			if err != nil {
+				t.Fatalf("Failed to bind contract: %v", err)
+			}
+			var _ func(*bind.CallOpts, S, [][2]T, [2][]T) (S, [][2]T, [2][]T, error) = tuple.Func1
+			var _ func(*bind.TransactOpts, S, [][2]T, [2][]T) (*types.Transaction, error) = tuple.Func2
+
+			a := S{A: big.NewInt(1), B: []*big.Int{big.NewInt(2), big.NewInt(3)}, C: []T{{X: big.NewInt(4), Y: big.NewInt(5)}}}
+			b := [][2]T{{{X: big.NewInt(6), Y: big.NewInt(7)}, {X: big.NewInt(8), Y: big.NewInt(9)}}}
+			c := [2][]T{{{X: big.NewInt(10), Y: big.NewInt(11)}}, {}}
+			_ = TupleTupleEvent{A: a, B: b, C: c}
+
+			// func1 returns its arguments, so its results are encoded the same as its inputs
+			input, err := parsed.Pack("func1", a, b, c)
+			if err != nil {
+				t.Fatalf("Failed to pack tuples: %v", err)
+			}
+			var (
+				ra S
+				rb [][2]T
+				rc [2][]T
+			)
+			if err := parsed.Unpack(&[]interface{}{&ra, &rb, &rc}, "func1", input[4:]); err != nil {
+				t.Fatalf("Failed to unpack tuples: %v", err)
+			}
+			if !reflect.DeepEqual(ra, a) || !reflect.DeepEqual(rb, b) || !reflect.DeepEqual(rc, c) {
+				t.Fatalf("Tuple mismatch: have %v %v %v, want %v %v %v", ra, rb, rc, a, b, c)
+			}
+		`,
+	},
 }
 
 // Tests that packages generated by the binder can be successfully compiled and
@@ -22,6 +22,7 @@ import "github.com/eeefan/dpeth/accounts/abi"
 type tmplData struct {
 	Package   string                   // Name of the package to place the generated file in
 	Contracts map[string]*tmplContract // List of contracts to generate into this file
+	Structs   map[string]*tmplStruct   // Structs of the tuples used by the contracts
 }
 
 // tmplContract contains the data needed to generate an individual contract binding.
@@ -49,6 +50,20 @@ type tmplEvent struct {
 	Normalized abi.Event // Normalized version of the parsed fields
 }
 
+// tmplField is a field of a struct generated for a tuple.
+type tmplField struct {
+	Type    string   // Field type in the binding language
+	Name    string   // Field name converted from the raw tuple field name
+	Tag     string   // Optional tag mapping the field to the raw tuple field name
+	SolKind abi.Type // Raw abi type information
+}
+
+// tmplStruct is a struct generated for a tuple.
+type tmplStruct struct {
+	Name   string       // Source struct name, or an auto-generated one for older compilers
+	Fields []*tmplField // Struct fields in tuple order
+}
+
 // tmplSource is language to template mapping containing all the supported
 // programming languages the package can generate to.
 var tmplSource = map[Lang]string{
@@ -64,6 +79,14 @@ const tmplSourceGo = `
 
 package {{.Package}}
 
+{{range .Structs}}
+	// {{.Name}} is an auto generated low-level Go binding around a user-defined struct.
+	type {{.Name}} struct {
+	{{range .Fields}}
+		{{.Name}} {{.Type}} {{.Tag}}{{end}}
+	}
+{{end}}
+
 {{range $contract := .Contracts}}
 	// {{.Type}}ABI is the input ABI used to generate the binding from.
 	const {{.Type}}ABI = "{{.InputABI}}"
@@ -415,6 +438,50 @@ package {{.Package}}
 				}
 			}), nil
 		}
+
+		// WatchConfirmed{{.Normalized.Name}} is a free log subscription operation binding the contract event 0x{{printf "%x" .Original.Id}},
+		// delivering the events once final according to finality. Events removed by a reorg after delivery are delivered
+		// again with Raw.Removed set.
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) WatchConfirmed{{.Normalized.Name}}(opts *bind.WatchOpts, finality bind.Finality, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type}}{{end}}{{end}}) (event.Subscription, error) {
+			{{range .Normalized.Inputs}}
+			{{if .Indexed}}var {{.Name}}Rule []interface{}
+			for _, {{.Name}}Item := range {{.Name}} {
+				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
+			}{{end}}{{end}}
+
+			logs, sub, err := _{{$contract.Type}}.contract.WatchLogsConfirmed(opts, finality, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
+			if err != nil {
+				return nil, err
+			}
+			return event.NewSubscription(func(quit <-chan struct{}) error {
+				defer sub.Unsubscribe()
+				for {
+					select {
+					case log := <-logs:
+						// Log confirmed or removed, parse the event and forward to the user
+						event := new({{$contract.Type}}{{.Normalized.Name}})
+						if err := _{{$contract.Type}}.contract.UnpackLog(event, "{{.Original.Name}}", log); err != nil {
+							return err
+						}
+						event.Raw = log
+
+						select {
+						case sink <- event:
+						case err := <-sub.Err():
+							return err
+						case <-quit:
+							return nil
+						}
+					case err := <-sub.Err():
+						return err
+					case <-quit:
+						return nil
+					}
+				}
+			}), nil
+		}
  	{{end}}
 {{end}}
 `
@@ -18,14 +18,74 @@ package bind
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/log"
 )
 
+// Finality decides which blocks are final, i.e. not expected to be reorganised
+// out of the chain anymore.
+type Finality interface {
+	// Finalized returns the number of the last final block, given the chain head.
+	Finalized(head *types.Header) (uint64, error)
+}
+
+// Confirmations is the finality of blocks with the given number of blocks on top
+// of them, counting the block itself: a block with 1 confirmation is mined.
+type Confirmations uint64
+
+// Finalized implements Finality.
+func (n Confirmations) Finalized(head *types.Header) (uint64, error) {
+	number := head.Number.Uint64()
+	switch {
+	case n <= 1:
+		return number, nil
+	case number+1 < uint64(n):
+		return 0, nil
+	default:
+		return number + 1 - uint64(n), nil
+	}
+}
+
+// AlienConfirmed is the finality of alien chains: blocks are final once they are
+// confirmed by the signers, as recorded in the extra-data of the chain head.
+var AlienConfirmed Finality = alienFinality{}
+
+type alienFinality struct{}
+
+// Finalized implements Finality.
+func (alienFinality) Finalized(head *types.Header) (uint64, error) {
+	if head.Number.Sign() == 0 {
+		return 0, nil
+	}
+	extra, err := alientypes.ParseHeaderExtra(head)
+	if err != nil {
+		return 0, err
+	}
+	return extra.ConfirmedBlockNumber, nil
+}
+
+// headerReader is the part of the backends needed to check the canonical chain.
+type headerReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// canonical checks whether the block with the given number and hash is in the
+// canonical chain.
+func canonical(ctx context.Context, b headerReader, number uint64, hash common.Hash) (bool, error) {
+	header, err := b.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return false, err
+	}
+	return header.Hash() == hash, nil
+}
+
 // WaitMined waits for tx to be mined on the blockchain.
 // It stops waiting when the context is canceled.
 func WaitMined(ctx context.Context, b DeployBackend, tx *types.Transaction) (*types.Receipt, error) {
@@ -74,3 +134,55 @@ func WaitDeployed(ctx context.Context, b DeployBackend, tx *types.Transaction) (
 	}
 	return receipt.ContractAddress, err
 }
+
+// WaitMinedConfirmed waits for tx to be mined in a block which is final according
+// to the given rule. If the block is reorganised out of the chain before, it
+// keeps waiting for the transaction to be mined again. It stops waiting when the
+// context is canceled.
+func WaitMinedConfirmed(ctx context.Context, b ConfirmBackend, finality Finality, tx *types.Transaction) (*types.Receipt, error) {
+	queryTicker := time.NewTicker(time.Second)
+	defer queryTicker.Stop()
+
+	logger := log.New("hash", tx.Hash())
+	for {
+		receipt, err := b.TransactionReceipt(ctx, tx.Hash())
+		if receipt != nil {
+			if receipt.BlockNumber == nil {
+				return nil, errors.New("receipt lacks inclusion information")
+			}
+			final, err := isFinal(ctx, b, finality, receipt.BlockNumber.Uint64(), receipt.BlockHash)
+			if final {
+				return receipt, nil
+			}
+			if err != nil {
+				logger.Trace("Confirmation check failed", "err", err)
+			} else {
+				logger.Trace("Transaction not yet confirmed", "number", receipt.BlockNumber)
+			}
+		} else if err != nil {
+			logger.Trace("Receipt retrieval failed", "err", err)
+		} else {
+			logger.Trace("Transaction not yet mined")
+		}
+		// Wait for the next round.
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-queryTicker.C:
+		}
+	}
+}
+
+// isFinal checks whether the block with the given number and hash is final and
+// in the canonical chain.
+func isFinal(ctx context.Context, b ConfirmBackend, finality Finality, number uint64, hash common.Hash) (bool, error) {
+	head, err := b.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	final, err := finality.Finalized(head)
+	if err != nil || number > final {
+		return false, err
+	}
+	return canonical(ctx, b, number, hash)
+}
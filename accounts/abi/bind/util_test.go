@@ -19,15 +19,19 @@ package bind_test
 import (
 	"context"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/eeefan/dpeth/accounts/abi"
 	"github.com/eeefan/dpeth/accounts/abi/bind"
 	"github.com/eeefan/dpeth/accounts/abi/bind/backends"
 	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
 	"github.com/eeefan/dpeth/core"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/rlp"
 )
 
 var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
@@ -90,3 +94,151 @@ func TestWaitDeployed(t *testing.T) {
 		}
 	}
 }
+
+func TestFinality(t *testing.T) {
+	head := &types.Header{Number: big.NewInt(10)}
+	for _, tt := range []struct {
+		confirmations bind.Confirmations
+		want          uint64
+	}{{0, 10}, {1, 10}, {2, 9}, {11, 0}, {12, 0}} {
+		if final, _ := tt.confirmations.Finalized(head); final != tt.want {
+			t.Errorf("%d confirmations: final block mismatch: have %d, want %d", tt.confirmations, final, tt.want)
+		}
+	}
+	// Alien heads carry the confirmed block number in their extra-data
+	empty := rlp.RawValue{0xc0}
+	extra, err := rlp.EncodeToBytes(alientypes.HeaderExtra{
+		CurrentBlockConfirmations: empty,
+		CurrentBlockVotes:         empty,
+		CurrentBlockProposals:     empty,
+		CurrentBlockDeclares:      empty,
+		ModifyPredecessorVotes:    empty,
+		PerBlockReward:            new(big.Int),
+		ConfirmedBlockNumber:      7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head.Extra = append(append(make([]byte, 32), extra...), make([]byte, 65)...)
+	if final, err := bind.AlienConfirmed.Finalized(head); err != nil || final != 7 {
+		t.Errorf("alien final block mismatch: have %d (%v), want 7", final, err)
+	}
+	if final, err := bind.AlienConfirmed.Finalized(&types.Header{Number: big.NewInt(0)}); err != nil || final != 0 {
+		t.Errorf("alien genesis final block mismatch: have %d (%v), want 0", final, err)
+	}
+}
+
+func TestWaitMinedConfirmed(t *testing.T) {
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		crypto.PubkeyToAddress(testKey.PublicKey): {Balance: big.NewInt(10000000000)},
+	})
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	backend.SendTransaction(context.Background(), tx)
+	backend.Commit()
+	backend.Commit()
+
+	// Two confirmations aren't enough
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if _, err := bind.WaitMinedConfirmed(ctx, backend, bind.Confirmations(3), tx); err != context.DeadlineExceeded {
+		t.Fatalf("unconfirmed transaction error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	backend.Commit()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	receipt, err := bind.WaitMinedConfirmed(ctx, backend, bind.Confirmations(3), tx)
+	if err != nil {
+		t.Fatalf("failed to wait for confirmations: %v", err)
+	}
+	if receipt.TxHash != tx.Hash() || receipt.BlockNumber.Uint64() != 1 {
+		t.Errorf("receipt mismatch: have tx %x in block %d, want %x in block 1", receipt.TxHash, receipt.BlockNumber, tx.Hash())
+	}
+}
+
+// pingerCode deploys a contract emitting Ping() whenever called, the topic is
+// filled in at offset 13.
+const pingerCode = "6027600c60003960276000f3" + "7f0000000000000000000000000000000000000000000000000000000000000000" + "60006000a100"
+
+const pingerABI = `[{"type":"event","name":"Ping","inputs":[]}]`
+
+func TestWatchLogsConfirmed(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		from    = crypto.PubkeyToAddress(testKey.PublicKey)
+		backend = backends.NewSimulatedBackend(core.GenesisAlloc{from: {Balance: big.NewInt(10000000000)}})
+	)
+	parsed, err := abi.JSON(strings.NewReader(pingerABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := common.FromHex(pingerCode)
+	copy(code[13:45], parsed.Events["Ping"].Id().Bytes())
+
+	deploy, _ := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), code), types.HomesteadSigner{}, testKey)
+	backend.SendTransaction(ctx, deploy)
+	backend.Commit()
+	receipt, _ := backend.TransactionReceipt(ctx, deploy.Hash())
+
+	contract := bind.NewBoundContract(receipt.ContractAddress, parsed, backend, backend, backend)
+	logs, sub, err := contract.WatchLogsConfirmed(nil, bind.Confirmations(2), "Ping")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	ping := func() {
+		nonce, _ := backend.PendingNonceAt(ctx, from)
+		tx, _ := types.SignTx(types.NewTransaction(nonce, receipt.ContractAddress, big.NewInt(0), 50000, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+		backend.SendTransaction(ctx, tx)
+	}
+	expect := func(removed bool, number uint64) types.Log {
+		select {
+		case log := <-logs:
+			if log.Removed != removed || log.BlockNumber != number {
+				t.Fatalf("log mismatch: have removed %v in block %d, want removed %v in block %d", log.Removed, log.BlockNumber, removed, number)
+			}
+			return log
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for log of block %d", number)
+		}
+		return types.Log{}
+	}
+	expectNone := func() {
+		select {
+		case log := <-logs:
+			t.Fatalf("unexpected log: %+v", log)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	// Emit a log in block 2 and reorg it out before it gets confirmed
+	deployed, _ := backend.HeaderByNumber(ctx, big.NewInt(1))
+	ping()
+	backend.Commit()
+	expectNone()
+
+	backend.Fork(ctx, deployed.Hash())
+	backend.Commit()
+	backend.Commit()
+	expectNone()
+
+	// Emit a log in block 4 of the side chain and confirm it
+	ping()
+	backend.Commit()
+	expectNone()
+	backend.Commit()
+	log := expect(false, 4)
+
+	// Reorg the confirmed log out with a deeper fork
+	parent, _ := backend.HeaderByNumber(ctx, big.NewInt(3))
+	backend.Fork(ctx, parent.Hash())
+	for i := 0; i < 3; i++ {
+		backend.Commit()
+	}
+	if removed := expect(true, 4); removed.BlockHash != log.BlockHash || removed.TxHash != log.TxHash {
+		t.Errorf("removed log mismatch: have %x/%x, want %x/%x", removed.BlockHash, removed.TxHash, log.BlockHash, log.TxHash)
+	}
+	expectNone()
+}
@@ -325,7 +325,7 @@ func TestPack(t *testing.T) {
 			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000006666f6f6261720000000000000000000000000000000000000000000000000000"),
 		},
 	} {
-		typ, err := NewType(test.typ)
+		typ, err := NewType(test.typ, nil)
 		if err != nil {
 			t.Fatalf("%v failed. Unexpected parse error: %v", i, err)
 		}
@@ -441,3 +441,122 @@ func TestPackNumber(t *testing.T) {
 		}
 	}
 }
+
+// abiEncoderV2 is the solc output for the ABIEncoderV2 example of the Solidity
+// documentation:
+//
+//	struct S { uint a; uint[] b; T[] c; }
+//	struct T { uint x; uint y; }
+//	function f(S memory s, T memory t, uint a) public;
+//	function g(uint[][] memory, string[] memory) public;
+const abiEncoderV2 = `[
+	{"constant":false,"inputs":[{"components":[{"name":"a","type":"uint256"},{"name":"b","type":"uint256[]"},{"components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}],"name":"c","type":"tuple[]"}],"name":"s","type":"tuple"},{"components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}],"name":"t","type":"tuple"},{"name":"a","type":"uint256"}],"name":"f","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},
+	{"constant":false,"inputs":[{"name":"","type":"uint256[][]"},{"name":"","type":"string[]"}],"name":"g","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"}
+]`
+
+func TestPackTuples(t *testing.T) {
+	abi, err := JSON(strings.NewReader(abiEncoderV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type T struct {
+		X *big.Int
+		Y *big.Int
+	}
+	type S struct {
+		A *big.Int
+		B []*big.Int
+		C []T
+	}
+	tests := []struct {
+		method string
+		sig    string
+		id     string
+		args   []interface{}
+		packed string
+	}{
+		{
+			method: "f",
+			sig:    "f((uint256,uint256[],(uint256,uint256)[]),(uint256,uint256),uint256)",
+			args: []interface{}{
+				S{big.NewInt(1), []*big.Int{big.NewInt(2), big.NewInt(3)}, []T{{big.NewInt(4), big.NewInt(5)}, {big.NewInt(6), big.NewInt(7)}}},
+				&T{big.NewInt(8), big.NewInt(9)},
+				big.NewInt(10),
+			},
+			packed: "0000000000000000000000000000000000000000000000000000000000000080" + // offset of s
+				"0000000000000000000000000000000000000000000000000000000000000008" + // t.x
+				"0000000000000000000000000000000000000000000000000000000000000009" + // t.y
+				"000000000000000000000000000000000000000000000000000000000000000a" + // a
+				"0000000000000000000000000000000000000000000000000000000000000001" + // s.a
+				"0000000000000000000000000000000000000000000000000000000000000060" + // offset of s.b
+				"00000000000000000000000000000000000000000000000000000000000000c0" + // offset of s.c
+				"0000000000000000000000000000000000000000000000000000000000000002" + // count of s.b
+				"0000000000000000000000000000000000000000000000000000000000000002" + // s.b[0]
+				"0000000000000000000000000000000000000000000000000000000000000003" + // s.b[1]
+				"0000000000000000000000000000000000000000000000000000000000000002" + // count of s.c
+				"0000000000000000000000000000000000000000000000000000000000000004" + // s.c[0].x
+				"0000000000000000000000000000000000000000000000000000000000000005" + // s.c[0].y
+				"0000000000000000000000000000000000000000000000000000000000000006" + // s.c[1].x
+				"0000000000000000000000000000000000000000000000000000000000000007", // s.c[1].y
+		},
+		{
+			method: "g",
+			sig:    "g(uint256[][],string[])",
+			id:     "2289b18c",
+			args: []interface{}{
+				[][]*big.Int{{big.NewInt(1), big.NewInt(2)}, {big.NewInt(3)}},
+				[]string{"one", "two", "three"},
+			},
+			packed: "0000000000000000000000000000000000000000000000000000000000000040" + // offset of [[1, 2], [3]]
+				"0000000000000000000000000000000000000000000000000000000000000140" + // offset of ["one", "two", "three"]
+				"0000000000000000000000000000000000000000000000000000000000000002" + // count for [[1, 2], [3]]
+				"0000000000000000000000000000000000000000000000000000000000000040" + // offset of [1, 2]
+				"00000000000000000000000000000000000000000000000000000000000000a0" + // offset of [3]
+				"0000000000000000000000000000000000000000000000000000000000000002" + // count for [1, 2]
+				"0000000000000000000000000000000000000000000000000000000000000001" + // encoding of 1
+				"0000000000000000000000000000000000000000000000000000000000000002" + // encoding of 2
+				"0000000000000000000000000000000000000000000000000000000000000001" + // count for [3]
+				"0000000000000000000000000000000000000000000000000000000000000003" + // encoding of 3
+				"0000000000000000000000000000000000000000000000000000000000000003" + // count for ["one", "two", "three"]
+				"0000000000000000000000000000000000000000000000000000000000000060" + // offset for "one"
+				"00000000000000000000000000000000000000000000000000000000000000a0" + // offset for "two"
+				"00000000000000000000000000000000000000000000000000000000000000e0" + // offset for "three"
+				"0000000000000000000000000000000000000000000000000000000000000003" + // count for "one"
+				"6f6e650000000000000000000000000000000000000000000000000000000000" + // encoding of "one"
+				"0000000000000000000000000000000000000000000000000000000000000003" + // count for "two"
+				"74776f0000000000000000000000000000000000000000000000000000000000" + // encoding of "two"
+				"0000000000000000000000000000000000000000000000000000000000000005" + // count for "three"
+				"7468726565000000000000000000000000000000000000000000000000000000", // encoding of "three"
+		},
+	}
+	for i, test := range tests {
+		method := abi.Methods[test.method]
+		if sig := method.Sig(); sig != test.sig {
+			t.Errorf("test %d: signature mismatch: have %s, want %s", i, sig, test.sig)
+		}
+		if test.id != "" && common.Bytes2Hex(method.Id()) != test.id {
+			t.Errorf("test %d: id mismatch: have %x, want %s", i, method.Id(), test.id)
+		}
+		packed, err := abi.Pack(test.method, test.args...)
+		if err != nil {
+			t.Fatalf("test %d: failed to pack: %v", i, err)
+		}
+		if want := append(method.Id(), common.Hex2Bytes(test.packed)...); !bytes.Equal(packed, want) {
+			t.Errorf("test %d: packed mismatch:\nhave %x\nwant %x", i, packed, want)
+		}
+		// Unpacking the inputs must give the packed values back
+		values, err := method.Inputs.UnpackValues(packed[4:])
+		if err != nil {
+			t.Fatalf("test %d: failed to unpack: %v", i, err)
+		}
+		for j, value := range values {
+			out := reflect.New(reflect.TypeOf(indirect(reflect.ValueOf(test.args[j])).Interface()))
+			if err := set(out.Elem(), reflect.ValueOf(value), method.Inputs[j]); err != nil {
+				t.Fatalf("test %d: failed to assign argument %d: %v", i, j, err)
+			}
+			if want := indirect(reflect.ValueOf(test.args[j])).Interface(); !reflect.DeepEqual(out.Elem().Interface(), want) {
+				t.Errorf("test %d: argument %d mismatch: have %v, want %v", i, j, out.Elem().Interface(), want)
+			}
+		}
+	}
+}
@@ -81,12 +81,51 @@ func set(dst, src reflect.Value, output Argument) error {
 		dst.Set(src)
 	case dstType.Kind() == reflect.Ptr:
 		return set(dst.Elem(), src, output)
+	case dstType.Kind() == reflect.Struct && srcType.Kind() == reflect.Struct:
+		return setStruct(dst, src, output)
+	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(dstType, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := set(slice.Index(i), src.Index(i), output); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case dstType.Kind() == reflect.Array && srcType.Kind() == reflect.Array && dst.Len() == src.Len():
+		for i := 0; i < src.Len(); i++ {
+			if err := set(dst.Index(i), src.Index(i), output); err != nil {
+				return err
+			}
+		}
 	default:
 		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
 	}
 	return nil
 }
 
+// setStruct assigns the fields of an unpacked tuple to the fields of dst they
+// map to, by name or abi tag.
+func setStruct(dst, src reflect.Value, output Argument) error {
+	names := make([]string, src.NumField())
+	for i := range names {
+		names[i] = src.Type().Field(i).Tag.Get("json")
+	}
+	fieldmap, err := mapTupleToStructFields(names, dst)
+	if err != nil {
+		return err
+	}
+	for i, name := range names {
+		field := dst.FieldByName(fieldmap[name])
+		if !field.IsValid() {
+			return fmt.Errorf("abi: cannot unmarshal %v in to %v: missing field for %s", src.Type(), dst.Type(), name)
+		}
+		if err := set(field, src.Field(i), output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // requireAssignable assures that `dest` is a pointer and it's not an interface.
 func requireAssignable(dst, src reflect.Value) error {
 	if dst.Kind() != reflect.Ptr && dst.Kind() != reflect.Interface {
@@ -210,3 +249,13 @@ func mapAbiToStructFields(args Arguments, value reflect.Value) (map[string]strin
 
 	return abi2struct, nil
 }
+
+// mapTupleToStructFields maps the raw field names of a tuple to the fields of
+// a struct, the same way arguments are.
+func mapTupleToStructFields(names []string, value reflect.Value) (map[string]string, error) {
+	args := make(Arguments, len(names))
+	for i, name := range names {
+		args[i].Name = name
+	}
+	return mapAbiToStructFields(args, value)
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Field is a typed value prepared for display, with normalised atomic values
+// (checksummed addresses, decimal integers) and the members of structs and arrays
+// as nested fields.
+type Field struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"` // string for atomic values, []*Field otherwise
+}
+
+// Format returns the domain and the message of the typed data prepared for
+// display, validating the values along the way.
+func (td *TypedData) Format() ([]*Field, error) {
+	if err := td.Validate(); err != nil {
+		return nil, err
+	}
+	domain, err := td.formatStruct(DomainType, td.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %v", err)
+	}
+	message, err := td.formatStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, err
+	}
+	return []*Field{
+		{Name: "domain", Type: DomainType, Value: domain},
+		{Name: "message", Type: td.PrimaryType, Value: message},
+	}, nil
+}
+
+// formatStruct formats the fields of a struct value in declaration order.
+func (td *TypedData) formatStruct(typ string, data map[string]interface{}) ([]*Field, error) {
+	var fields []*Field
+	for _, field := range td.Types[typ] {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing field %s in %s value", field.Name, typ)
+		}
+		formatted, err := td.formatValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s of %s: %v", field.Name, typ, err)
+		}
+		fields = append(fields, &Field{Name: field.Name, Type: field.Type, Value: formatted})
+	}
+	return fields, nil
+}
+
+// formatValue formats a single value of the given type.
+func (td *TypedData) formatValue(typ string, value interface{}) (interface{}, error) {
+	if match := arrayRegexp.FindStringSubmatch(typ); match != nil {
+		items, err := arrayItems(match[2], value)
+		if err != nil {
+			return nil, err
+		}
+		fields := make([]*Field, 0, len(items))
+		for i, item := range items {
+			formatted, err := td.formatValue(match[1], item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			fields = append(fields, &Field{Name: fmt.Sprintf("[%d]", i), Type: match[1], Value: formatted})
+		}
+		return fields, nil
+	}
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		return td.formatStruct(typ, data)
+	}
+	_, str, err := parseAtomic(typ, value)
+	return str, err
+}
+
+// Pprint returns an indented textual representation of the formatted fields.
+func Pprint(fields []*Field) string {
+	var buffer bytes.Buffer
+	pprint(&buffer, fields, 0)
+	return buffer.String()
+}
+
+func pprint(buffer *bytes.Buffer, fields []*Field, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, field := range fields {
+		if nested, ok := field.Value.([]*Field); ok {
+			fmt.Fprintf(buffer, "%s%s [%s]\n", indent, field.Name, field.Type)
+			pprint(buffer, nested, depth+1)
+			continue
+		}
+		fmt.Fprintf(buffer, "%s%s [%s]: %v\n", indent, field.Name, field.Type, field.Value)
+	}
+}
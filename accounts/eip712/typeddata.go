@@ -0,0 +1,458 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package eip712 implements the hashing of typed structured data as specified by
+// EIP-712, which is signed instead of opaque bytes so that signers can show and
+// check what they are signing.
+package eip712
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/common/math"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// DomainType is the name of the type describing the signing domain.
+const DomainType = "EIP712Domain"
+
+// maxSafeFloat is the largest integer a JSON number decoded into a float64 is
+// guaranteed to represent exactly, larger values must be passed as strings.
+const maxSafeFloat = 1 << 53
+
+var (
+	// typeNameRegexp matches the valid names of struct types.
+	typeNameRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+	// arrayRegexp matches array types, capturing the element type and length.
+	arrayRegexp = regexp.MustCompile(`^(.+)\[([0-9]*)\]$`)
+
+	// intRegexp and bytesRegexp match the sized integer and byte array types.
+	intRegexp   = regexp.MustCompile(`^(u?int)([0-9]*)$`)
+	bytesRegexp = regexp.MustCompile(`^bytes([0-9]+)$`)
+)
+
+// Type is a single named field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types are the struct types referenced by typed data, by name.
+type Types map[string][]Type
+
+// Domain is the signing domain separating the messages of different dapps and
+// chains, so that a signature is only valid for the intended one.
+type Domain struct {
+	Name              string                `json:"name,omitempty"`
+	Version           string                `json:"version,omitempty"`
+	ChainId           *math.HexOrDecimal256 `json:"chainId,omitempty"`
+	VerifyingContract string                `json:"verifyingContract,omitempty"`
+	Salt              string                `json:"salt,omitempty"`
+}
+
+// Map returns the domain as a struct value of the EIP712Domain type.
+func (d *Domain) Map() map[string]interface{} {
+	m := make(map[string]interface{})
+	if d.Name != "" {
+		m["name"] = d.Name
+	}
+	if d.Version != "" {
+		m["version"] = d.Version
+	}
+	if d.ChainId != nil {
+		m["chainId"] = (*big.Int)(d.ChainId)
+	}
+	if d.VerifyingContract != "" {
+		m["verifyingContract"] = d.VerifyingContract
+	}
+	if d.Salt != "" {
+		m["salt"] = d.Salt
+	}
+	return m
+}
+
+// TypedData is a structured message to sign along with its types and domain, in
+// the format of eth_signTypedData.
+type TypedData struct {
+	Types       Types                  `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      Domain                 `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// Validate checks that all the types are well formed and only reference known
+// types, and that the domain and primary types are defined.
+func (td *TypedData) Validate() error {
+	if _, ok := td.Types[DomainType]; !ok {
+		return fmt.Errorf("missing %s type", DomainType)
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return fmt.Errorf("undefined primary type %q", td.PrimaryType)
+	}
+	for name, fields := range td.Types {
+		if !typeNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		seen := make(map[string]bool)
+		for _, field := range fields {
+			if field.Name == "" {
+				return fmt.Errorf("unnamed field in type %s", name)
+			}
+			if seen[field.Name] {
+				return fmt.Errorf("duplicate field %s in type %s", field.Name, name)
+			}
+			seen[field.Name] = true
+
+			base := field.Type
+			for {
+				match := arrayRegexp.FindStringSubmatch(base)
+				if match == nil {
+					break
+				}
+				base = match[1]
+			}
+			if _, ok := td.Types[base]; !ok && !isAtomic(base) {
+				return fmt.Errorf("unknown type %q of field %s in type %s", field.Type, field.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// dependencies appends the struct types the given type references to found,
+// including itself, recursively.
+func (td *TypedData) dependencies(typ string, found []string) []string {
+	if match := arrayRegexp.FindStringSubmatch(typ); match != nil {
+		return td.dependencies(match[1], found)
+	}
+	if _, ok := td.Types[typ]; !ok {
+		return found
+	}
+	for _, dep := range found {
+		if dep == typ {
+			return found
+		}
+	}
+	found = append(found, typ)
+	for _, field := range td.Types[typ] {
+		found = td.dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeType returns the encoding of a struct type, the type itself followed by
+// the struct types it references sorted by name, e.g.
+//
+//	Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (td *TypedData) EncodeType(primaryType string) ([]byte, error) {
+	if _, ok := td.Types[primaryType]; !ok {
+		return nil, fmt.Errorf("undefined type %q", primaryType)
+	}
+	deps := td.dependencies(primaryType, nil)
+	sort.Strings(deps[1:])
+
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteByte('(')
+		for i, field := range td.Types[dep] {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteByte(' ')
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteByte(')')
+	}
+	return buffer.Bytes(), nil
+}
+
+// TypeHash returns the hash of the encoding of a struct type.
+func (td *TypedData) TypeHash(primaryType string) (common.Hash, error) {
+	enc, err := td.EncodeType(primaryType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// EncodeData returns the encoding of a struct value, its type hash followed by
+// the 32 byte encodings of its fields in declaration order.
+func (td *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := td.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("undefined type %q", primaryType)
+	}
+	if len(data) > len(fields) {
+		for name := range data {
+			if !hasField(fields, name) {
+				return nil, fmt.Errorf("unexpected field %s in %s value", name, primaryType)
+			}
+		}
+	}
+	typeHash, err := td.TypeHash(primaryType)
+	if err != nil {
+		return nil, err
+	}
+	enc := append(make([]byte, 0, 32*(len(fields)+1)), typeHash.Bytes()...)
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing field %s in %s value", field.Name, primaryType)
+		}
+		encValue, err := td.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s of %s: %v", field.Name, primaryType, err)
+		}
+		enc = append(enc, encValue...)
+	}
+	return enc, nil
+}
+
+// HashStruct returns the hash of the encoding of a struct value.
+func (td *TypedData) HashStruct(primaryType string, data map[string]interface{}) (common.Hash, error) {
+	enc, err := td.EncodeData(primaryType, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// DomainSeparator returns the struct hash of the signing domain.
+func (td *TypedData) DomainSeparator() (common.Hash, error) {
+	return td.HashStruct(DomainType, td.Domain.Map())
+}
+
+// SigningHash validates the typed data and returns the hash to sign,
+//
+//	keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (td *TypedData) SigningHash() (common.Hash, error) {
+	if err := td.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid domain: %v", err)
+	}
+	messageHash, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator.Bytes(), messageHash.Bytes()), nil
+}
+
+// encodeValue returns the 32 byte encoding of a value of the given type. Arrays
+// and structs are encoded as the hash of their contents.
+func (td *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	if match := arrayRegexp.FindStringSubmatch(typ); match != nil {
+		items, err := arrayItems(match[2], value)
+		if err != nil {
+			return nil, err
+		}
+		var enc []byte
+		for i, item := range items {
+			encItem, err := td.encodeValue(match[1], item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			enc = append(enc, encItem...)
+		}
+		return crypto.Keccak256(enc), nil
+	}
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		hash, err := td.HashStruct(typ, data)
+		if err != nil {
+			return nil, err
+		}
+		return hash.Bytes(), nil
+	}
+	enc, _, err := parseAtomic(typ, value)
+	return enc, err
+}
+
+// arrayItems checks that value is an array of the given length, if any.
+func arrayItems(length string, value interface{}) ([]interface{}, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid array value %v", value)
+	}
+	if length != "" {
+		if n, err := strconv.Atoi(length); err != nil || n != len(items) {
+			return nil, fmt.Errorf("array length mismatch: have %d, want %s", len(items), length)
+		}
+	}
+	return items, nil
+}
+
+// hasField reports whether the struct fields include one with the given name.
+func hasField(fields []Type, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isAtomic reports whether typ is one of the elementary types.
+func isAtomic(typ string) bool {
+	switch typ {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	if match := bytesRegexp.FindStringSubmatch(typ); match != nil {
+		size, err := strconv.Atoi(match[1])
+		return err == nil && size >= 1 && size <= 32
+	}
+	if match := intRegexp.FindStringSubmatch(typ); match != nil {
+		size, err := strconv.Atoi(match[2])
+		return err == nil && size >= 8 && size <= 256 && size%8 == 0
+	}
+	return false
+}
+
+// parseAtomic returns the 32 byte encoding of an elementary value along with its
+// normalised textual form for display.
+func parseAtomic(typ string, value interface{}) ([]byte, string, error) {
+	switch typ {
+	case "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, "", fmt.Errorf("invalid address %v", value)
+		}
+		addr := common.HexToAddress(str)
+		return common.LeftPadBytes(addr.Bytes(), 32), addr.Hex(), nil
+
+	case "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, "", fmt.Errorf("invalid bool %v", value)
+		}
+		enc := make([]byte, 32)
+		if b {
+			enc[31] = 1
+		}
+		return enc, strconv.FormatBool(b), nil
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("invalid string %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), str, nil
+
+	case "bytes":
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return crypto.Keccak256(blob), hexutil.Encode(blob), nil
+	}
+	if match := bytesRegexp.FindStringSubmatch(typ); match != nil {
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, "", err
+		}
+		if strconv.Itoa(len(blob)) != match[1] {
+			return nil, "", fmt.Errorf("invalid %s length %d", typ, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), hexutil.Encode(blob), nil
+	}
+	if match := intRegexp.FindStringSubmatch(typ); match != nil {
+		num, err := parseInteger(value)
+		if err != nil {
+			return nil, "", err
+		}
+		bits := 256
+		if match[2] != "" {
+			bits, _ = strconv.Atoi(match[2])
+		}
+		if match[1] == "uint" {
+			if num.Sign() < 0 || num.BitLen() > bits {
+				return nil, "", fmt.Errorf("%v out of %s range", num, typ)
+			}
+		} else {
+			limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+			if num.Cmp(limit) >= 0 || num.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, "", fmt.Errorf("%v out of %s range", num, typ)
+			}
+		}
+		return math.PaddedBigBytes(math.U256(new(big.Int).Set(num)), 32), num.String(), nil
+	}
+	return nil, "", fmt.Errorf("unknown type %q", typ)
+}
+
+// parseBytes decodes a hex encoded byte array.
+func parseBytes(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid bytes %v", value)
+	}
+	blob, err := hexutil.Decode(hexutil.CPToHex(str))
+	if err != nil && err != hexutil.ErrEmptyString {
+		return nil, fmt.Errorf("invalid bytes %q: %v", str, err)
+	}
+	return blob, nil
+}
+
+// errUnsafeNumber is returned for JSON numbers not representable exactly.
+var errUnsafeNumber = errors.New("number too large, pass it as a string")
+
+// parseInteger parses an integer passed as a decimal or hex string, or as a JSON
+// number small enough to be exact.
+func parseInteger(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case string:
+		neg := strings.HasPrefix(v, "-")
+		num, ok := math.ParseBig256(strings.TrimPrefix(v, "-"))
+		if !ok || v == "" {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		if neg {
+			num.Neg(num)
+		}
+		return num, nil
+	case json.Number:
+		return parseInteger(v.String())
+	case float64:
+		if v > maxSafeFloat || v < -maxSafeFloat {
+			return nil, errUnsafeNumber
+		}
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer %v", v)
+		}
+		return big.NewInt(int64(v)), nil
+	}
+	return nil, fmt.Errorf("invalid integer %v", value)
+}
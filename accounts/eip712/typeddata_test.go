@@ -0,0 +1,178 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eeefan/dpeth/common"
+)
+
+// mailJSON is the example message of the EIP-712 specification.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func mailTypedData(t *testing.T) *TypedData {
+	td := new(TypedData)
+	if err := json.Unmarshal([]byte(mailJSON), td); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	return td
+}
+
+// Tests the hashing against the reference values of the specification.
+func TestTypedDataHashing(t *testing.T) {
+	td := mailTypedData(t)
+
+	enc, err := td.EncodeType("Mail")
+	if err != nil {
+		t.Fatalf("failed to encode type: %v", err)
+	}
+	if want := "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; string(enc) != want {
+		t.Errorf("type encoding mismatch: have %s, want %s", enc, want)
+	}
+	tests := []struct {
+		name string
+		hash func() (common.Hash, error)
+		want string
+	}{
+		{"type hash", func() (common.Hash, error) { return td.TypeHash("Mail") }, "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"},
+		{"message hash", func() (common.Hash, error) { return td.HashStruct("Mail", td.Message) }, "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"},
+		{"domain separator", td.DomainSeparator, "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"},
+		{"signing hash", td.SigningHash, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	}
+	for _, tt := range tests {
+		hash, err := tt.hash()
+		if err != nil {
+			t.Errorf("%s: failed to hash: %v", tt.name, err)
+			continue
+		}
+		if hash != common.HexToHash(tt.want) {
+			t.Errorf("%s: mismatch: have %x, want %s", tt.name, hash, tt.want)
+		}
+	}
+}
+
+// Tests that malformed types and values are rejected.
+func TestTypedDataErrors(t *testing.T) {
+	tests := []struct {
+		modify func(td *TypedData)
+		err    string
+	}{
+		{func(td *TypedData) { delete(td.Types, DomainType) }, "missing EIP712Domain type"},
+		{func(td *TypedData) { td.PrimaryType = "Letter" }, `undefined primary type "Letter"`},
+		{func(td *TypedData) { td.Types["Mail"][2].Type = "text" }, `unknown type "text"`},
+		{func(td *TypedData) { td.Types["Person"][1].Type = "uint7" }, `unknown type "uint7"`},
+		{func(td *TypedData) { delete(td.Message, "contents") }, "missing field contents"},
+		{func(td *TypedData) { td.Message["cc"] = "Alice" }, "unexpected field cc"},
+		{func(td *TypedData) { td.Message["to"] = "Bob" }, "invalid Person value"},
+		{func(td *TypedData) { td.Message["to"].(map[string]interface{})["wallet"] = "0x1234" }, "invalid address"},
+		{func(td *TypedData) {
+			td.Types["Person"][1].Type = "uint8"
+			td.Message["from"].(map[string]interface{})["wallet"] = "256"
+		}, "out of uint8 range"},
+		{func(td *TypedData) {
+			td.Types["Person"][1].Type = "int8"
+			td.Message["from"].(map[string]interface{})["wallet"] = "-129"
+		}, "out of int8 range"},
+		{func(td *TypedData) {
+			td.Types["Person"][1].Type = "uint256"
+			td.Message["from"].(map[string]interface{})["wallet"] = 1e18
+		}, "pass it as a string"},
+		{func(td *TypedData) {
+			td.Types["Person"][1].Type = "bytes4"
+			td.Message["from"].(map[string]interface{})["wallet"] = "0x010203"
+		}, "invalid bytes4 length"},
+		{func(td *TypedData) {
+			td.Types["Mail"][1].Type = "Person[2]"
+			td.Message["to"] = []interface{}{td.Message["to"]}
+		}, "array length mismatch"},
+	}
+	for i, tt := range tests {
+		td := mailTypedData(t)
+		tt.modify(td)
+		if _, err := td.SigningHash(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that arrays and integers are encoded and formatted for display.
+func TestTypedDataFormat(t *testing.T) {
+	td := mailTypedData(t)
+	td.Types["Mail"] = append(td.Types["Mail"], Type{Name: "amounts", Type: "int64[]"})
+	td.Message["amounts"] = []interface{}{float64(-1), "0x10"}
+
+	if _, err := td.SigningHash(); err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	fields, err := td.Format()
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	want := `domain [EIP712Domain]
+  name [string]: Ether Mail
+  version [string]: 1
+  chainId [uint256]: 1
+  verifyingContract [address]: 0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC
+message [Mail]
+  from [Person]
+    name [string]: Cow
+    wallet [address]: 0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826
+  to [Person]
+    name [string]: Bob
+    wallet [address]: 0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB
+  contents [string]: Hello, Bob!
+  amounts [int64[]]
+    [0] [int64]: -1
+    [1] [int64]: 16
+`
+	if have := Pprint(fields); have != want {
+		t.Errorf("format mismatch:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend delegating all signing to an
+// external signer (clef) over its RPC API, so no key is ever held in process.
+package external
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/eeefan/dpeth"
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/event"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/rlp"
+	"github.com/eeefan/dpeth/rpc"
+)
+
+// ExternalSignerScheme is the protocol scheme prefixing account and wallet URLs
+// of the external signer.
+const ExternalSignerScheme = "extapi"
+
+// ExternalBackend is an account backend serving the single wallet of an external
+// signer.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at the given endpoint (an
+// IPC path or an HTTP/WS URL) and creates a backend serving its accounts.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. The external signer is always there, so
+// no events are ever fired.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is a wallet whose keys are held by an external signer, which
+// is asked to approve and perform every single signing operation.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+	status   string
+
+	cacheMu  sync.RWMutex
+	cache    []accounts.Account
+	cacheErr error
+}
+
+// NewExternalSigner connects to the external signer at the given endpoint.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	signer := &ExternalSigner{
+		client:   client,
+		endpoint: endpoint,
+	}
+	// Retrieve the accounts once, the listing may need to be approved manually
+	if _, err := signer.listAccounts(); err != nil {
+		log.Warn("Failed to list external signer accounts", "endpoint", endpoint, "err", err)
+	}
+	return signer, nil
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: ExternalSignerScheme, Path: api.endpoint}
+}
+
+// Status implements accounts.Wallet, reporting whether the accounts of the signer
+// could be listed.
+func (api *ExternalSigner) Status() (string, error) {
+	api.cacheMu.RLock()
+	defer api.cacheMu.RUnlock()
+
+	if api.cacheErr != nil {
+		return "Failed", api.cacheErr
+	}
+	return "Ok", nil
+}
+
+// Open implements accounts.Wallet. The external signer manages its own state,
+// so there is nothing to open.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return fmt.Errorf("operation not supported on external signers")
+}
+
+// Close implements accounts.Wallet, disconnecting from the signer.
+func (api *ExternalSigner) Close() error {
+	api.client.Close()
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts of the signer as
+// retrieved upon connecting (or the last successful retrieval).
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	api.cacheMu.RLock()
+	cache := api.cache
+	api.cacheMu.RUnlock()
+
+	if cache != nil {
+		return cache
+	}
+	cache, _ = api.listAccounts()
+	return cache
+}
+
+// listAccounts retrieves the accounts of the signer and caches them.
+func (api *ExternalSigner) listAccounts() ([]accounts.Account, error) {
+	var res []struct {
+		Address common.Address `json:"address"`
+	}
+	err := api.client.Call(&res, "account_list")
+
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+
+	api.cacheErr = err
+	if err != nil {
+		return nil, err
+	}
+	api.cache = make([]accounts.Account, 0, len(res))
+	for _, acc := range res {
+		api.cache = append(api.cache, accounts.Account{
+			Address: acc.Address,
+			URL:     api.URL(),
+		})
+	}
+	return api.cache, nil
+}
+
+// Contains implements accounts.Wallet, returning whether the signer holds the
+// given account.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	for _, acc := range api.Accounts() {
+		if acc.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == acc.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by external signers.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, fmt.Errorf("operation not supported on external signers")
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for external signers.
+func (api *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("Operation not supported on external signers")
+}
+
+// SignHash implements accounts.Wallet. External signers never sign blind hashes,
+// only data and objects they can show and check before signing.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignAlienHeader asks the signer to seal an alien block header, returning the
+// 65 byte seal. The parent header is handed over too, as the signer queue of the
+// parent decides whose slot the header is in. It implements alien.SignHeaderFn.
+func (api *ExternalSigner) SignAlienHeader(account accounts.Account, header *types.Header, parent *types.Header) ([]byte, error) {
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	parentData, err := rlp.EncodeToBytes(parent)
+	if err != nil {
+		return nil, err
+	}
+	var seal hexutil.Bytes
+	if err := api.client.Call(&seal, "account_signAlienHeader", common.NewMixedcaseAddress(account.Address), hexutil.Bytes(data), hexutil.Bytes(parentData)); err != nil {
+		return nil, err
+	}
+	return seal, nil
+}
+
+// sendTxArgs is the transaction format of the signer's signTransaction method.
+type sendTxArgs struct {
+	From     common.MixedcaseAddress  `json:"from"`
+	To       *common.MixedcaseAddress `json:"to"`
+	Gas      hexutil.Uint64           `json:"gas"`
+	GasPrice hexutil.Big              `json:"gasPrice"`
+	Value    hexutil.Big              `json:"value"`
+	Nonce    hexutil.Uint64           `json:"nonce"`
+	Data     *hexutil.Bytes           `json:"data"`
+}
+
+// SignTx implements accounts.Wallet, asking the signer to sign the transaction.
+// The signer signs with its own chain id, which has to match the requested one.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+	args := &sendTxArgs{
+		From:     common.NewMixedcaseAddress(account.Address),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     &data,
+	}
+	if to := tx.To(); to != nil {
+		addr := common.NewMixedcaseAddress(*to)
+		args.To = &addr
+	}
+	var res struct {
+		Raw hexutil.Bytes      `json:"raw"`
+		Tx  *types.Transaction `json:"tx"`
+	}
+	if err := api.client.Call(&res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	if chainID != nil && res.Tx.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("external signer chain id mismatch: have %v, want %v", res.Tx.ChainId(), chainID)
+	}
+	return res.Tx, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but passwords are managed
+// by the signer itself.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, fmt.Errorf("password-operations not supported on external signers")
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but passwords are managed by
+// the signer itself.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, fmt.Errorf("password-operations not supported on external signers")
+}
@@ -0,0 +1,376 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdwallet implements software wallets deriving their accounts from a
+// BIP-39 mnemonic along BIP-32 derivation paths. The mnemonics are stored in
+// files encrypted like the keys of the keystore.
+package hdwallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/keystore"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/event"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// WalletScheme is the protocol scheme prefixing the URLs of mnemonic wallets.
+const WalletScheme = "hd"
+
+// DirName is the name of the directory of the wallet files within the keystore.
+const DirName = "hdwallets"
+
+// walletVersion is the version of the wallet file format.
+const walletVersion = 1
+
+// mnemonicBits is the entropy of newly generated mnemonics, 24 words.
+const mnemonicBits = 256
+
+// walletRefreshCycle is the maximum time between wallet refreshes, picking up
+// wallet files created by other processes.
+const walletRefreshCycle = 3 * time.Second
+
+var (
+	// ErrLocked is returned when signing with a locked wallet without passphrase.
+	ErrLocked = accounts.NewAuthNeededError("wallet passphrase")
+
+	// ErrWalletExists is returned when importing a mnemonic already stored.
+	ErrWalletExists = errors.New("wallet of mnemonic already exists")
+
+	// ErrInvalidMnemonic is returned for mnemonics with unknown words or a bad
+	// checksum.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// errFingerprintMismatch is returned if a decrypted mnemonic does not match
+	// the wallet, i.e. the file was tampered with.
+	errFingerprintMismatch = errors.New("mnemonic does not match the wallet fingerprint")
+)
+
+// accountJSON is a pinned account of a wallet file.
+type accountJSON struct {
+	Address common.Address `json:"address"`
+	Path    string         `json:"path"`
+}
+
+// walletJSON is the content of a wallet file.
+type walletJSON struct {
+	Fingerprint common.Address      `json:"fingerprint"` // Address of the master key, identifying the mnemonic
+	Crypto      keystore.CryptoJSON `json:"crypto"`      // Encrypted mnemonic
+	Accounts    []accountJSON       `json:"accounts"`    // Pinned accounts, listed while locked
+	Version     int                 `json:"version"`
+}
+
+// decrypt decrypts the mnemonic of the wallet.
+func (w *walletJSON) decrypt(passphrase string) (string, error) {
+	mnemonic, err := keystore.DecryptDataV3(w.Crypto, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(mnemonic), nil
+}
+
+// pinned returns whether an account is pinned in the wallet file.
+func (w *walletJSON) pinned(addr common.Address) bool {
+	for _, acc := range w.Accounts {
+		if acc.Address == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// withAccount returns a copy of the wallet file with an account pinned.
+func (w *walletJSON) withAccount(addr common.Address, path accounts.DerivationPath) *walletJSON {
+	cpy := *w
+	cpy.Accounts = append(append([]accountJSON{}, w.Accounts...), accountJSON{Address: addr, Path: path.String()})
+	return &cpy
+}
+
+// BackendType is the reflect type of a mnemonic wallet backend.
+var BackendType = reflect.TypeOf(&Backend{})
+
+// Backend is an account backend serving the mnemonic wallets stored in a
+// directory, one file per mnemonic.
+type Backend struct {
+	dir     string // Directory of the wallet files
+	scryptN int    // Scrypt parameters encrypting new wallets
+	scryptP int
+
+	wallets   []*wallet // Wallets loaded from the directory, sorted by URL
+	refreshed time.Time // Time of the last directory scan
+
+	feed        event.Feed              // Event feed to notify wallet additions/removals/openings
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+	updating    bool                    // Whether the refresh loop is running
+
+	lock sync.Mutex
+}
+
+// NewBackend creates a backend for the mnemonic wallets in the given directory,
+// encrypting new ones with the given scrypt parameters.
+func NewBackend(dir string, scryptN, scryptP int) *Backend {
+	b := &Backend{
+		dir:     dir,
+		scryptN: scryptN,
+		scryptP: scryptP,
+	}
+	b.refresh()
+	return b
+}
+
+// Wallets implements accounts.Backend, returning the mnemonic wallets.
+func (b *Backend) Wallets() []accounts.Wallet {
+	b.refresh()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	cpy := make([]accounts.Wallet, len(b.wallets))
+	for i, w := range b.wallets {
+		cpy[i] = w
+	}
+	return cpy
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition, removal and opening of wallets.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sub := b.updateScope.Track(b.feed.Subscribe(sink))
+	if !b.updating {
+		b.updating = true
+		go b.updater()
+	}
+	return sub
+}
+
+// updater periodically refreshes the wallets while there are subscribers.
+func (b *Backend) updater() {
+	for {
+		time.Sleep(walletRefreshCycle)
+		b.refresh()
+
+		b.lock.Lock()
+		if b.updateScope.Count() == 0 {
+			b.updating = false
+			b.lock.Unlock()
+			return
+		}
+		b.lock.Unlock()
+	}
+}
+
+// refresh rescans the wallet directory, at most once per refresh cycle, firing
+// events for the arrived and dropped wallets.
+func (b *Backend) refresh() {
+	b.lock.Lock()
+	if time.Since(b.refreshed) < walletRefreshCycle {
+		b.lock.Unlock()
+		return
+	}
+	b.refreshed = time.Now()
+	b.lock.Unlock()
+
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	found := make(map[string]os.FileInfo)
+	for _, fi := range files {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || strings.HasSuffix(fi.Name(), "~") {
+			continue
+		}
+		found[filepath.Join(b.dir, fi.Name())] = fi
+	}
+	b.lock.Lock()
+	var (
+		events  []accounts.WalletEvent
+		wallets = make([]*wallet, 0, len(found))
+	)
+	for _, w := range b.wallets {
+		if _, ok := found[w.url.Path]; ok {
+			wallets = append(wallets, w)
+			delete(found, w.url.Path)
+			continue
+		}
+		events = append(events, accounts.WalletEvent{Wallet: w, Kind: accounts.WalletDropped})
+	}
+	for path := range found {
+		data, err := loadWallet(path)
+		if err != nil {
+			continue // Not a wallet file, or a partial write, retried on the next refresh
+		}
+		w := newWallet(b, accounts.URL{Scheme: WalletScheme, Path: path}, data)
+		wallets = append(wallets, w)
+		events = append(events, accounts.WalletEvent{Wallet: w, Kind: accounts.WalletArrived})
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].url.Cmp(wallets[j].url) < 0 })
+	b.wallets = wallets
+	b.lock.Unlock()
+
+	for _, event := range events {
+		b.feed.Send(event)
+	}
+}
+
+// NewMnemonic generates a new random mnemonic of 24 words.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// Import stores a mnemonic as a new wallet encrypted with the passphrase, pinning
+// the account at the given derivation path.
+func (b *Backend) Import(mnemonic, passphrase string, path accounts.DerivationPath) (accounts.Account, error) {
+	mnemonic = strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	master, err := masterKeyFromMnemonic(mnemonic)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer master.zero()
+
+	key, err := master.derive(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	addr, err := key.address()
+	key.zero()
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	id, err := fingerprint(master)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	data := &walletJSON{
+		Fingerprint: id,
+		Accounts:    []accountJSON{{Address: addr, Path: path.String()}},
+		Version:     walletVersion,
+	}
+	b.refresh()
+	b.lock.Lock()
+	for _, w := range b.wallets {
+		if w.data.Fingerprint == data.Fingerprint {
+			b.lock.Unlock()
+			return accounts.Account{}, ErrWalletExists
+		}
+	}
+	b.lock.Unlock()
+
+	if data.Crypto, err = keystore.EncryptDataV3([]byte(mnemonic), []byte(passphrase), b.scryptN, b.scryptP); err != nil {
+		return accounts.Account{}, err
+	}
+	file := filepath.Join(b.dir, walletFileName(data.Fingerprint))
+	if err := b.store(file, data); err != nil {
+		return accounts.Account{}, err
+	}
+	url := accounts.URL{Scheme: WalletScheme, Path: file}
+
+	// Track the new wallet right away, unless a concurrent refresh picked it up
+	b.lock.Lock()
+	for _, w := range b.wallets {
+		if w.url == url {
+			b.lock.Unlock()
+			return accounts.Account{Address: addr, URL: url}, nil
+		}
+	}
+	w := newWallet(b, url, data)
+	b.wallets = append(b.wallets, w)
+	sort.Slice(b.wallets, func(i, j int) bool { return b.wallets[i].url.Cmp(b.wallets[j].url) < 0 })
+	b.lock.Unlock()
+
+	b.feed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletArrived})
+	return accounts.Account{Address: addr, URL: url}, nil
+}
+
+// store atomically writes a wallet file.
+func (b *Backend) store(file string, data *walletJSON) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), file)
+}
+
+// loadWallet reads and checks a wallet file.
+func loadWallet(file string) (*walletJSON, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data := new(walletJSON)
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+	if data.Version != walletVersion {
+		return nil, fmt.Errorf("unsupported wallet version %d", data.Version)
+	}
+	return data, nil
+}
+
+// walletFileName returns the file name of a wallet in the style of the keystore,
+// e.g. UTC--2019-05-02T10-32-00.000000000Z--<fingerprint>.
+func walletFileName(fingerprint common.Address) string {
+	t := time.Now().UTC()
+	ts := fmt.Sprintf("%04d-%02d-%02dT%02d-%02d-%02d.%09dZ", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond())
+	return fmt.Sprintf("UTC--%s--%x", ts, fingerprint[:])
+}
+
+// masterKeyFromMnemonic checks a mnemonic and derives its master key, without a
+// BIP-39 passphrase.
+func masterKeyFromMnemonic(mnemonic string) (*extendedKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, ErrInvalidMnemonic
+	}
+	return newMasterKey(seed)
+}
+
+// fingerprint returns the address of the master key, identifying a mnemonic
+// without revealing any of its accounts.
+func fingerprint(master *extendedKey) (common.Address, error) {
+	return master.address()
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/math"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// hardenedOffset is the index offset of hardened child keys.
+const hardenedOffset = 0x80000000
+
+// masterSecret is the HMAC key deriving the master key from a seed, as fixed by
+// BIP-32.
+var masterSecret = []byte("Bitcoin seed")
+
+// errInvalidKey is returned in the (astronomically unlikely) case of a derived
+// key falling outside of the curve order, BIP-32 requires skipping the index.
+var errInvalidKey = errors.New("invalid derived key, use the next index")
+
+// extendedKey is a BIP-32 extended private key: a private key along with the
+// chain code needed to derive its children.
+type extendedKey struct {
+	key       *big.Int
+	chainCode []byte
+}
+
+// newMasterKey derives the master extended key of a BIP-39 seed.
+func newMasterKey(seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, masterSecret)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errInvalidKey
+	}
+	return &extendedKey{key: key, chainCode: sum[32:]}, nil
+}
+
+// child derives the child extended key at the given index, hardened if the index
+// is at least 2^31.
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= hardenedOffset {
+		data = append([]byte{0x00}, math.PaddedBigBytes(k.key, 32)...)
+	} else {
+		priv, err := k.privateKey()
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	var indexBytes [4]byte
+	binary.BigEndian.PutUint32(indexBytes[:], index)
+	data = append(data, indexBytes[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, errInvalidKey
+	}
+	key := tweak.Add(tweak, k.key)
+	key.Mod(key, n)
+	if key.Sign() == 0 {
+		return nil, errInvalidKey
+	}
+	return &extendedKey{key: key, chainCode: sum[32:]}, nil
+}
+
+// derive derives the extended key at the given path relative to this key.
+func (k *extendedKey) derive(path accounts.DerivationPath) (*extendedKey, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// privateKey returns the ECDSA private key of the extended key.
+func (k *extendedKey) privateKey() (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(math.PaddedBigBytes(k.key, 32))
+}
+
+// address returns the Ethereum address of the extended key.
+func (k *extendedKey) address() (common.Address, error) {
+	priv, err := k.privateKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(priv.PublicKey), nil
+}
+
+// clone returns a deep copy of the extended key, which can be used and zeroed
+// independently of the original.
+func (k *extendedKey) clone() *extendedKey {
+	return &extendedKey{
+		key:       new(big.Int).Set(k.key),
+		chainCode: append([]byte{}, k.chainCode...),
+	}
+}
+
+// zero wipes the key material from memory.
+func (k *extendedKey) zero() {
+	b := k.key.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+	for i := range k.chainCode {
+		k.chainCode[i] = 0
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/keystore"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/math"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// testMnemonic is the well known all-zero entropy mnemonic.
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// testAddresses are the accounts of testMnemonic along the default base path.
+var testAddresses = []common.Address{
+	common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94"),
+	common.HexToAddress("0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0"),
+	common.HexToAddress("0xb6716976A3ebe8D39aCEB04372f22Ff8e6802D7A"),
+}
+
+func testPath(index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(accounts.DefaultBaseDerivationPath))
+	copy(path, accounts.DefaultBaseDerivationPath)
+	path[len(path)-1] = index
+	return path
+}
+
+// Tests the key derivation against BIP-32 test vector 1.
+func TestBIP32Vector(t *testing.T) {
+	master, err := newMasterKey(common.FromHex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	tests := []struct {
+		path accounts.DerivationPath
+		key  string
+	}{
+		{accounts.DerivationPath{}, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{accounts.DerivationPath{hardenedOffset}, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{accounts.DerivationPath{hardenedOffset, 1}, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+	}
+	for _, tt := range tests {
+		key, err := master.derive(tt.path)
+		if err != nil {
+			t.Fatalf("%v: failed to derive: %v", tt.path, err)
+		}
+		if have := fmt.Sprintf("%x", math.PaddedBigBytes(key.key, 32)); have != tt.key {
+			t.Errorf("%v: key mismatch: have %s, want %s", tt.path, have, tt.key)
+		}
+	}
+}
+
+// Tests that mnemonics are imported, derived from and signed with, and that the
+// pinned accounts survive a reload.
+func TestBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdwallet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := NewBackend(dir, keystore.LightScryptN, keystore.LightScryptP)
+	events := make(chan accounts.WalletEvent, 4)
+	sub := backend.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	// Import the mnemonic, pinning the first account
+	account, err := backend.Import(" Abandon "+testMnemonic[8:], "foo", testPath(0))
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if account.Address != testAddresses[0] {
+		t.Fatalf("account mismatch: have %x, want %x", account.Address, testAddresses[0])
+	}
+	if _, err := backend.Import(testMnemonic, "bar", testPath(1)); err != ErrWalletExists {
+		t.Errorf("duplicate import error mismatch: have %v, want %v", err, ErrWalletExists)
+	}
+	if _, err := backend.Import(testMnemonic[:len(testMnemonic)-5]+"abandon", "foo", testPath(0)); err != ErrInvalidMnemonic {
+		t.Errorf("invalid mnemonic error mismatch: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	wallets := backend.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	wallet := wallets[0]
+	if ev := <-events; ev.Kind != accounts.WalletArrived || ev.Wallet.URL() != wallet.URL() {
+		t.Errorf("arrival event mismatch: %v", ev)
+	}
+	// Locked wallets only sign with the passphrase
+	hash := crypto.Keccak256([]byte("test"))
+	if _, err := wallet.SignHash(account, hash); err != ErrLocked {
+		t.Errorf("locked signing error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if _, err := wallet.SignHashWithPassphrase(account, "bar", hash); err != keystore.ErrDecrypt {
+		t.Errorf("wrong passphrase error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	sig, err := wallet.SignHashWithPassphrase(account, "foo", hash)
+	if err != nil {
+		t.Fatalf("failed to sign with passphrase: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Errorf("signer mismatch: %v", err)
+	}
+	// Unlocked wallets derive and sign on demand
+	if _, err := wallet.Derive(testPath(1), true); err != ErrLocked {
+		t.Errorf("locked derivation error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	if ev := <-events; ev.Kind != accounts.WalletOpened {
+		t.Errorf("open event mismatch: %v", ev)
+	}
+	for i, pin := range []bool{true, false} {
+		derived, err := wallet.Derive(testPath(uint32(i+1)), pin)
+		if err != nil {
+			t.Fatalf("failed to derive account %d: %v", i+1, err)
+		}
+		if derived.Address != testAddresses[i+1] {
+			t.Errorf("derived account %d mismatch: have %x, want %x", i+1, derived.Address, testAddresses[i+1])
+		}
+	}
+	if _, err := wallet.SignHash(accounts.Account{Address: testAddresses[2]}, hash); err != nil {
+		t.Errorf("failed to sign with unlocked wallet: %v", err)
+	}
+	if accs := wallet.Accounts(); len(accs) != 3 {
+		t.Errorf("account count mismatch: have %d, want 3", len(accs))
+	}
+	// Reloading only lists the pinned accounts
+	wallet.Close()
+	if status, _ := wallet.Status(); status != "Locked" {
+		t.Errorf("status mismatch after close: have %s, want Locked", status)
+	}
+	reloaded := NewBackend(dir, keystore.LightScryptN, keystore.LightScryptP).Wallets()
+	if len(reloaded) != 1 {
+		t.Fatalf("reloaded wallet count mismatch: have %d, want 1", len(reloaded))
+	}
+	accs := reloaded[0].Accounts()
+	if len(accs) != 2 || accs[0].Address != testAddresses[0] || accs[1].Address != testAddresses[1] {
+		t.Errorf("reloaded accounts mismatch: %v", accs)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event: %v", ev)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// Tests that closing a wallet while it signs never leaves the signing with the
+// zeroed master key, i.e. signatures are either refused or made by the account.
+func TestSignWhileClosing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdwallet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := NewBackend(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := backend.Import(testMnemonic, "foo", testPath(0))
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	wallet := backend.Wallets()[0]
+
+	hash := crypto.Keccak256([]byte("test"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			wallet.Open("foo")
+			wallet.Close()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		sig, err := wallet.SignHash(account, hash)
+		if err == ErrLocked {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+			t.Fatalf("signer mismatch: %v", err)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+
+	"github.com/eeefan/dpeth"
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/log"
+)
+
+// wallet is a software wallet deriving its accounts from a mnemonic. The accounts
+// derived with pinning are listed even while the wallet is locked, but signing
+// needs the wallet to be opened with its passphrase (or the passphrase given to
+// the signing call itself).
+type wallet struct {
+	backend *Backend     // Backend the wallet belongs to, for persistence and events
+	url     accounts.URL // Location of the wallet file
+
+	stateLock sync.RWMutex
+	data      *walletJSON                                // Contents of the wallet file
+	accounts  []accounts.Account                         // Accounts derived so far
+	paths     map[common.Address]accounts.DerivationPath // Derivation paths of the accounts
+	master    *extendedKey                               // Master key while unlocked, nil otherwise
+
+	deriveBase  accounts.DerivationPath   // Base path of the account discovery, nil if disabled
+	deriveChain ethereum.ChainStateReader // Chain state to discover the used accounts against
+}
+
+// newWallet creates a wallet from the contents of its file.
+func newWallet(backend *Backend, url accounts.URL, data *walletJSON) *wallet {
+	w := &wallet{
+		backend: backend,
+		url:     url,
+		data:    data,
+		paths:   make(map[common.Address]accounts.DerivationPath),
+	}
+	for _, acc := range data.Accounts {
+		path, err := accounts.ParseDerivationPath(acc.Path)
+		if err != nil {
+			log.Warn("Skipping invalid HD wallet account", "url", url, "path", acc.Path, "err", err)
+			continue
+		}
+		w.track(acc.Address, path)
+	}
+	return w
+}
+
+// track starts listing a derived account, returning it. The caller must hold the
+// state lock.
+func (w *wallet) track(addr common.Address, path accounts.DerivationPath) accounts.Account {
+	account := accounts.Account{Address: addr, URL: w.url}
+	if _, ok := w.paths[addr]; !ok {
+		w.accounts = append(w.accounts, account)
+		w.paths[addr] = path
+	}
+	return account
+}
+
+// URL implements accounts.Wallet, returning the location of the wallet file.
+func (w *wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the wallet is unlocked.
+func (w *wallet) Status() (string, error) {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	if w.master == nil {
+		return "Locked", nil
+	}
+	return "Unlocked", nil
+}
+
+// Open implements accounts.Wallet, unlocking the wallet with the passphrase. An
+// empty passphrase is accepted without unlocking, as the pinned accounts are
+// available anyway.
+func (w *wallet) Open(passphrase string) error {
+	if passphrase == "" {
+		return nil
+	}
+	master, err := w.unlock(passphrase)
+	if err != nil {
+		return err
+	}
+	w.stateLock.Lock()
+	if w.master != nil {
+		w.stateLock.Unlock()
+		master.zero()
+		return accounts.ErrWalletAlreadyOpen
+	}
+	w.master = master
+	discover := w.deriveBase != nil
+	w.stateLock.Unlock()
+
+	w.backend.feed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletOpened})
+	if discover {
+		go w.selfDerive()
+	}
+	return nil
+}
+
+// unlock decrypts the mnemonic of the wallet and derives its master key.
+func (w *wallet) unlock(passphrase string) (*extendedKey, error) {
+	w.stateLock.RLock()
+	data := w.data
+	w.stateLock.RUnlock()
+
+	mnemonic, err := data.decrypt(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := masterKeyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	if id, err := fingerprint(master); err != nil || id != data.Fingerprint {
+		master.zero()
+		if err != nil {
+			return nil, err
+		}
+		return nil, errFingerprintMismatch
+	}
+	return master, nil
+}
+
+// Close implements accounts.Wallet, locking the wallet and wiping its keys from
+// memory.
+func (w *wallet) Close() error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.master != nil {
+		w.master.zero()
+		w.master = nil
+	}
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts derived so far.
+func (w *wallet) Accounts() []accounts.Account {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether the account has been
+// derived from this wallet.
+func (w *wallet) Contains(account accounts.Account) bool {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists && (account.URL == (accounts.URL{}) || account.URL == w.url)
+}
+
+// Derive implements accounts.Wallet, deriving the account at the given path. If
+// pin is set, the account is saved in the wallet file and listed even while the
+// wallet is locked.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.master == nil {
+		return accounts.Account{}, ErrLocked
+	}
+	key, err := w.master.derive(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	addr, err := key.address()
+	key.zero()
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account := w.track(addr, path)
+	if pin && !w.data.pinned(addr) {
+		data := w.data.withAccount(addr, path)
+		if err := w.backend.store(w.url.Path, data); err != nil {
+			return accounts.Account{}, err
+		}
+		w.data = data
+	}
+	return account, nil
+}
+
+// SelfDerive implements accounts.Wallet, discovering the used accounts along the
+// base path (along with the first unused one) whenever the wallet is unlocked.
+func (w *wallet) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	w.stateLock.Lock()
+	w.deriveBase = make(accounts.DerivationPath, len(base))
+	copy(w.deriveBase, base)
+	w.deriveChain = chain
+	unlocked := w.master != nil
+	w.stateLock.Unlock()
+
+	if unlocked && chain != nil {
+		go w.selfDerive()
+	}
+}
+
+// selfDerive derives the accounts along the discovery base path until the first
+// one without any balance or nonce.
+func (w *wallet) selfDerive() {
+	// Derive from a copy of the master key, as locking the wallet zeroes it
+	w.stateLock.RLock()
+	var (
+		master = w.master
+		chain  = w.deriveChain
+		path   = make(accounts.DerivationPath, len(w.deriveBase))
+	)
+	copy(path, w.deriveBase)
+	if master == nil || chain == nil || len(path) == 0 {
+		w.stateLock.RUnlock()
+		return
+	}
+	clone := master.clone()
+	w.stateLock.RUnlock()
+
+	defer clone.zero()
+	for {
+		key, err := clone.derive(path)
+		if err != nil {
+			log.Warn("HD wallet account derivation failed", "url", w.url, "path", path, "err", err)
+			return
+		}
+		addr, err := key.address()
+		key.zero()
+		if err != nil {
+			log.Warn("HD wallet account derivation failed", "url", w.url, "path", path, "err", err)
+			return
+		}
+
+		balance, err := chain.BalanceAt(context.Background(), addr, nil)
+		if err != nil {
+			log.Warn("HD wallet balance retrieval failed", "url", w.url, "err", err)
+			return
+		}
+		nonce, err := chain.NonceAt(context.Background(), addr, nil)
+		if err != nil {
+			log.Warn("HD wallet nonce retrieval failed", "url", w.url, "err", err)
+			return
+		}
+		w.stateLock.Lock()
+		if w.master != master {
+			w.stateLock.Unlock()
+			return // Locked in the meantime
+		}
+		if _, known := w.paths[addr]; !known {
+			log.Info("HD wallet discovered new account", "address", addr, "path", path, "balance", balance, "nonce", nonce)
+		}
+		w.track(addr, append(accounts.DerivationPath{}, path...))
+		w.stateLock.Unlock()
+
+		if balance.Sign() == 0 && nonce == 0 {
+			return
+		}
+		path[len(path)-1]++
+	}
+}
+
+// signingKey derives the private key of an account, using the unlocked master key
+// or the one decrypted with the passphrase if given.
+func (w *wallet) signingKey(account accounts.Account, passphrase *string) (*ecdsa.PrivateKey, error) {
+	// Take a copy of the unlocked master key, as locking the wallet zeroes it
+	w.stateLock.RLock()
+	path, ok := w.paths[account.Address]
+	if !ok {
+		w.stateLock.RUnlock()
+		return nil, accounts.ErrUnknownAccount
+	}
+	var master *extendedKey
+	if passphrase == nil && w.master != nil {
+		master = w.master.clone()
+	}
+	w.stateLock.RUnlock()
+
+	if passphrase != nil {
+		var err error
+		if master, err = w.unlock(*passphrase); err != nil {
+			return nil, err
+		}
+	}
+	if master == nil {
+		return nil, ErrLocked
+	}
+	defer master.zero()
+
+	key, err := master.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer key.zero()
+	return key.privateKey()
+}
+
+// SignHash implements accounts.Wallet, signing the hash with an account of the
+// unlocked wallet.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return w.signHash(account, nil, hash)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, signing the hash with an
+// account of the wallet decrypted with the passphrase.
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return w.signHash(account, &passphrase, hash)
+}
+
+func (w *wallet) signHash(account accounts.Account, passphrase *string, hash []byte) ([]byte, error) {
+	key, err := w.signingKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with an account of
+// the unlocked wallet.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.signTx(account, nil, tx, chainID)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, signing the transaction with
+// an account of the wallet decrypted with the passphrase.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.signTx(account, &passphrase, tx, chainID)
+}
+
+func (w *wallet) signTx(account accounts.Account, passphrase *string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.signingKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+
+	// Depending on the presence of the chain ID, sign with EIP155 or homestead
+	if chainID != nil {
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+	}
+	return types.SignTx(tx, types.HomesteadSigner{}, key)
+}
+
+// zeroKey zeroes a private key in memory.
+func zeroKey(k *ecdsa.PrivateKey) {
+	b := k.D.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -25,6 +25,7 @@ import (
 	crand "crypto/rand"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -463,15 +464,49 @@ func (ks *KeyStore) importKey(key *Key, passphrase string) (accounts.Account, er
 	return a, nil
 }
 
-// Update changes the passphrase of an existing account.
+// Update changes the passphrase of an existing account. Key files encrypted with
+// Argon2id are re-encrypted with the same Argon2id parameters.
 func (ks *KeyStore) Update(a accounts.Account, passphrase, newPassphrase string) error {
 	a, key, err := ks.getDecryptedKey(a, passphrase)
 	if err != nil {
 		return err
 	}
+	// Keep upgraded key files on Argon2id instead of falling back to scrypt
+	if _, ok := ks.storage.(*keyStorePassphrase); ok {
+		if keyjson, err := ioutil.ReadFile(a.URL.Path); err == nil {
+			if t, m, ok := argon2idParams(keyjson); ok {
+				defer zeroKey(key.PrivateKey)
+
+				if keyjson, err = EncryptKeyArgon2id(key, newPassphrase, t, m); err != nil {
+					return err
+				}
+				return writeKeyFile(a.URL.Path, keyjson)
+			}
+		}
+	}
 	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
 }
 
+// UpgradeArgon2id re-encrypts the key file of an account with the same passphrase,
+// deriving the key with Argon2id of the given number of passes and memory (in KiB)
+// and encrypting it with AES-256-GCM. The file is replaced atomically.
+func (ks *KeyStore) UpgradeArgon2id(a accounts.Account, passphrase string, time, memory uint32) error {
+	if _, ok := ks.storage.(*keyStorePassphrase); !ok {
+		return errors.New("cannot encrypt keys of a plaintext keystore")
+	}
+	a, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	keyjson, err := EncryptKeyArgon2id(key, passphrase, time, memory)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(a.URL.Path, keyjson)
+}
+
 // ImportPreSaleKey decrypts the given Ethereum presale wallet and stores
 // a key file in the key directory. The key file is encrypted with the same passphrase.
 func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (accounts.Account, error) {
@@ -28,11 +28,13 @@ package keystore
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	crand "crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
 
@@ -41,12 +43,17 @@ import (
 	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/crypto/randentropy"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
 
 const (
-	keyHeaderKDF = "scrypt"
+	keyHeaderKDF         = "scrypt"
+	keyHeaderKDFArgon2id = "argon2id"
+
+	cipherAESCTR = "aes-128-ctr"
+	cipherAESGCM = "aes-256-gcm"
 
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
@@ -66,6 +73,33 @@ const (
 
 	scryptR     = 8
 	scryptDKLen = 32
+
+	// StandardArgon2idTime is the number of passes of Argon2id encryption, using
+	// 256MB memory and taking approximately 1s CPU time on a modern processor.
+	StandardArgon2idTime = 3
+
+	// StandardArgon2idMemory is the memory (in KiB) of Argon2id encryption, using
+	// 256MB memory and taking approximately 1s CPU time on a modern processor.
+	StandardArgon2idMemory = 256 * 1024
+
+	// LightArgon2idTime is the number of passes of Argon2id encryption, using 4MB
+	// memory and taking approximately 20ms CPU time on a modern processor.
+	LightArgon2idTime = 3
+
+	// LightArgon2idMemory is the memory (in KiB) of Argon2id encryption, using 4MB
+	// memory and taking approximately 20ms CPU time on a modern processor.
+	LightArgon2idMemory = 4 * 1024
+
+	// MaxArgon2idTime and MaxArgon2idMemory cap the number of passes and memory
+	// (in KiB) of Argon2id, both when encrypting and when decrypting, so a crafted
+	// key file can't exhaust the memory or CPU of the node.
+	MaxArgon2idTime   = 64
+	MaxArgon2idMemory = 4 * StandardArgon2idMemory
+
+	argon2idThreads = 4
+	argon2idDKLen   = 32
+
+	gcmTagSize = 16
 )
 
 type keyStorePassphrase struct {
@@ -112,22 +146,21 @@ func (ks keyStorePassphrase) JoinPath(filename string) string {
 	return filepath.Join(ks.keysDirPath, filename)
 }
 
-// EncryptKey encrypts a key using the specified scrypt parameters into a json
-// blob that can be decrypted later on.
-func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
-	authArray := []byte(auth)
+// EncryptDataV3 encrypts the data given as 'data' with the password 'auth',
+// using the specified scrypt parameters and the cipher of the version 3 key
+// files.
+func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error) {
 	salt := randentropy.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(authArray, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	derivedKey, err := scrypt.Key(auth, salt, scryptN, scryptR, scryptP, scryptDKLen)
 	if err != nil {
-		return nil, err
+		return CryptoJSON{}, err
 	}
 	encryptKey := derivedKey[:16]
-	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
 
 	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
-	cipherText, err := aesCTRXOR(encryptKey, keyBytes, iv)
+	cipherText, err := aesCTRXOR(encryptKey, data, iv)
 	if err != nil {
-		return nil, err
+		return CryptoJSON{}, err
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
@@ -142,14 +175,78 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		IV: hex.EncodeToString(iv),
 	}
 
-	cryptoStruct := cryptoJSON{
-		Cipher:       "aes-128-ctr",
+	cryptoStruct := CryptoJSON{
+		Cipher:       cipherAESCTR,
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
 		KDF:          keyHeaderKDF,
 		KDFParams:    scryptParamsJSON,
 		MAC:          hex.EncodeToString(mac),
 	}
+	return cryptoStruct, nil
+}
+
+// EncryptKey encrypts a key using the specified scrypt parameters into a json
+// blob that can be decrypted later on.
+func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	cryptoStruct, err := EncryptDataV3(keyBytes, []byte(auth), scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKeyJSONV3 := encryptedKeyJSONV3{
+		hex.EncodeToString(key.Address[:]),
+		cryptoStruct,
+		key.Id.String(),
+		version,
+	}
+	return json.Marshal(encryptedKeyJSONV3)
+}
+
+// EncryptDataArgon2id encrypts the data given as 'data' with the password 'auth'
+// using AES-256-GCM, deriving the key with Argon2id of the specified number of
+// passes and memory (in KiB).
+func EncryptDataArgon2id(data, auth []byte, time, memory uint32) (CryptoJSON, error) {
+	if err := checkArgon2idParams(int(time), int(memory), argon2idThreads); err != nil {
+		return CryptoJSON{}, err
+	}
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey := argon2.IDKey(auth, salt, time, memory, argon2idThreads, argon2idDKLen)
+
+	sealed, nonce, err := AESGCMEncrypt(derivedKey, data)
+	if err != nil {
+		return CryptoJSON{}, err
+	}
+	// Store the authentication tag separately, as the MAC of the other formats
+	cipherText, tag := sealed[:len(sealed)-gcmTagSize], sealed[len(sealed)-gcmTagSize:]
+
+	argon2idParamsJSON := make(map[string]interface{}, 5)
+	argon2idParamsJSON["t"] = int(time)
+	argon2idParamsJSON["m"] = int(memory)
+	argon2idParamsJSON["p"] = argon2idThreads
+	argon2idParamsJSON["dklen"] = argon2idDKLen
+	argon2idParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	cryptoStruct := CryptoJSON{
+		Cipher:       cipherAESGCM,
+		CipherText:   hex.EncodeToString(cipherText),
+		CipherParams: cipherparamsJSON{IV: hex.EncodeToString(nonce)},
+		KDF:          keyHeaderKDFArgon2id,
+		KDFParams:    argon2idParamsJSON,
+		MAC:          hex.EncodeToString(tag),
+	}
+	return cryptoStruct, nil
+}
+
+// EncryptKeyArgon2id encrypts a key using Argon2id with the specified number of
+// passes and memory (in KiB) and AES-256-GCM into a json blob that can be
+// decrypted later on.
+func EncryptKeyArgon2id(key *Key, auth string, time, memory uint32) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	cryptoStruct, err := EncryptDataArgon2id(keyBytes, []byte(auth), time, memory)
+	if err != nil {
+		return nil, err
+	}
 	encryptedKeyJSONV3 := encryptedKeyJSONV3{
 		hex.EncodeToString(key.Address[:]),
 		cryptoStruct,
@@ -197,42 +294,79 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	}, nil
 }
 
-func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
-	if keyProtected.Version != version {
-		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
+// DecryptDataV3 decrypts data encrypted by EncryptDataV3 or EncryptDataArgon2id
+// with the password 'auth'.
+func DecryptDataV3(cryptoJson CryptoJSON, auth string) ([]byte, error) {
+	if cryptoJson.Cipher == cipherAESGCM {
+		return decryptDataGCM(cryptoJson, auth)
 	}
-
-	if keyProtected.Crypto.Cipher != "aes-128-ctr" {
-		return nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
+	if cryptoJson.Cipher != cipherAESCTR {
+		return nil, fmt.Errorf("Cipher not supported: %v", cryptoJson.Cipher)
 	}
-
-	keyId = uuid.Parse(keyProtected.Id)
-	mac, err := hex.DecodeString(keyProtected.Crypto.MAC)
+	mac, err := hex.DecodeString(cryptoJson.MAC)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	iv, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+	iv, err := hex.DecodeString(cryptoJson.CipherParams.IV)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	cipherText, err := hex.DecodeString(cryptoJson.CipherText)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	derivedKey, err := getKDFKey(cryptoJson, auth)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
 	if !bytes.Equal(calculatedMAC, mac) {
-		return nil, nil, ErrDecrypt
+		return nil, ErrDecrypt
 	}
 
 	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	return plainText, err
+}
+
+// decryptDataGCM decrypts data encrypted with AES-256-GCM, the authentication
+// tag being stored as the MAC.
+func decryptDataGCM(cryptoJson CryptoJSON, auth string) ([]byte, error) {
+	tag, err := hex.DecodeString(cryptoJson.MAC)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(cryptoJson.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(cryptoJson.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := getKDFKey(cryptoJson, auth)
+	if err != nil {
+		return nil, err
+	}
+	plainText, err := AESGCMDecrypt(derivedKey, nonce, append(cipherText, tag...))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plainText, nil
+}
+
+func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
+	if keyProtected.Version != version {
+		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
+	}
+	keyId = uuid.Parse(keyProtected.Id)
+	plainText, err := DecryptDataV3(keyProtected.Crypto, auth)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -273,7 +407,31 @@ func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byt
 	return plainText, keyId, err
 }
 
-func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
+// checkArgon2idParams checks the number of passes, memory (in KiB) and threads
+// of Argon2id against the bounds of the algorithm and the caps of the keystore.
+func checkArgon2idParams(t, m, p int) error {
+	if t < 1 || t > MaxArgon2idTime || p < 1 || p > 255 || m < 8*p || m > MaxArgon2idMemory {
+		return fmt.Errorf("Invalid Argon2id parameters: t=%d m=%d p=%d", t, m, p)
+	}
+	return nil
+}
+
+// argon2idParams returns the number of passes and memory (in KiB) of the key
+// file, if it is encrypted with Argon2id.
+func argon2idParams(keyjson []byte) (time, memory uint32, ok bool) {
+	k := new(encryptedKeyJSONV3)
+	if err := json.Unmarshal(keyjson, k); err != nil || k.Crypto.KDF != keyHeaderKDFArgon2id {
+		return 0, 0, false
+	}
+	t, tok := k.Crypto.KDFParams["t"].(float64)
+	m, mok := k.Crypto.KDFParams["m"].(float64)
+	if !tok || !mok {
+		return 0, 0, false
+	}
+	return uint32(t), uint32(m), true
+}
+
+func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 	authArray := []byte(auth)
 	salt, err := hex.DecodeString(cryptoJSON.KDFParams["salt"].(string))
 	if err != nil {
@@ -287,6 +445,18 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == keyHeaderKDFArgon2id {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if err := checkArgon2idParams(t, m, p); err != nil {
+			return nil, err
+		}
+		if dkLen < 1 {
+			return nil, fmt.Errorf("Invalid Argon2id key length: %d", dkLen)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)
@@ -300,6 +470,40 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 	return nil, fmt.Errorf("Unsupported KDF: %s", cryptoJSON.KDF)
 }
 
+// AESGCMEncrypt encrypts and authenticates the plaintext with AES-GCM under a
+// random nonce, returning the ciphertext (with the tag appended) and the nonce.
+func AESGCMEncrypt(key, plainText []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(crand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	return aesgcm.Seal(nil, nonce, plainText, nil), nonce, nil
+}
+
+// AESGCMDecrypt authenticates and decrypts a ciphertext produced by AESGCMEncrypt.
+func AESGCMDecrypt(key, nonce, cipherText []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aesgcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+	return aesgcm.Open(nil, nonce, cipherText, nil)
+}
+
 // TODO: can we do without this when unmarshalling dynamic JSON?
 // why do integers in KDF params end up as float64 and not int after
 // unmarshal?
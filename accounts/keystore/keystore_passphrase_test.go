@@ -17,7 +17,9 @@
 package keystore
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/eeefan/dpeth/common"
@@ -58,3 +60,77 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that keys encrypted with Argon2id and AES-GCM can be decrypted, and that
+// tampering with them is detected.
+func TestKeyEncryptDecryptArgon2id(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if keyjson, err = EncryptKeyArgon2id(key, "foo", 1, 64); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+		t.Errorf("bad password error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	decrypted, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if decrypted.Address != key.Address || decrypted.Id.String() != key.Id.String() {
+		t.Errorf("key mismatch: have %x/%s, want %x/%s", decrypted.Address, decrypted.Id, key.Address, key.Id)
+	}
+	// Flip a bit of the ciphertext and ensure the authentication fails
+	k := new(encryptedKeyJSONV3)
+	if err := json.Unmarshal(keyjson, k); err != nil {
+		t.Fatal(err)
+	}
+	if k.Crypto.KDF != keyHeaderKDFArgon2id || k.Crypto.Cipher != cipherAESGCM {
+		t.Errorf("crypto mismatch: have %s/%s, want %s/%s", k.Crypto.KDF, k.Crypto.Cipher, keyHeaderKDFArgon2id, cipherAESGCM)
+	}
+	cipherText := common.FromHex(k.Crypto.CipherText)
+	cipherText[0] ^= 1
+	k.Crypto.CipherText = common.Bytes2Hex(cipherText)
+	if _, err := DecryptDataV3(k.Crypto, "foo"); err != ErrDecrypt {
+		t.Errorf("tampered ciphertext error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+}
+
+// Tests that Argon2id parameters beyond the caps are refused, both when
+// encrypting and when decrypting a crafted key file.
+func TestArgon2idParamsCapped(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if _, err := EncryptKeyArgon2id(key, "foo", 1, MaxArgon2idMemory+1); err == nil {
+		t.Errorf("encrypted with memory beyond the cap")
+	}
+	if _, err := EncryptKeyArgon2id(key, "foo", MaxArgon2idTime+1, 64); err == nil {
+		t.Errorf("encrypted with passes beyond the cap")
+	}
+	if keyjson, err = EncryptKeyArgon2id(key, "foo", 1, 64); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	for _, params := range []map[string]interface{}{{"m": MaxArgon2idMemory + 1}, {"t": MaxArgon2idTime + 1}} {
+		k := new(encryptedKeyJSONV3)
+		if err := json.Unmarshal(keyjson, k); err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range params {
+			k.Crypto.KDFParams[name] = value
+		}
+		if _, err := DecryptDataV3(k.Crypto, "foo"); err == nil || !strings.Contains(err.Error(), "Invalid Argon2id parameters") {
+			t.Errorf("crafted parameters %v error mismatch: %v", params, err)
+		}
+	}
+}
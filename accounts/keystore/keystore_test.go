@@ -68,6 +68,45 @@ func TestKeyStore(t *testing.T) {
 	}
 }
 
+func TestKeyStoreUpgradeArgon2id(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.UpgradeArgon2id(a, "bar", 1, 64); err != ErrDecrypt {
+		t.Errorf("wrong passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.UpgradeArgon2id(a, "foo", 1, 64); err != nil {
+		t.Fatalf("Upgrade error: %v", err)
+	}
+	keyjson, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(keyjson), `"kdf":"argon2id"`) {
+		t.Errorf("key file not upgraded: %s", keyjson)
+	}
+	if _, err := ks.SignHashWithPassphrase(a, "foo", testSigData); err != nil {
+		t.Errorf("failed to sign with upgraded key: %v", err)
+	}
+	// Changing the passphrase must keep the key on Argon2id
+	if err := ks.Update(a, "foo", "bar"); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if keyjson, err = ioutil.ReadFile(a.URL.Path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(keyjson), `"kdf":"argon2id"`) || !strings.Contains(string(keyjson), `"m":64`) {
+		t.Errorf("updated key file not on Argon2id: %s", keyjson)
+	}
+	if _, err := ks.SignHashWithPassphrase(a, "bar", testSigData); err != nil {
+		t.Errorf("failed to sign with updated key: %v", err)
+	}
+}
+
 func TestSign(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -0,0 +1,266 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/eeefan/dpeth/cmd/utils"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/signer/core"
+	"github.com/eeefan/dpeth/signer/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	auditSealerFlag = cli.StringFlag{
+		Name:  "sealer",
+		Usage: "Address the seals of the audit log must be signed by",
+	}
+	auditAccountFlag = cli.StringFlag{
+		Name:  "account",
+		Usage: "Only show the entries concerning this account",
+	}
+	auditFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Only show the entries from this time on (RFC3339 or YYYY-MM-DD)",
+	}
+	auditToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Only show the entries before this time (RFC3339 or YYYY-MM-DD)",
+	}
+	auditCommand = cli.Command{
+		Name:     "audit",
+		Usage:    "Inspect the audit log",
+		Category: "AUDIT COMMANDS",
+		Description: `
+The audit log is an append-only file of hash-chained entries, each committing to
+the one before it. Signed seals, made with a key derived from the master seed
+every --auditlog.seal entries, prevent rewriting the log without that key.
+
+Clef refuses to start with an audit log failing the integrity checks. With
+--auditlog.rotate, it moves the log aside instead and starts a new chain, whose
+first entry records the rotation and commits to the head of the old chain.`,
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(auditVerify),
+				Name:   "verify",
+				Usage:  "Check the integrity of the audit log",
+				Flags: []cli.Flag{
+					auditLogFlag,
+					auditSealerFlag,
+					configdirFlag,
+					signerSecretFlag,
+				},
+				Description: `
+The verify command checks the hash chain and the seals of the audit log. With
+--sealer, every seal must be signed by the given address, as printed by Clef
+when starting with sealing enabled.
+
+If the master seed is available, the log must also reach the head Clef stored in
+its encrypted configuration on every append, detecting entries cut off the end.
+Otherwise, entries written after the last seal are reported, as truncating them
+cannot be detected.`,
+			},
+			{
+				Action: utils.MigrateFlags(auditShow),
+				Name:   "show",
+				Usage:  "Print the entries of the audit log",
+				Flags: []cli.Flag{
+					auditLogFlag,
+					auditAccountFlag,
+					auditFromFlag,
+					auditToFlag,
+				},
+				Description: `
+The show command prints the entries of the audit log matching the filters, after
+checking their integrity. Printing stops at the first corrupted entry.`,
+			},
+		},
+	}
+)
+
+func auditVerify(c *cli.Context) error {
+	var want *common.Address
+	if c.IsSet(auditSealerFlag.Name) {
+		hex := c.String(auditSealerFlag.Name)
+		if !common.IsHexAddress(hex) {
+			utils.Fatalf("Invalid sealer address %q", hex)
+		}
+		addr := common.HexToAddress(hex)
+		want = &addr
+	}
+	var (
+		path     = c.String(auditLogFlag.Name)
+		head     *core.AuditHead
+		reached  bool
+		entries  uint64
+		sealed   uint64
+		sealers  = make(map[common.Address]uint64)
+		lastSeal = int64(-1)
+	)
+	if heads := auditHeadStorage(c); heads != nil {
+		head = core.StoredAuditHead(heads, path)
+	}
+	err := core.ReadAuditLog(path, func(rec *core.AuditRecord) error {
+		entries++
+		if rec.Method == core.AuditRotated {
+			printAuditRotation(rec)
+		}
+		if head != nil && rec.Index == head.Index {
+			if rec.Hash != head.Hash {
+				return fmt.Errorf("entry %d hash %x, stored head %x", rec.Index, rec.Hash, head.Hash)
+			}
+			reached = true
+		}
+		if rec.Sealer == nil {
+			return nil
+		}
+		if want != nil && *rec.Sealer != *want {
+			return fmt.Errorf("entry %d sealed by %s, want %s", rec.Index, rec.Sealer.Hex(), want.Hex())
+		}
+		sealed++
+		sealers[*rec.Sealer]++
+		lastSeal = int64(rec.Index)
+		return nil
+	})
+	if err != nil {
+		utils.Fatalf("Audit log verification failed: %v", err)
+	}
+	if head != nil && !reached {
+		utils.Fatalf("Audit log truncated: stored head #%d [%x] missing", head.Index, head.Hash)
+	}
+	fmt.Printf("Verified %d entries of %s, hash chain intact\n", entries, path)
+	for sealer, count := range sealers {
+		fmt.Printf("%d seals by %s\n", count, sealer.Hex())
+	}
+	if want != nil && sealed == 0 && entries > 0 {
+		utils.Fatalf("Audit log is not sealed by %s", want.Hex())
+	}
+	if len(sealers) > 1 {
+		utils.Fatalf("Audit log is sealed by multiple accounts")
+	}
+	if head != nil {
+		fmt.Printf("Stored head #%d reached, no entries cut off\n", head.Index)
+	} else if unsealed := int64(entries) - lastSeal - 1; sealed > 0 && unsealed > 0 {
+		fmt.Printf("The last %d entries are not covered by a seal\n", unsealed)
+	}
+	return nil
+}
+
+// printAuditRotation reports the old chain a rotated audit log continues from.
+func printAuditRotation(rec *core.AuditRecord) {
+	var rotation core.AuditRotation
+	if err := json.Unmarshal(rec.Request, &rotation); err != nil {
+		utils.Fatalf("Invalid rotation entry: %v", err)
+	}
+	fmt.Printf("Audit log rotated at %s: %s\n", rec.Time.Format(time.RFC3339), rotation.Reason)
+	if rotation.Moved != "" {
+		fmt.Printf("  old log moved to %s\n", rotation.Moved)
+	}
+	if rotation.Head != nil {
+		fmt.Printf("  continuing from its head #%d [%x]\n", rotation.Head.Index, rotation.Head.Hash)
+	}
+}
+
+// auditHeadStorage opens the encrypted configuration storage holding the head of
+// the audit log, or returns nil if the master seed is not available.
+func auditHeadStorage(c *cli.Context) storage.Storage {
+	stretchedKey, err := readMasterKey(c)
+	if err != nil {
+		return nil
+	}
+	vaultLocation := filepath.Join(c.String(configdirFlag.Name), common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
+	confkey := crypto.Keccak256([]byte("config"), stretchedKey)
+	return storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
+}
+
+func auditShow(c *cli.Context) error {
+	var account *common.Address
+	if hex := c.String(auditAccountFlag.Name); hex != "" {
+		if !common.IsHexAddress(hex) {
+			utils.Fatalf("Invalid account address %q", hex)
+		}
+		addr := common.HexToAddress(hex)
+		account = &addr
+	}
+	from, err := parseAuditTime(c.String(auditFromFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid --%s time: %v", auditFromFlag.Name, err)
+	}
+	to, err := parseAuditTime(c.String(auditToFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid --%s time: %v", auditToFlag.Name, err)
+	}
+	err = core.ReadAuditLog(c.String(auditLogFlag.Name), func(rec *core.AuditRecord) error {
+		switch {
+		case account != nil && (rec.Account == nil || *rec.Account != *account):
+			return nil
+		case !from.IsZero() && rec.Time.Before(from):
+			return nil
+		case !to.IsZero() && !rec.Time.Before(to):
+			return nil
+		}
+		printAuditRecord(rec)
+		return nil
+	})
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	return nil
+}
+
+// parseAuditTime parses a time given either in RFC3339 or as a plain date.
+func parseAuditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func printAuditRecord(rec *core.AuditRecord) {
+	fmt.Printf("#%d %s %s\n", rec.Index, rec.Time.Format(time.RFC3339), rec.Method)
+	fmt.Printf("  remote:   %s (%s)\n", rec.Metadata.Remote, rec.Metadata.Scheme)
+	if rec.Account != nil {
+		fmt.Printf("  account:  %s\n", rec.Account.Hex())
+	}
+	if len(rec.Request) > 0 {
+		fmt.Printf("  request:  %s\n", rec.Request)
+	}
+	if rec.Verdict != "" {
+		fmt.Printf("  rules:    %s\n", rec.Verdict)
+	}
+	if rec.Decision != "" {
+		fmt.Printf("  ui:       %s\n", rec.Decision)
+	}
+	if len(rec.Result) > 0 {
+		fmt.Printf("  result:   %s\n", rec.Result)
+	}
+	if rec.Error != "" {
+		fmt.Printf("  error:    %s\n", rec.Error)
+	}
+	if rec.Sealer != nil {
+		fmt.Printf("  sealed:   %s\n", rec.Sealer.Hex())
+	}
+}
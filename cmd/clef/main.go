@@ -21,6 +21,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -42,16 +43,17 @@ import (
 	"github.com/eeefan/dpeth/node"
 	"github.com/eeefan/dpeth/rpc"
 	"github.com/eeefan/dpeth/signer/core"
+	"github.com/eeefan/dpeth/signer/policy"
 	"github.com/eeefan/dpeth/signer/rules"
 	"github.com/eeefan/dpeth/signer/storage"
 	"gopkg.in/urfave/cli.v1"
 )
 
 // ExternalAPIVersion -- see extapi_changelog.md
-const ExternalAPIVersion = "2.0.0"
+const ExternalAPIVersion = "2.2.0"
 
 // InternalAPIVersion -- see intapi_changelog.md
-const InternalAPIVersion = "2.0.0"
+const InternalAPIVersion = "2.2.0"
 
 const legalWarning = `
 WARNING! 
@@ -104,11 +106,28 @@ var (
 		Usage: "File used to emit audit logs. Set to \"\" to disable",
 		Value: "audit.log",
 	}
+	auditSealFlag = cli.Uint64Flag{
+		Name:  "auditlog.seal",
+		Usage: "Sign the audit log every N entries with a key derived from the master seed (0 = disabled)",
+	}
+	auditRotateFlag = cli.BoolFlag{
+		Name:  "auditlog.rotate",
+		Usage: "Move an audit log failing the integrity checks aside and start a new chain, instead of refusing to start",
+	}
 	ruleFlag = cli.StringFlag{
 		Name:  "rules",
 		Usage: "Enable rule-engine",
 		Value: "rules.json",
 	}
+	policyFlag = cli.StringFlag{
+		Name:  "policy",
+		Usage: "Enable value and rate limit policies (JSON, or TOML with a .toml extension)",
+		Value: "policy.json",
+	}
+	attestPolicyFlag = cli.BoolFlag{
+		Name:  "policy",
+		Usage: "Attest a policy file instead of a ruleset",
+	}
 	stdiouiFlag = cli.BoolFlag{
 		Name: "stdio-ui",
 		Usage: "Use STDIN/STDOUT as a channel for an external UI. " +
@@ -142,13 +161,16 @@ the rule-engine to work.`,
 			logLevelFlag,
 			configdirFlag,
 			signerSecretFlag,
+			attestPolicyFlag,
 		},
 		Description: `
 The attest command stores the sha256 of the rule.js-file that you want to use for automatic processing of 
 incoming requests. 
 
 Whenever you make an edit to the rule file, you need to use attestation to tell 
-Clef that the file is 'safe' to execute.`,
+Clef that the file is 'safe' to execute.
+
+With --policy, the sha256 of the policy file is attested instead.`,
 	}
 
 	addCredentialCommand = cli.Command{
@@ -188,12 +210,15 @@ func init() {
 		dBFlag,
 		customDBFlag,
 		auditLogFlag,
+		auditSealFlag,
+		auditRotateFlag,
 		ruleFlag,
+		policyFlag,
 		stdiouiFlag,
 		testFlag,
 	}
 	app.Action = signer
-	app.Commands = []cli.Command{initCommand, attestCommand, addCredentialCommand}
+	app.Commands = []cli.Command{initCommand, attestCommand, addCredentialCommand, auditCommand}
 
 }
 func main() {
@@ -261,6 +286,11 @@ func attestFile(ctx *cli.Context) error {
 	// Initialize the encrypted storages
 	configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
 	val := ctx.Args().First()
+	if ctx.Bool(attestPolicyFlag.Name) {
+		configStorage.Put("policy_sha256", val)
+		log.Info("Policy attestation updated", "sha256", val)
+		return nil
+	}
 	configStorage.Put("ruleset_sha256", val)
 	log.Info("Ruleset attestation updated", "sha256", val)
 	return nil
@@ -325,6 +355,11 @@ func signer(c *cli.Context) error {
 		log.Info("Using CLI as UI-channel")
 		ui = core.NewCommandlineUI()
 	}
+	// Record the decisions of the UI and the rules in the audit log
+	logfile := c.String(auditLogFlag.Name)
+	if logfile != "" {
+		ui = core.NewAuditUI(ui)
+	}
 	db, err := core.NewAbiDBFromFiles(c.String(dBFlag.Name), c.String(customDBFlag.Name))
 	if err != nil {
 		utils.Fatalf(err.Error())
@@ -332,7 +367,10 @@ func signer(c *cli.Context) error {
 	log.Info("Loaded 4byte db", "signatures", db.Size(), "file", c.String("4bytedb"))
 
 	var (
-		api core.ExternalAPI
+		api          core.ExternalAPI
+		sealKey      *ecdsa.PrivateKey
+		auditHeads   storage.Storage
+		rulesEnabled bool
 	)
 
 	configDir := c.String(configdirFlag.Name)
@@ -349,11 +387,15 @@ func signer(c *cli.Context) error {
 		pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
 		jskey := crypto.Keccak256([]byte("jsstorage"), stretchedKey)
 		confkey := crypto.Keccak256([]byte("config"), stretchedKey)
+		policykey := crypto.Keccak256([]byte("policies"), stretchedKey)
+		sealKey, _ = crypto.ToECDSA(crypto.Keccak256([]byte("auditlog"), stretchedKey))
 
 		// Initialize the encrypted storages
 		pwStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
 		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
 		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
+		policyStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "policies.json"), policykey)
+		auditHeads = configStorage
 
 		//Do we have a rule-file?
 		ruleJS, err := ioutil.ReadFile(c.String(ruleFlag.Name))
@@ -374,9 +416,31 @@ func signer(c *cli.Context) error {
 				}
 				ruleEngine.Init(string(ruleJS))
 				ui = ruleEngine
+				rulesEnabled = true
 				log.Info("Rule engine configured", "file", c.String(ruleFlag.Name))
 			}
 		}
+		// Do we have a policy file? Policies are enforced before the rules
+		policyFile := c.String(policyFlag.Name)
+		if policyBlob, err := ioutil.ReadFile(policyFile); err != nil {
+			log.Info("Could not load policy file, policies not enabled", "file", policyFile)
+		} else {
+			shasum := sha256.Sum256(policyBlob)
+			if storedShasum := configStorage.Get("policy_sha256"); storedShasum != hex.EncodeToString(shasum[:]) {
+				log.Info("Could not validate policy hash, policies not enabled", "got", hex.EncodeToString(shasum[:]), "expected", storedShasum)
+			} else {
+				config, err := policy.LoadConfig(policyFile)
+				if err != nil {
+					utils.Fatalf("Invalid policy file: %v", err)
+				}
+				ui = policy.NewPolicyEvaluator(ui, config, policyStorage)
+				rulesEnabled = true
+				log.Info("Policies configured", "file", policyFile, "accounts", len(config.Accounts))
+			}
+		}
+	}
+	if rulesEnabled && logfile != "" {
+		ui = core.NewAuditRulesUI(ui)
 	}
 
 	apiImpl := core.NewSignerAPI(
@@ -389,11 +453,28 @@ func signer(c *cli.Context) error {
 	api = apiImpl
 
 	// Audit logging
-	if logfile := c.String(auditLogFlag.Name); logfile != "" {
-		api, err = core.NewAuditLogger(logfile, api)
+	if logfile != "" {
+		if auditHeads == nil {
+			log.Warn("No master seed provided, audit log truncation not detectable")
+		}
+		auditLogger, err := core.NewAuditLogger(logfile, api, auditHeads, c.Bool(auditRotateFlag.Name))
+		if _, ok := err.(*core.AuditChainError); ok {
+			utils.Fatalf("%v\nInspect it with 'clef audit verify', or pass --%s to move it aside and start a new chain", err, auditRotateFlag.Name)
+		}
 		if err != nil {
 			utils.Fatalf(err.Error())
 		}
+		defer auditLogger.Close()
+
+		if interval := c.Uint64(auditSealFlag.Name); interval > 0 {
+			if sealKey == nil {
+				log.Warn("No master seed provided, audit log sealing disabled")
+			} else {
+				auditLogger.EnableSealing(sealKey, interval)
+				log.Info("Audit log sealing enabled", "sealer", crypto.PubkeyToAddress(sealKey.PublicKey), "interval", interval)
+			}
+		}
+		api = auditLogger
 		log.Info("Audit logs configured", "file", logfile)
 	}
 	// register signer API with server
@@ -415,7 +496,7 @@ func signer(c *cli.Context) error {
 
 		// start http server
 		httpEndpoint := fmt.Sprintf("%s:%d", c.String(utils.RPCListenAddrFlag.Name), c.Int(rpcPortFlag.Name))
-		listener, _, err := rpc.StartHTTPEndpoint(httpEndpoint, rpcAPI, []string{"account"}, cors, vhosts)
+		listener, _, err := rpc.StartHTTPEndpoint(httpEndpoint, rpcAPI, []string{"account"}, cors, vhosts, rpc.ServerLimits{}, rpc.AuthConfig{})
 		if err != nil {
 			utils.Fatalf("Could not start RPC api: %v", err)
 		}
@@ -19,8 +19,10 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/hdwallet"
 	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/cmd/utils"
 	"github.com/eeefan/dpeth/console"
@@ -72,7 +74,8 @@ passwordfile as argument containing the wallet password in plaintext.`,
 		Description: `
 
 Manage accounts, list all existing accounts, import a private key into a new
-account, create a new account or update an existing account.
+account, create a new account, update an existing account or upgrade the
+encryption of existing accounts.
 
 It supports interactive mode, when you are prompted for password as well as
 non-interactive mode where passwords are supplied via a given password file.
@@ -84,7 +87,8 @@ either new or import). Without it you are not able to unlock your account.
 
 Note that exporting your key in unencrypted format is NOT supported.
 
-Keys are stored under <DATADIR>/keystore.
+Keys are stored under <DATADIR>/keystore, mnemonic wallets under
+<DATADIR>/keystore/hdwallets.
 It is safe to transfer the entire directory or the individual keys therein
 between ethereum nodes by simply copying.
 
@@ -110,6 +114,8 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.MnemonicFlag,
+					utils.HDPathFlag,
 				},
 				Description: `
     geth account new
@@ -120,6 +126,11 @@ The account is saved in encrypted format, you are prompted for a passphrase.
 
 You must remember this passphrase to unlock your account in the future.
 
+With --mnemonic, a new hierarchical deterministic wallet is created instead and
+its mnemonic printed, which is the backup of all accounts derived from it. The
+account at --hdpath is derived right away, further ones can be derived with
+personal.deriveAccount once the wallet is opened with personal.openWallet.
+
 For non-interactive use the passphrase can be specified with the --password flag:
 
 Note, this is meant to be used for testing only, it is a bad idea to save your
@@ -153,6 +164,32 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:      "upgrade",
+				Usage:     "Re-encrypt existing accounts with a stronger key derivation",
+				Action:    utils.MigrateFlags(accountUpgrade),
+				ArgsUsage: "[<address>...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.KDFFlag,
+				},
+				Description: `
+    dpeth account upgrade [--kdf argon2id] [<address>...]
+
+Re-encrypts the given accounts, or all accounts of the keystore if none is given,
+keeping their passphrases. With --kdf argon2id (the default) the keys are derived
+with Argon2id and encrypted with AES-256-GCM, with --kdf scrypt they are saved in
+the version 3 format using scrypt and AES-128-CTR. Each key file is replaced
+atomically, so an interrupted upgrade leaves every account readable.
+
+You are prompted for the passphrase of each account. For non-interactive use the
+passphrases can be specified with the --password flag, one per line in the order
+the accounts are upgraded.
 `,
 			},
 			{
@@ -186,6 +223,31 @@ Note:
 As you can directly copy your encrypted accounts to another ethereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "import-mnemonic",
+				Usage:  "Import a mnemonic into a new hierarchical deterministic wallet",
+				Action: utils.MigrateFlags(accountImportMnemonic),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.HDPathFlag,
+				},
+				ArgsUsage: "[<mnemonicFile>]",
+				Description: `
+    dpeth account import-mnemonic [<mnemonicFile>]
+
+Imports a BIP-39 mnemonic from <mnemonicFile>, or prompts for it, and creates a
+new hierarchical deterministic wallet. Prints the address of the account at
+--hdpath (m/44'/60'/0'/0/0 by default), which is derived right away.
+
+The mnemonic is saved in encrypted format under <KEYSTORE>/hdwallets, you are
+prompted for a passphrase.
+
+You must remember this passphrase to open the wallet in the future.
 `,
 			},
 		},
@@ -289,8 +351,9 @@ func ambiguousAddrRecovery(ks *keystore.KeyStore, err *keystore.AmbiguousAddrErr
 	return *match
 }
 
-// accountCreate creates a new account into the keystore defined by the CLI flags.
-func accountCreate(ctx *cli.Context) error {
+// accountConfig returns the keystore location and encryption parameters defined
+// by the config file and the CLI flags.
+func accountConfig(ctx *cli.Context) (scryptN, scryptP int, keydir string) {
 	cfg := gethConfig{Node: defaultNodeConfig()}
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
@@ -304,9 +367,29 @@ func accountCreate(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Failed to read configuration: %v", err)
 	}
+	return scryptN, scryptP, keydir
+}
+
+// accountCreate creates a new account into the keystore defined by the CLI flags,
+// or into a new mnemonic wallet if requested.
+func accountCreate(ctx *cli.Context) error {
+	scryptN, scryptP, keydir := accountConfig(ctx)
 
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
+	if ctx.Bool(utils.MnemonicFlag.Name) {
+		mnemonic, err := hdwallet.NewMnemonic()
+		if err != nil {
+			utils.Fatalf("Failed to generate mnemonic: %v", err)
+		}
+		account := storeMnemonic(ctx, keydir, scryptN, scryptP, mnemonic, password)
+		fmt.Printf("Address: {%x}\n", account.Address)
+		fmt.Printf("Wallet:  %s\n\n", account.URL.Path)
+		fmt.Println("Write down the mnemonic below and keep it safe, it is the only backup of all the")
+		fmt.Println("accounts of the wallet and it will not be shown again:")
+		fmt.Printf("\n%s\n\n", mnemonic)
+		return nil
+	}
 	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
 
 	if err != nil {
@@ -316,6 +399,48 @@ func accountCreate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountImportMnemonic stores an existing mnemonic as a new wallet, read from
+// the given file or requested interactively.
+func accountImportMnemonic(ctx *cli.Context) error {
+	var mnemonic string
+	if file := ctx.Args().First(); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = string(content)
+	} else {
+		input, err := console.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = input
+	}
+	scryptN, scryptP, keydir := accountConfig(ctx)
+
+	password := getPassPhrase("Your new wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	account := storeMnemonic(ctx, keydir, scryptN, scryptP, mnemonic, password)
+	fmt.Printf("Address: {%x}\n", account.Address)
+	fmt.Printf("Wallet:  %s\n", account.URL.Path)
+	return nil
+}
+
+// storeMnemonic stores a mnemonic wallet in the keystore directory, pinning the
+// account at the derivation path given by the CLI flags.
+func storeMnemonic(ctx *cli.Context, keydir string, scryptN, scryptP int, mnemonic, password string) accounts.Account {
+	path, err := accounts.ParseDerivationPath(ctx.String(utils.HDPathFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid derivation path: %v", err)
+	}
+	backend := hdwallet.NewBackend(filepath.Join(keydir, hdwallet.DirName), scryptN, scryptP)
+	account, err := backend.Import(mnemonic, password, path)
+	if err != nil {
+		utils.Fatalf("Failed to store the mnemonic wallet: %v", err)
+	}
+	return account
+}
+
 // accountUpdate transitions an account from a previous format to the current
 // one, also providing the possibility to change the pass-phrase.
 func accountUpdate(ctx *cli.Context) error {
@@ -335,6 +460,47 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountUpgrade re-encrypts the given accounts, or all of them, with the key
+// derivation function requested by the CLI flags.
+func accountUpgrade(ctx *cli.Context) error {
+	kdf := ctx.String(utils.KDFFlag.Name)
+	if kdf != "argon2id" && kdf != "scrypt" {
+		utils.Fatalf("Unsupported key derivation function %q", kdf)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	addrs := []string(ctx.Args())
+	if len(addrs) == 0 {
+		for _, account := range ks.Accounts() {
+			addrs = append(addrs, account.Address.Hex())
+		}
+	}
+	if len(addrs) == 0 {
+		utils.Fatalf("No accounts to upgrade")
+	}
+	time, memory := uint32(keystore.StandardArgon2idTime), uint32(keystore.StandardArgon2idMemory)
+	if ctx.Bool(utils.LightKDFFlag.Name) {
+		time, memory = keystore.LightArgon2idTime, keystore.LightArgon2idMemory
+	}
+	passwords := utils.MakePasswordList(ctx)
+	for i, addr := range addrs {
+		account, password := unlockAccount(ctx, ks, addr, i, passwords)
+
+		var err error
+		if kdf == "argon2id" {
+			err = ks.UpgradeArgon2id(account, password, time, memory)
+		} else {
+			err = ks.Update(account, password, password)
+		}
+		if err != nil {
+			utils.Fatalf("Could not upgrade the account %s: %v", addr, err)
+		}
+		fmt.Printf("Upgraded account {%x} to %s\n", account.Address, kdf)
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
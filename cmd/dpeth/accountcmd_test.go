@@ -91,6 +91,24 @@ Fatal: Passphrases do not match
 `)
 }
 
+func TestAccountImportMnemonic(t *testing.T) {
+	mnemonic := filepath.Join(tmpdir(t), "mnemonic")
+	phrase := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about\n"
+	if err := ioutil.WriteFile(mnemonic, []byte(phrase), 0600); err != nil {
+		t.Fatal(err)
+	}
+	geth := runGeth(t, "account", "import-mnemonic", "--lightkdf", "--hdpath", "m/44'/60'/0'/0/1", mnemonic)
+	defer geth.ExpectExit()
+	geth.Expect(`
+Your new wallet is locked with a password. Please give a password. Do not forget this password.
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Repeat passphrase: {{.InputLine "foobar"}}
+Address: {6fac4d18c912343bf86fa7049364dd4e424ab9c0}
+`)
+	geth.ExpectRegexp(`Wallet:  .*hdwallets.UTC--.*--[0-9a-f]{40}\n`)
+}
+
 func TestAccountUpdate(t *testing.T) {
 	datadir := tmpDatadirWithKeystore(t)
 	geth := runGeth(t, "account", "update",
@@ -107,6 +125,20 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountUpgrade(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	geth := runGeth(t, "account", "upgrade",
+		"--datadir", datadir, "--lightkdf", "--kdf", "argon2id",
+		"f466859ead1932d743d622cb74fc058882e8648a")
+	defer geth.ExpectExit()
+	geth.Expect(`
+Unlocking account f466859ead1932d743d622cb74fc058882e8648a | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Upgraded account {f466859ead1932d743d622cb74fc058882e8648a} to argon2id
+`)
+}
+
 func TestWalletImport(t *testing.T) {
 	geth := runGeth(t, "wallet", "import", "--lightkdf", "testdata/guswallet.json")
 	defer geth.ExpectExit()
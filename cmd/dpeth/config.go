@@ -30,6 +30,7 @@ import (
 	"github.com/eeefan/dpeth/cmd/utils"
 	"github.com/eeefan/dpeth/dashboard"
 	"github.com/eeefan/dpeth/eth"
+	"github.com/eeefan/dpeth/graphql"
 	"github.com/eeefan/dpeth/node"
 	"github.com/eeefan/dpeth/params"
 	whisper "github.com/eeefan/dpeth/whisper/whisperv6"
@@ -80,6 +81,7 @@ type gethConfig struct {
 	Node      node.Config
 	Ethstats  ethstatsConfig
 	Dashboard dashboard.Config
+	GraphQL   graphql.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -114,6 +116,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		Shh:       whisper.DefaultConfig,
 		Node:      defaultNodeConfig(),
 		Dashboard: dashboard.DefaultConfig,
+		GraphQL:   graphql.DefaultConfig,
 	}
 
 	// Load config file.
@@ -136,6 +139,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
+	utils.SetGraphQLConfig(ctx, &cfg.GraphQL)
 
 	return stack, cfg
 }
@@ -171,6 +175,10 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+	// Add the GraphQL server if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack, &cfg.GraphQL)
+	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
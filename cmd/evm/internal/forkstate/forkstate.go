@@ -0,0 +1,330 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkstate implements a state database forking the state of a remote
+// node at a given block, fetching accounts, code and storage on first access.
+package forkstate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/core/state"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/ethclient"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/params"
+	"github.com/eeefan/dpeth/rlp"
+	"github.com/eeefan/dpeth/rpc"
+)
+
+// fetchTimeout is the time allowed for retrieving a piece of remote state.
+const fetchTimeout = 30 * time.Second
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// Database is a state.Database forking the state of a remote node at a block.
+// Accounts, code and storage are fetched from the remote node the first time
+// they are accessed, and cached in an in-memory database holding everything
+// fetched and modified since. The root hashes of the forked tries are thus
+// those of the accessed part of the state, not of the remote state.
+type Database struct {
+	state.Database // In-memory database holding the fetched and modified state
+
+	client *rpc.Client
+	eth    *ethclient.Client
+	block  *big.Int
+
+	lock     sync.Mutex
+	noProof  bool                                      // Whether the remote node lacks eth_getProof
+	accounts map[common.Address][]byte                 // Fetched accounts, RLP encoded, nil if missing
+	roots    map[common.Address]common.Hash            // Remote storage roots of the fetched accounts
+	addrs    map[common.Hash]common.Address            // Addresses of the fetched accounts by hash
+	storage  map[common.Address]map[common.Hash][]byte // Fetched storage slots, RLP encoded
+	codes    map[common.Hash][]byte                    // Fetched contract code by hash
+}
+
+// New creates a state database forking the state of the remote node behind
+// client, as of the given block.
+func New(client *rpc.Client, block uint64) *Database {
+	return &Database{
+		Database: state.NewDatabase(ethdb.NewMemDatabase()),
+		client:   client,
+		eth:      ethclient.NewClient(client),
+		block:    new(big.Int).SetUint64(block),
+		accounts: make(map[common.Address][]byte),
+		roots:    make(map[common.Address]common.Hash),
+		addrs:    make(map[common.Hash]common.Address),
+		storage:  make(map[common.Address]map[common.Hash][]byte),
+		codes:    make(map[common.Hash][]byte),
+	}
+}
+
+// ChainConfig returns the configuration of the chain the remote node behind
+// client runs, recognised by its genesis block. It fails for chains other than
+// the main and test networks, whose configuration has to be supplied instead.
+func ChainConfig(client *rpc.Client) (*params.ChainConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	genesis, err := ethclient.NewClient(client).HeaderByNumber(ctx, common.Big0)
+	if err != nil {
+		return nil, err
+	}
+	return genesisConfig(genesis.Hash())
+}
+
+// genesisConfig returns the chain config of the network with the given genesis.
+func genesisConfig(hash common.Hash) (*params.ChainConfig, error) {
+	switch hash {
+	case params.MainnetGenesisHash:
+		return params.MainnetChainConfig, nil
+	case params.TestnetGenesisHash:
+		return params.TestnetChainConfig, nil
+	default:
+		return nil, fmt.Errorf("unknown chain with genesis %x", hash)
+	}
+}
+
+// OpenTrie opens the account trie, backed by the remote accounts.
+func (db *Database) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, fetch: db.account, dirty: make(map[string]struct{})}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account, backed by the remote
+// storage if the account was fetched from the remote node. Tries opened with a
+// root other than the remote one are the ones committed locally, which contain
+// every slot accessed before their commit.
+func (db *Database) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	db.lock.Lock()
+	addr, remote := db.addrs[addrHash]
+	remoteRoot := db.roots[addr]
+	db.lock.Unlock()
+
+	if !remote || root == (common.Hash{}) || root == types.EmptyRootHash {
+		return db.Database.OpenStorageTrie(addrHash, root)
+	}
+	local := root
+	if root == remoteRoot {
+		local = common.Hash{}
+	}
+	tr, err := db.Database.OpenStorageTrie(addrHash, local)
+	if err != nil {
+		return nil, err
+	}
+	fetch := func(key []byte) ([]byte, error) {
+		return db.slot(addr, common.BytesToHash(key))
+	}
+	return &forkTrie{Trie: tr, fetch: fetch, dirty: make(map[string]struct{})}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *Database) CopyTrie(t state.Trie) state.Trie {
+	if t, ok := t.(*forkTrie); ok {
+		dirty := make(map[string]struct{}, len(t.dirty))
+		for key := range t.dirty {
+			dirty[key] = struct{}{}
+		}
+		return &forkTrie{Trie: db.Database.CopyTrie(t.Trie), fetch: t.fetch, dirty: dirty}
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// ContractCode retrieves a particular contract's code, fetched or local.
+func (db *Database) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	db.lock.Lock()
+	code, ok := db.codes[codeHash]
+	db.lock.Unlock()
+
+	if ok {
+		return code, nil
+	}
+	return db.Database.ContractCode(addrHash, codeHash)
+}
+
+// ContractCodeSize retrieves a particular contract's code size, fetched or local.
+func (db *Database) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	db.lock.Lock()
+	code, ok := db.codes[codeHash]
+	db.lock.Unlock()
+
+	if ok {
+		return len(code), nil
+	}
+	return db.Database.ContractCodeSize(addrHash, codeHash)
+}
+
+// proofResult is the account part of an eth_getProof result.
+type proofResult struct {
+	Balance     *hexutil.Big   `json:"balance"`
+	CodeHash    common.Hash    `json:"codeHash"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	StorageHash common.Hash    `json:"storageHash"`
+}
+
+// account returns the RLP encoded remote account with the given address, or
+// nil if it doesn't exist.
+func (db *Database) account(key []byte) ([]byte, error) {
+	addr := common.BytesToAddress(key)
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if enc, ok := db.accounts[addr]; ok {
+		return enc, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	var (
+		account = state.Account{Balance: new(big.Int), Root: types.EmptyRootHash, CodeHash: emptyCodeHash[:]}
+		code    []byte
+	)
+	// Retrieve the account through eth_getProof, resorting to the individual
+	// getters if the remote node doesn't support it
+	var proof proofResult
+	if !db.noProof {
+		err := db.client.CallContext(ctx, &proof, "eth_getProof", addr, []common.Hash{}, hexutil.EncodeBig(db.block))
+		if rpcErr, ok := err.(rpc.Error); ok && rpcErr.ErrorCode() == -32601 {
+			log.Debug("Remote node doesn't support eth_getProof, using individual getters")
+			db.noProof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if !db.noProof {
+		if proof.Balance != nil {
+			account.Balance = proof.Balance.ToInt()
+		}
+		account.Nonce = uint64(proof.Nonce)
+		if proof.StorageHash != (common.Hash{}) {
+			account.Root = proof.StorageHash
+		}
+		if proof.CodeHash != (common.Hash{}) && proof.CodeHash != emptyCodeHash {
+			var err error
+			if code, err = db.eth.CodeAt(ctx, addr, db.block); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		var err error
+		if account.Balance, err = db.eth.BalanceAt(ctx, addr, db.block); err != nil {
+			return nil, err
+		}
+		if account.Nonce, err = db.eth.NonceAt(ctx, addr, db.block); err != nil {
+			return nil, err
+		}
+		if code, err = db.eth.CodeAt(ctx, addr, db.block); err != nil {
+			return nil, err
+		}
+		// The storage root is unknown, assume only contracts have storage
+		if len(code) > 0 {
+			account.Root = crypto.Keccak256Hash(addr[:])
+		}
+	}
+	if len(code) > 0 {
+		account.CodeHash = crypto.Keccak256(code)
+		db.codes[common.BytesToHash(account.CodeHash)] = code
+	}
+	var enc []byte
+	if account.Nonce != 0 || account.Balance.Sign() != 0 || len(code) > 0 {
+		var err error
+		if enc, err = rlp.EncodeToBytes(&account); err != nil {
+			return nil, err
+		}
+	}
+	log.Trace("Fetched remote account", "address", addr, "exists", enc != nil)
+
+	db.accounts[addr] = enc
+	db.roots[addr] = account.Root
+	db.addrs[crypto.Keccak256Hash(addr[:])] = addr
+	return enc, nil
+}
+
+// slot returns the RLP encoded value of a remote storage slot, or nil if empty.
+func (db *Database) slot(addr common.Address, key common.Hash) ([]byte, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if enc, ok := db.storage[addr][key]; ok {
+		return enc, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	value, err := db.eth.StorageAt(ctx, addr, key, db.block)
+	if err != nil {
+		return nil, err
+	}
+	var enc []byte
+	if value = bytes.TrimLeft(value, "\x00"); len(value) > 0 {
+		if enc, err = rlp.EncodeToBytes(value); err != nil {
+			return nil, err
+		}
+	}
+	log.Trace("Fetched remote storage slot", "address", addr, "key", key)
+
+	if db.storage[addr] == nil {
+		db.storage[addr] = make(map[common.Hash][]byte)
+	}
+	db.storage[addr][key] = enc
+	return enc, nil
+}
+
+// forkTrie is a trie falling back to the remote state for the entries missing
+// locally. Fetched entries are inserted into the local trie.
+type forkTrie struct {
+	state.Trie // Local trie holding the fetched and modified entries
+
+	fetch func(key []byte) ([]byte, error) // Retrieves an entry from the remote state
+	dirty map[string]struct{}              // Entries modified locally, never to be fetched
+}
+
+func (t *forkTrie) TryGet(key []byte) ([]byte, error) {
+	if enc, err := t.Trie.TryGet(key); enc != nil || err != nil {
+		return enc, err
+	}
+	if _, ok := t.dirty[string(key)]; ok {
+		return nil, nil
+	}
+	enc, err := t.fetch(key)
+	if enc == nil || err != nil {
+		return nil, err
+	}
+	return enc, t.Trie.TryUpdate(key, enc)
+}
+
+func (t *forkTrie) TryUpdate(key, value []byte) error {
+	t.dirty[string(key)] = struct{}{}
+	return t.Trie.TryUpdate(key, value)
+}
+
+func (t *forkTrie) TryDelete(key []byte) error {
+	t.dirty[string(key)] = struct{}{}
+	return t.Trie.TryDelete(key)
+}
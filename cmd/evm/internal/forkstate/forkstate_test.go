@@ -0,0 +1,312 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package forkstate
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/core/state"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/core/vm/runtime"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/params"
+	"github.com/eeefan/dpeth/rpc"
+)
+
+// remoteAccount is an account of the stand-in remote node.
+type remoteAccount struct {
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// RemoteNode is a stand-in for the eth API of a remote node, serving a single
+// block of state. It is exported for the RPC server to accept it.
+type RemoteNode struct {
+	block    string
+	accounts map[common.Address]*remoteAccount
+
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+func (n *RemoteNode) account(method string, addr common.Address, block string) (*remoteAccount, error) {
+	n.lock.Lock()
+	n.calls[method]++
+	n.lock.Unlock()
+
+	if block != n.block {
+		return nil, fmt.Errorf("unknown block %s", block)
+	}
+	if account := n.accounts[addr]; account != nil {
+		return account, nil
+	}
+	return &remoteAccount{balance: new(big.Int)}, nil
+}
+
+func (n *RemoteNode) GetBalance(addr common.Address, block string) (*hexutil.Big, error) {
+	account, err := n.account("balance", addr, block)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(account.balance), nil
+}
+
+func (n *RemoteNode) GetTransactionCount(addr common.Address, block string) (hexutil.Uint64, error) {
+	account, err := n.account("nonce", addr, block)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(account.nonce), nil
+}
+
+func (n *RemoteNode) GetCode(addr common.Address, block string) (hexutil.Bytes, error) {
+	account, err := n.account("code", addr, block)
+	if err != nil {
+		return nil, err
+	}
+	return account.code, nil
+}
+
+func (n *RemoteNode) GetStorageAt(addr common.Address, key string, block string) (hexutil.Bytes, error) {
+	account, err := n.account("storage", addr, block)
+	if err != nil {
+		return nil, err
+	}
+	value := account.storage[common.HexToHash(key)]
+	return value[:], nil
+}
+
+// ChainNode is a stand-in remote node also serving the genesis header of a chain.
+type ChainNode struct {
+	*RemoteNode
+	genesis *types.Header
+}
+
+func (n ChainNode) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	if number != "0x0" {
+		return nil, fmt.Errorf("unknown block %s", number)
+	}
+	return n.genesis, nil
+}
+
+// ProofNode is a stand-in remote node also supporting eth_getProof.
+type ProofNode struct {
+	*RemoteNode
+}
+
+func (n ProofNode) GetProof(addr common.Address, keys []string, block string) (map[string]interface{}, error) {
+	account, err := n.account("proof", addr, block)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"balance":     (*hexutil.Big)(account.balance),
+		"nonce":       hexutil.Uint64(account.nonce),
+		"codeHash":    crypto.Keccak256Hash(account.code),
+		"storageHash": emptyRoot,
+	}
+	if len(account.storage) > 0 {
+		result["storageHash"] = common.HexToHash("0x1234") // Anything non-empty will do
+	}
+	return result, nil
+}
+
+var (
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	user     = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	contract = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	missing  = common.HexToAddress("0x3000000000000000000000000000000000000003")
+
+	// sloadCode returns the value of the storage slot 0
+	sloadCode = common.FromHex("60005460005260206000f3")
+
+	// revertCode reverts with the value of the storage slot 0
+	revertCode = common.FromHex("60005460005260206000fd")
+)
+
+func newRemoteNode() *RemoteNode {
+	return &RemoteNode{
+		block: "0x5",
+		accounts: map[common.Address]*remoteAccount{
+			user: {balance: big.NewInt(1000), nonce: 3},
+			contract: {balance: new(big.Int), nonce: 1, code: sloadCode, storage: map[common.Hash]common.Hash{
+				common.HexToHash("0x00"): common.HexToHash("0x2a"),
+				common.HexToHash("0x01"): common.HexToHash("0x07"),
+			}},
+		},
+		calls: make(map[string]int),
+	}
+}
+
+func TestForkedState(t *testing.T) {
+	for _, proof := range []bool{true, false} {
+		t.Run(fmt.Sprintf("proof=%v", proof), func(t *testing.T) {
+			node := newRemoteNode()
+
+			server := rpc.NewServer()
+			var err error
+			if proof {
+				err = server.RegisterName("eth", ProofNode{node})
+			} else {
+				err = server.RegisterName("eth", node)
+			}
+			if err != nil {
+				t.Fatalf("failed to register stand-in node: %v", err)
+			}
+			defer server.Stop()
+			client := rpc.DialInProc(server)
+			defer client.Close()
+
+			statedb, err := state.New(common.Hash{}, New(client, 5))
+			if err != nil {
+				t.Fatalf("failed to create forked state: %v", err)
+			}
+			// Accounts, code and storage must be fetched on access
+			if balance := statedb.GetBalance(user); balance.Cmp(big.NewInt(1000)) != 0 {
+				t.Errorf("balance mismatch: have %v, want 1000", balance)
+			}
+			if nonce := statedb.GetNonce(user); nonce != 3 {
+				t.Errorf("nonce mismatch: have %d, want 3", nonce)
+			}
+			if statedb.Exist(missing) {
+				t.Errorf("missing account exists")
+			}
+			if code := statedb.GetCode(contract); string(code) != string(sloadCode) {
+				t.Errorf("code mismatch: have %x, want %x", code, sloadCode)
+			}
+			if value := statedb.GetState(contract, common.HexToHash("0x01")); value != common.HexToHash("0x07") {
+				t.Errorf("storage mismatch: have %x, want 0x07", value)
+			}
+			// Code run over the forked state must see the remote storage
+			ret, _, err := runtime.Call(contract, nil, &runtime.Config{State: statedb})
+			if err != nil {
+				t.Fatalf("failed to run contract: %v", err)
+			}
+			if common.BytesToHash(ret) != common.HexToHash("0x2a") {
+				t.Errorf("call result mismatch: have %x, want 0x2a", ret)
+			}
+			// Local modifications must override the remote state, including deletions
+			statedb.SetState(contract, common.HexToHash("0x00"), common.Hash{})
+			statedb.SetState(contract, common.HexToHash("0x02"), common.HexToHash("0x01"))
+			statedb.AddBalance(user, big.NewInt(1))
+			statedb.IntermediateRoot(true)
+
+			if value := statedb.GetState(contract, common.HexToHash("0x00")); value != (common.Hash{}) {
+				t.Errorf("deleted slot refetched: have %x", value)
+			}
+			if value := statedb.GetState(contract, common.HexToHash("0x02")); value != common.HexToHash("0x01") {
+				t.Errorf("modified slot mismatch: have %x, want 0x01", value)
+			}
+			if balance := statedb.GetBalance(user); balance.Cmp(big.NewInt(1001)) != 0 {
+				t.Errorf("balance mismatch: have %v, want 1001", balance)
+			}
+			if err := statedb.Error(); err != nil {
+				t.Fatalf("state error: %v", err)
+			}
+			// Everything must have been fetched once, through the proofs if available:
+			// the accounts above and the zero address origin of the call, and the
+			// slots read or overwritten
+			node.lock.Lock()
+			defer node.lock.Unlock()
+
+			if proof {
+				if node.calls["proof"] != 4 || node.calls["balance"] != 0 || node.calls["nonce"] != 0 {
+					t.Errorf("unexpected account fetches: %v", node.calls)
+				}
+				if node.calls["code"] != 1 {
+					t.Errorf("code fetched %d times, want once", node.calls["code"])
+				}
+			} else {
+				if node.calls["balance"] != 4 || node.calls["nonce"] != 4 || node.calls["code"] != 4 {
+					t.Errorf("unexpected account fetches: %v", node.calls)
+				}
+			}
+			if node.calls["storage"] != 3 {
+				t.Errorf("storage fetched %d times, want 3", node.calls["storage"])
+			}
+		})
+	}
+}
+
+func TestForkedStateErrors(t *testing.T) {
+	node := newRemoteNode()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("failed to register stand-in node: %v", err)
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Failures of the remote node must surface as state errors
+	statedb, _ := state.New(common.Hash{}, New(client, 6))
+	statedb.GetBalance(user)
+	if err := statedb.Error(); err == nil {
+		t.Fatalf("no error for unknown block")
+	}
+}
+
+// Tests that forked runs are executed with the chain config of the remote node,
+// so that failing calls reproduce with the opcodes of the forked block.
+func TestForkedChainConfig(t *testing.T) {
+	node := newRemoteNode()
+	node.accounts[contract].code = revertCode
+
+	server := rpc.NewServer()
+	genesis := &types.Header{Number: common.Big0, Difficulty: common.Big1, Extra: []byte("other")}
+	if err := server.RegisterName("eth", ChainNode{node, genesis}); err != nil {
+		t.Fatalf("failed to register stand-in node: %v", err)
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Chains other than mainnet and testnet are not recognised
+	if config, err := ChainConfig(client); err == nil {
+		t.Fatalf("unknown chain recognised as %v", config)
+	}
+	for hash, want := range map[common.Hash]*params.ChainConfig{
+		params.MainnetGenesisHash: params.MainnetChainConfig,
+		params.TestnetGenesisHash: params.TestnetChainConfig,
+	} {
+		config, err := genesisConfig(hash)
+		if err != nil {
+			t.Fatalf("failed to determine chain config of %x: %v", hash, err)
+		}
+		if config != want {
+			t.Fatalf("chain config mismatch for %x: have %v, want %v", hash, config, want)
+		}
+		statedb, _ := state.New(common.Hash{}, New(client, 5))
+		ret, _, err := runtime.Call(contract, nil, &runtime.Config{State: statedb, ChainConfig: config, BlockNumber: big.NewInt(5)})
+		if err == nil || err.Error() != "evm: execution reverted" {
+			t.Fatalf("revert mismatch: have %v, want evm: execution reverted", err)
+		}
+		if !bytes.Equal(ret, common.HexToHash("0x2a").Bytes()) {
+			t.Errorf("revert data mismatch: have %x, want 0x2a", ret)
+		}
+	}
+}
@@ -90,6 +90,15 @@ var (
 		Name:  "prestate",
 		Usage: "JSON file with prestate (genesis) config",
 	}
+	ForkRPCFlag = cli.StringFlag{
+		Name:  "fork-rpc",
+		Usage: "RPC endpoint of a node to fork the state of",
+	}
+	ForkBlockFlag = cli.StringFlag{
+		Name:  "fork-block",
+		Usage: "Number of the block to fork the state at (number or latest)",
+		Value: "latest",
+	}
 	MachineFlag = cli.BoolFlag{
 		Name:  "json",
 		Usage: "output trace logs in machine readable format (json)",
@@ -128,6 +137,8 @@ func init() {
 		CPUProfileFlag,
 		StatDumpFlag,
 		GenesisFlag,
+		ForkRPCFlag,
+		ForkBlockFlag,
 		MachineFlag,
 		SenderFlag,
 		ReceiverFlag,
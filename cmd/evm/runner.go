@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -28,24 +29,35 @@ import (
 	"time"
 
 	"github.com/eeefan/dpeth/cmd/evm/internal/compiler"
+	"github.com/eeefan/dpeth/cmd/evm/internal/forkstate"
 	"github.com/eeefan/dpeth/cmd/utils"
 	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/math"
 	"github.com/eeefan/dpeth/core"
 	"github.com/eeefan/dpeth/core/state"
+	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/core/vm"
 	"github.com/eeefan/dpeth/core/vm/runtime"
+	"github.com/eeefan/dpeth/ethclient"
 	"github.com/eeefan/dpeth/ethdb"
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/params"
+	"github.com/eeefan/dpeth/rpc"
 	cli "gopkg.in/urfave/cli.v1"
 )
 
 var runCommand = cli.Command{
-	Action:      runCmd,
-	Name:        "run",
-	Usage:       "run arbitrary evm binary",
-	ArgsUsage:   "<code>",
-	Description: `The run command runs arbitrary EVM code.`,
+	Action:    runCmd,
+	Name:      "run",
+	Usage:     "run arbitrary evm binary",
+	ArgsUsage: "<code>",
+	Description: `The run command runs arbitrary EVM code.
+
+With --fork-rpc, the code runs on top of the state of a remote node at the
+--fork-block block, in the context of that block. Accounts, code and storage
+are fetched from the node on first access. The chain config is that of the main
+or test network the node runs, or the one of the --prestate file, whose state
+is ignored.`,
 }
 
 // readGenesis will read the given JSON format genesis file and return
@@ -69,6 +81,22 @@ func readGenesis(genesisPath string) *core.Genesis {
 	return genesis
 }
 
+// forkBlock retrieves the header of the block to fork the state of a remote node
+// at, given by number or as latest.
+func forkBlock(client *rpc.Client, block string) (*types.Header, error) {
+	var number *big.Int
+	if block != "latest" {
+		var ok bool
+		if number, ok = math.ParseBig256(block); !ok {
+			return nil, fmt.Errorf("invalid block number %q", block)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return ethclient.NewClient(client).HeaderByNumber(ctx, number)
+}
+
 func runCmd(ctx *cli.Context) error {
 	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
@@ -84,6 +112,7 @@ func runCmd(ctx *cli.Context) error {
 		debugLogger *vm.StructLogger
 		statedb     *state.StateDB
 		chainConfig *params.ChainConfig
+		forkHeader  *types.Header
 		sender      = common.BytesToAddress([]byte("sender"))
 		receiver    = common.BytesToAddress([]byte("receiver"))
 		blockNumber uint64
@@ -96,7 +125,29 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		debugLogger = vm.NewStructLogger(logconfig)
 	}
-	if ctx.GlobalString(GenesisFlag.Name) != "" {
+	if ctx.GlobalString(ForkRPCFlag.Name) != "" {
+		client, err := rpc.Dial(ctx.GlobalString(ForkRPCFlag.Name))
+		if err != nil {
+			utils.Fatalf("Failed to connect to fork node: %v", err)
+		}
+		defer client.Close()
+
+		// The prestate only supplies the chain config of forked runs
+		if ctx.GlobalString(GenesisFlag.Name) != "" {
+			if chainConfig = readGenesis(ctx.GlobalString(GenesisFlag.Name)).Config; chainConfig == nil {
+				utils.Fatalf("No chain config in --%s file", GenesisFlag.Name)
+			}
+		} else if chainConfig, err = forkstate.ChainConfig(client); err != nil {
+			utils.Fatalf("Failed to determine chain config of fork node, supply it with --%s: %v", GenesisFlag.Name, err)
+		}
+
+		forkHeader, err = forkBlock(client, ctx.GlobalString(ForkBlockFlag.Name))
+		if err != nil {
+			utils.Fatalf("Failed to retrieve fork block: %v", err)
+		}
+		blockNumber = forkHeader.Number.Uint64()
+		statedb, _ = state.New(common.Hash{}, forkstate.New(client, blockNumber))
+	} else if ctx.GlobalString(GenesisFlag.Name) != "" {
 		gen := readGenesis(ctx.GlobalString(GenesisFlag.Name))
 		db := ethdb.NewMemDatabase()
 		genesis := gen.ToBlock(db)
@@ -109,7 +160,10 @@ func runCmd(ctx *cli.Context) error {
 	if ctx.GlobalString(SenderFlag.Name) != "" {
 		sender = common.HexToAddress(ctx.GlobalString(SenderFlag.Name))
 	}
-	statedb.CreateAccount(sender)
+	// Keep the sender of a forked state as it is, if it exists
+	if forkHeader == nil || !statedb.Exist(sender) {
+		statedb.CreateAccount(sender)
+	}
 
 	if ctx.GlobalString(ReceiverFlag.Name) != "" {
 		receiver = common.HexToAddress(ctx.GlobalString(ReceiverFlag.Name))
@@ -185,6 +239,11 @@ func runCmd(ctx *cli.Context) error {
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
+	if forkHeader != nil {
+		runtimeConfig.Coinbase = forkHeader.Coinbase
+		runtimeConfig.Time = new(big.Int).Set(forkHeader.Time)
+		runtimeConfig.Difficulty = new(big.Int).Set(forkHeader.Difficulty)
+	}
 	tstart := time.Now()
 	var leftOverGas uint64
 	if ctx.GlobalBool(CreateFlag.Name) {
@@ -198,6 +257,10 @@ func runCmd(ctx *cli.Context) error {
 	}
 	execTime := time.Since(tstart)
 
+	if err := statedb.Error(); err != nil {
+		utils.Fatalf("Failed to access state: %v", err)
+	}
+
 	if ctx.GlobalBool(DumpFlag.Name) {
 		statedb.IntermediateRoot(true)
 		fmt.Println(string(statedb.Dump()))
@@ -0,0 +1,178 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// fundRequest is a funding request received from a faucet website client.
+type fundRequest struct {
+	URL       string `json:"url"`       // Social network URL or plain address, depending on the authentication
+	Tier      uint   `json:"tier"`      // Funding tier requested
+	Captcha   string `json:"captcha"`   // Recaptcha response if bot protection is enabled
+	Signature string `json:"signature"` // Signature of the funding message, for signed authentication
+	Signer    bool   `json:"signer"`    // Whether the self-vote stake of a new alien signer is requested
+}
+
+// authenticator verifies funding requests against a single kind of proof.
+type authenticator interface {
+	// accepts returns whether the request carries the proof the authenticator
+	// is able to verify.
+	accepts(req *fundRequest) bool
+
+	// authenticate verifies the request, returning the username to rate limit,
+	// the avatar URL and the Ethereum address to fund.
+	authenticate(req *fundRequest) (string, string, common.Address, error)
+}
+
+// urlAuth authenticates requests linking to a post on a social network.
+type urlAuth struct {
+	prefix string                                                   // URL prefix of the posts on the network
+	auth   func(url string) (string, string, common.Address, error) // Scraper verifying the post
+}
+
+func (a *urlAuth) accepts(req *fundRequest) bool {
+	return strings.HasPrefix(req.URL, a.prefix)
+}
+
+func (a *urlAuth) authenticate(req *fundRequest) (string, string, common.Address, error) {
+	return a.auth(req.URL)
+}
+
+// signedAuth authenticates requests by the signature of the funding message
+// with the key of the requested address, restricted to an allowlist.
+type signedAuth struct {
+	allowed map[common.Address]bool // Addresses allowed to request funds
+}
+
+// errNoAllowlist is returned if signed authentication is requested without an
+// allowlist. Fresh keys cost nothing, so anyone could sign for as many funded
+// addresses as they liked, draining the faucet and its signer stakes.
+var errNoAllowlist = errors.New("signed authentication requires an allowlist")
+
+// newSignedAuth creates a signed message authenticator, restricted to the
+// addresses listed in the allowlist file.
+func newSignedAuth(allowlist string) (*signedAuth, error) {
+	if allowlist == "" {
+		return nil, errNoAllowlist
+	}
+	file, err := os.Open(allowlist)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	auth := &signedAuth{allowed: make(map[common.Address]bool)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !common.IsHexAddress(line) {
+			return nil, fmt.Errorf("invalid allowlist address %q", line)
+		}
+		auth.allowed[common.HexToAddress(line)] = true
+	}
+	return auth, scanner.Err()
+}
+
+// fundMessage returns the text to sign by the key of address to request funds.
+func fundMessage(address common.Address) string {
+	return fmt.Sprintf("Requesting faucet funds into %s", address.Hex())
+}
+
+// textHash computes the hash of the given text as signed by personal_sign.
+func textHash(text string) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(text), text)))
+}
+
+func (a *signedAuth) accepts(req *fundRequest) bool {
+	return req.Signature != ""
+}
+
+func (a *signedAuth) authenticate(req *fundRequest) (string, string, common.Address, error) {
+	address := common.HexToAddress(regexp.MustCompile("0x[0-9a-fA-F]{40}").FindString(req.URL))
+	if address == (common.Address{}) {
+		return "", "", common.Address{}, errors.New("No Ethereum address found to fund")
+	}
+	if !a.allowed[address] {
+		return "", "", common.Address{}, errors.New("Address not allowed to request funds")
+	}
+	sig, err := hexutil.Decode(req.Signature)
+	if err != nil || len(sig) != 65 {
+		return "", "", common.Address{}, errors.New("Invalid signature")
+	}
+	// Accept both the Ethereum (27/28) and the raw (0/1) recovery ids
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	pubkey, err := crypto.SigToPub(textHash(fundMessage(address)), sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != address {
+		return "", "", common.Address{}, errors.New("Signature doesn't match the address to fund")
+	}
+	return address.Hex() + "@signed", "", address, nil
+}
+
+// noAuth accepts any request containing an address, see authNoAuth.
+type noAuth struct{}
+
+func (noAuth) accepts(req *fundRequest) bool { return true }
+
+func (noAuth) authenticate(req *fundRequest) (string, string, common.Address, error) {
+	return authNoAuth(req.URL)
+}
+
+// makeAuthenticators creates the authenticators of the given comma separated
+// modes, in the order they are tried.
+func makeAuthenticators(modes string, allowlist string, noauth bool) ([]authenticator, error) {
+	var auths []authenticator
+	for _, mode := range strings.Split(modes, ",") {
+		switch strings.TrimSpace(mode) {
+		case "":
+		case "twitter":
+			auths = append(auths, &urlAuth{prefix: "https://twitter.com/", auth: authTwitter})
+		case "googleplus":
+			auths = append(auths, &urlAuth{prefix: "https://plus.google.com/", auth: authGooglePlus})
+		case "facebook":
+			auths = append(auths, &urlAuth{prefix: "https://www.facebook.com/", auth: authFacebook})
+		case "signed":
+			auth, err := newSignedAuth(allowlist)
+			if err != nil {
+				return nil, err
+			}
+			auths = append(auths, auth)
+		default:
+			return nil, fmt.Errorf("unknown authentication mode %q", mode)
+		}
+	}
+	// Unauthenticated requests are the fallback of every other mode
+	if noauth {
+		auths = append(auths, noAuth{})
+	}
+	return auths, nil
+}
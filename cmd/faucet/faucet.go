@@ -14,7 +14,8 @@
 // You should have received a copy of the GNU General Public License
 // along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
 
-// faucet is a Ether faucet backed by a light client.
+// faucet is a Ether faucet backed by a light client, a full node or the RPC
+// endpoint of an external node.
 package main
 
 //go:generate go-bindata -nometadata -o website.go faucet.html
@@ -44,6 +45,7 @@ import (
 	"github.com/eeefan/dpeth/accounts"
 	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
 	"github.com/eeefan/dpeth/core"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/eth"
@@ -58,6 +60,7 @@ import (
 	"github.com/eeefan/dpeth/p2p/discv5"
 	"github.com/eeefan/dpeth/p2p/nat"
 	"github.com/eeefan/dpeth/params"
+	"github.com/eeefan/dpeth/rpc"
 	"golang.org/x/net/websocket"
 )
 
@@ -68,11 +71,15 @@ var (
 	bootFlag    = flag.String("bootnodes", "", "Comma separated bootnode enode URLs to seed with")
 	netFlag     = flag.Uint64("network", 0, "Network ID to use for the Ethereum protocol")
 	statsFlag   = flag.String("ethstats", "", "Ethstats network monitoring auth string")
+	syncFlag    = flag.String("syncmode", "light", `Sync mode of the embedded node ("full", "fast" or "light")`)
+	rpcFlag     = flag.String("rpc", "", "WebSocket or IPC endpoint of a node to fund requests through instead of an embedded one")
 
 	netnameFlag = flag.String("faucet.name", "", "Network name to assign to the faucet")
 	payoutFlag  = flag.Int("faucet.amount", 1, "Number of Ethers to pay out per user request")
 	minutesFlag = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
 	tiersFlag   = flag.Int("faucet.tiers", 3, "Number of funding tiers to enable (x3 time, x2.5 funds)")
+	signerFlag  = flag.Bool("faucet.signers", false, "Enables funding the self-vote stake of new alien signers")
+	stateFlag   = flag.String("faucet.state", filepath.Join(os.Getenv("HOME"), ".faucet", "timeouts.json"), "File to persist the funding timeouts of users across restarts")
 
 	accJSONFlag = flag.String("account.json", "", "Key json file to fund user requests with")
 	accPassFlag = flag.String("account.pass", "", "Decryption password to access faucet funds")
@@ -83,6 +90,8 @@ var (
 	captchaToken  = flag.String("captcha.token", "", "Recaptcha site key to authenticate client side")
 	captchaSecret = flag.String("captcha.secret", "", "Recaptcha secret key to authenticate server side")
 
+	authFlag   = flag.String("auth", "twitter,googleplus,facebook", "Comma separated authentication modes to accept (twitter, googleplus, facebook, signed)")
+	allowFlag  = flag.String("auth.allowlist", "", "File of addresses allowed to request funds by signed messages, one per line (required by signed auth)")
 	noauthFlag = flag.Bool("noauth", false, "Enables funding requests without authentication")
 	logFlag    = flag.Int("loglevel", 3, "Log level to use for Ethereum and the faucet")
 )
@@ -122,6 +131,32 @@ func main() {
 			periods[i] = strings.TrimSuffix(periods[i], "s")
 		}
 	}
+	// Load and parse the genesis block requested by the user
+	blob, err := ioutil.ReadFile(*genesisFlag)
+	if err != nil {
+		log.Crit("Failed to read genesis block contents", "genesis", *genesisFlag, "err", err)
+	}
+	genesis := new(core.Genesis)
+	if err = json.Unmarshal(blob, genesis); err != nil {
+		log.Crit("Failed to parse genesis block json", "err", err)
+	}
+	// Self-vote stakes can only be funded on alien networks
+	var signerAmount string
+	if *signerFlag {
+		if genesis.Config == nil || genesis.Config.Alien == nil {
+			log.Crit("Signer funding requires an alien genesis")
+		}
+		signerAmount = fmt.Sprintf("%s Ethers", new(big.Int).Div(signerFunds(genesis.Config), ether))
+	}
+	// Assemble the authentication modes accepted by the faucet
+	auths, err := makeAuthenticators(*authFlag, *allowFlag, *noauthFlag)
+	if err != nil {
+		log.Crit("Failed to set up faucet authentication", "err", err)
+	}
+	modes := make(map[string]bool)
+	for _, mode := range strings.Split(*authFlag, ",") {
+		modes[strings.TrimSpace(mode)] = true
+	}
 	// Load up and render the faucet website
 	tmpl, err := Asset("faucet.html")
 	if err != nil {
@@ -133,29 +168,14 @@ func main() {
 		"Amounts":   amounts,
 		"Periods":   periods,
 		"Recaptcha": *captchaToken,
+		"Auth":      modes,
 		"NoAuth":    *noauthFlag,
+		"Signer":    signerAmount,
+		"Message":   fundMessage(common.Address{}),
 	})
 	if err != nil {
 		log.Crit("Failed to render the faucet template", "err", err)
 	}
-	// Load and parse the genesis block requested by the user
-	blob, err := ioutil.ReadFile(*genesisFlag)
-	if err != nil {
-		log.Crit("Failed to read genesis block contents", "genesis", *genesisFlag, "err", err)
-	}
-	genesis := new(core.Genesis)
-	if err = json.Unmarshal(blob, genesis); err != nil {
-		log.Crit("Failed to parse genesis block json", "err", err)
-	}
-	// Convert the bootnodes to internal enode representations
-	var enodes []*discv5.Node
-	for _, boot := range strings.Split(*bootFlag, ",") {
-		if url, err := discv5.ParseNode(boot); err == nil {
-			enodes = append(enodes, url)
-		} else {
-			log.Error("Failed to parse bootnode URL", "url", boot, "err", err)
-		}
-	}
 	// Load up the account key and decrypt its password
 	if blob, err = ioutil.ReadFile(*accPassFlag); err != nil {
 		log.Crit("Failed to read account password contents", "file", *accPassFlag, "err", err)
@@ -172,13 +192,25 @@ func main() {
 	}
 	ks.Unlock(acc, pass)
 
-	// Assemble and start the faucet light service
-	faucet, err := newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, website.Bytes())
+	// Load the funding history surviving from earlier runs
+	timeouts, err := loadTimeouts(*stateFlag)
+	if err != nil {
+		log.Crit("Failed to load funding timeouts", "file", *stateFlag, "err", err)
+	}
+	// Assemble and start the faucet service, or connect to the requested node
+	var faucet *faucet
+	if *rpcFlag != "" {
+		faucet, err = dialFaucet(genesis, *rpcFlag, ks, website.Bytes())
+	} else {
+		faucet, err = newFaucet(genesis, *ethPortFlag, strings.Split(*bootFlag, ","), *netFlag, *statsFlag, ks, website.Bytes())
+	}
 	if err != nil {
 		log.Crit("Failed to start faucet", "err", err)
 	}
 	defer faucet.close()
 
+	faucet.auths, faucet.timeouts = auths, timeouts
+
 	if err := faucet.listenAndServe(*apiPortFlag); err != nil {
 		log.Crit("Failed to launch faucet API", "err", err)
 	}
@@ -192,12 +224,14 @@ type request struct {
 	Tx      *types.Transaction `json:"tx"`      // Transaction funding the account
 }
 
-// faucet represents a crypto faucet backed by an Ethereum light client.
+// faucet represents a crypto faucet backed by an Ethereum node.
 type faucet struct {
 	config *params.ChainConfig // Chain configurations for signing
-	stack  *node.Node          // Ethereum protocol stack
+	stack  *node.Node          // Ethereum protocol stack, nil if backed by an external node
+	rpc    *rpc.Client         // RPC connection to the Ethereum node
 	client *ethclient.Client   // Client connection to the Ethereum chain
 	index  []byte              // Index page to serve up on the web
+	auths  []authenticator     // Authentication modes to verify requests with, tried in order
 
 	keystore *keystore.KeyStore // Keystore containing the single signer
 	account  accounts.Account   // Account funding user faucet requests
@@ -212,40 +246,76 @@ type faucet struct {
 	lock sync.RWMutex // Lock protecting the faucet's internals
 }
 
-func newFaucet(genesis *core.Genesis, port int, enodes []*discv5.Node, network uint64, stats string, ks *keystore.KeyStore, index []byte) (*faucet, error) {
+// newFaucet creates a faucet backed by an embedded node. Light nodes find their
+// peers through the v5 discovery, full and fast ones through the v4 discovery,
+// which is the one alien networks run.
+func newFaucet(genesis *core.Genesis, port int, bootnodes []string, network uint64, stats string, ks *keystore.KeyStore, index []byte) (*faucet, error) {
+	var mode downloader.SyncMode
+	if err := mode.UnmarshalText([]byte(*syncFlag)); err != nil {
+		return nil, err
+	}
+	// Convert the bootnodes to internal enode representations
+	var (
+		enodes   []*discover.Node
+		enodesV5 []*discv5.Node
+	)
+	for _, boot := range bootnodes {
+		if boot == "" {
+			continue
+		}
+		if url, err := discover.ParseNode(boot); err == nil {
+			enodes = append(enodes, url)
+		} else {
+			log.Error("Failed to parse bootnode URL", "url", boot, "err", err)
+			continue
+		}
+		if url, err := discv5.ParseNode(boot); err == nil {
+			enodesV5 = append(enodesV5, url)
+		}
+	}
 	// Assemble the raw devp2p protocol stack
+	config := p2p.Config{
+		NAT:        nat.Any(),
+		ListenAddr: fmt.Sprintf(":%d", port),
+		MaxPeers:   25,
+	}
+	if mode == downloader.LightSync {
+		config.NoDiscovery, config.DiscoveryV5, config.BootstrapNodesV5 = true, true, enodesV5
+	} else {
+		config.BootstrapNodes = enodes
+	}
 	stack, err := node.New(&node.Config{
 		Name:    "geth",
 		Version: params.Version,
 		DataDir: filepath.Join(os.Getenv("HOME"), ".faucet"),
-		P2P: p2p.Config{
-			NAT:              nat.Any(),
-			NoDiscovery:      true,
-			DiscoveryV5:      true,
-			ListenAddr:       fmt.Sprintf(":%d", port),
-			MaxPeers:         25,
-			BootstrapNodesV5: enodes,
-		},
+		P2P:     config,
 	})
 	if err != nil {
 		return nil, err
 	}
-	// Assemble the Ethereum light client protocol
+	// Assemble the Ethereum light client or full node protocol
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		cfg := eth.DefaultConfig
-		cfg.SyncMode = downloader.LightSync
+		cfg.SyncMode = mode
 		cfg.NetworkId = network
 		cfg.Genesis = genesis
-		return les.New(ctx, &cfg)
+		if mode == downloader.LightSync {
+			return les.New(ctx, &cfg)
+		}
+		return eth.New(ctx, &cfg)
 	}); err != nil {
 		return nil, err
 	}
 	// Assemble the ethstats monitoring and reporting service'
 	if stats != "" {
 		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			var serv *les.LightEthereum
-			ctx.Service(&serv)
-			return ethstats.New(stats, nil, serv)
+			var ethServ *eth.Ethereum
+			ctx.Service(&ethServ)
+
+			var lesServ *les.LightEthereum
+			ctx.Service(&lesServ)
+
+			return ethstats.New(stats, ethServ, lesServ)
 		}); err != nil {
 			return nil, err
 		}
@@ -255,8 +325,7 @@ func newFaucet(genesis *core.Genesis, port int, enodes []*discv5.Node, network u
 		return nil, err
 	}
 	for _, boot := range enodes {
-		old, _ := discover.ParseNode(boot.String())
-		stack.Server().AddPeer(old)
+		stack.Server().AddPeer(boot)
 	}
 	// Attach to the client and retrieve and interesting metadatas
 	api, err := stack.Attach()
@@ -264,23 +333,64 @@ func newFaucet(genesis *core.Genesis, port int, enodes []*discv5.Node, network u
 		stack.Stop()
 		return nil, err
 	}
-	client := ethclient.NewClient(api)
+	f := newFaucetClient(genesis, api, ks, index)
+	f.stack = stack
+	return f, nil
+}
 
+// dialFaucet creates a faucet backed by the node at the given RPC endpoint. The
+// endpoint needs to support subscriptions to follow the chain head.
+func dialFaucet(genesis *core.Genesis, endpoint string, ks *keystore.KeyStore, index []byte) (*faucet, error) {
+	api, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return newFaucetClient(genesis, api, ks, index), nil
+}
+
+// newFaucetClient creates a faucet funding requests through the given client.
+func newFaucetClient(genesis *core.Genesis, api *rpc.Client, ks *keystore.KeyStore, index []byte) *faucet {
 	return &faucet{
 		config:   genesis.Config,
-		stack:    stack,
-		client:   client,
+		rpc:      api,
+		client:   ethclient.NewClient(api),
 		index:    index,
 		keystore: ks,
 		account:  ks.Accounts()[0],
 		timeouts: make(map[string]time.Time),
 		update:   make(chan struct{}, 1),
-	}, nil
+	}
 }
 
 // close terminates the Ethereum connection and tears down the faucet.
 func (f *faucet) close() error {
-	return f.stack.Stop()
+	f.rpc.Close()
+	if f.stack != nil {
+		return f.stack.Stop()
+	}
+	return nil
+}
+
+// peerCount retrieves the number of peers of the node backing the faucet.
+func (f *faucet) peerCount() int {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var count hexutil.Uint
+	if err := f.rpc.CallContext(ctx, &count, "net_peerCount"); err != nil {
+		log.Warn("Failed to retrieve peer count", "err", err)
+	}
+	return int(count)
+}
+
+// signerFunds returns the amount funding the self-vote of a new alien signer,
+// the minimum stake of a voter topped up with the base payout for the fees.
+func signerFunds(config *params.ChainConfig) *big.Int {
+	stake := config.Alien.MinVoterBalance
+	if stake == nil {
+		stake = new(big.Int).Mul(big.NewInt(100), ether) // Default of the engine
+	}
+	return new(big.Int).Add(stake, new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether))
 }
 
 // listenAndServe registers the HTTP handlers for the faucet and boots it up
@@ -354,7 +464,7 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 	if err = send(conn, map[string]interface{}{
 		"funds":    balance.Div(balance, ether),
 		"funded":   nonce,
-		"peers":    f.stack.Server().PeerCount(),
+		"peers":    f.peerCount(),
 		"requests": f.reqs,
 	}, 3*time.Second); err != nil {
 		log.Warn("Failed to send initial stats to client", "err", err)
@@ -366,31 +476,47 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 	}
 	// Keep reading requests from the websocket until the connection breaks
 	for {
-		// Fetch the next funding request and validate against github
-		var msg struct {
-			URL     string `json:"url"`
-			Tier    uint   `json:"tier"`
-			Captcha string `json:"captcha"`
-		}
+		// Fetch the next funding request and find the authentication to verify it with
+		var msg fundRequest
 		if err = websocket.JSON.Receive(conn, &msg); err != nil {
 			return
 		}
-		if !*noauthFlag && !strings.HasPrefix(msg.URL, "https://gist.github.com/") && !strings.HasPrefix(msg.URL, "https://twitter.com/") &&
-			!strings.HasPrefix(msg.URL, "https://plus.google.com/") && !strings.HasPrefix(msg.URL, "https://www.facebook.com/") {
+		if strings.HasPrefix(msg.URL, "https://gist.github.com/") {
+			if err = sendError(conn, errors.New("GitHub authentication discontinued at the official request of GitHub")); err != nil {
+				log.Warn("Failed to send GitHub deprecation to client", "err", err)
+				return
+			}
+			continue
+		}
+		var auth authenticator
+		for _, candidate := range f.auths {
+			if candidate.accepts(&msg) {
+				auth = candidate
+				break
+			}
+		}
+		if auth == nil {
 			if err = sendError(conn, errors.New("URL doesn't link to supported services")); err != nil {
 				log.Warn("Failed to send URL error to client", "err", err)
 				return
 			}
 			continue
 		}
-		if msg.Tier >= uint(*tiersFlag) {
+		if msg.Signer && !*signerFlag {
+			if err = sendError(conn, errors.New("Signer funding not enabled")); err != nil {
+				log.Warn("Failed to send signer error to client", "err", err)
+				return
+			}
+			continue
+		}
+		if !msg.Signer && msg.Tier >= uint(*tiersFlag) {
 			if err = sendError(conn, errors.New("Invalid funding tier requested")); err != nil {
 				log.Warn("Failed to send tier error to client", "err", err)
 				return
 			}
 			continue
 		}
-		log.Info("Faucet funds requested", "url", msg.URL, "tier", msg.Tier)
+		log.Info("Faucet funds requested", "url", msg.URL, "tier", msg.Tier, "signer", msg.Signer)
 
 		// If captcha verifications are enabled, make sure we're not dealing with a robot
 		if *captchaToken != "" {
@@ -429,29 +555,7 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 			}
 		}
 		// Retrieve the Ethereum address to fund, the requesting user and a profile picture
-		var (
-			username string
-			avatar   string
-			address  common.Address
-		)
-		switch {
-		case strings.HasPrefix(msg.URL, "https://gist.github.com/"):
-			if err = sendError(conn, errors.New("GitHub authentication discontinued at the official request of GitHub")); err != nil {
-				log.Warn("Failed to send GitHub deprecation to client", "err", err)
-				return
-			}
-			continue
-		case strings.HasPrefix(msg.URL, "https://twitter.com/"):
-			username, avatar, address, err = authTwitter(msg.URL)
-		case strings.HasPrefix(msg.URL, "https://plus.google.com/"):
-			username, avatar, address, err = authGooglePlus(msg.URL)
-		case strings.HasPrefix(msg.URL, "https://www.facebook.com/"):
-			username, avatar, address, err = authFacebook(msg.URL)
-		case *noauthFlag:
-			username, avatar, address, err = authNoAuth(msg.URL)
-		default:
-			err = errors.New("Something funky happened, please open an issue at https://github.com/eeefan/dpeth/issues")
-		}
+		username, avatar, address, err := auth.authenticate(&msg)
 		if err != nil {
 			if err = sendError(conn, err); err != nil {
 				log.Warn("Failed to send prefix error to client", "err", err)
@@ -459,19 +563,30 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 			}
 			continue
 		}
-		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "user", username, "address", address)
+		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "signer", msg.Signer, "user", username, "address", address)
 
+		// Signer stakes are rate limited separately, once per period of the last tier
+		var (
+			key    = username
+			amount = new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
+			period = time.Duration(*minutesFlag*int(math.Pow(3, float64(msg.Tier)))) * time.Minute
+		)
+		if msg.Signer {
+			key = username + "/signer"
+			amount = signerFunds(f.config)
+			period = time.Duration(*minutesFlag*int(math.Pow(3, float64(*tiersFlag-1)))) * time.Minute
+		} else {
+			amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(msg.Tier)), nil))
+			amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(msg.Tier)), nil))
+		}
 		// Ensure the user didn't request funds too recently
 		f.lock.Lock()
 		var (
 			fund    bool
 			timeout time.Time
 		)
-		if timeout = f.timeouts[username]; time.Now().After(timeout) {
+		if timeout = f.timeouts[key]; time.Now().After(timeout) {
 			// User wasn't funded recently, create the funding transaction
-			amount := new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
-			amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(msg.Tier)), nil))
-			amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(msg.Tier)), nil))
 
 			tx := types.NewTransaction(f.nonce+uint64(len(f.reqs)), address, amount, 21000, f.price, nil)
 			signed, err := f.keystore.SignTx(f.account, tx, f.config.ChainId)
@@ -498,7 +613,10 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 				Time:    time.Now(),
 				Tx:      signed,
 			})
-			f.timeouts[username] = time.Now().Add(time.Duration(*minutesFlag*int(math.Pow(3, float64(msg.Tier)))) * time.Minute)
+			f.timeouts[key] = time.Now().Add(period)
+			if err := saveTimeouts(*stateFlag, f.timeouts); err != nil {
+				log.Error("Failed to persist funding timeouts", "file", *stateFlag, "err", err)
+			}
 			fund = true
 		}
 		f.lock.Unlock()
@@ -511,7 +629,11 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 			}
 			continue
 		}
-		if err = sendSuccess(conn, fmt.Sprintf("Funding request accepted for %s into %s", username, address.Hex())); err != nil {
+		success := fmt.Sprintf("Funding request accepted for %s into %s", username, address.Hex())
+		if msg.Signer {
+			success += ", self-vote by sending a dpos:1:event:vote transaction to yourself once funded"
+		}
+		if err = sendSuccess(conn, success); err != nil {
 			log.Warn("Failed to send funding success to client", "err", err)
 			return
 		}
@@ -577,7 +699,7 @@ func (f *faucet) loop() {
 				if err := send(conn, map[string]interface{}{
 					"funds":    balance,
 					"funded":   f.nonce,
-					"peers":    f.stack.Server().PeerCount(),
+					"peers":    f.peerCount(),
 					"requests": f.reqs,
 				}, time.Second); err != nil {
 					log.Warn("Failed to send stats to client", "err", err)
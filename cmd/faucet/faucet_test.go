@@ -0,0 +1,143 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// Tests that signed message requests are only accepted with a signature of the
+// funding message by the key of the funded address on the allowlist.
+func TestSignedAuth(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	sign := func(key *ecdsa.PrivateKey, address common.Address) string {
+		sig, _ := crypto.Sign(textHash(fundMessage(address)), key)
+		sig[64] += 27
+		return hexutil.Encode(sig)
+	}
+	dir, err := ioutil.TempDir("", "faucet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	allowlist := filepath.Join(dir, "allowlist")
+	if err := ioutil.WriteFile(allowlist, []byte("# test signers\n"+address.Hex()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := makeAuthenticators("twitter,signed", "", false); err != errNoAllowlist {
+		t.Fatalf("signed authentication without allowlist: have %v, want %v", err, errNoAllowlist)
+	}
+	auths, err := makeAuthenticators("twitter,signed", allowlist, false)
+	if err != nil {
+		t.Fatalf("failed to create authenticators: %v", err)
+	}
+	auth := auths[1]
+
+	req := &fundRequest{URL: address.Hex(), Signature: sign(key, address)}
+	if !auth.accepts(req) || auths[0].accepts(req) {
+		t.Fatalf("signed request dispatched to the wrong authenticator")
+	}
+	user, _, funded, err := auth.authenticate(req)
+	if err != nil {
+		t.Fatalf("failed to authenticate signed request: %v", err)
+	}
+	if funded != address || user != address.Hex()+"@signed" {
+		t.Errorf("authenticated request mismatch: have %s/%x, want %x", user, funded, address)
+	}
+	// Signatures by other keys and addresses off the allowlist are refused
+	if _, _, _, err := auth.authenticate(&fundRequest{URL: address.Hex(), Signature: sign(other, address)}); err == nil {
+		t.Errorf("request signed by another key accepted")
+	}
+	stranger := crypto.PubkeyToAddress(other.PublicKey)
+	if _, _, _, err := auth.authenticate(&fundRequest{URL: stranger.Hex(), Signature: sign(other, stranger)}); err == nil {
+		t.Errorf("request of an address off the allowlist accepted")
+	}
+}
+
+// Tests that funding timeouts survive a restart, dropping the expired ones.
+func TestTimeoutsPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faucet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state", "timeouts.json")
+	if timeouts, err := loadTimeouts(path); err != nil || len(timeouts) != 0 {
+		t.Fatalf("missing state not empty: %v, %v", timeouts, err)
+	}
+	future := time.Now().Add(time.Hour).Round(time.Second)
+	if err := saveTimeouts(path, map[string]time.Time{
+		"alice@twitter": future,
+		"bob@twitter":   time.Now().Add(-time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to save timeouts: %v", err)
+	}
+	timeouts, err := loadTimeouts(path)
+	if err != nil {
+		t.Fatalf("failed to load timeouts: %v", err)
+	}
+	if len(timeouts) != 1 || !timeouts["alice@twitter"].Equal(future) {
+		t.Errorf("loaded timeouts mismatch: have %v, want alice until %v", timeouts, future)
+	}
+}
+
+// Tests that the embedded website renders the signed message and signer sections.
+func TestWebsiteTemplate(t *testing.T) {
+	tmpl, err := Asset("faucet.html")
+	if err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	website := new(bytes.Buffer)
+	err = template.Must(template.New("").Parse(string(tmpl))).Execute(website, map[string]interface{}{
+		"Network":   "test",
+		"Amounts":   []string{"1 Ether"},
+		"Periods":   []string{"1 day"},
+		"Recaptcha": "",
+		"Auth":      map[string]bool{"signed": true},
+		"NoAuth":    false,
+		"Signer":    "101 Ethers",
+		"Message":   fundMessage(common.Address{}),
+	})
+	if err != nil {
+		t.Fatalf("failed to render template: %v", err)
+	}
+	for _, want := range []string{`id="signature"`, "101 Ethers signer stake", "dpos:1:event:vote"} {
+		if !strings.Contains(website.String(), want) {
+			t.Errorf("rendered website misses %q", want)
+		}
+	}
+	if strings.Contains(website.String(), "fa-twitter") {
+		t.Errorf("rendered website contains disabled Twitter authentication")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// loadTimeouts reads the funding timeouts of the users persisted at path,
+// dropping the already expired ones. A missing file yields an empty history.
+func loadTimeouts(path string) (map[string]time.Time, error) {
+	timeouts := make(map[string]time.Time)
+
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return timeouts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, &timeouts); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for user, timeout := range timeouts {
+		if now.After(timeout) {
+			delete(timeouts, user)
+		}
+	}
+	return timeouts, nil
+}
+
+// saveTimeouts persists the unexpired funding timeouts to path, replacing the
+// previous file only once the new one is fully written.
+func saveTimeouts(path string, timeouts map[string]time.Time) error {
+	now := time.Now()
+
+	live := make(map[string]time.Time)
+	for user, timeout := range timeouts {
+		if timeout.After(now) {
+			live[user] = timeout
+		}
+	}
+	blob, err := json.MarshalIndent(live, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
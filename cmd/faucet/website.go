@@ -67,7 +67,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _faucetHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x5a\x71\x73\xdb\xb6\x92\xff\xdb\xf9\x14\x5b\x5e\xfc\x24\x9d\x4d\x52\xb6\x93\x3c\x9f\x44\xaa\x93\x97\xd7\xd7\xcb\xcd\x5d\xdb\x69\xd3\xb9\x7b\xd3\xd7\xb9\x01\x89\x95\x88\x18\x04\x58\x00\x94\xac\x7a\xf4\xdd\x6f\x00\x90\x14\x29\xc9\x6e\xd2\xe4\xde\x34\x7f\x38\x24\xb0\xd8\x5d\xec\xfe\x16\xbb\x58\x2a\xf9\xe2\xaf\xdf\xbe\x79\xf7\xf7\xef\xbe\x82\xc2\x94\x7c\xf1\x2c\xb1\xff\x01\x27\x62\x95\x06\x28\x82\xc5\xb3\xb3\xa4\x40\x42\x17\xcf\xce\xce\x92\x12\x0d\x81\xbc\x20\x4a\xa3\x49\x83\xda\x2c\xc3\xdb\x60\x3f\x51\x18\x53\x85\xf8\x4b\xcd\xd6\x69\xf0\x3f\xe1\x8f\xaf\xc3\x37\xb2\xac\x88\x61\x19\xc7\x00\x72\x29\x0c\x0a\x93\x06\x6f\xbf\x4a\x91\xae\xb0\xb7\x4e\x90\x12\xd3\x60\xcd\x70\x53\x49\x65\x7a\xa4\x1b\x46\x4d\x91\x52\x5c\xb3\x1c\x43\xf7\x72\x09\x4c\x30\xc3\x08\x0f\x75\x4e\x38\xa6\x57\xc1\xe2\x99\xe5\x63\x98\xe1\xb8\x78\x78\x88\xbe\x41\xb3\x91\xea\x6e\xb7\x9b\xc1\xeb\xda\x14\x28\x0c\xcb\x89\x41\x0a\x7f\x23\x75\x8e\x26\x89\x3d\xa5\x5b\xc4\x99\xb8\x83\x42\xe1\x32\x0d\xac\xea\x7a\x16\xc7\x39\x15\xef\x75\x94\x73\x59\xd3\x25\x27\x0a\xa3\x5c\x96\x31\x79\x4f\xee\x63\xce\x32\x1d\x9b\x0d\x33\x06\x55\x98\x49\x69\xb4\x51\xa4\x8a\x6f\xa2\x9b\xe8\xcf\x71\xae\x75\xdc\x8d\x45\x25\x13\x51\xae\x75\x00\x0a\x79\x1a\x68\xb3\xe5\xa8\x0b\x44\x13\x40\xbc\xf8\x7d\x72\x97\x52\x98\x90\x6c\x50\xcb\x12\xe3\x17\xd1\x9f\xa3\xa9\x13\xd9\x1f\x7e\x5a\xaa\x15\xab\x73\xc5\x2a\x03\x5a\xe5\x1f\x2c\xf7\xfd\x2f\x35\xaa\x6d\x7c\x13\x5d\x45\x57\xcd\x8b\x93\xf3\x5e\x07\x8b\x24\xf6\x0c\x17\x9f\xc4\x3b\x14\xd2\x6c\xe3\xeb\xe8\x45\x74\x15\x57\x24\xbf\x23\x2b\xa4\xad\x24\x3b\x15\xb5\x83\x9f\x4d\xee\x63\x3e\x7c\x7f\xe8\xc2\xcf\x21\xac\x94\x25\x0a\x13\xbd\xd7\xf1\x75\x74\x75\x1b\x4d\xdb\x81\x63\xfe\x4e\x80\x75\x9a\x15\x75\x16\xad\x51\x59\xe4\xf2\x30\x47\x61\x50\xc1\x83\x1d\x3d\x2b\x99\x08\x0b\x64\xab\xc2\xcc\xe0\x6a\x3a\x3d\x9f\x9f\x1a\x5d\x17\x7e\x98\x32\x5d\x71\xb2\x9d\xc1\x92\xe3\xbd\x1f\x22\x9c\xad\x44\xc8\x0c\x96\x7a\x06\x9e\xb3\x9b\xd8\x39\x99\x95\x92\x2b\x85\x5a\x37\xc2\x2a\xa9\x99\x61\x52\xcc\x2c\xa2\x88\x61\x6b\x3c\x45\xab\x2b\x22\x8e\x16\x90\x4c\x4b\x5e\x1b\x3c\x50\x24\xe3\x32\xbf\xf3\x63\x2e\x9a\xfb\x9b\xc8\x25\x97\x6a\x06\x9b\x82\x35\xcb\xc0\x09\x82\x4a\x61\xc3\x1e\x2a\x42\x29\x13\xab\x19\xbc\xaa\x9a\xfd\x40\x49\xd4\x8a\x89\x19\x4c\xf7\x4b\x92\xb8\x35\x63\x12\xfb\x83\xeb\xd9\x59\x92\x49\xba\x75\x3e\xa4\x6c\x0d\x39\x27\x5a\xa7\xc1\x81\x89\xdd\x81\x34\x20\xb0\xe7\x10\x61\xa2\x9d\x1a\xcc\x29\xb9\x09\xc0\x09\x4a\x03\xaf\x44\x98\x49\x63\x64\x39\x83\x2b\xab\x5e\xb3\xe4\x80\x1f\x0f\xf9\x2a\xbc\xba\x6e\x27\xcf\x92\xe2\xaa\x65\x62\xf0\xde\x84\xce\x3f\x9d\x67\x82\x45\xc2\xda\xb5\x4b\x02\x4b\x12\x66\xc4\x14\x01\x10\xc5\x48\x58\x30\x4a\x51\xa4\x81\x51\x35\x5a\x1c\xb1\x05\xf4\x8f\xbf\x47\x4e\xbf\xe2\xaa\xd5\x2b\xa6\x6c\xdd\x6c\xab\xf7\x78\xb0\xc3\xc7\x37\x71\x0b\xcd\x83\x5c\x2e\x35\x9a\xb0\xb7\xa7\x1e\x31\x13\x55\x6d\xc2\x95\x92\x75\xd5\xcd\x9f\x25\x6e\x14\x18\x4d\x83\x5a\xf1\xa0\x39\xfe\xdd\xa3\xd9\x56\x8d\x29\x82\x6e\xe3\x52\x95\xa1\xf5\x84\x92\x3c\x80\x8a\x93\x1c\x0b\xc9\x29\xaa\x34\xf8\x41\xe6\x8c\x70\x10\x7e\xcf\xf0\xe3\xf7\xff\x09\x8d\xcb\x98\x58\xc1\x56\xd6\x0a\xbe\x32\x05\x2a\xac\x4b\x20\x94\x5a\xb8\x46\x51\xd4\x53\xc4\x61\xf7\x58\xd5\x30\x33\x62\x4f\x75\x96\x64\xb5\x31\xb2\x23\xcc\x8c\x80\xcc\x88\x90\xe2\x92\xd4\xdc\x00\x55\xb2\xa2\x72\x23\x42\x23\x57\x2b\x9b\xe9\xfc\x26\xfc\xa2\x00\x28\x31\xa4\x99\x4a\x83\x96\xb6\xf5\x21\xd1\x95\xac\xea\xaa\xf1\xa2\x1f\xc4\xfb\x8a\x08\x8a\xd4\xfa\x9c\x6b\x0c\x16\x5f\xb3\x35\x42\x89\x7e\x2f\x67\x87\x90\xc8\x89\x42\x13\xf6\x99\x1e\x01\x23\x89\xbd\x32\x7e\x4b\xd0\xfc\x4b\x6a\xde\x72\xea\xb6\x50\xa2\xa8\x61\xf0\x16\x2a\x7b\xae\x04\x8b\x87\x07\x45\xc4\x0a\xe1\x39\xa3\xf7\x97\xf0\x9c\x94\xb2\x16\x06\x66\x29\x44\xaf\xdd\xa3\xde\xed\x06\xdc\x01\x12\xce\x16\x09\x79\x0a\xde\x20\x45\xce\x59\x7e\x97\x06\x86\xa1\x4a\x1f\x1e\x2c\xf3\xdd\x6e\x0e\x0f\x0f\x6c\x09\xcf\xa3\xef\x31\x27\x95\xc9\x0b\xb2\xdb\xad\x54\xfb\x1c\xe1\x3d\xe6\xb5\xc1\xf1\xe4\xe1\x01\xb9\xc6\xdd\x4e\xd7\x59\xc9\xcc\xb8\x5d\x6e\xc7\x05\xdd\xed\xac\xce\x8d\x9e\xbb\x1d\xc4\x96\xa9\xa0\x78\x0f\xcf\xa3\xef\x50\x31\x49\x35\x78\xfa\x24\x26\x8b\x24\xe6\x6c\xd1\xac\x1b\x1a\x29\xae\xf9\x1e\x2f\xb1\x05\x4c\x87\x73\x17\x36\x4e\xd5\xbe\xa6\x27\xa2\x60\x15\x76\xda\x37\x78\xd0\xcc\xe0\x1d\x6e\xd3\xe0\xe1\xa1\xbf\xb6\x99\xcd\x09\xe7\x19\xb1\x76\xf1\x5b\xeb\x16\xfd\x8a\x16\xa7\x6b\xa6\x5d\x49\xb5\x68\x35\xd8\xab\xfd\x81\x61\x7d\x70\x70\x19\x59\xcd\xe0\xe6\xba\x77\x6a\x9d\x8a\xf8\x57\x07\x11\x7f\x73\x92\xb8\x22\x02\x39\xb8\xbf\xa1\x2e\x09\x6f\x9f\x9b\x68\xe9\x05\xdf\xe1\xa2\xd0\x9e\xd1\x9d\x6a\xdd\x59\x3f\x9d\x83\x5c\xa3\x5a\x72\xb9\x99\x01\xa9\x8d\x9c\x43\x49\xee\xbb\x7c\x77\x33\x9d\xf6\xf5\xb6\xa5\x20\xc9\x38\xba\xd3\x45\xe1\x2f\x35\x6a\xa3\xbb\xb3\xc4\x4f\xb9\xbf\xf6\x48\xa1\x28\x34\xd2\x03\x6b\x58\x89\xd6\xb4\x8e\xaa\xe7\xfa\xce\x98\x27\x75\x5f\x4a\xd9\xa5\x90\xbe\x1a\x0d\xeb\x5e\xb6\x0b\x16\x89\x51\x7b\xba\xb3\xc4\xd0\x8f\x4a\x01\xca\x96\x78\x8f\x65\x00\x7f\xa2\xd9\xbd\x57\x88\xca\xd7\x17\x16\xb2\xe0\x5e\x93\xd8\xd0\x4f\x90\x6c\x41\x98\x11\x8d\x1f\x22\xde\x65\xfa\xbd\x78\xf7\xfa\xa9\xf2\x0b\x24\xca\x64\x48\xcc\x87\x28\xb0\xac\x05\xed\xed\xdf\x9d\x9d\x9f\xaa\x40\x2d\xd8\x1a\x95\x66\x66\xfb\xa1\x1a\x20\xdd\xab\xe0\xdf\x87\x2a\x24\xb1\x51\x4f\x63\xad\xff\xf2\x99\x82\xfb\xb7\x4a\x92\x9b\xc5\xbf\xcb\x0d\x50\x89\x1a\x4c\xc1\x34\xd8\xe4\xfa\x65\x12\x17\x37\x1d\x49\xb5\x78\x67\x27\x9c\x51\x61\xe9\x4a\x0b\x60\x1a\x54\x2d\x5c\xe6\x95\x02\x4c\x81\xc3\x72\xa4\x49\xd2\x11\xbc\x93\xb6\xa4\x5b\xa3\x30\x50\x12\xce\x72\x26\x6b\x0d\x24\x37\x52\x69\x58\x2a\x59\x02\xde\x17\xa4\xd6\xc6\x32\xb2\xc7\x07\x59\x13\xc6\x5d\x2c\x39\x97\x82\x54\x40\xf2\xbc\x2e\x6b\x5b\x92\x8a\x15\xa0\x90\xf5\xaa\x68\x74\x31\x12\x7c\x62\xe2\x52\xac\x3a\x7d\x74\x45\x4a\x20\xc6\x90\xfc\x4e\x5f\x42\x7b\x2a\x00\x51\x08\x86\x21\xb5\xab\x72\x59\x96\x52\xc0\x8d\xa2\x50\x11\x65\xb6\xa0\x87\xb5\x05\xc9\x73\x97\xe5\x22\x78\x2d\xb6\x52\x20\x14\x64\xed\x34\x84\x77\xfe\x3a\x71\x09\x5f\x4b\xb9\xe2\x78\x61\x15\xfc\x1b\xc9\x31\x93\xb2\x5b\x06\x25\xd9\xb6\x72\x9b\x6d\x6c\x98\x29\x98\xb7\x53\x85\xaa\xb4\x3c\x28\x70\x56\x32\xa3\xa3\x24\xae\xf6\x47\xeb\x3e\x49\xf3\xb0\x90\x8a\xfd\x6a\x2b\x1c\xde\x3f\x47\xcd\xc1\x29\xd3\x1e\x92\xce\xfd\x1c\x97\x66\x06\x2f\xfc\x21\x79\x08\xe8\xe6\x2a\x74\x0a\xcd\x2d\x4f\x77\xc5\xb4\x99\x67\x06\x37\xbe\xae\xf5\x15\x05\x35\x3d\x0d\xe8\x01\xe6\xbc\xd0\xdb\xdb\xea\xbe\xd3\xa3\x2b\x8e\xa7\x1d\x13\x0b\x85\xa1\x51\xd6\xac\x67\xcf\x92\xdc\x21\x10\x48\xc8\xc1\x55\xb9\x51\xda\x5d\xb4\x98\x6b\x14\xc4\x66\x83\x68\xbe\xb4\x31\x9c\x7e\xef\x19\x32\xb1\x3a\xbf\x9e\x7a\x68\xda\x07\xcb\xfe\xfc\x7a\xca\x84\x91\xe7\xd7\xd3\xe9\xfd\xf4\x03\xff\x9d\x5f\x4f\xa5\x38\xbf\x9e\x9a\x02\xcf\xaf\xa7\xe7\xd7\x37\x7d\x50\xfb\x91\xb6\xc4\xb4\x54\xa8\xad\xb4\x16\xeb\x01\x18\xa2\x56\x68\xd2\xe0\x7f\x49\x26\x6b\x33\xcb\x38\x11\x77\xc1\xc2\xa9\x6b\xcb\x0e\x87\x82\xd3\x85\x2a\x54\x44\x5b\x48\x58\x8d\x1d\x4a\x9a\xa6\x88\x86\xb1\xae\x95\x92\xb5\xb0\xe9\x11\xec\x9e\x5d\xa8\x8a\x91\x45\x99\x35\xcc\x24\x4a\x32\x15\x2f\xde\xc8\x6a\x1b\x3a\x26\x6e\xf9\x91\x19\x75\x5d\x55\x52\x99\xa8\x6f\x4e\x62\x2f\x44\x1c\x75\x7c\x3b\x7d\x79\xfb\xea\x49\xf5\xb5\x2d\xb7\xdd\x1e\x3a\x0d\x49\x26\xd7\x08\xbe\xb8\xcf\xe4\x3d\x10\x41\x61\xc9\x14\x02\xd9\x90\xed\x17\x49\x4c\xdd\x55\xec\xd3\x51\xbb\x72\x81\x16\x56\xbc\xd6\xb6\x16\x61\x36\x50\xff\x50\x10\xf6\x27\x01\x7c\xc7\x6b\x7d\x09\x55\x9d\x71\xa6\x0b\x20\x20\x70\x03\x89\x36\x4a\x8a\xd5\xc2\x8d\xe6\xf6\xaa\xea\x5e\xa1\x92\xda\x3c\x85\x06\x2c\x33\xa4\xf4\x04\x1e\x7e\x27\x1c\xac\x3c\xe7\xc2\x7f\xbe\xfb\x96\xcd\xe1\xf8\x87\x72\x59\x7b\x62\xff\x51\xfd\x75\x14\xbe\x9b\xcd\x26\x6a\x2d\xe9\x62\xb7\x40\x5e\xc5\x36\x8d\xd5\x82\x99\x6d\xec\x4f\x41\x29\xe2\x2f\x19\x4d\xaf\x6f\xaf\x5f\xbd\xba\x7e\xf1\x6f\xb7\x2f\x5f\x5e\xdf\xbe\x78\xf9\x58\x60\x77\xa0\xf8\xfd\x71\xed\xaf\x43\xdf\xc8\xd7\xb5\x29\xba\xbb\x90\xc7\x4b\x5b\x83\xdb\x4a\x8b\xda\xbb\xa4\x0a\x7e\x37\x86\x6a\x61\x0b\xca\x90\xf0\x93\xb5\xe0\x47\xa0\xc8\xc1\xe8\x09\xcd\x3e\x11\x5a\x2d\x7c\x2c\x52\x64\x6d\xec\x0e\xdb\xa6\x0c\x93\xa2\x83\xd3\x25\x68\x56\x56\x7c\x0b\xf9\xde\xeb\xa7\x71\xf5\xa8\x53\x7e\x13\x56\x43\xb7\x79\x90\xb9\x2a\xae\x94\x14\x6d\xf5\xa6\x6b\x9d\x63\xe5\xba\xf5\xb6\x22\xfa\xcb\xf6\x57\x22\x0c\x13\xd8\x56\x4e\x11\x7c\x2b\xf8\x16\x6a\x8d\xb0\x94\x0a\x28\x66\xf5\x6a\xe5\xca\x3d\x05\x95\x62\x6b\x62\xb0\x2d\x97\x74\x83\x8a\x0e\x14\xbd\x1b\xaa\x2d\x5d\x79\xaf\x92\xfc\xbb\xac\x21\x27\x02\x8c\x22\xf9\x9d\x8f\x94\x5a\x29\x1b\x29\x15\xfa\xdd\x74\x05\x5b\x86\x5c\x6e\x1c\x89\xdf\xf7\x92\x21\x77\xd5\x9b\x46\x84\x42\x6e\xa0\xac\x73\x17\x90\xb6\x3a\x73\x9b\xd8\x10\x66\xa0\x16\x86\x71\x6f\x4f\x53\x2b\x61\x6b\x3d\x1c\x14\x59\x47\x77\xf8\x04\xcb\xc5\xbb\x02\x4f\x94\xb6\xdd\xed\x1b\x14\xbe\xf1\xe4\x50\x29\x69\x30\xb7\x0e\x05\xb2\x22\x4c\x68\xeb\x11\x57\xc6\x61\xf9\x01\xb7\xf3\xee\xa9\x79\xd8\x77\x9a\xdd\x74\x1c\xc3\xd7\x5c\x66\x84\xc3\xda\x22\x3d\xe3\xb6\x2c\x97\x50\x48\xbb\xf5\x9e\xb5\xb4\x21\xa6\xd6\x20\x97\x6e\xd4\x6b\x6e\xd7\xaf\x89\xb2\x1e\xc4\xb2\x32\x90\x36\x7d\x52\x3b\xa6\x51\xad\x9b\xee\xaf\x7d\x35\x0c\xd5\x60\xbe\xb3\x7a\x0a\x3f\xfd\x3c\x7f\xd6\xa8\xf2\x57\x5c\x3a\x48\x58\x7c\xfb\x2d\x9b\x82\x18\xc8\x15\x12\x83\x1a\x72\x2e\x75\xad\xbc\x86\x54\xc9\x0a\xac\x96\x2d\xa7\x96\xb3\x9d\xa8\x9c\xb4\x96\xc9\xb8\x20\xba\x98\x34\x6d\x5e\x85\xce\x4b\xdd\x5c\x3b\x7e\x66\x51\x37\xb6\x0c\x58\x3a\x9d\x03\x4b\x5a\xbe\x11\x47\xb1\x32\xc5\x1c\xd8\xc5\x45\x47\x7c\xc6\x96\x30\x6e\x29\x7e\x62\x3f\x47\xe6\x3e\xb2\x52\x20\x4d\xa1\x2f\xcd\x09\x6c\xf8\xe8\x8a\xb3\x1c\xc7\xec\x12\xae\x26\xf3\x76\x36\x53\x48\xee\xda\xb7\xc6\x8f\xfe\x3f\xf7\x77\x37\x1f\x5a\xc6\x19\x7f\x60\x1b\xdf\xc3\xd1\x40\x60\xc5\xb4\x81\x5a\x71\x68\x62\xd8\xbb\xa0\x73\x88\xa3\xeb\x5b\xe5\x08\x97\xcd\x43\x83\xa9\x76\x0b\x9e\x4d\xa4\x51\xd0\xf1\x7f\xfc\xf0\xed\x37\x91\x36\x8a\x89\x15\x5b\x6e\xc7\x0f\xb5\xe2\x33\x78\x3e\x0e\xfe\xa5\x56\x3c\x98\xfc\x34\xfd\x39\x5a\x13\x5e\xe3\xa5\xf3\xf7\xcc\xfd\x3d\x92\x72\x09\xcd\xe3\x0c\x86\x02\x77\x93\xc9\xfc\x74\xbf\xab\xd7\x9e\x53\xa8\xd1\x8c\x2d\x61\x07\xfc\x43\x1b\x11\x28\xd1\x14\xd2\x85\xae\xc2\x5c\x0a\x81\xb9\x81\xba\x92\xa2\x31\x09\x70\xa9\xf5\x1e\x88\x2d\x45\x7a\x0c\x8a\x86\x3e\x75\xc9\xfa\xbf\x31\xfb\x41\xe6\x77\x68\xc6\xe3\xf1\x86\x09\x2a\x37\x11\x97\xfe\xa8\x8d\x6c\x90\xca\x5c\x72\x48\xd3\x14\x9a\x2c\x1a\x4c\xe0\x4b\x08\x36\xda\xe6\xd3\x00\x66\xf6\xd1\x3e\x4d\xe0\x02\x0e\x97\x17\x36\xdf\x5f\x40\x10\x93\x8a\x05\x13\x1f\x0e\xad\xe1\xa5\x28\x51\x6b\xb2\xc2\xbe\x82\xee\x86\xdb\x81\xcc\xee\xa3\xd4\x2b\x48\xc1\x39\xa8\x22\x4a\xa3\x27\x89\x28\x31\xa4\x45\x9b\xc5\xac\x23\x4b\x53\x10\x35\xe7\x7b\x90\xfa\xa0\x98\xb7\xf0\x1b\x90\x47\x3e\xd7\x7c\x91\xa6\x50\x0b\xea\x4c\x4c\xf7\x2b\xad\xf3\x7d\x33\x64\x12\xd9\xbc\xb0\x5f\x31\x99\xf7\xd1\x3c\xe0\x86\xf4\xb7\xd8\x21\x3d\xe4\x87\xf4\x11\x86\xae\xf7\xf4\x14\x3f\xdf\xab\xea\xb1\x73\x03\x8f\x70\x13\x75\x99\xa1\x7a\x8a\x9d\xef\x3d\x35\xec\x9c\xa9\xdf\x0a\xd3\x5b\x7b\x09\x57\xaf\x26\x8f\x70\x47\xa5\xe4\xa3\xcc\x85\x34\xdb\xf1\x03\x27\x5b\x5b\x33\xc1\xc8\xc8\xea\x8d\x6b\x15\x8d\x2e\x5d\xc6\x9d\x41\xc7\xe1\xd2\x7d\x04\x98\xc1\xc8\xbd\xd9\x79\x56\xa2\x5b\xf5\x72\x3a\x9d\x5e\x42\xfb\xf5\xec\x2f\xc4\x06\xa1\xaa\x71\xf7\x88\x3e\xba\xce\x73\x9b\xf7\x3f\x45\xa3\x86\x47\xa7\x53\xf3\xfe\x09\x5a\x75\xb9\x61\xa0\x16\xfc\xe9\x4f\x70\x34\x3b\x84\x71\x1c\xc3\x7f\x11\x75\xe7\x1a\x3b\x95\xc2\xb5\x6b\xfe\x74\xf4\x25\xd3\xda\x35\x55\x34\x50\x29\xb0\x59\xf3\x71\xc7\xfe\x91\x8e\x0d\x19\x2c\x60\x7a\xa8\xa0\x3d\x0e\x7b\x69\xe1\x44\xb6\xe8\xf1\x1d\x26\x82\xb3\x5d\x5f\xde\x60\x25\x2b\x11\xbe\x48\x21\x08\xfa\x8b\x8f\x28\x2c\x41\xc7\xec\x4c\xa3\x79\xe7\x7d\x31\x6e\xb2\xe3\xa9\xdc\x35\xb9\x84\x9b\xe9\x74\x3a\x39\x52\x62\xb7\x37\xef\xeb\xca\x96\x4d\x40\xc4\xd6\x1d\x89\x9d\x6d\x5d\xe1\x68\x4b\x20\x7b\xa4\x71\xc8\x25\xe7\xbe\x66\x69\x96\x5a\x03\x37\x4d\xb0\x14\xc2\xab\xf9\x89\x2c\xda\xb3\x64\x6f\x6b\x87\xee\x39\x61\xfb\x43\x17\x0d\x6d\x76\x40\x1c\x5e\x0d\x9c\x32\xf0\xd7\x69\xc7\x9c\x75\x7a\xb3\xbd\x45\x0f\xdc\xb5\xf7\xd7\xa1\xcd\x7a\xfa\x7b\x3e\x17\x57\x1f\xb8\x8d\x6e\xba\xaa\x75\x31\x3e\x50\x74\x32\x3f\xf6\xcd\x5b\x83\xca\x56\xc9\xd2\xa6\x2c\xeb\x0b\x7b\x15\x50\x78\xe4\x12\x57\xaa\x2b\x0c\x15\x0a\x8a\xaa\x2d\x29\x7c\x65\x6f\x0b\xc0\x81\xcb\xfc\xad\xb2\x0f\xa7\x8f\x0c\x18\x57\x92\x49\x81\x00\x00\x07\x41\xe0\x80\x3a\x40\xaa\x25\x46\x4e\x2a\x8d\x14\x52\xf0\x3f\x66\x18\x4f\xa2\x5a\xb0\xfb\xf1\x24\x6c\xde\x0f\x79\xb4\xf3\xf3\xee\x9a\xd8\xaa\x7d\x91\x42\x90\x18\x05\x8c\xa6\xa3\x00\x2e\x4e\x85\xa0\xcd\xba\xa3\xc5\x5e\x83\xfe\x52\x80\xc4\xd0\x85\xeb\x67\xfb\xfb\xda\x3f\x82\x8c\xe4\x77\x2b\x77\x11\x9a\xd9\x52\x6b\x7c\xc4\x96\xac\x89\x21\xca\x71\x9d\xcc\x61\x4f\xde\x5c\x14\x73\xeb\x9c\x39\xf8\x1b\xa9\x6b\x9b\x43\xf7\xa9\xc9\xbd\x65\x52\x51\x54\xa1\x22\x94\xd5\x7a\x06\x2f\xaa\xfb\xf9\x3f\xda\x4f\x71\xae\xb9\xff\xa4\xaa\x95\xc2\xc5\x91\x46\x4d\x93\xf8\x02\x82\x24\xb6\x04\xbf\xc5\xa6\xdb\x6c\xff\x47\x14\x70\xe2\x13\x06\x74\x3f\x71\x68\xc6\x4b\x46\x29\x47\xab\xf0\x9e\xbd\x0d\x46\xeb\xff\x7e\x48\x0d\x45\x42\xf3\xed\x62\xbf\x66\x07\xc8\x35\x3e\xb1\xa0\xfb\x0c\x32\xb2\x00\x08\xed\x96\x99\xb3\x79\x73\xd9\x76\xc3\x6a\xe4\x6c\xd1\xfc\x24\x86\xd6\xca\xd5\x5a\xe3\xb0\x01\xd8\x25\x8c\xb4\xad\xfd\xa8\x1e\x4d\xa2\xa2\x2e\x89\x60\xbf\xe2\xd8\xe6\xa5\x89\xb7\x95\xfb\xae\x12\x1c\x1f\xc9\x47\xca\xec\x3f\x78\x8c\xda\x1c\x37\x6a\x8c\x38\x6a\xbd\xfb\x62\x7f\xb7\x9f\xc1\x74\x3e\xfa\x48\x0b\x9d\x96\x12\x66\x44\x41\xff\x25\x6c\x93\x2f\x28\x69\xa5\xb7\x73\x19\x51\x23\xdf\xc9\x70\xf5\xb9\x90\x9b\x74\x74\x33\xed\x94\xf4\x8e\x76\x7e\x1e\x35\x58\x3b\x72\x86\xd5\xb2\x0d\xcd\x05\xdc\x4c\x3f\x87\xb6\xbe\x1b\x72\xb0\x03\xa3\x58\x85\x14\x48\x6e\xd8\x1a\xff\x1f\x36\xf2\x19\x8c\xfc\xd1\x2a\x5a\x1c\xb6\xc6\x73\x30\x1d\xe8\x6b\x67\x3b\xdb\xfe\xab\x8d\x37\x88\x9d\x85\x2f\x20\x38\xb9\x91\x47\x91\x78\x40\x78\x10\xda\x8f\xc7\xbd\xfb\x50\x18\x1c\xe6\x14\x5b\xed\x76\x1f\xb9\x27\x51\x61\x4a\x3e\x0e\x12\xe3\x7e\xec\x64\x75\xee\x38\x38\x06\x7e\x78\x58\xd2\xed\x86\x17\x19\x7b\x7f\xc7\x83\x7b\x16\xf4\x8a\x93\xee\x2e\xd6\x56\x22\xb0\xdb\xff\x26\x2c\x8e\xe1\x07\x43\x94\x01\x02\x3f\xbe\x85\xba\xa2\xc4\xf8\x4f\x72\x36\x3f\xfa\xae\x73\xfb\xa3\xb1\x8c\x28\x0d\x4b\xa9\x36\x44\xd1\xa6\x3f\x63\x0a\xdc\xba\x4f\x72\x6d\xe9\xa7\xd1\xbc\xb5\xa7\xd8\x9a\xf0\xf1\xd1\xbd\xef\xf9\x78\x14\xf5\x5d\x3e\x9a\x44\x48\xf2\xe2\x98\xd0\x65\xac\x4e\x6e\x0a\xdf\xb8\x2b\xc0\xf8\xf9\xd8\x14\x4c\x4f\x22\x62\x8c\x1a\x8f\x06\x60\x18\x4d\xac\x5f\xaf\x7a\x57\xb2\x6e\x79\x32\x08\xab\xa7\x78\xec\x8b\xe9\xae\x10\x68\xc9\x73\xad\xc7\x1e\x57\xa3\xcb\x1e\xef\x21\xac\x46\xe7\xa3\xce\x51\xfb\xf0\xde\xef\x23\x3d\xa9\xc9\x80\xf5\xc8\x46\xd9\xe8\x48\x3c\xa1\xf4\x8d\x8d\x9f\x71\x70\x22\xd2\x0f\xd1\x31\xe9\x8c\xed\xcf\xeb\x27\xad\xec\x7f\x5e\xf3\x88\x89\x19\x1d\x4d\x22\x5d\x67\xbe\x37\x31\x7e\xd9\x5d\xc0\x5a\x32\x07\xde\xc3\x54\x70\x54\x50\x58\x11\xc3\xa2\x22\x3c\x28\x42\x9e\xc8\x1a\x8d\x48\xbf\xab\xdd\xa5\x35\xf8\x74\xd2\xb5\xb6\xbe\xd2\xb6\xb8\xf2\xad\xff\x0d\x66\xda\x75\x12\xa0\xc1\xbb\xeb\xe6\xf8\xae\xcd\xeb\xef\xde\xf6\x3a\x37\x5d\x44\x8c\x1d\xf7\xee\xf7\x9c\xa7\xfa\x24\x27\x7f\x40\xba\xd9\x6c\x22\xff\x45\xcb\xb5\xf1\xbb\x46\x4a\x4c\x2a\x16\xbd\xd7\x01\x10\xbd\x15\x39\x50\x5c\xa2\x5a\xf4\xd8\x37\xdd\x95\x24\xf6\x3f\x6d\x4c\x62\xff\xeb\xed\xff\x0b\x00\x00\xff\xff\x56\xf8\xb5\xef\xce\x2d\x00\x00")
+var _faucetHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x7b\x6f\x93\xdb\x36\x92\xf7\x6b\xcd\xa7\xe8\xf0\xb1\x57\xd2\x63\x91\xd4\xcc\xd8\x5e\x9f\x86\x54\xca\xeb\xcd\xee\xf9\xea\x36\x9b\xda\x38\x75\xb7\x95\xa4\xae\x20\xb2\x25\xc2\x03\x02\x0c\x00\x4a\x23\x4f\xe9\xbb\x5f\x35\x08\x52\xd4\x9f\x99\xd8\xb1\xaf\x36\xf3\x62\x86\x04\x1a\x8d\x46\xf7\x0f\x8d\xee\x06\x27\xf9\xea\xcf\x7f\x7f\xf3\xee\x9f\xdf\x7d\x03\x85\x2d\xc5\xfc\x22\xa1\x3f\x20\x98\x5c\xa5\x01\xca\x60\x7e\x31\x48\x0a\x64\xf9\xfc\x62\x30\x48\x4a\xb4\x0c\xb2\x82\x69\x83\x36\x0d\x6a\xbb\x0c\x5f\x05\xfb\x8e\xc2\xda\x2a\xc4\x5f\x6a\xbe\x4e\x83\xff\x0e\x7f\x78\x1d\xbe\x51\x65\xc5\x2c\x5f\x08\x0c\x20\x53\xd2\xa2\xb4\x69\xf0\xf6\x9b\x14\xf3\x15\xf6\xc6\x49\x56\x62\x1a\xac\x39\x6e\x2a\xa5\x6d\x8f\x74\xc3\x73\x5b\xa4\x39\xae\x79\x86\xa1\x7b\x99\x00\x97\xdc\x72\x26\x42\x93\x31\x81\xe9\x65\x30\xbf\x20\x3e\x96\x5b\x81\xf3\xfb\xfb\xe8\x5b\xb4\x1b\xa5\x6f\x77\xbb\x19\xbc\xae\x6d\x81\xd2\xf2\x8c\x59\xcc\xe1\x2f\xac\xce\xd0\x26\x71\x43\xe9\x06\x09\x2e\x6f\xa1\xd0\xb8\x4c\x03\x12\xdd\xcc\xe2\x38\xcb\xe5\x7b\x13\x65\x42\xd5\xf9\x52\x30\x8d\x51\xa6\xca\x98\xbd\x67\x77\xb1\xe0\x0b\x13\xdb\x0d\xb7\x16\x75\xb8\x50\xca\x1a\xab\x59\x15\x5f\x47\xd7\xd1\x1f\xe3\xcc\x98\xb8\x6b\x8b\x4a\x2e\xa3\xcc\x98\x00\x34\x8a\x34\x30\x76\x2b\xd0\x14\x88\x36\x80\x78\xfe\xdb\xe6\x5d\x2a\x69\x43\xb6\x41\xa3\x4a\x8c\x9f\x47\x7f\x8c\xa6\x6e\xca\x7e\xf3\xe3\xb3\xd2\xb4\x26\xd3\xbc\xb2\x60\x74\xf6\xd1\xf3\xbe\xff\xa5\x46\xbd\x8d\xaf\xa3\xcb\xe8\xd2\xbf\xb8\x79\xde\x9b\x60\x9e\xc4\x0d\xc3\xf9\x67\xf1\x0e\xa5\xb2\xdb\xf8\x2a\x7a\x1e\x5d\xc6\x15\xcb\x6e\xd9\x0a\x73\xdf\x15\x51\x57\xd4\x36\x7e\xb1\x79\x1f\xb2\xe1\xfb\x63\x13\x7e\x89\xc9\x4a\x55\xa2\xb4\xd1\x7b\x13\x5f\x45\x97\xaf\xa2\x69\xdb\x70\xca\xdf\xad\x86\x8c\x46\x53\x0d\xa2\x35\x6a\x42\xae\x08\x33\x94\x16\x35\xdc\x53\xeb\xa0\xe4\x32\x2c\x90\xaf\x0a\x3b\x83\xcb\xe9\xf4\xe9\xcd\xb9\xd6\x75\xd1\x34\xe7\xdc\x54\x82\x6d\x67\xb0\x14\x78\xd7\x34\x31\xc1\x57\x32\xe4\x16\x4b\x33\x83\x86\xb3\xeb\xd8\xd1\xaf\xa8\xd2\x6a\xa5\xd1\x18\x3f\x59\xa5\x0c\xb7\x5c\xc9\x19\xe1\x98\x59\xbe\xc6\x73\xb4\xa6\x62\xf2\x64\x00\x5b\x18\x25\x6a\x8b\x47\x82\x2c\x84\xca\x6e\x9b\x36\xb7\x9b\xfb\x8b\xc8\x94\x50\x7a\x06\x9b\x82\xfb\x61\xe0\x84\x82\x4a\xa3\x67\x0f\x15\xcb\x73\x2e\x57\x33\x78\x59\xf9\xf5\x40\xc9\xf4\x8a\xcb\x19\x4c\xf7\x43\x92\xb8\x55\x63\x12\x37\x8e\xeb\x62\x90\x2c\x54\xbe\x25\xc5\x26\x39\x5f\x43\x26\x98\x31\x69\x70\xa4\x62\xe7\x90\x0e\x08\xc8\x0f\x31\x2e\xdb\xae\x83\x3e\xad\x36\x01\xb8\x89\xd2\xa0\x11\x22\x5c\x28\x6b\x55\x39\x83\x4b\x12\xcf\x0f\x39\xe2\x27\x42\xb1\x0a\x2f\xaf\xda\xce\x41\x52\x5c\xb6\x4c\x2c\xde\xd9\xd0\xd9\xa7\xb3\x4c\x30\x4f\x78\x3b\x76\xc9\x60\xc9\xc2\x05\xb3\x45\x00\x4c\x73\x16\x16\x3c\xcf\x51\xa6\x81\xd5\x35\x12\x8e\xf8\x1c\xfa\xee\xef\x01\xef\x57\x5c\xb6\x72\xc5\x39\x5f\xcf\x2f\x8e\x1f\x8f\x56\xf8\xf0\x22\x5e\x81\x7f\x50\xcb\xa5\x41\x1b\xf6\xd6\xd4\x23\xe6\xb2\xaa\x6d\xb8\xd2\xaa\xae\xba\xfe\x41\xe2\x5a\x81\xe7\x69\x50\x6b\x11\x78\xf7\xef\x1e\xed\xb6\xf2\xaa\x08\x5a\x16\x4b\xa5\xcb\x90\x2c\xa1\x95\x08\xa0\x12\x2c\xc3\x42\x89\x1c\x75\x1a\x7c\xaf\x32\xce\x04\xc8\x66\xcd\xf0\xc3\x3f\xfe\x13\xbc\xc9\xb8\x5c\xc1\x56\xd5\x1a\xbe\xb1\x05\x6a\xac\x4b\x60\x79\x4e\xd0\x8e\xa2\x28\x98\xdf\xdf\xf3\x25\x70\x99\xe3\x1d\x44\xa4\x26\x08\x0c\x5f\x49\xcc\x83\xdd\xee\x8c\x8c\xd4\xc7\x6c\xad\xb1\x95\xb4\xd7\xf0\x89\xf2\xb6\x03\x41\x2d\xc1\x16\x08\xcb\x5a\x12\xa0\xa1\x44\x63\xd8\x0a\x27\xc0\x97\xa0\xf1\x97\x1a\x8d\xa5\xe6\xc5\x16\x68\x2e\xcc\x5b\x02\x2f\x3d\xca\xbc\x27\xa9\xdb\x80\xa7\xfa\x0e\x17\x56\xee\x75\x3e\x48\x16\xb5\xb5\xaa\x23\x5c\x58\x09\x0b\x2b\xc3\x1c\x97\xac\x16\x16\x72\xad\xaa\x5c\x6d\x64\x68\xd5\x6a\x25\xba\x95\x35\x83\x02\xc8\x99\x65\xbe\x2b\x0d\x5a\xda\x16\x88\xcc\x54\xaa\xaa\x2b\x0f\xc5\xa6\x11\xef\x2a\x26\x73\xcc\x09\xb8\xc2\x60\x30\xff\x2b\x5f\x23\x94\xd8\x18\x64\x70\x8c\xeb\x8c\x69\xb4\x61\x9f\xe9\x09\xba\x93\xb8\x11\xa6\x59\x12\xf8\x9f\xa4\x16\x2d\xa7\x6e\x09\x25\xca\x7a\xbf\x20\x7a\x0b\x35\xb9\x4c\x32\xbc\x66\x72\x85\xf0\x84\xe7\x77\x13\x78\xc2\x4a\x55\x4b\x0b\xb3\x14\xa2\xd7\xee\xd1\xec\x76\x07\xdc\x01\x12\xc1\xe7\x09\x7b\x6c\x8f\x82\x92\x99\xe0\xd9\x6d\x1a\x58\x8e\x3a\xbd\xbf\x27\xe6\xbb\xdd\x4d\x63\x3a\x9d\xba\xf5\xdf\x80\xc3\xdc\x93\xe8\x1f\x98\xb1\xca\x66\x05\xdb\xed\x56\xba\x7d\x8e\xf0\x0e\xb3\xda\xe2\x68\x7c\x7f\x8f\xc2\xe0\x6e\x67\xea\x45\xc9\xed\xa8\x65\x36\xf6\x36\xa7\x15\x78\xa9\x77\x3b\x88\x89\xa9\x43\xf1\x93\xe8\x3b\xd4\x5c\xe5\xc6\xad\x6c\xb7\x4b\x62\x36\x4f\x62\xc1\x5b\xac\xb8\xc9\xa3\xef\x9d\x40\xe7\x96\x08\x5a\xd1\xf2\x0c\x56\x4c\x33\xab\x74\x07\xe6\x9c\xaf\x79\x4e\xfe\xcf\x71\xfb\x0c\xdd\x78\x65\x10\x42\x7e\xa3\x2e\xfa\x3a\xe8\x96\xe2\x95\x0c\xc6\xb2\x5b\x3c\x5e\xf5\x81\xb8\x49\x5c\x8b\xbd\x07\x8a\x69\xd3\xb4\xaf\x8d\xff\x73\x42\xf5\x65\x3a\xe3\xce\x56\x61\x27\xa7\xdf\x13\x86\x5b\xbc\xc5\x6d\x1a\xdc\xdf\xf7\xc7\xfa\xde\x8c\x09\xb1\x60\x84\x8d\x66\x11\xdd\xa0\x0f\x98\x06\x5c\xae\xb9\x71\xb1\xf1\xbc\x95\xa0\xb7\xb1\x3f\xce\x3f\x1f\x9d\x40\x56\x55\x33\xb8\xbe\xea\x1d\x3f\xe7\x5c\xf7\xcb\x23\xd7\x7d\x7d\x96\xb8\x62\x12\x05\xb8\xdf\xa1\x29\x99\x68\x9f\xbd\xc7\xe8\xc6\x9c\x0e\x0a\xe9\xb0\xed\x44\xeb\x0e\xed\xe9\x0d\xa8\x35\xea\xa5\x50\x9b\x19\xb0\xda\xaa\x1b\x28\xd9\x5d\x17\xb8\x5c\x4f\xa7\x7d\xb9\x29\xa6\x67\x0b\x81\xee\x98\xf0\x3e\xd1\x74\xb8\x6c\xba\xdc\x6f\x3a\x1b\x72\x94\x06\xf3\x23\x6d\x50\x50\x40\xc0\x75\x54\x7b\x69\xf7\xca\x3c\x2b\xfb\x52\xa9\x2e\x16\xe8\x8b\xe1\x59\xf7\xc2\x96\x60\x9e\x58\xbd\xa7\x1b\x24\x36\x7f\x6c\x2f\x9c\x9c\xe5\xda\x98\x07\x9d\x1d\x24\x04\x50\xb7\xf6\x0a\x51\x37\x81\x28\x41\x16\xdc\x6b\x12\xdb\xfc\x33\x66\x26\x10\x2e\x98\xc1\x8f\x99\xde\x85\x6c\xfb\xe9\xdd\xeb\xe7\xce\x5f\x20\xd3\x76\x81\xcc\x7e\x8c\x00\x74\x46\xf6\xd6\xef\xce\x8f\xcf\x15\xa0\x96\x7c\x8d\xda\x70\xbb\xfd\x58\x09\x30\xdf\x8b\xd0\xbc\x1f\x8a\x90\xc4\x56\x3f\x8e\xb5\xfe\x4b\xef\xb9\xff\xf8\xa9\x9b\xfb\xcc\xde\x3e\x88\x2d\xaf\xe7\xff\xae\x36\x90\x2b\x34\x60\x0b\x6e\x80\xa2\xa4\xaf\x93\xb8\xb8\xee\x48\xaa\xf9\x3b\xea\x70\x4a\x85\xa5\x8b\x11\x81\x1b\xd0\xb5\x94\x14\x80\x28\xe9\xc2\x94\x83\xb8\xd2\x47\x5b\x11\xbc\x53\x14\x9b\xaf\x51\x5a\x28\x99\xe0\x19\x57\xb5\x01\x96\x59\xa5\x0d\x2c\xb5\x2a\x01\xef\x0a\x56\x37\x91\x0c\xb9\x0f\xb6\x66\x5c\x90\x7e\x5c\xd8\x63\x40\x69\x60\x59\x56\x97\xb5\x60\x8e\x06\xa5\xaa\x57\x85\x97\xc5\x2a\x70\xc7\x1c\x08\x25\x57\x9d\x3c\xa6\x62\x25\x30\x6b\x59\x76\x6b\x26\x6d\xa4\x64\x80\x69\x04\xcb\x31\x07\xab\x80\x1d\x84\xbd\x2c\xcb\x88\x8b\x89\xe0\xb5\xdc\x2a\x89\xe0\xe6\x3f\x22\x83\xc5\x16\x94\xec\x82\xb2\x12\x6d\x41\x47\xe9\x02\x85\xda\x40\xc9\xb6\xed\x44\x5e\xee\x0d\xb7\x05\x6f\x14\x53\xa1\x2e\x29\x99\xcc\x41\xf0\x92\x5b\x13\x25\x71\xd5\xe9\x36\xdf\x47\x26\x22\x2c\x94\xe6\x1f\x28\x36\x15\xe7\xc2\x4f\x9f\x92\xf6\xe3\xcf\xdc\x1e\xb9\x9c\xd6\x63\x3a\x47\x2f\x70\x69\x67\xf0\xbc\xf1\x98\xc7\xe8\x6e\xb9\x9d\x81\x76\xcb\xd3\x15\x0e\x0c\xff\x80\x33\xb8\x6e\xb2\x15\xda\xf6\x49\x9c\xdb\x56\xfc\x41\x92\xe7\x47\x00\x6c\x26\x7d\xf5\xaa\xba\xbb\x81\xe3\x94\xc7\x4b\x42\x1b\xe7\x9d\x3a\x52\xd8\x9a\x33\x78\xd7\xc8\x34\x81\x92\xdd\x22\x30\x48\xd8\x51\x01\xc4\x0b\xed\x72\x75\xee\xca\x3f\xb1\xdd\x20\xda\xaf\xc9\xa3\xa4\xff\xe8\x82\xe2\xa7\x57\xd3\x06\xa7\xf4\x40\xec\x9f\x5e\x4d\xb9\xb4\xea\xe9\xd5\x74\x7a\x37\xfd\xc8\x9f\xa7\x57\x53\x25\x9f\x5e\x4d\x6d\x81\x4f\xaf\xa6\x4f\xaf\xae\xfb\x08\x6f\x5a\xda\xc4\x81\xa8\xd0\xd8\xa7\x57\xd3\x16\xf8\x01\x58\xa6\x57\x54\xff\xfa\x1f\xb6\x50\xb5\x9d\x2d\x04\x93\xb7\xc1\xdc\x89\x4b\x31\x88\x43\xc8\xf9\xf4\x03\x2a\x66\x08\x2e\x24\xb1\x43\x90\x2f\x75\x19\x18\x99\x5a\x6b\xe5\xf3\x01\x5a\xb3\xdb\xb7\x72\x68\xa1\x64\xa4\xb9\x71\x94\x2c\x74\x3c\x7f\xa3\xaa\x6d\xe8\x98\xb8\xe1\x27\x6a\x34\x75\x45\x35\xb4\xa8\xaf\x4e\x46\x69\xae\x40\x13\xbf\x9a\xbe\x78\xf5\xf2\x51\xf1\x0d\x25\x51\x6e\x0d\x9d\x84\x6c\xa1\xd6\x08\x4d\x3a\xb4\x50\x77\xc0\x64\x0e\x4b\xae\x11\xd8\x86\x6d\xbf\x4a\xe2\x7c\xef\x05\x7d\x00\x73\x8a\xef\x95\x52\x2b\x81\x95\xa8\xcd\x17\x82\x78\xc3\x30\x24\x8e\xa1\x5a\x2e\x39\xe5\x81\xbf\x2b\xbc\xff\xd5\x09\x08\xdf\x89\xda\x4c\xa0\xaa\x17\x82\x9b\x02\x18\x48\xdc\x40\x62\xac\x56\x72\x35\x77\xad\x19\x55\x2b\xdc\x2b\x54\xca\xd8\xc7\xa0\x83\xe5\x02\xf3\xfc\x0c\x78\x7e\x23\x76\x68\x3e\x67\xef\x2f\x6b\xeb\x25\xcb\x70\xa1\xd4\xed\x17\xb2\x74\xc7\xee\xf7\x64\xdd\xbf\x78\xa1\x7e\xaf\xa6\x3d\x71\x0b\x9b\xcd\x26\x6a\x35\xe9\x5c\x6c\x81\xa2\x8a\x33\x55\x96\xb5\xe4\x76\x1b\xbb\x05\x72\x25\xe3\xaf\x79\x9e\x5e\xbd\xba\x7a\xf9\xf2\xea\xf9\xbf\xbd\x7a\xf1\xe2\xea\xd5\xf3\x17\x0f\x39\x8c\x0e\x3f\x5f\xde\x5f\x9c\x96\x63\x3e\x03\x41\xb7\x78\x36\xca\xfb\x97\x80\xe7\xa4\xa2\x33\x71\xef\x6e\x3f\xba\x8d\x9b\x64\x2a\x77\xf7\x18\x7f\x6b\x2a\x3e\x94\xcb\xbb\xa6\x49\x83\x1f\x22\xfc\x80\x5a\x75\xd0\xd1\xe8\x4a\x62\x39\x05\x31\x0e\x5c\x6a\x23\x27\x50\x1b\xe7\x0a\x0a\x84\x5b\xdc\x52\xb5\xc9\x75\xf9\x68\x08\x46\x18\xad\x22\x3f\x55\x85\xda\x28\xc9\x44\x44\x72\xf8\xb9\xc0\x87\x37\x99\x92\x46\x09\x3c\xc5\xd8\x79\x14\x93\xa5\x49\xbe\xae\x3e\xf6\x20\x28\xd0\x7c\x2c\x2c\x0e\x8b\x16\x9f\x89\x84\xda\x50\x2a\xf5\x2f\xc2\xc2\xb7\xb8\xf1\xd5\x0a\xd3\x46\x9a\xe7\xe2\xea\x83\x80\xd3\x13\xed\xab\x1d\x54\xe6\xa0\x28\x77\xad\x2c\xc2\x52\x69\x32\x53\x69\x50\xac\xd1\x44\xf0\x77\x99\xa1\x4f\x4e\x26\x60\x50\xe6\xc0\xc0\x6a\x26\x0d\xcb\xa8\x8c\xdf\x04\xe5\x07\x40\xb0\x0a\xb8\x35\x28\x96\x7b\x70\x51\x6e\xe8\xa1\x91\x57\xca\xcc\x2e\x67\x2e\xc4\x9f\xd1\x8c\x2d\x3c\xac\x82\x05\x66\xaa\xa4\x40\x2e\x63\x32\xe7\x39\x45\xd1\xcd\xe2\xa2\x73\xa6\xdc\xbf\x92\x49\xbf\x55\xb4\xcb\xf7\xad\xce\xa6\xde\x50\xb4\x09\xc2\x9c\x8a\x74\x3a\xf8\xed\x76\x96\x94\xa5\x86\x4c\x9c\x4d\x30\x3f\xc1\xd8\x6e\xe7\x3f\x22\xd9\xa7\x21\xe0\xc4\x1b\xb4\xc7\x05\x29\x5f\xd5\xb6\x9f\x94\x70\x25\xbb\xe3\x83\x7c\x44\x59\x89\x2d\x64\xbf\xb6\x03\x1f\x74\xc2\xbf\x7a\x8c\x1c\xee\xc7\x66\xc3\xbb\xd4\xb0\x54\x39\x52\x4a\x68\x6a\x93\x61\xe5\xee\x72\x09\x80\x7f\xda\x7e\x60\xd2\x72\xca\xa8\x9a\x74\x8c\xe0\x27\xb6\x50\x9b\x06\x97\x39\x2e\xea\xd5\x8a\x9c\x90\xd2\x50\x69\xbe\x26\x84\x78\x88\x9b\xb3\xdb\xdd\xbf\x26\x71\xde\xd5\xe9\x92\x6a\xfe\x4f\x55\x43\xc6\x24\xe1\x38\xbb\x75\x4b\xcb\x6a\xad\xe9\x64\xac\xb0\x89\x77\xbc\x4a\xdb\x7c\x8d\x48\x1a\x3f\xb3\xe4\x28\x5c\x4a\x68\x10\xa1\xa0\x54\xae\xce\xdc\x01\x0c\x05\x5b\x23\x75\x6c\x18\xb7\x50\x4b\xcb\x05\x35\x6b\xb0\xb5\x96\x40\xb8\x3e\x48\xe4\x4e\x0a\x83\x09\x96\xf3\x77\x05\x9e\xc9\x97\xbb\x92\x1e\x68\x7c\xd3\xd4\x02\xa1\xd2\xca\x62\xb3\x01\xd9\x8a\x71\x69\xc8\x03\xba\x54\x11\xcb\x8f\x28\xf9\x75\x4f\xfe\x61\x7f\x0f\xe9\xba\xe3\x18\xfe\x2a\xd4\x82\x09\x58\x93\x5b\x5b\x08\xca\xf5\x15\xd0\x8d\xc3\x81\xb6\x8c\x65\xb6\xee\x1c\x4f\x93\x41\xd1\xf8\x35\xd3\x64\x41\x2c\x2b\x0b\xa9\xbf\x45\xa3\x36\x83\x7a\xed\xef\x06\xe9\x95\x4a\xdb\x87\xfd\x6e\xb3\x43\x0a\x4d\x79\xbb\x6d\xee\x8c\x91\xc2\x8f\x3f\xdf\x5c\x78\x09\xff\x8c\x4b\x87\x14\x82\x7d\xa3\x09\x5b\x30\x0b\x99\x46\x66\xd1\x40\x26\x94\xa9\x75\x23\x38\x95\xed\x81\x84\x6f\x39\xb5\x9c\xa9\xa3\x72\x42\xb4\x4c\x46\x05\x33\xc5\xd8\xdf\x0d\x6a\x74\xc6\xeb\xfa\xda\xf6\x01\x81\x71\x44\x0c\x78\x3a\xbd\x01\x9e\xb4\x7c\x23\x81\x72\x65\x8b\x1b\xe0\xcf\x9e\x75\xc4\x03\xbe\x84\x51\x4b\xf1\x23\xff\x39\xb2\x77\x11\xcd\x02\x69\x0a\xfd\xd9\xdc\x84\x9e\x8f\xa9\x04\xcf\x70\xc4\x27\x70\x39\xbe\x69\x7b\x17\x1a\x99\xbf\xe8\x1c\x0c\xbc\x79\x9b\x3f\xee\xf7\xee\xe6\x50\x33\xfe\x2e\xa8\xa7\x9b\xa6\x5e\x6c\x80\xc1\x8a\x1b\x0b\xb5\x16\xe0\xb7\x76\x63\x99\x56\x2d\x0d\x5d\x5f\x2b\x27\x70\xf5\x0f\x1e\x6a\xed\x12\x1a\x36\x11\x1d\x13\xa3\xff\xf8\xfe\xef\xdf\x46\xc6\x6a\x2e\x57\x7c\xb9\x1d\xdd\xd7\x5a\xcc\xe0\xc9\x28\xf8\x7f\x74\x27\x37\xfe\x71\xfa\x73\xb4\x66\xa2\xc6\x09\x15\x59\xf4\xcc\xfd\x9e\x78\x04\xcc\xfc\xdf\x47\x62\xb8\xc9\x3e\x20\x68\xb8\x76\xaf\x3d\xde\x5e\xba\x13\xe1\x27\xe0\x1f\x67\x70\xb8\x8e\xdd\x78\x7c\x73\x42\x4d\x5a\x19\xf4\xee\x12\x34\x1a\xb4\xa3\xf1\x4d\x6f\x9b\x1d\xab\x9e\xf9\x7a\x0f\xa9\x57\x63\xa6\xa4\xc4\xcc\x42\x5d\x29\xe9\x35\x0d\x42\x99\x0e\x85\x7b\x8a\x9e\xc6\x0f\x55\x0a\xa9\xcb\xf2\xfe\x0b\x17\xdf\xab\xec\x16\xed\x68\x34\xda\x70\x99\xab\x4d\x24\x54\xe3\xd8\xe9\xee\xdc\xaa\x4c\x09\x48\xd3\x14\x7c\x8c\x1e\x8c\xe1\x6b\x08\x36\x86\x3e\x60\x08\x60\x46\x8f\xf4\x34\x86\x67\x70\x3c\xbc\xa0\x44\xf1\x19\x04\x31\xab\x78\x30\xbe\xb9\xe8\x4d\x1e\x29\xe9\x03\xcb\xbe\x80\xee\x04\x6f\xa5\x74\xeb\x28\xcd\x0a\x52\x70\x76\xaf\xe8\x63\x9e\x86\x24\xa2\xc3\xbf\x05\x31\x6d\x05\x47\x96\xa6\x20\x6b\x21\xba\xf1\x7e\xaf\x79\xb2\xdd\xc5\x01\x79\x44\xf1\x87\x81\xaf\xd2\x14\xa8\x4a\x4a\xe8\xce\xf7\x23\xc9\xfa\x8e\x20\x18\x47\x74\xd2\xef\x47\xb4\xb3\xee\x4e\xb9\x61\xfe\x6b\xec\x30\x3f\xe6\x87\xf9\x03\x0c\x5d\xf9\xfc\x31\x7e\x8e\xa0\xcf\xce\x35\x3c\xc0\x4d\xd6\xe5\x02\xf5\x63\xec\x5c\xbd\xbc\x65\xe7\x54\xfd\x56\xda\xde\xd8\x09\x5c\xbe\x1c\x3f\xc0\x1d\xb5\x56\x0f\x32\xa7\x6f\x63\x46\xf7\x82\x6d\xa9\x02\x05\x43\xab\xaa\x37\xae\xda\x3d\x9c\xb8\xf3\x7d\x06\x1d\x87\x89\xbb\xcb\x9d\xc1\xd0\xbd\x51\x3f\x2f\xd1\x8d\x7a\x31\x9d\x4e\x27\xd0\x7e\xc9\xf1\x27\x46\x7b\x5b\xd7\xb8\x7b\x40\x1e\x53\x67\x19\x45\x19\x9f\x23\x91\xe7\xd1\xc9\xe4\xdf\x3f\x43\xaa\xd6\x11\x1f\x8a\x05\x7f\xf8\x03\x9c\xf4\x1e\xc2\x38\x8e\xe1\x6f\x4c\xdf\xba\xda\x34\x15\xb2\x5d\xfd\xba\xa3\x2f\xb9\x71\x39\x14\x33\x90\x2b\x89\x17\x83\xdf\x70\x9a\x9c\xc8\xe8\xc9\x60\x0e\xd3\x63\x01\x7f\x9c\x1e\x9c\x36\x67\x0e\xa1\x1e\xdf\xc3\xf3\xa5\xd5\xc8\x99\xe3\x8b\x97\x08\x5f\xa5\x10\x04\xfd\xc1\x27\x14\x44\xd0\x31\x1b\x18\xb4\xef\x1a\x5b\x8c\xfc\xa1\x7b\xee\x48\x1c\x4f\xe0\x7a\x3a\x9d\xb6\x46\xe9\xcc\xd2\xfd\x8d\x63\x78\x5d\x51\x90\x06\x4c\x6e\x9d\x4b\x6c\xb9\x34\x69\x21\x05\x5c\xe4\x11\x05\xdd\x46\x8a\x26\x42\xf2\x43\x49\xc1\x54\x9b\x50\x12\x52\x08\x2f\x6f\x2e\x4e\x57\xd7\xd3\x64\x6f\x69\xc7\xe6\x39\xa3\xfb\x63\x13\x1d\xea\xec\x88\x38\xbc\xec\xd6\x4b\x21\xc0\x81\xbd\xce\x1b\x66\xd0\xc9\xcd\x3b\xcd\x1c\x85\x03\x7b\x55\x75\x0f\xbb\x8b\x13\xf9\x1b\x3e\xcf\x2e\x3f\x72\x19\x5d\x77\x55\x9b\xe2\x00\x73\x3f\xf2\x9f\xc7\x37\x47\xf3\xc4\x31\xbc\xb5\xa8\x99\x45\x77\x25\xeb\x6c\x41\x5f\x4a\x6a\x3c\x31\x89\x4b\x0c\x34\x86\x1a\x65\x8e\xfa\xe0\xab\x15\x77\x31\xea\x39\x36\x26\x6b\x6a\x56\x7d\x38\x1d\x5b\xe4\x57\x96\x41\x6c\x68\xbb\xd1\x95\xfd\xd1\x26\x20\x28\xa7\x07\x48\x25\x62\x14\xac\x32\x98\x43\x0a\xcd\x87\x75\xa3\x71\x54\x4b\x7e\x37\x1a\x87\xfe\xfd\x98\x47\xdb\xef\x8f\x4d\x67\xb1\x46\xec\x67\x29\x04\x89\xd5\x74\xeb\x39\x0c\xe0\xd9\xe1\xec\x1e\x04\xcf\x20\x18\xce\x83\x9b\x73\x43\x01\x12\x9b\xcf\xdd\x95\x5c\x93\x1d\xfe\x14\xd0\xd5\x3f\x7d\xfb\x24\xf3\x19\x45\x70\xa3\x13\xb6\x6c\xcd\x2c\xd3\x8e\xeb\xf8\x06\xf6\xe4\x3e\x2d\xcd\xc8\x38\x37\xd0\xd4\x39\xdc\xb5\x3e\x74\xb7\xe5\xee\x6d\xa1\x74\x8e\x3a\xd4\x2c\xe7\xb5\x99\xc1\xf3\xea\xee\xe6\xa7\xf6\x6b\x02\x77\x3f\xf9\xa8\xa8\x95\xc6\xf9\x89\x44\xbe\x32\xf0\x0c\x82\x24\x26\x82\x5f\x63\xe3\x53\xe1\x9f\xda\x2c\xdd\x7d\xd0\x07\x67\x6e\x61\xa1\xfb\xdc\xce\xb7\x97\x3c\xcf\x05\x92\xc0\x7b\xf6\xb4\x19\xc9\xfe\x3d\x48\x1c\x4d\x09\xbe\xc2\xb1\x1f\xb3\x03\xfa\x4e\xe4\x91\x01\xdd\x4d\xee\x90\x00\x10\xd2\x92\xb9\xd3\xb9\xaf\x1a\xb8\x66\x3d\x74\xba\xf0\x9f\x67\xe6\xb5\x76\xb1\xd6\x28\xf4\x00\x9b\xc0\xd0\x50\xec\x97\x9b\xe1\x38\x2a\xea\x92\x49\xfe\x01\x47\x54\x4f\xa0\x08\x2d\xf0\x57\xc3\x3d\xa1\x2e\x1e\x12\x66\x7f\x67\x3b\x6c\xcf\xb8\xa1\x57\xe2\xb0\xb5\xee\xf3\x7d\x25\x81\xbe\x62\x18\x7e\xa2\x86\xce\xcf\x12\x2e\x98\xee\x8e\x55\x7a\x09\xdb\xc3\xb7\xf9\xfc\xa7\x23\x5c\x30\x3d\x6c\xea\x26\x2e\x34\x97\x6a\x93\x0e\xaf\xa7\x9d\x90\x8d\xa1\x9d\x9d\x87\x1e\x6b\xbd\x75\x37\xc6\x20\x29\xdb\xad\x39\x87\xeb\xe9\x97\x90\xb6\xa9\xbd\x1c\xad\xc0\x6a\x5e\x61\x4e\xd7\xce\x7c\x8d\xff\x07\x0b\xf9\x02\x4a\xfe\x64\x11\x09\x87\xad\xf2\x1c\x4c\x0f\xe4\xa5\xde\x4e\xb7\xff\x9f\xbe\x44\x81\xd8\x69\xf8\x19\x04\x67\x17\x72\xf1\xc0\x02\x8e\x09\x0f\xfb\x1f\xd9\xf7\xee\x5b\x87\xe0\xf8\x4c\xa1\x68\xb7\xf5\x24\xc1\x38\xa2\x7f\x22\x18\x05\x89\xa5\x6f\x81\xdc\xce\xea\x38\x90\x67\xf1\xcd\xe3\x9b\x93\xd4\x78\x9f\xc8\x50\x59\x00\x8f\xf2\x2c\xe8\x05\x27\x5d\x2e\xd6\x46\x22\xb0\xdb\x7f\x9f\x1c\xc7\xf0\xbd\x65\xda\x02\x83\x1f\xde\x42\x5d\x51\x85\x52\x53\x8e\x47\xe7\xa3\x3b\xc5\x5a\x0b\xc0\x82\xd1\x07\x0b\x4a\x6f\x98\xce\x7d\x35\xc8\x16\xb8\x75\x5f\x15\xb4\xa1\x9f\x41\xfb\x96\xa2\xeb\x35\x13\xa3\xbe\x3c\xd4\x37\x78\x32\x1a\x76\x9f\x43\x93\xfd\x87\xe3\x08\x59\x56\x9c\x12\x0e\xd6\x3d\x70\x40\x0a\xdf\xba\x14\x60\xf4\x64\x64\x0b\x6e\xc6\x11\xb3\x56\x8f\x86\x07\x60\x18\x8e\xc9\xbd\xb4\x11\x10\xed\xaa\x6e\x78\x72\xb0\xad\x1e\xe3\xb1\x0f\xa6\xc7\x37\x47\xe4\x99\x31\xa3\x06\x57\xc3\x49\x8f\xf7\x21\xac\x86\x4f\x87\x9d\xa1\xf6\xdb\xbb\x23\x4e\xd3\xb3\x92\x1c\xb0\x1e\x92\xbb\x18\x9e\x4c\xcf\xf2\xfc\x0d\xed\x9f\x51\x70\x66\xa7\x07\xdd\xa4\x8e\xf3\x6e\xdc\x29\xbb\xf1\xd7\x8f\x6a\xb9\xf9\xba\xf7\x01\x15\xf3\x7c\x38\x8e\x4c\xbd\x68\x4a\x1e\xa3\x17\x5d\x02\xd6\x92\x39\xf0\x1e\x1f\x05\x27\x01\x05\x4d\x71\x18\x54\xb4\x41\x47\xfb\xfe\xc8\xa9\xe1\xa7\x6c\x56\xb5\x9b\x90\xc2\xa7\x3e\x28\x89\x63\xf8\xc6\x50\x70\xd5\x5c\x2c\x6e\x70\x61\x5c\x25\x01\x3c\xde\x29\x2a\xf3\xc5\xa0\xd7\xdf\xbd\xed\x15\x84\xba\x1d\x41\xe1\xcd\x60\xd0\xfd\x6f\xc1\xb9\x3a\xc9\xd9\x7f\x66\xa0\x6b\xc2\xe6\x6a\xdd\x5d\x12\x76\x85\x14\xaa\x34\xd0\xff\x5e\x00\x33\x5b\x99\x41\x8e\x4b\xd4\xf3\x1e\x7b\x5f\x5d\x49\x62\xb7\xad\x2f\x92\xb8\xb0\xa5\x98\x5f\xfc\xef\x00\xb8\x17\x58\x4d\x5a\x34\x00\x00")
 
 func faucetHtmlBytes() ([]byte, error) {
 	return bindataRead(
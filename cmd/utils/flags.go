@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -46,9 +47,11 @@ import (
 	"github.com/eeefan/dpeth/eth/gasprice"
 	"github.com/eeefan/dpeth/ethdb"
 	"github.com/eeefan/dpeth/ethstats"
+	"github.com/eeefan/dpeth/graphql"
 	"github.com/eeefan/dpeth/les"
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/metrics"
+	"github.com/eeefan/dpeth/metrics/exp"
 	"github.com/eeefan/dpeth/node"
 	"github.com/eeefan/dpeth/p2p"
 	"github.com/eeefan/dpeth/p2p/discover"
@@ -127,6 +130,11 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (url or path to ipc file)",
+		Value: "",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 8848=Mainnet, 8341=Testnet)",
@@ -190,6 +198,20 @@ var (
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	MnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Create the account in a new hierarchical deterministic wallet, backed up by a mnemonic",
+	}
+	HDPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "Derivation path of the account created in a mnemonic wallet",
+		Value: accounts.DefaultBaseDerivationPath.String(),
+	}
+	KDFFlag = cli.StringFlag{
+		Name:  "kdf",
+		Usage: "Key derivation function to re-encrypt the key files with (argon2id, scrypt)",
+		Value: "argon2id",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -365,6 +387,16 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Enable stand-alone metrics HTTP server listening interface",
+		Value: "127.0.0.1",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics HTTP server listening port",
+		Value: 6060,
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -403,6 +435,41 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCBatchItemLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in a batch over HTTP-RPC and WS-RPC (0 = unlimited)",
+		Value: node.DefaultConfig.RPCBatchItemLimit,
+	}
+	RPCBatchResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.batchresponsemax",
+		Usage: "Maximum number of bytes returned in a response over HTTP-RPC and WS-RPC (0 = unlimited)",
+		Value: node.DefaultConfig.RPCBatchResponseMaxSize,
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Requests per second allowed per method and remote IP over HTTP-RPC and WS-RPC (0 = unlimited)",
+		Value: node.DefaultConfig.RPCRateLimit,
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.rateburst",
+		Usage: "Number of requests per method and remote IP allowed in a burst above the rate limit",
+		Value: node.DefaultConfig.RPCRateBurst,
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded HS256 secret required to authenticate JWTs on HTTP-RPC and WS-RPC",
+		Value: "",
+	}
+	RPCAuthTokensFlag = cli.StringFlag{
+		Name:  "rpc.authtokens",
+		Usage: "Path to a file of static bearer tokens for HTTP-RPC and WS-RPC, one per line followed by the API's it may call",
+		Value: "",
+	}
+	RPCPublicApiFlag = cli.StringFlag{
+		Name:  "rpc.publicapi",
+		Usage: "API's callable without credentials when HTTP-RPC and WS-RPC authentication is enabled",
+		Value: "",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -435,6 +502,50 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: graphql.DefaultConfig.Host,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: graphql.DefaultConfig.Port,
+	}
+	GraphQLPathFlag = cli.StringFlag{
+		Name:  "graphql.path",
+		Usage: "HTTP path the GraphQL queries are served on",
+		Value: graphql.DefaultConfig.Path,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(graphql.DefaultConfig.VirtualHosts, ","),
+	}
+	GraphQLMaxDepthFlag = cli.IntFlag{
+		Name:  "graphql.maxdepth",
+		Usage: "Maximum field nesting depth of a GraphQL query (0 = unlimited)",
+		Value: graphql.DefaultConfig.MaxDepth,
+	}
+	GraphQLMaxRangeFlag = cli.Uint64Flag{
+		Name:  "graphql.maxrange",
+		Usage: "Maximum number of blocks a GraphQL blocks or logs query may span (0 = unlimited)",
+		Value: graphql.DefaultConfig.MaxRange,
+	}
+	GraphQLMaxCostFlag = cli.Uint64Flag{
+		Name:  "graphql.maxcost",
+		Usage: "Maximum number of blocks, receipts, transactions, states and snapshots a GraphQL request may look up (0 = unlimited)",
+		Value: graphql.DefaultConfig.MaxCost,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -744,6 +855,37 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits applies the RPC request limits from the command line flags to the
+// node configuration.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchItemLimitFlag.Name) {
+		cfg.RPCBatchItemLimit = ctx.GlobalInt(RPCBatchItemLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseMaxSizeFlag.Name) {
+		cfg.RPCBatchResponseMaxSize = ctx.GlobalInt(RPCBatchResponseMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+}
+
+// setRPCAuth applies the RPC authentication settings from the command line flags
+// to the node configuration.
+func setRPCAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.RPCJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuthTokensFlag.Name) {
+		cfg.RPCAuthTokenFile = ctx.GlobalString(RPCAuthTokensFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCPublicApiFlag.Name) {
+		cfg.RPCPublicModules = splitAndTrim(ctx.GlobalString(RPCPublicApiFlag.Name))
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -902,6 +1044,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCAuth(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -924,6 +1068,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -1152,6 +1299,34 @@ func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 	cfg.Refresh = ctx.GlobalDuration(DashboardRefreshFlag.Name)
 }
 
+// SetGraphQLConfig applies GraphQL related command line flags to the config.
+func SetGraphQLConfig(ctx *cli.Context, cfg *graphql.Config) {
+	if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+		cfg.Host = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.Port = ctx.GlobalInt(GraphQLPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLPathFlag.Name) {
+		cfg.Path = ctx.GlobalString(GraphQLPathFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.Cors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.VirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.MaxDepth = ctx.GlobalInt(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxRangeFlag.Name) {
+		cfg.MaxRange = ctx.GlobalUint64(GraphQLMaxRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxCostFlag.Name) {
+		cfg.MaxCost = ctx.GlobalUint64(GraphQLMaxCostFlag.Name)
+	}
+}
+
 // RegisterEthService adds an Ethereum client to the stack.
 func RegisterEthService(stack *node.Node, cfg *eth.Config) {
 	var err error
@@ -1174,13 +1349,42 @@ func RegisterEthService(stack *node.Node, cfg *eth.Config) {
 	}
 }
 
-// RegisterDashboardService adds a dashboard to the stack.
+// RegisterDashboardService adds a dashboard to the stack. The chain panels are
+// only fed by full nodes.
 func RegisterDashboardService(stack *node.Node, cfg *dashboard.Config, commit string) {
 	stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		return dashboard.New(cfg, commit)
+		var backend dashboard.Backend
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			backend = ethServ
+		}
+		return dashboard.New(cfg, commit, backend)
 	})
 }
 
+// RegisterGraphQLService adds a GraphQL server over the chain of the Ethereum
+// service to the stack, protected by the RPC authentication of the node. Alien
+// snapshots are only served by full nodes.
+func RegisterGraphQLService(stack *node.Node, cfg *graphql.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		auth, err := ctx.RPCAuth()
+		if err != nil {
+			return nil, err
+		}
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(cfg, auth, ethServ.APIBackend, graphql.AlienSnapshots(ethServ.Engine(), ethServ.BlockChain()))
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(cfg, auth, lesServ.ApiBackend, nil)
+		}
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // RegisterShhService configures Whisper and adds it to the given node.
 func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	if err := stack.Register(func(n *node.ServiceContext) (node.Service, error) {
@@ -1207,6 +1411,16 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// SetupMetrics starts the stand-alone metrics HTTP server if metrics are enabled
+// and a listening interface was explicitly requested.
+func SetupMetrics(ctx *cli.Context) {
+	if !metrics.Enabled || !ctx.GlobalIsSet(MetricsHTTPFlag.Name) {
+		return
+	}
+	address := fmt.Sprintf("%s:%d", ctx.GlobalString(MetricsHTTPFlag.Name), ctx.GlobalInt(MetricsPortFlag.Name))
+	exp.Setup(address)
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
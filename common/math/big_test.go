@@ -19,6 +19,7 @@ package math
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -61,6 +62,27 @@ func TestHexOrDecimal256(t *testing.T) {
 	}
 }
 
+func TestHexOrDecimal256JSON(t *testing.T) {
+	tests := []struct {
+		input string
+		num   *big.Int
+	}{
+		{`"0x12345678"`, big.NewInt(0x12345678)},
+		{`"12345678"`, big.NewInt(12345678)},
+		{`12345678`, big.NewInt(12345678)},
+	}
+	for _, test := range tests {
+		var num HexOrDecimal256
+		if err := json.Unmarshal([]byte(test.input), &num); err != nil {
+			t.Errorf("Unmarshal(%s) failed: %v", test.input, err)
+			continue
+		}
+		if (*big.Int)(&num).Cmp(test.num) != 0 {
+			t.Errorf("Unmarshal(%s) -> %d, want %d", test.input, (*big.Int)(&num), test.num)
+		}
+	}
+}
+
 func TestMustParseBig256(t *testing.T) {
 	defer func() {
 		if recover() == nil {
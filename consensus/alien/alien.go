@@ -29,14 +29,14 @@ import (
 	"github.com/eeefan/dpeth/accounts"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
 	"github.com/eeefan/dpeth/core/state"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/crypto"
-	"github.com/eeefan/dpeth/crypto/sha3"
 	"github.com/eeefan/dpeth/ethdb"
 	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/metrics"
 	"github.com/eeefan/dpeth/params"
-	"github.com/eeefan/dpeth/rlp"
 	"github.com/eeefan/dpeth/rpc"
 	lru "github.com/hashicorp/golang-lru"
 )
@@ -74,6 +74,14 @@ var (
 	scRentLengthRecommend            = uint64(0)                                             // block number for split each side chain rent fee
 )
 
+// Alien consensus metrics.
+var (
+	sealLatencyTimer   = metrics.NewRegisteredTimer("alien/seal/latency", nil)   // Delay between the start of the slot and the signature of a local block
+	missedSignersGauge = metrics.NewRegisteredGauge("alien/signers/missed", nil) // Number of signers the last finalized block records as missed
+	loopNumberGauge    = metrics.NewRegisteredGauge("alien/loop/number", nil)    // Index of the signer loop of the last finalized block
+	loopStartGauge     = metrics.NewRegisteredGauge("alien/loop/start", nil)     // Start time of the signer loop of the last finalized block
+)
+
 // Various error messages to mark blocks invalid. These should be private to
 // prevent engine specific errors from being referenced in the remainder of the
 // codebase, inherently breaking if the engine is swapped out. Please put common
@@ -91,6 +99,10 @@ var (
 	// to contain a 65 byte secp256k1 signature.
 	errMissingSignature = errors.New("extra-data 65 byte suffix signature missing")
 
+	// errInvalidSignature is returned if the signer hands back a seal which is not
+	// a 65 byte secp256k1 signature.
+	errInvalidSignature = errors.New("invalid seal signature length")
+
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	errInvalidMixDigest = errors.New("non-zero mix digest")
 
@@ -128,6 +140,10 @@ var (
 	// errSignerQueueEmpty is returned if no signer when calculate
 	errSignerQueueEmpty = errors.New("signer queue is empty")
 
+	// errSlotBeforeLoop is returned if the signer in turn is requested for a
+	// time earlier than the start of the loop.
+	errSlotBeforeLoop = errors.New("slot before loop start")
+
 	// errGetLastLoopInfoFail is returned if get last loop info fail
 	errGetLastLoopInfoFail = errors.New("get last loop info fail")
 
@@ -149,9 +165,13 @@ type Alien struct {
 	signatures *lru.ARCCache       // Signatures of recent blocks to speed up mining
 	signer     common.Address      // Ethereum address of the signing key
 	signFn     SignerFn            // Signer function to authorize hashes with
+	signHdrFn  SignHeaderFn        // Signer function to authorize headers with, replacing signFn if set
 	signTxFn   SignTxFn            // Sign transaction function to sign tx
 	lock       sync.RWMutex        // Protects the signer fields
 	lcsc       uint64              // Last confirmed side chain
+	feeds      *eventFeeds         // Feeds of snapshot changes for subscribers
+	nodes      *signerNodes        // Registry of the p2p nodes run by the signers
+	now        func() time.Time    // Clock to time and delay blocks by, the system clock outside simulations
 }
 
 // SignerFn is a signer callback function to request a hash to be signed by a
@@ -161,37 +181,17 @@ type SignerFn func(accounts.Account, []byte) ([]byte, error)
 // SignTxFn is a signTx
 type SignTxFn func(accounts.Account, *types.Transaction, *big.Int) (*types.Transaction, error)
 
+// SignHeaderFn is a signer callback function to request a header to be sealed by
+// a backing account. Unlike SignerFn it hands over the header itself along with
+// its parent, whose signer queue decides the slots of the header, so external
+// signers can check what they are signing before calculating its seal hash.
+type SignHeaderFn func(account accounts.Account, header *types.Header, parent *types.Header) ([]byte, error)
+
 // sigHash returns the hash which is used as input for the delegated-proof-of-stake
 // signing. It is the hash of the entire header apart from the 65 byte signature
 // contained at the end of the extra data.
-//
-// Note, the method requires the extra data to be at least 65 bytes, otherwise it
-// panics. This is done to avoid accidentally using both forms (signature present
-// or not), which could be abused to produce different hashes for the same header.
 func sigHash(header *types.Header) (hash common.Hash, err error) {
-	hasher := sha3.NewKeccak256()
-	if err := rlp.Encode(hasher, []interface{}{
-		header.ParentHash,
-		header.UncleHash,
-		header.Coinbase,
-		header.Root,
-		header.TxHash,
-		header.ReceiptHash,
-		header.Bloom,
-		header.Difficulty,
-		header.Number,
-		header.GasLimit,
-		header.GasUsed,
-		header.Time,
-		header.Extra[:len(header.Extra)-65], // Yes, this will panic if extra is too short
-		header.MixDigest,
-		header.Nonce,
-	}); err != nil {
-		return common.Hash{}, err
-	}
-
-	hasher.Sum(hash[:0])
-	return hash, nil
+	return alientypes.SealHash(header)
 }
 
 // ecrecover extracts the Ethereum account address from a signed header.
@@ -250,9 +250,21 @@ func New(config *params.AlienConfig, db ethdb.Database) *Alien {
 		db:         db,
 		recents:    recents,
 		signatures: signatures,
+		feeds:      new(eventFeeds),
+		nodes:      newSignerNodes(db),
+		now:        time.Now,
 	}
 }
 
+// NewSimulated creates a Alien delegated-proof-of-stake consensus engine which
+// reads the time from the given clock instead of the system one. It allows
+// simulated chains to seal blocks ahead of the wall clock without waiting.
+func NewSimulated(config *params.AlienConfig, db ethdb.Database, now func() time.Time) *Alien {
+	a := New(config, db)
+	a.now = now
+	return a
+}
+
 // Author implements consensus.Engine, returning the Ethereum address recovered
 // from the signature in the header's extra-data section.
 func (a *Alien) Author(header *types.Header) (common.Address, error) {
@@ -295,7 +307,7 @@ func (a *Alien) verifyHeader(chain consensus.ChainReader, header *types.Header,
 	}
 
 	// Don't waste time checking blocks from the future
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+	if header.Time.Cmp(big.NewInt(a.now().Unix())) > 0 {
 		return consensus.ErrFutureBlock
 	}
 
@@ -596,20 +608,20 @@ func (a *Alien) Prepare(chain consensus.ChainReader, header *types.Header) error
 	// Set the correct difficulty
 	header.Difficulty = new(big.Int).Set(defaultDifficulty)
 	// If now is later than genesis timestamp, skip prepare
-	if a.config.GenesisTimestamp < uint64(time.Now().Unix()) {
+	if a.config.GenesisTimestamp < uint64(a.now().Unix()) {
 		return nil
 	}
 	// Count down for start
 	if header.Number.Uint64() == 1 {
 		for {
-			delay := time.Unix(int64(a.config.GenesisTimestamp-2), 0).Sub(time.Now())
+			delay := time.Unix(int64(a.config.GenesisTimestamp-2), 0).Sub(a.now())
 			if delay <= time.Duration(0) {
-				log.Info("Ready for seal block", "time", time.Now())
+				log.Info("Ready for seal block", "time", a.now())
 				break
 			} else if delay > time.Duration(a.config.Period)*time.Second {
 				delay = time.Duration(a.config.Period) * time.Second
 			}
-			log.Info("Waiting for seal block", "delay", common.PrettyDuration(time.Unix(int64(a.config.GenesisTimestamp-2), 0).Sub(time.Now())))
+			log.Info("Waiting for seal block", "delay", common.PrettyDuration(time.Unix(int64(a.config.GenesisTimestamp-2), 0).Sub(a.now())))
 			select {
 			case <-time.After(delay):
 				continue
@@ -756,8 +768,8 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 		return nil, consensus.ErrUnknownAncestor
 	}
 	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(a.config.Period))
-	if header.Time.Int64() < time.Now().Unix() {
-		header.Time = big.NewInt(time.Now().Unix())
+	if header.Time.Int64() < a.now().Unix() {
+		header.Time = big.NewInt(a.now().Unix())
 	}
 
 	// Ensure the extra data has all it's components
@@ -882,6 +894,10 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 		}
 		sideChainRewards(chain.Config(), state, header, snap)
 	}
+	missedSignersGauge.Update(int64(len(currentHeaderExtra.SignerMissing)))
+	loopNumberGauge.Update(int64(number / a.config.MaxSignerCount))
+	loopStartGauge.Update(int64(currentHeaderExtra.LoopStartTime))
+
 	// encode header.extra
 	currentHeaderExtraEnc, err := encodeHeaderExtra(a.config, header.Number, currentHeaderExtra)
 	if err != nil {
@@ -909,9 +925,111 @@ func (a *Alien) Authorize(signer common.Address, signFn SignerFn, signTxFn SignT
 
 	a.signer = signer
 	a.signFn = signFn
+	a.signHdrFn = nil
+	a.signTxFn = signTxFn
+}
+
+// AuthorizeHeaderSigner injects a header signer, such as an external signer not
+// willing to sign blind hashes, into the consensus engine to mint new blocks with.
+func (a *Alien) AuthorizeHeaderSigner(signer common.Address, signHdrFn SignHeaderFn, signTxFn SignTxFn) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.signer = signer
+	a.signFn = nil
+	a.signHdrFn = signHdrFn
 	a.signTxFn = signTxFn
 }
 
+// Signer returns the address of the key the engine is authorized to seal with.
+func (a *Alien) Signer() common.Address {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.signer
+}
+
+// HeaderExtra decodes the consensus fields carried in the extra-data of a
+// non-genesis header.
+func (a *Alien) HeaderExtra(header *types.Header) (*HeaderExtra, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	extra := new(HeaderExtra)
+	if err := decodeHeaderExtra(a.config, header.Number, header.Extra[extraVanity:len(header.Extra)-extraSeal], extra); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// signerQueue returns the loop start time and the signer queue carried by the
+// given header.
+func (a *Alien) signerQueue(header *types.Header) (uint64, []common.Address, error) {
+	if header.Number.Sign() == 0 {
+		// The genesis block carries no queue, the self voting signers take turns
+		var queue []common.Address
+		for i := 0; i < int(a.config.MaxSignerCount) && len(a.config.SelfVoteSigners) > 0; i++ {
+			queue = append(queue, common.Address(a.config.SelfVoteSigners[i%len(a.config.SelfVoteSigners)]))
+		}
+		return a.config.GenesisTimestamp, queue, nil
+	}
+	extra, err := a.HeaderExtra(header)
+	if err != nil {
+		return 0, nil, err
+	}
+	return extra.LoopStartTime, extra.SignerQueue, nil
+}
+
+// SignerAt returns the signer in turn to seal a child of the given header at
+// the given time, based on the signer queue and loop the header carries.
+func (a *Alien) SignerAt(header *types.Header, headerTime uint64) (common.Address, error) {
+	loopStartTime, queue, err := a.signerQueue(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(queue) == 0 {
+		return common.Address{}, errSignerQueueEmpty
+	}
+	if headerTime < loopStartTime {
+		return common.Address{}, errSlotBeforeLoop
+	}
+	return queue[(headerTime-loopStartTime)/a.config.Period%uint64(len(queue))], nil
+}
+
+// UpcomingSigners returns the distinct signers in turn for the given number of
+// slots following the given header, in the order of their slots. The horizon
+// is capped at the end of the loop the header carries, as the queue of the next
+// loop is not known yet.
+func (a *Alien) UpcomingSigners(header *types.Header, slots int) ([]common.Address, error) {
+	loopStartTime, queue, err := a.signerQueue(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(queue) == 0 {
+		return nil, errSignerQueueEmpty
+	}
+	var (
+		loopEndTime = loopStartTime + uint64(len(queue))*a.config.Period
+		seen        = make(map[common.Address]bool)
+		signers     []common.Address
+	)
+	for i := 1; i <= slots; i++ {
+		slot := header.Time.Uint64() + uint64(i)*a.config.Period
+		if slot >= loopEndTime {
+			break
+		}
+		signer, err := a.SignerAt(header, slot)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[signer] {
+			seen[signer] = true
+			signers = append(signers, signer)
+		}
+	}
+	return signers, nil
+}
+
 // ApplyGenesis
 func (a *Alien) ApplyGenesis(chain consensus.ChainReader, genesisHash common.Hash) error {
 	if a.config.LightConfig != nil {
@@ -958,7 +1076,7 @@ func (a *Alien) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	a.lock.RLock()
-	signer, signFn := a.signer, a.signFn
+	signer, signFn, signHdrFn := a.signer, a.signFn, a.signHdrFn
 	a.lock.RUnlock()
 
 	// Bail out if we're unauthorized to sign a block
@@ -1003,7 +1121,7 @@ func (a *Alien) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 
 	// correct the time
-	delay := time.Unix(header.Time.Int64(), 0).Sub(time.Now())
+	delay := time.Unix(header.Time.Int64(), 0).Sub(a.now())
 
 	select {
 	case <-stop:
@@ -1012,18 +1130,34 @@ func (a *Alien) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 
 	// Sign all the things!
-	headerSigHash, err := sigHash(header)
-	if err != nil {
-		return nil, err
+	var sighash []byte
+	if signHdrFn != nil {
+		parent := chain.GetHeader(header.ParentHash, number-1)
+		if parent == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		sighash, err = signHdrFn(accounts.Account{Address: signer}, header, parent)
+	} else {
+		var headerSigHash common.Hash
+		if headerSigHash, err = sigHash(header); err != nil {
+			return nil, err
+		}
+		sighash, err = signFn(accounts.Account{Address: signer}, headerSigHash.Bytes())
 	}
-
-	sighash, err := signFn(accounts.Account{Address: signer}, headerSigHash.Bytes())
 	if err != nil {
 		return nil, err
 	}
+	if len(sighash) != extraSeal {
+		return nil, errInvalidSignature
+	}
 
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
 
+	// Measure how late into its slot the block was sealed
+	if slot := header.Time.Uint64(); !chain.Config().Alien.SideChain && a.config.Period > 0 && slot >= snap.LoopStartTime {
+		slot -= (slot - snap.LoopStartTime) % a.config.Period
+		sealLatencyTimer.Update(a.now().Sub(time.Unix(int64(slot), 0)))
+	}
 	return block.WithSeal(header), nil
 }
 
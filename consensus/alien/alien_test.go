@@ -17,9 +17,13 @@
 package alien
 
 import (
+	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
+	"github.com/eeefan/dpeth/core/types"
 )
 
 func TestAlien_PenaltyTrantor(t *testing.T) {
@@ -235,3 +239,51 @@ func TestAlien_Penalty(t *testing.T) {
 
 	}
 }
+
+// Tests that the leaf decoder of the header extra-data used by clients agrees
+// with the engine on the fields and seal hash of a fully populated header.
+func TestAlienTypesHeaderExtra(t *testing.T) {
+	signers := []common.Address{common.HexToAddress("0x1"), common.HexToAddress("0x2")}
+	extra := HeaderExtra{
+		CurrentBlockConfirmations: []Confirmation{{Signer: signers[0], BlockNumber: big.NewInt(5)}},
+		CurrentBlockVotes:         []Vote{{Voter: signers[1], Candidate: signers[0], Stake: big.NewInt(10)}},
+		LoopStartTime:             1000,
+		SignerQueue:               signers,
+		CandidateSigners:          signers,
+		SignerAdmin:               signers[1],
+		PerBlockReward:            big.NewInt(3),
+		MinerRewardRatio:          500,
+		SignerMissing:             signers[1:],
+		ConfirmedBlockNumber:      4,
+		SideChainConfirmations:    []SCConfirmation{{Hash: common.HexToHash("0x3"), Coinbase: signers[0], Number: 7}},
+	}
+	encoded, err := encodeHeaderExtra(nil, nil, extra)
+	if err != nil {
+		t.Fatalf("failed to encode extra-data: %v", err)
+	}
+	header := &types.Header{
+		Number:     big.NewInt(6),
+		Time:       big.NewInt(1003),
+		Difficulty: big.NewInt(1),
+		Extra:      append(append(make([]byte, extraVanity), encoded...), make([]byte, extraSeal)...),
+	}
+	have, err := alientypes.ParseHeaderExtra(header)
+	if err != nil {
+		t.Fatalf("failed to decode extra-data: %v", err)
+	}
+	if have.LoopStartTime != extra.LoopStartTime || !reflect.DeepEqual(have.SignerQueue, extra.SignerQueue) ||
+		!reflect.DeepEqual(have.SignerMissing, extra.SignerMissing) || have.ConfirmedBlockNumber != extra.ConfirmedBlockNumber ||
+		have.PerBlockReward.Cmp(extra.PerBlockReward) != 0 || have.SignerAdmin != extra.SignerAdmin {
+		t.Errorf("extra-data mismatch: have %+v, want %+v", have, extra)
+	}
+	if len(have.SideChainFields) != 4 {
+		t.Errorf("side chain field count mismatch: have %d, want 4", len(have.SideChainFields))
+	}
+	want, err := sigHash(header)
+	if err != nil {
+		t.Fatalf("failed to hash header: %v", err)
+	}
+	if hash, _ := alientypes.SealHash(header); hash != want {
+		t.Errorf("seal hash mismatch: have %x, want %x", hash, want)
+	}
+}
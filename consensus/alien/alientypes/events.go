@@ -0,0 +1,63 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alientypes contains the types the alien consensus engine shares with
+// its clients, kept free of the dependencies of the engine itself.
+package alientypes
+
+import (
+	"math/big"
+
+	"github.com/eeefan/dpeth/common"
+)
+
+// NewLoopEvent is posted when a header starting a new loop becomes canonical,
+// carrying the signer queue created for that loop.
+type NewLoopEvent struct {
+	Number        uint64           `json:"number"`        // Number of the header starting the loop
+	Hash          common.Hash      `json:"hash"`          // Hash of the header starting the loop
+	LoopStartTime uint64           `json:"loopStartTime"` // Timestamp the first slot of the loop starts at
+	Signers       []common.Address `json:"signers"`       // Signer queue of the new loop
+}
+
+// SignerQueueChangedEvent is posted when a canonical header carries a signer
+// queue different from the one of its parent.
+type SignerQueueChangedEvent struct {
+	Number   uint64           `json:"number"`   // Number of the header changing the queue
+	Hash     common.Hash      `json:"hash"`     // Hash of the header changing the queue
+	Previous []common.Address `json:"previous"` // Signer queue before the header
+	Signers  []common.Address `json:"signers"`  // Signer queue after the header
+}
+
+// AdminActionEvent is posted when a canonical header changes any of the
+// settings controlled by the signer admin through custom transactions.
+type AdminActionEvent struct {
+	Number           uint64           `json:"number"`           // Number of the header carrying the change
+	Hash             common.Hash      `json:"hash"`             // Hash of the header carrying the change
+	SignerAdmin      common.Address   `json:"signerAdmin"`      // Admin of the signer set after the header
+	CandidateSigners []common.Address `json:"candidateSigners"` // Candidate signers after the header
+	PerBlockReward   *big.Int         `json:"perBlockReward"`   // Block reward after the header
+	MinerRewardRatio uint64           `json:"minerRewardRatio"` // Miner reward ratio after the header
+}
+
+// PunishedEvent is posted for every signer a canonical header punishes for
+// missing its slot.
+type PunishedEvent struct {
+	Number   uint64         `json:"number"`   // Number of the header recording the missed slot
+	Hash     common.Hash    `json:"hash"`     // Hash of the header recording the missed slot
+	Signer   common.Address `json:"signer"`   // Signer which missed its slot
+	Punished uint64         `json:"punished"` // Accumulated punished credit of the signer
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package alientypes
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto/sha3"
+	"github.com/eeefan/dpeth/rlp"
+)
+
+const (
+	ExtraVanity = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
+	ExtraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+)
+
+// ErrMissingSignature is returned if a header's extra-data is too short to hold
+// the vanity and the 65 byte secp256k1 seal.
+var ErrMissingSignature = errors.New("extra-data 65 byte suffix signature missing")
+
+// HeaderExtra is the leading part of the consensus fields carried in the
+// extra-data of alien headers, up to the confirmed block number. The votes and
+// confirmations of the block are kept undecoded, as are the side chain fields
+// following the confirmed block number, which have to be set when encoding.
+type HeaderExtra struct {
+	CurrentBlockConfirmations rlp.RawValue
+	CurrentBlockVotes         rlp.RawValue
+	CurrentBlockProposals     rlp.RawValue
+	CurrentBlockDeclares      rlp.RawValue
+	ModifyPredecessorVotes    rlp.RawValue
+	LoopStartTime             uint64
+	SignerQueue               []common.Address
+	CandidateSigners          []common.Address
+	SignerAdmin               common.Address
+	PerBlockReward            *big.Int
+	MinerRewardRatio          uint64
+	SignerMissing             []common.Address
+	ConfirmedBlockNumber      uint64
+	SideChainFields           []rlp.RawValue `rlp:"tail"`
+}
+
+// ParseHeaderExtra decodes the consensus fields carried in the extra-data of a
+// non-genesis header.
+func ParseHeaderExtra(header *types.Header) (*HeaderExtra, error) {
+	if len(header.Extra) < ExtraVanity+ExtraSeal {
+		return nil, ErrMissingSignature
+	}
+	extra := new(HeaderExtra)
+	if err := rlp.DecodeBytes(header.Extra[ExtraVanity:len(header.Extra)-ExtraSeal], extra); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// SealHash returns the hash of a header the signer signs to seal it, i.e. the
+// hash of the entire header apart from the 65 byte seal at the end of the
+// extra-data.
+func SealHash(header *types.Header) (hash common.Hash, err error) {
+	if len(header.Extra) < ExtraSeal {
+		return common.Hash{}, ErrMissingSignature
+	}
+	hasher := sha3.NewKeccak256()
+	if err := rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-ExtraSeal],
+		header.MixDigest,
+		header.Nonce,
+	}); err != nil {
+		return common.Hash{}, err
+	}
+	hasher.Sum(hash[:0])
+	return hash, nil
+}
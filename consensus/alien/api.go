@@ -19,11 +19,18 @@
 package alien
 
 import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
 	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/event"
+	"github.com/eeefan/dpeth/p2p/discover"
 	"github.com/eeefan/dpeth/rpc"
-	"math/big"
 )
 
 // API is a user facing RPC API to allow controlling the signer and voting
@@ -159,3 +166,80 @@ func (api *API) GetSnapshotByHeaderTime(targetTime uint64, scHash common.Hash) (
 	}
 	return nil, errUnknownBlock
 }
+
+// SetSignerNode registers the enode URL of the p2p node run by the given signer.
+// Nodes of the signers in the current queue are kept connected to each other.
+func (api *API) SetSignerNode(signer common.Address, url string) error {
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return fmt.Errorf("invalid enode: %v", err)
+	}
+	return api.alien.SetSignerNode(signer, node)
+}
+
+// RemoveSignerNode drops the p2p node registered for the given signer.
+func (api *API) RemoveSignerNode(signer common.Address) error {
+	return api.alien.RemoveSignerNode(signer)
+}
+
+// SignerNodes returns the enode URLs registered for the signers.
+func (api *API) SignerNodes() map[common.Address]string {
+	urls := make(map[common.Address]string)
+	for signer, node := range api.alien.SignerNodes() {
+		urls[signer] = node.String()
+	}
+	return urls
+}
+
+// NewLoop creates a subscription that fires whenever a header starting a new
+// loop, and with it a new signer queue, becomes canonical.
+func (api *API) NewLoop(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribeFeed(ctx, &api.alien.feeds.newLoop, make(chan alientypes.NewLoopEvent))
+}
+
+// SignerQueueChanged creates a subscription that fires whenever a canonical header
+// carries a signer queue different from the one of its parent.
+func (api *API) SignerQueueChanged(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribeFeed(ctx, &api.alien.feeds.signerQueue, make(chan alientypes.SignerQueueChangedEvent))
+}
+
+// AdminAction creates a subscription that fires whenever a canonical header changes
+// the candidate signers, the signer admin or the reward parameters.
+func (api *API) AdminAction(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribeFeed(ctx, &api.alien.feeds.adminAction, make(chan alientypes.AdminActionEvent))
+}
+
+// Punished creates a subscription that fires for every signer a canonical header
+// punishes for missing its slot.
+func (api *API) Punished(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribeFeed(ctx, &api.alien.feeds.punished, make(chan alientypes.PunishedEvent))
+}
+
+// subscribeFeed creates an RPC subscription forwarding the events of a feed,
+// received through ch, a channel of the feed's event type.
+func subscribeFeed(ctx context.Context, feed *event.Feed, ch interface{}) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	eventsSub := feed.Subscribe(ch)
+
+	go func() {
+		defer eventsSub.Unsubscribe()
+
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(rpcSub.Err())},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(notifier.Closed())},
+		}
+		for {
+			chosen, ev, _ := reflect.Select(cases)
+			if chosen != 0 {
+				return
+			}
+			notifier.Notify(rpcSub.ID, ev.Interface())
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2018 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/event"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/params"
+)
+
+// maxEventHeaders is the maximum number of headers the events are published for
+// on a single chain head change, bounding the work after a long sync.
+const maxEventHeaders = 1024
+
+// eventFeeds bundles the feeds the engine publishes the changes of the canonical
+// chain to.
+type eventFeeds struct {
+	newLoop     event.Feed
+	signerQueue event.Feed
+	adminAction event.Feed
+	punished    event.Feed
+
+	head *types.Header // Chain head the events were last published for
+	lock sync.Mutex    // Serializes the chain head changes
+}
+
+// headerEvents collects the events raised by a single header, given the
+// snapshots before and after applying it.
+type headerEvents struct {
+	newLoop     []alientypes.NewLoopEvent
+	signerQueue []alientypes.SignerQueueChangedEvent
+	adminAction []alientypes.AdminActionEvent
+	punished    []alientypes.PunishedEvent
+}
+
+// newHeaderEvents diffs the snapshots of a header and of its parent into the
+// events the header raises.
+func newHeaderEvents(config *params.AlienConfig, parent, snap *Snapshot, header *types.Header) (*headerEvents, error) {
+	headerExtra := HeaderExtra{}
+	if err := decodeHeaderExtra(config, header.Number, header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+		return nil, err
+	}
+	var (
+		events      = new(headerEvents)
+		number      = header.Number.Uint64()
+		hash        = header.Hash()
+		prevSigners = parent.signerQueue()
+		signers     = snap.signerQueue()
+	)
+	if number == 1 || number%config.MaxSignerCount == 0 {
+		events.newLoop = append(events.newLoop, alientypes.NewLoopEvent{
+			Number:        number,
+			Hash:          hash,
+			LoopStartTime: snap.LoopStartTime,
+			Signers:       signers,
+		})
+	}
+	if !addressesEqual(prevSigners, signers) {
+		events.signerQueue = append(events.signerQueue, alientypes.SignerQueueChangedEvent{
+			Number:   number,
+			Hash:     hash,
+			Previous: prevSigners,
+			Signers:  signers,
+		})
+	}
+	if parent.SignerAdmin != snap.SignerAdmin || !addressesEqual(parent.CandidateSigners, snap.CandidateSigners) ||
+		!bigEqual(parent.PerBlockReward, snap.PerBlockReward) || parent.MinerRewardRatio != snap.MinerRewardRatio {
+		events.adminAction = append(events.adminAction, alientypes.AdminActionEvent{
+			Number:           number,
+			Hash:             hash,
+			SignerAdmin:      snap.SignerAdmin,
+			CandidateSigners: snap.CandidateSigners,
+			PerBlockReward:   snap.PerBlockReward,
+			MinerRewardRatio: snap.MinerRewardRatio,
+		})
+	}
+	for _, signer := range headerExtra.SignerMissing {
+		events.punished = append(events.punished, alientypes.PunishedEvent{
+			Number:   number,
+			Hash:     hash,
+			Signer:   signer,
+			Punished: snap.Punished[signer],
+		})
+	}
+	return events, nil
+}
+
+// publish sends the collected events to the subscribers of the feeds.
+func (f *eventFeeds) publish(events *headerEvents) {
+	for _, ev := range events.newLoop {
+		f.newLoop.Send(ev)
+	}
+	for _, ev := range events.signerQueue {
+		f.signerQueue.Send(ev)
+	}
+	for _, ev := range events.adminAction {
+		f.adminAction.Send(ev)
+	}
+	for _, ev := range events.punished {
+		f.punished.Send(ev)
+	}
+}
+
+// NewChainHead publishes the events of the headers that became canonical when
+// the chain head moved to head, including the ones reorged in. Headers that are
+// only verified, or imported as side forks, raise no events.
+func (a *Alien) NewChainHead(chain consensus.ChainReader, head *types.Header) {
+	a.feeds.lock.Lock()
+	defer a.feeds.lock.Unlock()
+
+	headers := canonicalHeaders(chain, a.feeds.head, head, maxEventHeaders)
+	a.feeds.head = head
+
+	for i := len(headers) - 1; i >= 0; i-- {
+		header := headers[i]
+		number := header.Number.Uint64()
+		if number == 0 {
+			continue
+		}
+		parent, err := a.snapshot(chain, number-1, header.ParentHash, nil, nil, defaultLoopCntRecalculateSigners)
+		if err != nil {
+			log.Debug("Failed to retrieve parent snapshot for events", "number", number, "hash", header.Hash(), "err", err)
+			return
+		}
+		snap, err := a.snapshot(chain, number, header.Hash(), nil, nil, defaultLoopCntRecalculateSigners)
+		if err != nil {
+			log.Debug("Failed to retrieve snapshot for events", "number", number, "hash", header.Hash(), "err", err)
+			return
+		}
+		events, err := newHeaderEvents(a.config, parent, snap, header)
+		if err != nil {
+			log.Debug("Failed to collect header events", "number", number, "hash", header.Hash(), "err", err)
+			return
+		}
+		a.feeds.publish(events)
+	}
+}
+
+// canonicalHeaders returns the headers that became canonical when the chain head
+// moved from old to head, newest first and at most limit of them. Without a
+// previous head, only the new head is returned.
+func canonicalHeaders(chain consensus.ChainReader, old, head *types.Header, limit int) []*types.Header {
+	if old == nil {
+		return []*types.Header{head}
+	}
+	parent := func(header *types.Header) *types.Header {
+		if header.Number.Sign() == 0 {
+			return nil
+		}
+		return chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	var headers []*types.Header
+	for head != nil && len(headers) < limit {
+		// Rewind the old chain to the height of the new one, stopping at the
+		// common ancestor of the two
+		for old != nil && old.Number.Cmp(head.Number) > 0 {
+			old = parent(old)
+		}
+		if old != nil && old.Hash() == head.Hash() {
+			break
+		}
+		headers = append(headers, head)
+		head = parent(head)
+	}
+	return headers
+}
+
+// SubscribeNewLoopEvent registers a subscription of NewLoopEvent.
+func (a *Alien) SubscribeNewLoopEvent(ch chan<- alientypes.NewLoopEvent) event.Subscription {
+	return a.feeds.newLoop.Subscribe(ch)
+}
+
+// SubscribeSignerQueueChangedEvent registers a subscription of SignerQueueChangedEvent.
+func (a *Alien) SubscribeSignerQueueChangedEvent(ch chan<- alientypes.SignerQueueChangedEvent) event.Subscription {
+	return a.feeds.signerQueue.Subscribe(ch)
+}
+
+// SubscribeAdminActionEvent registers a subscription of AdminActionEvent.
+func (a *Alien) SubscribeAdminActionEvent(ch chan<- alientypes.AdminActionEvent) event.Subscription {
+	return a.feeds.adminAction.Subscribe(ch)
+}
+
+// SubscribePunishedEvent registers a subscription of PunishedEvent.
+func (a *Alien) SubscribePunishedEvent(ch chan<- alientypes.PunishedEvent) event.Subscription {
+	return a.feeds.punished.Subscribe(ch)
+}
+
+// addressesEqual reports whether two address lists hold the same entries in
+// the same order.
+func addressesEqual(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// bigEqual reports whether two possibly nil big integers hold the same value.
+func bigEqual(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
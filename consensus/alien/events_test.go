@@ -0,0 +1,169 @@
+// Copyright 2018 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/params"
+)
+
+// eventChainReader implements consensus.ChainReader on top of a set of headers,
+// serving the genesis block from the database.
+type eventChainReader struct {
+	testerChainReader
+	headers map[common.Hash]*types.Header
+}
+
+func (r *eventChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := r.headers[hash]; ok && header.Number.Uint64() == number {
+		return header
+	}
+	if genesis := r.GetHeaderByNumber(0); number == 0 && genesis.Hash() == hash {
+		return genesis
+	}
+	return nil
+}
+
+// Tests that chain head changes publish the loop, signer queue, admin and
+// punishment events of the headers becoming canonical, reorged in ones included,
+// while snapshots of side forks publish nothing.
+func TestChainHeadEvents(t *testing.T) {
+	accounts := newTesterAccountPool()
+	a, b, c := accounts.address("A"), accounts.address("B"), accounts.address("C")
+
+	db := ethdb.NewMemDatabase()
+	genesis := &core.Genesis{ExtraData: make([]byte, extraVanity+extraSeal)}
+	genesis.Commit(db)
+
+	engine := New(&params.AlienConfig{
+		Period:          3,
+		Epoch:           30000,
+		MaxSignerCount:  2,
+		MinVoterBalance: big.NewInt(50),
+		PerBlockReward:  big.NewInt(1000),
+		AdminAddress:    a,
+		SelfVoteSigners: []common.UnprefixedAddress{common.UnprefixedAddress(a), common.UnprefixedAddress(b)},
+	}, db)
+
+	newLoops := make(chan alientypes.NewLoopEvent, 10)
+	queues := make(chan alientypes.SignerQueueChangedEvent, 10)
+	admins := make(chan alientypes.AdminActionEvent, 10)
+	punishes := make(chan alientypes.PunishedEvent, 10)
+	defer engine.SubscribeNewLoopEvent(newLoops).Unsubscribe()
+	defer engine.SubscribeSignerQueueChangedEvent(queues).Unsubscribe()
+	defer engine.SubscribeAdminActionEvent(admins).Unsubscribe()
+	defer engine.SubscribePunishedEvent(punishes).Unsubscribe()
+
+	chain := &eventChainReader{testerChainReader{db: db}, make(map[common.Hash]*types.Header)}
+	makeHeader := func(parent *types.Header, extra HeaderExtra) *types.Header {
+		number := new(big.Int).Add(parent.Number, common.Big1)
+
+		extra.SignerAdmin = a
+		extra.PerBlockReward = big.NewInt(1000)
+		blob, err := encodeHeaderExtra(engine.config, number, extra)
+		if err != nil {
+			t.Fatalf("failed to encode header extra %d: %v", number, err)
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     number,
+			Time:       new(big.Int).Mul(number, big.NewInt(3)),
+			Coinbase:   a,
+			Extra:      append(append(make([]byte, extraVanity), blob...), make([]byte, extraSeal)...),
+		}
+		accounts.sign(header, "A")
+		chain.headers[header.Hash()] = header
+		return header
+	}
+	// Block 1 starts the chain with the genesis signer queue, block 2 starts a
+	// new loop with a shuffled queue as B missed its slot, block 3 carries an
+	// admin transaction adding a candidate signer
+	header1 := makeHeader(chain.GetHeaderByNumber(0), HeaderExtra{SignerQueue: []common.Address{a, b}, CandidateSigners: []common.Address{a, b}})
+	header2 := makeHeader(header1, HeaderExtra{SignerQueue: []common.Address{b, a}, CandidateSigners: []common.Address{a, b}, SignerMissing: []common.Address{b}})
+	header3 := makeHeader(header2, HeaderExtra{SignerQueue: []common.Address{b, a}, CandidateSigners: []common.Address{a, b, c}})
+
+	// The first head only publishes its own events, later ones the events of all
+	// the headers since the previous head
+	engine.NewChainHead(chain, header1)
+	engine.NewChainHead(chain, header3)
+
+	if len(newLoops) != 2 {
+		t.Fatalf("new loop events mismatch: have %d, want 2", len(newLoops))
+	}
+	if ev := <-newLoops; ev.Number != 1 {
+		t.Errorf("first loop number mismatch: have %d, want 1", ev.Number)
+	}
+	if ev := <-newLoops; ev.Number != 2 || ev.Hash != header2.Hash() || !addressesEqual(ev.Signers, []common.Address{b, a}) {
+		t.Errorf("second loop mismatch: %+v", ev)
+	}
+	if len(queues) != 1 {
+		t.Fatalf("signer queue events mismatch: have %d, want 1", len(queues))
+	}
+	if ev := <-queues; ev.Number != 2 || !addressesEqual(ev.Previous, []common.Address{a, b}) || !addressesEqual(ev.Signers, []common.Address{b, a}) {
+		t.Errorf("signer queue change mismatch: %+v", ev)
+	}
+	if len(admins) != 1 {
+		t.Fatalf("admin events mismatch: have %d, want 1", len(admins))
+	}
+	if ev := <-admins; ev.Number != 3 || !addressesEqual(ev.CandidateSigners, []common.Address{a, b, c}) {
+		t.Errorf("admin action mismatch: %+v", ev)
+	}
+	if len(punishes) != 1 {
+		t.Fatalf("punished events mismatch: have %d, want 1", len(punishes))
+	}
+	if ev := <-punishes; ev.Number != 2 || ev.Signer != b || ev.Punished != missingPublishCredit-autoRewardCredit {
+		t.Errorf("punishment mismatch: %+v", ev)
+	}
+
+	// A side fork replacing block 3, where A missed its slot, publishes nothing
+	// while only being verified
+	fork3 := makeHeader(header2, HeaderExtra{SignerQueue: []common.Address{b, a}, CandidateSigners: []common.Address{a, b}, SignerMissing: []common.Address{a}})
+	fork4 := makeHeader(fork3, HeaderExtra{SignerQueue: []common.Address{b, a}, CandidateSigners: []common.Address{a, b}})
+	if _, err := engine.snapshot(chain, fork4.Number.Uint64(), fork4.Hash(), nil, nil, defaultLoopCntRecalculateSigners); err != nil {
+		t.Fatalf("failed to create fork snapshot: %v", err)
+	}
+	if n := len(newLoops) + len(queues) + len(admins) + len(punishes); n != 0 {
+		t.Fatalf("side fork published %d events", n)
+	}
+	// Reorging onto the fork publishes the events of the reorged in headers only
+	engine.NewChainHead(chain, fork4)
+
+	if len(newLoops) != 1 {
+		t.Fatalf("reorg new loop events mismatch: have %d, want 1", len(newLoops))
+	}
+	if ev := <-newLoops; ev.Number != 4 || ev.Hash != fork4.Hash() {
+		t.Errorf("reorg loop mismatch: %+v", ev)
+	}
+	if len(queues) != 0 || len(admins) != 0 {
+		t.Fatalf("reorg published unexpected events: %d queue, %d admin", len(queues), len(admins))
+	}
+	if len(punishes) != 1 {
+		t.Fatalf("reorg punished events mismatch: have %d, want 1", len(punishes))
+	}
+	if ev := <-punishes; ev.Number != 3 || ev.Hash != fork3.Hash() || ev.Signer != a {
+		t.Errorf("reorg punishment mismatch: %+v", ev)
+	}
+}
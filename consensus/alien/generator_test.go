@@ -0,0 +1,340 @@
+// Copyright 2018 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/internal/blocksim"
+	"github.com/eeefan/dpeth/params"
+)
+
+// testerChainScenario scripts the chain generated by newTesterChain. Blocks are
+// numbered from 1 and signers are referred to by the index of their key.
+type testerChainScenario struct {
+	signers int                        // Number of signer keys, all self voting in the genesis block
+	blocks  int                        // Number of blocks to generate
+	offline map[uint64][]int           // Signers skipping their slots while a block is due
+	skew    map[uint64]uint64          // Seconds the sealing clock runs ahead when a block is sealed
+	txs     map[uint64][]testerChainTx // Transactions landing in a block
+	config  func(*params.AlienConfig)  // Optional changes to the default alien config
+}
+
+// testerChainTx is a transaction of a scenario, sent between two signers.
+type testerChainTx struct {
+	from  int      // Index of the sending signer
+	to    int      // Index of the receiving signer, the target of admin commands
+	value *big.Int // Wei transferred, nil for none
+	data  string   // Payload, the custom transaction command if any
+}
+
+// testerChain is a fully sealed alien chain generated from a scenario.
+type testerChain struct {
+	keys    []*ecdsa.PrivateKey
+	signers []common.Address
+	genesis *core.Genesis
+	blocks  []*types.Block
+	now     uint64 // Time of the sealing clock after the last block
+}
+
+// newTesterChain generates a chain following the scenario. Every block is
+// assembled through the real Prepare, Finalize and Seal path by the signer in
+// turn, skipping the slots of offline signers, on a simulated clock.
+func newTesterChain(t *testing.T, scenario *testerChainScenario) *testerChain {
+	c := &testerChain{
+		keys:    make([]*ecdsa.PrivateKey, scenario.signers),
+		signers: make([]common.Address, scenario.signers),
+	}
+	config := *params.AllAlienProtocolChanges
+	alienConfig := *config.Alien
+	config.Alien = &alienConfig
+
+	alienConfig.MaxSignerCount = uint64(scenario.signers)
+	alienConfig.GenesisTimestamp = 1000
+	alienConfig.MaxRewardOutBlock = new(big.Int).SetUint64(1e10)
+	alienConfig.PerBlockReward = big.NewInt(params.Ether)
+	alienConfig.MinerRewardRatio = 50
+	alienConfig.LuckyDrawAddress = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	alienConfig.SelfVoteSigners = nil
+
+	alloc := make(core.GenesisAlloc)
+	for i := range c.keys {
+		c.keys[i], _ = crypto.GenerateKey()
+		c.signers[i] = crypto.PubkeyToAddress(c.keys[i].PublicKey)
+		alloc[c.signers[i]] = core.GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(100000), big.NewInt(params.Ether))}
+		alienConfig.SelfVoteSigners = append(alienConfig.SelfVoteSigners, common.UnprefixedAddress(c.signers[i]))
+	}
+	alienConfig.AdminAddress = c.signers[0]
+	if scenario.config != nil {
+		scenario.config(&alienConfig)
+	}
+	c.genesis = &core.Genesis{
+		Config:    &config,
+		Timestamp: alienConfig.GenesisTimestamp,
+		ExtraData: make([]byte, extraVanity+extraSeal),
+		Alloc:     alloc,
+	}
+	c.now = alienConfig.GenesisTimestamp
+
+	// Create the sealing node and generate the blocks one by one on top of it
+	db := ethdb.NewMemDatabase()
+	c.genesis.MustCommit(db)
+
+	engine := NewSimulated(&alienConfig, db, func() time.Time { return time.Unix(int64(c.now), 0) })
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create sealing chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		keys   = make(map[common.Address]*ecdsa.PrivateKey)
+		signer = types.NewEIP155Signer(config.ChainId)
+		nonces = make(map[int]uint64)
+	)
+	for i, key := range c.keys {
+		keys[c.signers[i]] = key
+	}
+	for number := uint64(1); number <= uint64(scenario.blocks); number++ {
+		parent := chain.CurrentBlock()
+
+		// Find the first slot with an online signer in turn
+		slot, online := parent.Time().Uint64()+alienConfig.Period, false
+		for tries := 0; !online; tries++ {
+			if tries > int(alienConfig.MaxSignerCount) {
+				t.Fatalf("block %d: all signers offline", number)
+			}
+			sealer, err := engine.SignerAt(parent.Header(), slot+scenario.skew[number])
+			if err != nil {
+				t.Fatalf("block %d: failed to resolve signer in turn: %v", number, err)
+			}
+			online = true
+			for _, offline := range scenario.offline[number] {
+				if sealer == c.signers[offline] {
+					slot, online = slot+alienConfig.Period, false
+					break
+				}
+			}
+		}
+		c.now = slot + scenario.skew[number]
+
+		// Seal the scripted transactions by the signer in turn and import the
+		// block locally
+		var txs types.Transactions
+		for _, scripted := range scenario.txs[number] {
+			value := scripted.value
+			if value == nil {
+				value = new(big.Int)
+			}
+			tx, _ := types.SignTx(types.NewTransaction(nonces[scripted.from], c.signers[scripted.to], value, 100000, big.NewInt(1), []byte(scripted.data)), signer, c.keys[scripted.from])
+			nonces[scripted.from]++
+			txs = append(txs, tx)
+		}
+		block, err := sealBlock(engine, chain, parent, c.now, txs, keys)
+		if err != nil {
+			t.Fatalf("block %d: failed to seal block: %v", number, err)
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to import sealed block: %v", number, err)
+		}
+		c.blocks = append(c.blocks, block)
+	}
+	return c
+}
+
+// sealBlock seals a block of txs on top of parent at the given time with the key
+// of the signer in turn.
+func sealBlock(engine *Alien, chain *core.BlockChain, parent *types.Block, time uint64, txs types.Transactions, keys map[common.Address]*ecdsa.PrivateKey) (*types.Block, error) {
+	signer, err := engine.SignerAt(parent.Header(), time)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keys[signer]
+	if !ok {
+		return nil, fmt.Errorf("missing key of signer %x", signer)
+	}
+	engine.Authorize(signer, blocksim.SignFn(key), blocksim.SignTxFn(key))
+	return blocksim.Seal(engine, chain, parent, signer, time, txs)
+}
+
+// insert imports the generated chain into a fresh node, whose clock reads the
+// given time.
+func (c *testerChain) insert(now uint64) (*core.BlockChain, *Alien, error) {
+	db := ethdb.NewMemDatabase()
+	c.genesis.MustCommit(db)
+
+	engine := NewSimulated(c.genesis.Config.Alien, db, func() time.Time { return time.Unix(int64(now), 0) })
+	chain, err := core.NewBlockChain(db, nil, c.genesis.Config, engine, vm.Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = chain.InsertChain(c.blocks)
+	return chain, engine, err
+}
+
+// Tests that generated chains apply the admin transactions and rewards of the
+// scenario and are accepted by a fresh node.
+func TestGeneratedChainImport(t *testing.T) {
+	// The signer admin is only set up by the first block, so remove the third
+	// signer in the second one. The loop starting at block 3 drops it.
+	c := newTesterChain(t, &testerChainScenario{
+		signers: 3,
+		blocks:  7,
+		txs: map[uint64][]testerChainTx{
+			2: {{from: 0, to: 2, data: "dpos:1:admin:dels"}},
+		},
+	})
+
+	chain, engine, err := c.insert(c.now)
+	if err != nil {
+		t.Fatalf("failed to import generated chain: %v", err)
+	}
+	defer chain.Stop()
+
+	head := chain.CurrentBlock()
+	if head.NumberU64() != 7 {
+		t.Fatalf("head mismatch: have %d, want 7", head.NumberU64())
+	}
+	snap, err := engine.snapshot(chain, head.NumberU64(), head.Hash(), nil, nil, defaultLoopCntRecalculateSigners)
+	if err != nil {
+		t.Fatalf("failed to retrieve head snapshot: %v", err)
+	}
+	removed := c.signers[2]
+	for _, signer := range snap.CandidateSigners {
+		if signer == removed {
+			t.Errorf("removed signer %x still a candidate", removed)
+		}
+	}
+	for _, block := range c.blocks[3:] {
+		if block.Coinbase() == removed {
+			t.Errorf("block %d sealed by removed signer", block.NumberU64())
+		}
+	}
+	// Every block paid half of its reward to the lucky draw pool
+	statedb, _ := chain.State()
+	want := new(big.Int).Mul(big.NewInt(7), new(big.Int).Div(c.genesis.Config.Alien.PerBlockReward, big.NewInt(2)))
+	if have := statedb.GetBalance(c.genesis.Config.Alien.LuckyDrawAddress); have.Cmp(want) != 0 {
+		t.Errorf("lucky draw balance mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that signers skipping their slots are recorded as missing and punished,
+// and that the records are verified on import.
+func TestGeneratedChainPunishment(t *testing.T) {
+	// Block 6 is due in the slot of the third signer in the second loop
+	c := newTesterChain(t, &testerChainScenario{
+		signers: 4,
+		blocks:  7,
+		offline: map[uint64][]int{6: {2}},
+	})
+	chain, engine, err := c.insert(c.now)
+	if err != nil {
+		t.Fatalf("failed to import generated chain: %v", err)
+	}
+	defer chain.Stop()
+
+	block := c.blocks[5]
+	if block.Coinbase() == c.signers[2] {
+		t.Fatalf("block sealed by offline signer")
+	}
+	var extra HeaderExtra
+	if err := decodeHeaderExtra(engine.config, block.Number(), block.Extra()[extraVanity:len(block.Extra())-extraSeal], &extra); err != nil {
+		t.Fatalf("failed to decode header extra: %v", err)
+	}
+	if len(extra.SignerMissing) != 1 || extra.SignerMissing[0] != c.signers[2] {
+		t.Errorf("missing signers mismatch: have %x, want [%x]", extra.SignerMissing, c.signers[2])
+	}
+	snap, err := engine.snapshot(chain, block.NumberU64(), block.Hash(), nil, nil, defaultLoopCntRecalculateSigners)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if have, want := snap.Punished[c.signers[2]], uint64(missingPublishCredit-autoRewardCredit); have != want {
+		t.Errorf("punishment mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that blocks sealed by a clock running ahead land in a later slot and
+// are only accepted once the importing node's clock caught up.
+func TestGeneratedChainClockSkew(t *testing.T) {
+	c := newTesterChain(t, &testerChainScenario{
+		signers: 3,
+		blocks:  3,
+		skew:    map[uint64]uint64{3: 5},
+	})
+	parent, block := c.blocks[1], c.blocks[2]
+	if have, want := block.Time().Uint64(), parent.Time().Uint64()+c.genesis.Config.Alien.Period+5; have != want {
+		t.Fatalf("skewed block time mismatch: have %d, want %d", have, want)
+	}
+	// A node lagging behind the sealer treats the block as a future one
+	chain, _, _ := c.insert(c.now - 1)
+	if head := chain.CurrentBlock().NumberU64(); head != 2 {
+		t.Errorf("lagging node head mismatch: have %d, want 2", head)
+	}
+	chain.Stop()
+
+	chain, _, err := c.insert(c.now)
+	if err != nil {
+		t.Fatalf("failed to import generated chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock().NumberU64(); head != 3 {
+		t.Errorf("head mismatch: have %d, want 3", head)
+	}
+}
+
+// Tests that the signer in turn derived from the parent header matches the
+// signers which sealed a generated chain, including across loops and skipped
+// slots.
+func TestSignerAt(t *testing.T) {
+	c := newTesterChain(t, &testerChainScenario{
+		signers: 3,
+		blocks:  8,
+		offline: map[uint64][]int{5: {1, 2}},
+	})
+	chain, engine, err := c.insert(c.now)
+	if err != nil {
+		t.Fatalf("failed to import generated chain: %v", err)
+	}
+	defer chain.Stop()
+
+	for _, block := range c.blocks {
+		parent := chain.GetHeaderByHash(block.ParentHash())
+		signer, err := engine.SignerAt(parent, block.Time().Uint64())
+		if err != nil {
+			t.Fatalf("block %d: failed to resolve signer in turn: %v", block.NumberU64(), err)
+		}
+		if signer != block.Coinbase() {
+			t.Errorf("block %d: signer in turn mismatch: have %x, want %x", block.NumberU64(), signer, block.Coinbase())
+		}
+	}
+	if _, err := engine.SignerAt(chain.CurrentHeader(), 0); err != errSlotBeforeLoop {
+		t.Errorf("slot before loop error mismatch: have %v, want %v", err, errSlotBeforeLoop)
+	}
+}
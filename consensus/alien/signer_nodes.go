@@ -0,0 +1,156 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p/discover"
+)
+
+// signerNodesKey is the database key the signer node registry is stored under.
+var signerNodesKey = []byte("alien-signer-nodes")
+
+// signerNodes is the admin maintained registry of the p2p nodes run by the
+// signers, used to keep the nodes of the signer queue connected to each other.
+type signerNodes struct {
+	db    ethdb.Database
+	nodes map[common.Address]*discover.Node
+	lock  sync.RWMutex
+}
+
+// newSignerNodes creates a signer node registry, loading the entries persisted
+// in the database if there are any.
+func newSignerNodes(db ethdb.Database) *signerNodes {
+	r := &signerNodes{
+		db:    db,
+		nodes: make(map[common.Address]*discover.Node),
+	}
+	if db == nil {
+		return r
+	}
+	blob, err := db.Get(signerNodesKey)
+	if err != nil {
+		return r
+	}
+	urls := make(map[common.Address]string)
+	if err := json.Unmarshal(blob, &urls); err != nil {
+		log.Warn("Failed to decode signer nodes", "err", err)
+		return r
+	}
+	for signer, url := range urls {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			log.Warn("Dropping invalid signer node", "signer", signer, "enode", url, "err", err)
+			continue
+		}
+		r.nodes[signer] = node
+	}
+	return r
+}
+
+// store persists the registry into the database. The caller must hold the lock.
+func (r *signerNodes) store() error {
+	if r.db == nil {
+		return nil
+	}
+	urls := make(map[common.Address]string, len(r.nodes))
+	for signer, node := range r.nodes {
+		urls[signer] = node.String()
+	}
+	blob, err := json.Marshal(urls)
+	if err != nil {
+		return err
+	}
+	return r.db.Put(signerNodesKey, blob)
+}
+
+// set registers the node run by the signer, replacing any earlier one.
+func (r *signerNodes) set(signer common.Address, node *discover.Node) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nodes[signer] = node
+	return r.store()
+}
+
+// remove drops the node of the signer from the registry.
+func (r *signerNodes) remove(signer common.Address) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.nodes, signer)
+	return r.store()
+}
+
+// all returns a copy of the registry.
+func (r *signerNodes) all() map[common.Address]*discover.Node {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	nodes := make(map[common.Address]*discover.Node, len(r.nodes))
+	for signer, node := range r.nodes {
+		nodes[signer] = node
+	}
+	return nodes
+}
+
+// SetSignerNode registers the p2p node run by the given signer.
+func (a *Alien) SetSignerNode(signer common.Address, node *discover.Node) error {
+	return a.nodes.set(signer, node)
+}
+
+// RemoveSignerNode drops the p2p node registered for the given signer.
+func (a *Alien) RemoveSignerNode(signer common.Address) error {
+	return a.nodes.remove(signer)
+}
+
+// SignerNodes returns the p2p nodes registered for the signers.
+func (a *Alien) SignerNodes() map[common.Address]*discover.Node {
+	return a.nodes.all()
+}
+
+// SignerQueueNodes returns the registered p2p nodes of the signers in the queue
+// carried by the given header, apart from the one of the local signer. Signers
+// without a registered node are skipped.
+func (a *Alien) SignerQueueNodes(header *types.Header) ([]*discover.Node, error) {
+	_, queue, err := a.signerQueue(header)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		self  = a.Signer()
+		seen  = make(map[common.Address]bool)
+		nodes []*discover.Node
+	)
+	registry := a.nodes.all()
+	for _, signer := range queue {
+		if signer == self || seen[signer] {
+			continue
+		}
+		seen[signer] = true
+		if node, ok := registry[signer]; ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
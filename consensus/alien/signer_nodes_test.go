@@ -0,0 +1,86 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/params"
+)
+
+// Tests that the registered signer nodes survive a restart and that only the
+// nodes of the remote signers in the queue are returned.
+func TestSignerQueueNodes(t *testing.T) {
+	accounts := newTesterAccountPool()
+	a, b, c := accounts.address("A"), accounts.address("B"), accounts.address("C")
+
+	config := &params.AlienConfig{
+		Period:          3,
+		MaxSignerCount:  3,
+		MinVoterBalance: big.NewInt(50),
+		SelfVoteSigners: []common.UnprefixedAddress{common.UnprefixedAddress(a), common.UnprefixedAddress(b)},
+	}
+	db := ethdb.NewMemDatabase()
+	engine := New(config, db)
+
+	nodes := make(map[common.Address]*discover.Node)
+	for i, signer := range []common.Address{a, b, c} {
+		nodes[signer] = discover.NewNode(discover.NodeID{byte(i + 1)}, net.IP{127, 0, 0, 1}, 30303, uint16(30303+i))
+		if err := engine.SetSignerNode(signer, nodes[signer]); err != nil {
+			t.Fatalf("failed to register node of %x: %v", signer, err)
+		}
+	}
+	if err := engine.RemoveSignerNode(c); err != nil {
+		t.Fatalf("failed to remove node of %x: %v", c, err)
+	}
+	// Reload the registry and sign with A, leaving only B in the genesis queue
+	engine = New(config, db)
+	engine.Authorize(a, nil, nil)
+
+	if registry := engine.SignerNodes(); len(registry) != 2 || registry[a].String() != nodes[a].String() {
+		t.Fatalf("reloaded registry mismatch: have %v", registry)
+	}
+	queue, err := engine.SignerQueueNodes(&types.Header{Number: big.NewInt(0)})
+	if err != nil {
+		t.Fatalf("failed to retrieve queue nodes: %v", err)
+	}
+	if len(queue) != 1 || queue[0].String() != nodes[b].String() {
+		t.Errorf("queue nodes mismatch: have %v, want [%v]", queue, nodes[b])
+	}
+	// The signers in turn after the genesis are taken from the same queue
+	upcoming, err := engine.UpcomingSigners(&types.Header{Number: big.NewInt(0), Time: big.NewInt(0)}, 3)
+	if err != nil {
+		t.Fatalf("failed to retrieve upcoming signers: %v", err)
+	}
+	if len(upcoming) != 2 || upcoming[0] != b || upcoming[1] != a {
+		t.Errorf("upcoming signers mismatch: have %x, want [%x %x]", upcoming, b, a)
+	}
+	// The slots past the end of the loop belong to a queue not known yet
+	upcoming, err = engine.UpcomingSigners(&types.Header{Number: big.NewInt(0), Time: big.NewInt(3)}, 3)
+	if err != nil {
+		t.Fatalf("failed to retrieve upcoming signers at the loop end: %v", err)
+	}
+	if len(upcoming) != 1 || upcoming[0] != a {
+		t.Errorf("upcoming signers at the loop end mismatch: have %x, want [%x]", upcoming, a)
+	}
+}
@@ -517,6 +517,15 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	return snap, nil
 }
 
+// signerQueue returns a copy of the signer queue of the snapshot.
+func (s *Snapshot) signerQueue() []common.Address {
+	queue := make([]common.Address, len(s.Signers))
+	for i, signer := range s.Signers {
+		queue[i] = *signer
+	}
+	return queue
+}
+
 func (s *Snapshot) removeExtraCandidate() {
 	// remove minimum tickets tally beyond candidateMaxLen
 	tallySlice := s.buildTallySlice()
@@ -0,0 +1,112 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"hash/crc32"
+	"math/big"
+	"testing"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/params"
+)
+
+// testConfig is an alien chain config with the Ethereum forks activated at
+// genesis and the alien forks scheduled later on.
+var testConfig = &params.ChainConfig{
+	ChainId:        big.NewInt(1),
+	HomesteadBlock: big.NewInt(0),
+	EIP150Block:    big.NewInt(0),
+	EIP155Block:    big.NewInt(0),
+	EIP158Block:    big.NewInt(0),
+	ByzantiumBlock: big.NewInt(0),
+	Alien: &params.AlienConfig{
+		TrantorBlock:  big.NewInt(1000),
+		TerminusBlock: big.NewInt(2000),
+	},
+}
+
+var testGenesis = common.HexToHash("0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff")
+
+// Tests that fork IDs are calculated correctly at various points of the chain.
+func TestCreation(t *testing.T) {
+	genesis := checksumToBytes(crc32.ChecksumIEEE(testGenesis[:]))
+	trantor := checksumToBytes(checksumUpdate(crc32.ChecksumIEEE(testGenesis[:]), 1000))
+	terminus := checksumToBytes(checksumUpdate(checksumUpdate(crc32.ChecksumIEEE(testGenesis[:]), 1000), 2000))
+
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: genesis, Next: 1000}},
+		{999, ID{Hash: genesis, Next: 1000}},
+		{1000, ID{Hash: trantor, Next: 2000}},
+		{1999, ID{Hash: trantor, Next: 2000}},
+		{2000, ID{Hash: terminus, Next: 0}},
+		{5000, ID{Hash: terminus, Next: 0}},
+	}
+	for i, tt := range tests {
+		if have := NewID(testConfig, testGenesis, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+	// Chains of another genesis or fork schedule get different identifiers
+	if NewID(testConfig, common.Hash{}, 0) == NewID(testConfig, testGenesis, 0) {
+		t.Errorf("fork ID of different genesis blocks match")
+	}
+	other := *testConfig
+	other.Alien = &params.AlienConfig{TrantorBlock: big.NewInt(1500)}
+	if NewID(&other, testGenesis, 1600) == NewID(testConfig, testGenesis, 1600) {
+		t.Errorf("fork ID of different fork schedules match")
+	}
+}
+
+// Tests that remote fork IDs are validated against the local chain state.
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local and remote are in sync at the same fork
+		{1500, NewID(testConfig, testGenesis, 1500), nil},
+
+		// Remote is still before a fork we have passed, but knows about it
+		{1500, NewID(testConfig, testGenesis, 500), nil},
+
+		// Remote is before a fork we have passed and doesn't know about it
+		{1500, ID{Hash: NewID(testConfig, testGenesis, 500).Hash, Next: 0}, ErrRemoteStale},
+
+		// Remote is past a fork we haven't reached yet, we're syncing
+		{500, NewID(testConfig, testGenesis, 2500), nil},
+
+		// Remote announces a fork we have already passed without applying it
+		{2500, ID{Hash: NewID(testConfig, testGenesis, 1500).Hash, Next: 1800}, ErrRemoteStale},
+
+		// Remote is on the same fork, but announces a fork block we passed
+		{1900, ID{Hash: NewID(testConfig, testGenesis, 1500).Hash, Next: 1800}, ErrLocalIncompatibleOrStale},
+
+		// Remote is on another network altogether
+		{1500, NewID(testConfig, common.Hash{}, 1500), ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		filter := newFilter(testConfig, testGenesis, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -4421,6 +4421,12 @@ var _bundleJs = []byte((((((((((`!function(modules) {
             title: "Network",
             icon: "globe"
         }
+    }, {
+        id: "consensus",
+        menu: {
+            title: "Consensus",
+            icon: "users"
+        }
     }, {
         id: "system",
         menu: {
@@ -26560,7 +26566,13 @@ var _bundleJs = []byte((((((((((`!function(modules) {
     Object.defineProperty(exports, "__esModule", {
         value: !0
     });
-    var _createClass = function() {
+    var _extends = Object.assign || function(target) {
+        for (var i = 1; i < arguments.length; i++) {
+            var source = arguments[i];
+            for (var key in source) Object.prototype.hasOwnProperty.call(source, key) && (target[key] = source[key]);
+        }
+        return target;
+    }, _createClass = function() {
         function defineProperties(target, props) {
             for (var i = 0; i < props.length; i++) {
                 var descriptor = props[i];
@@ -26593,15 +26605,49 @@ var _bundleJs = []byte((((((((((`!function(modules) {
                 return mapper(sample);
             }))).slice(-limit);
         };
+    }, rewind = function(msg, prev) {
+        if (!msg.chain || !msg.chain.blocks || msg.chain.blocks.length < 1) return prev;
+        var number = msg.chain.blocks[0].number;
+        return _extends({}, prev, {
+            chain: _extends({}, prev.chain, {
+                blocks: prev.chain.blocks.filter(function(block) {
+                    return block.number < number;
+                })
+            }),
+            consensus: _extends({}, prev.consensus, {
+                missed: prev.consensus.missed.filter(function(missed) {
+                    return missed.number < number;
+                })
+            })
+        });
     }, defaultContent = {
         general: {
             version: null,
             commit: null
         },
-        home: {},
-        chain: {},
-        txpool: {},
-        network: {},
+        home: {
+            head: null,
+            peers: 0,
+            pending: 0,
+            queued: 0
+        },
+        chain: {
+            blocks: []
+        },
+        txpool: {
+            pending: [],
+            queued: []
+        },
+        network: {
+            peers: []
+        },
+        consensus: {
+            signerQueue: [],
+            loopStartTime: 0,
+            nextSigner: null,
+            nextSlot: 0,
+            missed: []
+        },
         system: {
             activeMemory: [],
             virtualMemory: [],
@@ -26620,10 +26666,29 @@ var _bundleJs = []byte((((((((((`!function(modules) {
             version: replacer,
             commit: replacer
         },
-        home: null,
-        chain: null,
-        txpool: null,
-        network: null,
+        home: {
+            head: replacer,
+            peers: replacer,
+            pending: replacer,
+            queued: replacer
+        },
+        chain: {
+            blocks: appender(32)
+        },
+        txpool: {
+            pending: appender(200),
+            queued: appender(200)
+        },
+        network: {
+            peers: replacer
+        },
+        consensus: {
+            signerQueue: replacer,
+            loopStartTime: replacer,
+            nextSigner: replacer,
+            nextSlot: replacer,
+            missed: appender(32)
+        },
         system: {
             activeMemory: appender(200),
             virtualMemory: appender(200),
@@ -26673,7 +26738,7 @@ var _bundleJs = []byte((((((((((`!function(modules) {
             }, _this.update = function(msg) {
                 _this.setState(function(prevState) {
                     return {
-                        content: deepUpdate(updaters, msg, prevState.content),
+                        content: deepUpdate(updaters, msg, rewind(msg, prevState.content)),
                         shouldUpdate: shouldUpdate(updaters, msg)
                     };
                 });
@@ -31203,9 +31268,43 @@ var _bundleJs = []byte((((((((((`!function(modules) {
                 var _props = this.props, classes = _props.classes, active = _props.active, content = _props.content, shouldUpdate = _props.shouldUpdate, children = null;
                 switch (active) {
                   case _common.MENU.get("home").id:
+                    children = _react2.default.createElement("div", null, _react2.default.createElement("div", null, "Head: ", content.home.head ? "#" + content.home.head.number + " " + content.home.head.hash : "-"), _react2.default.createElement("div", null, "Peers: ", content.home.peers), _react2.default.createElement("div", null, "Transactions: ", content.home.pending, " pending, ", content.home.queued, " queued"));
+                    break;
+
                   case _common.MENU.get("chain").id:
+                    children = _react2.default.createElement("div", null, content.chain.blocks.slice().reverse().map(function(block) {
+                        return _react2.default.createElement("div", {
+                            key: block.hash
+                        }, "#", block.number, " ", block.hash, " by ", block.coinbase, ": ", block.txs, " txs, ", block.gasUsed, "/", block.gasLimit, " gas");
+                    }));
+                    break;
+
                   case _common.MENU.get("txpool").id:
+                    var _content$txpool = content.txpool, pending = _content$txpool.pending, queued = _content$txpool.queued;
+                    children = _react2.default.createElement("div", null, _react2.default.createElement("div", null, "Pending: ", pending.length > 0 ? pending[pending.length - 1].value || 0 : 0), _react2.default.createElement("div", null, "Queued: ", queued.length > 0 ? queued[queued.length - 1].value || 0 : 0));
+                    break;
+
                   case _common.MENU.get("network").id:
+                    children = _react2.default.createElement("div", null, content.network.peers.map(function(peer) {
+                        return _react2.default.createElement("div", {
+                            key: peer.id
+                        }, peer.name, " ", peer.network.remoteAddress);
+                    }));
+                    break;
+
+                  case _common.MENU.get("consensus").id:
+                    var consensus = content.consensus;
+                    children = _react2.default.createElement("div", null, _react2.default.createElement("div", null, "Next slot: ", consensus.nextSigner || "-", " at ", new Date(1e3 * consensus.nextSlot).toLocaleString()), _react2.default.createElement("div", null, "Signer queue:"), consensus.signerQueue.map(function(signer, index) {
+                        return _react2.default.createElement("div", {
+                            key: index
+                        }, index, ": ", signer);
+                    }), _react2.default.createElement("div", null, "Missed:"), consensus.missed.slice().reverse().map(function(missed) {
+                        return _react2.default.createElement("div", {
+                            key: missed.number
+                        }, "#", missed.number, ": ", missed.signers.join(", "));
+                    }));
+                    break;
+
                   case _common.MENU.get("system").id:
                     children = _react2.default.createElement("div", null, "Work in progress.");
                     break;
@@ -38435,7 +38534,7 @@ func bundleJs() (*asset, error) {
 	}
 
 	info := bindataFileInfo{name: "bundle.js", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
-	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe2, 0xc0, 0xfa, 0x13, 0x71, 0x8b, 0x49, 0x5c, 0xa5, 0xae, 0xc8, 0x41, 0x3, 0x7a, 0x1, 0x3d, 0x5, 0x2a, 0xe7, 0xb7, 0x80, 0x2c, 0x6e, 0xbd, 0x9, 0xaf, 0xab, 0xd5, 0x25, 0xce, 0x1, 0x9f}}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x55, 0xe5, 0x5a, 0x43, 0x89, 0xef, 0xe0, 0xed, 0x66, 0x16, 0x41, 0xda, 0x78, 0x24, 0x1c, 0xae, 0xa9, 0x17, 0x45, 0x87, 0x18, 0x5d, 0xa4, 0xa1, 0x15, 0x6b, 0xef, 0xdd, 0x2, 0xab, 0xba, 0xad}}
 	return a, nil
 }
 
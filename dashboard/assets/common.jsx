@@ -42,6 +42,12 @@ const menuSkeletons: Array<{|id: string, menu: ProvidedMenuProp|}> = [
 			title: 'Network',
 			icon:  'globe',
 		},
+	}, {
+		id:   'consensus',
+		menu: {
+			title: 'Consensus',
+			icon:  'users',
+		},
 	}, {
 		id:   'system',
 		menu: {
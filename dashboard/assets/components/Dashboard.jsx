@@ -75,17 +75,56 @@ const appender = <T>(limit: number, mapper = replacer) => (update: Array<T>, pre
 	...update.map(sample => mapper(sample)),
 ].slice(-limit);
 
+// rewind drops the recent blocks and the missed signers of the blocks from the
+// first block of the chain update on, since the update replaces them after a reorg.
+const rewind = (msg: $Shape<Content>, prev: Content): Content => {
+	if (!msg.chain || !msg.chain.blocks || msg.chain.blocks.length < 1) {
+		return prev;
+	}
+	const {number} = msg.chain.blocks[0];
+	return {
+		...prev,
+		chain: {
+			...prev.chain,
+			blocks: prev.chain.blocks.filter(block => block.number < number),
+		},
+		consensus: {
+			...prev.consensus,
+			missed: prev.consensus.missed.filter(missed => missed.number < number),
+		},
+	};
+};
+
 // defaultContent is the initial value of the state content.
 const defaultContent: Content = {
 	general: {
 		version: null,
 		commit:  null,
 	},
-	home:    {},
-	chain:   {},
-	txpool:  {},
-	network: {},
-	system:  {
+	home: {
+		head:    null,
+		peers:   0,
+		pending: 0,
+		queued:  0,
+	},
+	chain: {
+		blocks: [],
+	},
+	txpool: {
+		pending: [],
+		queued:  [],
+	},
+	network: {
+		peers: [],
+	},
+	consensus: {
+		signerQueue:   [],
+		loopStartTime: 0,
+		nextSigner:    null,
+		nextSlot:      0,
+		missed:        [],
+	},
+	system: {
 		activeMemory:   [],
 		virtualMemory:  [],
 		networkIngress: [],
@@ -108,11 +147,30 @@ const updaters = {
 		version: replacer,
 		commit:  replacer,
 	},
-	home:    null,
-	chain:   null,
-	txpool:  null,
-	network: null,
-	system:  {
+	home: {
+		head:    replacer,
+		peers:   replacer,
+		pending: replacer,
+		queued:  replacer,
+	},
+	chain: {
+		blocks: appender(32),
+	},
+	txpool: {
+		pending: appender(200),
+		queued:  appender(200),
+	},
+	network: {
+		peers: replacer,
+	},
+	consensus: {
+		signerQueue:   replacer,
+		loopStartTime: replacer,
+		nextSigner:    replacer,
+		nextSlot:      replacer,
+		missed:        appender(32),
+	},
+	system: {
 		activeMemory:   appender(200),
 		virtualMemory:  appender(200),
 		networkIngress: appender(200),
@@ -199,7 +257,7 @@ class Dashboard extends Component<Props, State> {
 	// update updates the content corresponding to the incoming message.
 	update = (msg: $Shape<Content>) => {
 		this.setState(prevState => ({
-			content:      deepUpdate(updaters, msg, prevState.content),
+			content:      deepUpdate(updaters, msg, rewind(msg, prevState.content)),
 			shouldUpdate: shouldUpdate(updaters, msg),
 		}));
 	};
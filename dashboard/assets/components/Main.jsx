@@ -62,9 +62,59 @@ class Main extends Component<Props> {
 		let children = null;
 		switch (active) {
 		case MENU.get('home').id:
+			children = (
+				<div>
+					<div>Head: {content.home.head ? `#${content.home.head.number} ${content.home.head.hash}` : '-'}</div>
+					<div>Peers: {content.home.peers}</div>
+					<div>Transactions: {content.home.pending} pending, {content.home.queued} queued</div>
+				</div>
+			);
+			break;
 		case MENU.get('chain').id:
-		case MENU.get('txpool').id:
+			children = (
+				<div>
+					{content.chain.blocks.slice().reverse().map(block => (
+						<div key={block.hash}>
+							#{block.number} {block.hash} by {block.coinbase}: {block.txs} txs, {block.gasUsed}/{block.gasLimit} gas
+						</div>
+					))}
+				</div>
+			);
+			break;
+		case MENU.get('txpool').id: {
+			const {pending, queued} = content.txpool;
+			children = (
+				<div>
+					<div>Pending: {pending.length > 0 ? pending[pending.length - 1].value || 0 : 0}</div>
+					<div>Queued: {queued.length > 0 ? queued[queued.length - 1].value || 0 : 0}</div>
+				</div>
+			);
+			break;
+		}
 		case MENU.get('network').id:
+			children = (
+				<div>
+					{content.network.peers.map(peer => (
+						<div key={peer.id}>{peer.name} {peer.network.remoteAddress}</div>
+					))}
+				</div>
+			);
+			break;
+		case MENU.get('consensus').id: {
+			const {consensus} = content;
+			children = (
+				<div>
+					<div>Next slot: {consensus.nextSigner || '-'} at {new Date(consensus.nextSlot * 1000).toLocaleString()}</div>
+					<div>Signer queue:</div>
+					{consensus.signerQueue.map((signer, index) => <div key={index}>{index}: {signer}</div>)}
+					<div>Missed:</div>
+					{consensus.missed.slice().reverse().map(missed => (
+						<div key={missed.number}>#{missed.number}: {missed.signers.join(', ')}</div>
+					))}
+				</div>
+			);
+			break;
+		}
 		case MENU.get('system').id:
 			children = <div>Work in progress.</div>;
 			break;
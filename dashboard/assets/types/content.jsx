@@ -22,6 +22,7 @@ export type Content = {
 	chain: Chain,
 	txpool: TxPool,
 	network: Network,
+	consensus: Consensus,
 	system: System,
 	logs: Logs,
 };
@@ -39,19 +40,55 @@ export type General = {
 };
 
 export type Home = {
-	/* TODO (kurkomisi) */
+	head: ?Block,
+	peers: number,
+	pending: number,
+	queued: number,
 };
 
 export type Chain = {
-	/* TODO (kurkomisi) */
+	blocks: Array<Block>,
+};
+
+export type Block = {
+	number: number,
+	hash: string,
+	time: number,
+	coinbase: string,
+	txs: number,
+	gasUsed: number,
+	gasLimit: number,
 };
 
 export type TxPool = {
-	/* TODO (kurkomisi) */
+	pending: ChartEntries,
+	queued: ChartEntries,
 };
 
 export type Network = {
-	/* TODO (kurkomisi) */
+	peers: Array<Peer>,
+};
+
+export type Peer = {
+	id: string,
+	name: string,
+	network: {
+		localAddress: string,
+		remoteAddress: string,
+	},
+};
+
+export type Consensus = {
+	signerQueue: Array<string>,
+	loopStartTime: number,
+	nextSigner: ?string,
+	nextSlot: number,
+	missed: Array<Missed>,
+};
+
+export type Missed = {
+	number: number,
+	signers: Array<string>,
 };
 
 export type System = {
@@ -32,6 +32,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/metrics"
 	"github.com/eeefan/dpeth/p2p"
@@ -50,19 +54,38 @@ const (
 	systemCPUSampleLimit      = 200 // Maximum number of system cpu data samples
 	diskReadSampleLimit       = 200 // Maximum number of disk read data samples
 	diskWriteSampleLimit      = 200 // Maximum number of disk write data samples
+	pendingTxSampleLimit      = 200 // Maximum number of pending transaction data samples
+	queuedTxSampleLimit       = 200 // Maximum number of queued transaction data samples
+	recentBlockLimit          = 32  // Maximum number of recent blocks shown on the chain panel
+	missedEntryLimit          = 32  // Maximum number of blocks with missed signers shown on the consensus panel
+	chainHeadChanSize         = 10  // Size of the channel listening to ChainHeadEvent
 )
 
+// Backend is the interface of the full node service the chain, transaction
+// pool and consensus panels are fed from.
+type Backend interface {
+	BlockChain() *core.BlockChain
+	TxPool() *core.TxPool
+}
+
 var nextID uint32 // Next connection id
 
 // Dashboard contains the dashboard internals.
 type Dashboard struct {
-	config *Config
-
-	listener net.Listener
-	conns    map[uint32]*client // Currently live websocket connections
-	charts   *SystemMessage
-	commit   string
-	lock     sync.RWMutex // Lock protecting the dashboard's internals
+	config  *Config
+	backend Backend     // Full node service to collect the chain data from, nil on light nodes
+	server  *p2p.Server // Peer-to-peer server to collect the network data from
+
+	listener  net.Listener
+	conns     map[uint32]*client // Currently live websocket connections
+	charts    *SystemMessage
+	home      *HomeMessage
+	blocks    []*BlockEntry // Recent blocks of the canonical chain
+	txpool    *TxPoolMessage
+	peers     []*p2p.PeerInfo
+	consensus *ConsensusMessage // Alien consensus state of the chain head, nil on other chains
+	commit    string
+	lock      sync.RWMutex // Lock protecting the dashboard's internals
 
 	quit chan chan error // Channel used for graceful exit
 	wg   sync.WaitGroup
@@ -75,13 +98,15 @@ type client struct {
 	logger log.Logger      // Logger for the particular live websocket connection
 }
 
-// New creates a new dashboard instance with the given configuration.
-func New(config *Config, commit string) (*Dashboard, error) {
+// New creates a new dashboard instance with the given configuration. The chain,
+// transaction pool and consensus panels are only fed if backend is not nil.
+func New(config *Config, commit string, backend Backend) (*Dashboard, error) {
 	now := time.Now()
 	db := &Dashboard{
-		conns:  make(map[uint32]*client),
-		config: config,
-		quit:   make(chan chan error),
+		conns:   make(map[uint32]*client),
+		config:  config,
+		backend: backend,
+		quit:    make(chan chan error),
 		charts: &SystemMessage{
 			ActiveMemory:   emptyChartEntries(now, activeMemorySampleLimit, config.Refresh),
 			VirtualMemory:  emptyChartEntries(now, virtualMemorySampleLimit, config.Refresh),
@@ -92,6 +117,11 @@ func New(config *Config, commit string) (*Dashboard, error) {
 			DiskRead:       emptyChartEntries(now, diskReadSampleLimit, config.Refresh),
 			DiskWrite:      emptyChartEntries(now, diskWriteSampleLimit, config.Refresh),
 		},
+		home: &HomeMessage{},
+		txpool: &TxPoolMessage{
+			Pending: emptyChartEntries(now, pendingTxSampleLimit, config.Refresh),
+			Queued:  emptyChartEntries(now, queuedTxSampleLimit, config.Refresh),
+		},
 		commit: commit,
 	}
 	return db, nil
@@ -118,9 +148,12 @@ func (db *Dashboard) APIs() []rpc.API { return nil }
 func (db *Dashboard) Start(server *p2p.Server) error {
 	log.Info("Starting dashboard")
 
-	db.wg.Add(2)
+	db.server = server
+
+	db.wg.Add(3)
 	go db.collectData()
-	go db.collectLogs() // In case of removing this line change 2 back to 1 in wg.Add.
+	go db.collectLogs() // In case of removing this line decrease the number in wg.Add.
+	go db.collectChain()
 
 	http.HandleFunc("/", db.webHandler)
 	http.Handle("/api", websocket.Handler(db.apiHandler))
@@ -145,7 +178,7 @@ func (db *Dashboard) Stop() error {
 	}
 	// Close the collectors.
 	errc := make(chan error, 1)
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 3; i++ {
 		db.quit <- errc
 		if err := <-errc; err != nil {
 			errs = append(errs, err)
@@ -239,6 +272,8 @@ func (db *Dashboard) apiHandler(conn *websocket.Conn) {
 			DiskWrite:      db.charts.DiskWrite,
 		},
 	}
+	client.msg <- db.chainState()
+
 	// Start tracking the connection and drop at connection loss.
 	db.lock.Lock()
 	db.conns[id] = client
@@ -400,3 +435,247 @@ func (db *Dashboard) sendToAll(msg *Message) {
 	}
 	db.lock.Unlock()
 }
+
+// chainState returns the current content of the home, chain, transaction pool,
+// network and consensus panels.
+func (db *Dashboard) chainState() Message {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	home := *db.home
+	msg := Message{
+		Home:  &home,
+		Chain: &ChainMessage{Blocks: append([]*BlockEntry{}, db.blocks...)},
+		TxPool: &TxPoolMessage{
+			Pending: db.txpool.Pending,
+			Queued:  db.txpool.Queued,
+		},
+		Network: &NetworkMessage{Peers: append([]*p2p.PeerInfo{}, db.peers...)},
+	}
+	if db.consensus != nil {
+		consensus := *db.consensus
+		msg.Consensus = &consensus
+	}
+	return msg
+}
+
+// collectChain collects the chain, transaction pool, network and consensus data
+// and sends the updates to the active dashboards.
+func (db *Dashboard) collectChain() {
+	defer db.wg.Done()
+
+	var headCh chan core.ChainHeadEvent
+	if db.backend != nil {
+		headCh = make(chan core.ChainHeadEvent, chainHeadChanSize)
+		headSub := db.backend.BlockChain().SubscribeChainHeadEvent(headCh)
+		defer headSub.Unsubscribe()
+
+		db.updateHead(db.backend.BlockChain().CurrentBlock())
+	}
+	refresh := time.NewTicker(db.config.Refresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case errc := <-db.quit:
+			errc <- nil
+			return
+		case ev := <-headCh:
+			db.updateHead(ev.Block)
+		case <-refresh.C:
+			db.updatePoolAndPeers()
+		}
+	}
+}
+
+// updateHead records the new chain head, along with the canonical blocks leading
+// to it since the last recorded one, and sends them to the active dashboards. The
+// recorded blocks and missed signers from the first new block on are dropped, as
+// they were reorged out. The dashboards drop them the same way on receipt.
+func (db *Dashboard) updateHead(head *types.Block) {
+	chain := db.backend.BlockChain()
+
+	db.lock.RLock()
+	recorded := make(map[common.Hash]bool, len(db.blocks))
+	for _, entry := range db.blocks {
+		recorded[entry.Hash] = true
+	}
+	db.lock.RUnlock()
+
+	// Head events are not fired for every block, collect the ones since the last
+	// recorded block on the canonical chain
+	var (
+		blocks    []*BlockEntry
+		missed    []*MissedEntry
+		consensus *ConsensusMessage
+	)
+	for block := head; block != nil && len(blocks) < recentBlockLimit && !recorded[block.Hash()]; block = chain.GetBlock(block.ParentHash(), block.NumberU64()-1) {
+		blocks = append([]*BlockEntry{newBlockEntry(block)}, blocks...)
+
+		state := db.consensusState(block.Header())
+		if state != nil {
+			missed = append(state.Missed, missed...)
+		}
+		if block == head {
+			consensus = state
+		}
+		if block.NumberU64() == 0 {
+			break
+		}
+	}
+	if len(blocks) == 0 {
+		return
+	}
+	first := blocks[0].Number
+
+	db.lock.Lock()
+	db.blocks = append(rewindBlocks(db.blocks, first), blocks...)
+	if len(db.blocks) > recentBlockLimit {
+		db.blocks = db.blocks[len(db.blocks)-recentBlockLimit:]
+	}
+	db.home.Head = blocks[len(blocks)-1]
+	home := *db.home
+	if consensus != nil {
+		// Keep the missed signers of the earlier blocks, the update only carries the new ones
+		state := *consensus
+		state.Missed = nil
+		if db.consensus != nil {
+			state.Missed = rewindMissed(db.consensus.Missed, first)
+		}
+		state.Missed = append(state.Missed, missed...)
+		if len(state.Missed) > missedEntryLimit {
+			state.Missed = state.Missed[len(state.Missed)-missedEntryLimit:]
+		}
+		db.consensus = &state
+		consensus.Missed = missed
+	}
+	db.lock.Unlock()
+
+	db.sendToAll(&Message{
+		Home:      &home,
+		Chain:     &ChainMessage{Blocks: blocks},
+		Consensus: consensus,
+	})
+}
+
+// rewindBlocks returns the recorded blocks below the given number.
+func rewindBlocks(blocks []*BlockEntry, number uint64) []*BlockEntry {
+	kept := make([]*BlockEntry, 0, len(blocks))
+	for _, entry := range blocks {
+		if entry.Number < number {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// rewindMissed returns the recorded missed signers of the blocks below the given
+// number.
+func rewindMissed(missed []*MissedEntry, number uint64) []*MissedEntry {
+	kept := make([]*MissedEntry, 0, len(missed))
+	for _, entry := range missed {
+		if entry.Number < number {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// updatePoolAndPeers samples the transaction pool and the connected peers and
+// sends them, together with the next in-turn slot, to the active dashboards.
+func (db *Dashboard) updatePoolAndPeers() {
+	var (
+		now   = time.Now()
+		peers = db.server.PeersInfo()
+		msg   = &Message{Network: &NetworkMessage{Peers: peers}}
+	)
+	db.lock.Lock()
+	db.peers = peers
+	db.home.Peers = len(peers)
+	if db.backend != nil {
+		pending, queued := db.backend.TxPool().Stats()
+		pendingEntry := &ChartEntry{
+			Time:  now,
+			Value: float64(pending),
+		}
+		queuedEntry := &ChartEntry{
+			Time:  now,
+			Value: float64(queued),
+		}
+		db.txpool.Pending = append(db.txpool.Pending[1:], pendingEntry)
+		db.txpool.Queued = append(db.txpool.Queued[1:], queuedEntry)
+		db.home.Pending, db.home.Queued = pending, queued
+
+		msg.TxPool = &TxPoolMessage{
+			Pending: ChartEntries{pendingEntry},
+			Queued:  ChartEntries{queuedEntry},
+		}
+		// The in-turn signer changes over time if the head is not extended
+		if consensus := db.consensusState(db.backend.BlockChain().CurrentHeader()); consensus != nil {
+			if db.consensus != nil {
+				db.consensus.NextSigner, db.consensus.NextSlot = consensus.NextSigner, consensus.NextSlot
+			}
+			consensus.Missed = nil
+			msg.Consensus = consensus
+		}
+	}
+	home := *db.home
+	msg.Home = &home
+	db.lock.Unlock()
+
+	db.sendToAll(msg)
+}
+
+// consensusState returns the alien signer queue carried by header, the signer
+// in turn to seal its child and the signers it records as missed. It returns
+// nil if the chain is not sealed by the alien engine.
+func (db *Dashboard) consensusState(header *types.Header) *ConsensusMessage {
+	engine, ok := db.backend.BlockChain().Engine().(*alien.Alien)
+	if !ok {
+		return nil
+	}
+	config := db.backend.BlockChain().Config().Alien
+
+	consensus := &ConsensusMessage{LoopStartTime: config.GenesisTimestamp}
+	if header.Number.Sign() > 0 {
+		extra, err := engine.HeaderExtra(header)
+		if err != nil {
+			log.Warn("Failed to decode alien header extra", "number", header.Number, "hash", header.Hash(), "err", err)
+			return nil
+		}
+		consensus.SignerQueue, consensus.LoopStartTime = extra.SignerQueue, extra.LoopStartTime
+		if len(extra.SignerMissing) > 0 {
+			consensus.Missed = []*MissedEntry{{Number: header.Number.Uint64(), Signers: extra.SignerMissing}}
+		}
+	} else {
+		for i := 0; i < int(config.MaxSignerCount) && len(config.SelfVoteSigners) > 0; i++ {
+			consensus.SignerQueue = append(consensus.SignerQueue, common.Address(config.SelfVoteSigners[i%len(config.SelfVoteSigners)]))
+		}
+	}
+	// The child is sealed a period after the head at the earliest, or right now
+	// if that slot has already passed
+	next := header.Time.Uint64() + config.Period
+	if now := uint64(time.Now().Unix()); now > next {
+		next = now
+	}
+	if next >= consensus.LoopStartTime {
+		next -= (next - consensus.LoopStartTime) % config.Period
+	}
+	if signer, err := engine.SignerAt(header, next); err == nil {
+		consensus.NextSigner, consensus.NextSlot = signer, next
+	}
+	return consensus
+}
+
+// newBlockEntry returns the chain panel entry of the given block.
+func newBlockEntry(block *types.Block) *BlockEntry {
+	return &BlockEntry{
+		Number:   block.NumberU64(),
+		Hash:     block.Hash(),
+		Time:     block.Time().Uint64(),
+		Coinbase: block.Coinbase(),
+		Txs:      len(block.Transactions()),
+		GasUsed:  block.GasUsed(),
+		GasLimit: block.GasLimit(),
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package dashboard
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien"
+	"github.com/eeefan/dpeth/consensus/ethash"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/internal/blocksim"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p"
+	"github.com/eeefan/dpeth/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// testBackend is a full node backend the chain panels are fed from.
+type testBackend struct {
+	chain *core.BlockChain
+	pool  *core.TxPool
+}
+
+func (b *testBackend) BlockChain() *core.BlockChain { return b.chain }
+func (b *testBackend) TxPool() *core.TxPool         { return b.pool }
+
+// newTestBackend creates a backend over the given chain with a transaction pool
+// on top of it.
+func newTestBackend(chain *core.BlockChain) *testBackend {
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	return &testBackend{
+		chain: chain,
+		pool:  core.NewTxPool(config, chain.Config(), chain),
+	}
+}
+
+func (b *testBackend) close() {
+	b.pool.Stop()
+	b.chain.Stop()
+}
+
+// newTestDashboard creates a dashboard over the backend with a single connected
+// client, whose received messages are returned by the channel.
+func newTestDashboard(t *testing.T, backend Backend) (*Dashboard, chan Message) {
+	db, err := New(&Config{Refresh: time.Second}, "", backend)
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+	msgs := make(chan Message, 128)
+	db.conns[1] = &client{msg: msgs, logger: log.New("id", 1)}
+	return db, msgs
+}
+
+// newEthashTestChain creates a chain of n blocks sealed by the fake ethash engine
+// and returns it along with the generated blocks.
+func newEthashTestChain(t *testing.T, n int) (*core.BlockChain, []*types.Block, ethdb.Database) {
+	db := ethdb.NewMemDatabase()
+	genesis := (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, n, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain, append([]*types.Block{genesis}, blocks...), db
+}
+
+// checkBlocks checks that the block entries are the ones of the given blocks.
+func checkBlocks(t *testing.T, context string, entries []*BlockEntry, blocks []*types.Block) {
+	t.Helper()
+
+	if len(entries) != len(blocks) {
+		t.Fatalf("%s: block count mismatch: have %d, want %d", context, len(entries), len(blocks))
+	}
+	for i, block := range blocks {
+		if entries[i].Number != block.NumberU64() || entries[i].Hash != block.Hash() {
+			t.Errorf("%s: block %d mismatch: have #%d %x, want #%d %x", context, i, entries[i].Number, entries[i].Hash, block.NumberU64(), block.Hash())
+		}
+	}
+}
+
+// Tests that the recent blocks are filled in from the last recorded block, as
+// head events are not fired for every block, and that the blocks reorged out are
+// replaced.
+func TestUpdateHeadReorg(t *testing.T) {
+	chain, blocks, chaindb := newEthashTestChain(t, 5)
+	backend := newTestBackend(chain)
+	defer backend.close()
+
+	db, msgs := newTestDashboard(t, backend)
+
+	// Record the genesis block and the chain on top of it in one go
+	db.updateHead(blocks[0])
+	<-msgs
+	db.updateHead(blocks[5])
+	checkBlocks(t, "initial", db.blocks, blocks)
+	if msg := <-msgs; msg.Chain == nil {
+		t.Fatalf("initial: chain update missing")
+	} else {
+		checkBlocks(t, "initial update", msg.Chain.Blocks, blocks[1:])
+	}
+	// Reorg to a longer side chain forking off block 2
+	fork, _ := core.GenerateChain(params.TestChainConfig, blocks[2], ethash.NewFaker(), chaindb, 4, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	db.updateHead(fork[len(fork)-1])
+
+	checkBlocks(t, "reorg", db.blocks, append(append([]*types.Block{}, blocks[:3]...), fork...))
+	if db.home.Head.Hash != fork[len(fork)-1].Hash() {
+		t.Errorf("reorg: head mismatch: have %x, want %x", db.home.Head.Hash, fork[len(fork)-1].Hash())
+	}
+	if msg := <-msgs; msg.Chain == nil {
+		t.Fatalf("reorg: chain update missing")
+	} else {
+		checkBlocks(t, "reorg update", msg.Chain.Blocks, fork)
+	}
+	// Repeated head events must not duplicate the recorded blocks
+	db.updateHead(fork[len(fork)-1])
+	if len(db.blocks) != 7 {
+		t.Errorf("repeated head: block count mismatch: have %d, want %d", len(db.blocks), 7)
+	}
+	select {
+	case msg := <-msgs:
+		t.Errorf("repeated head: unexpected update %+v", msg)
+	default:
+	}
+}
+
+// Tests that only the most recent blocks are recorded.
+func TestUpdateHeadLimit(t *testing.T) {
+	chain, blocks, _ := newEthashTestChain(t, recentBlockLimit+8)
+	backend := newTestBackend(chain)
+	defer backend.close()
+
+	db, _ := newTestDashboard(t, backend)
+	db.updateHead(blocks[10])
+	db.updateHead(blocks[len(blocks)-1])
+
+	checkBlocks(t, "limit", db.blocks, blocks[len(blocks)-recentBlockLimit:])
+}
+
+// Tests that the transaction pool and the peers are sampled and sent along with
+// the home panel.
+func TestUpdatePoolAndPeers(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testAddress: {Balance: big.NewInt(params.Ether)}}}
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	backend := newTestBackend(chain)
+	defer backend.close()
+
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+	for _, nonce := range []uint64{0, 1, 3} {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, testKey)
+		if err := backend.pool.AddLocal(tx); err != nil {
+			t.Fatalf("failed to pool transaction %d: %v", nonce, err)
+		}
+	}
+	key, _ := crypto.GenerateKey()
+	server := &p2p.Server{Config: p2p.Config{PrivateKey: key, MaxPeers: 1, NoDiscovery: true, NoDial: true}}
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start p2p server: %v", err)
+	}
+	defer server.Stop()
+
+	dash, msgs := newTestDashboard(t, backend)
+	dash.server = server
+	dash.updatePoolAndPeers()
+
+	msg := <-msgs
+	if msg.Home == nil || msg.Home.Pending != 2 || msg.Home.Queued != 1 || msg.Home.Peers != 0 {
+		t.Fatalf("home mismatch: have %+v, want 2 pending, 1 queued, 0 peers", msg.Home)
+	}
+	if msg.TxPool == nil || len(msg.TxPool.Pending) != 1 || msg.TxPool.Pending[0].Value != 2 || len(msg.TxPool.Queued) != 1 || msg.TxPool.Queued[0].Value != 1 {
+		t.Errorf("transaction pool samples mismatch: have %+v", msg.TxPool)
+	}
+	if msg.Network == nil || msg.Network.Peers == nil {
+		t.Errorf("network peers missing: have %+v", msg.Network)
+	}
+	if msg.Consensus != nil {
+		t.Errorf("unexpected consensus update on an ethash chain: %+v", msg.Consensus)
+	}
+	if have := dash.txpool.Pending[len(dash.txpool.Pending)-1].Value; have != 2 {
+		t.Errorf("recorded pending sample mismatch: have %v, want 2", have)
+	}
+	// Connecting dashboards receive the recorded state, peers included
+	if state := dash.chainState(); state.Network.Peers == nil || state.Home.Pending != 2 {
+		t.Errorf("chain state mismatch: have %+v", state)
+	}
+}
+
+// newAlienTestChain creates a chain sealed by the alien engine, signed in turn by
+// the given number of self voting signers. The slots of the signers in skip are
+// skipped at the matching block numbers.
+func newAlienTestChain(t *testing.T, signers int, blocks int, skip map[uint64]int) (*core.BlockChain, *alien.Alien, []*types.Block) {
+	config := *params.AllAlienProtocolChanges
+	alienConfig := *config.Alien
+	config.Alien = &alienConfig
+
+	alienConfig.MaxSignerCount = uint64(signers)
+	alienConfig.GenesisTimestamp = 1000
+	alienConfig.MaxRewardOutBlock = new(big.Int).SetUint64(1e10)
+	alienConfig.PerBlockReward = big.NewInt(params.Ether)
+	alienConfig.MinerRewardRatio = 50
+	alienConfig.LuckyDrawAddress = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	alienConfig.SelfVoteSigners = nil
+
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	alloc := make(core.GenesisAlloc)
+	for i := 0; i < signers; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+
+		keys[addr] = key
+		alloc[addr] = core.GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(100000), big.NewInt(params.Ether))}
+		alienConfig.SelfVoteSigners = append(alienConfig.SelfVoteSigners, common.UnprefixedAddress(addr))
+		if i == 0 {
+			alienConfig.AdminAddress = addr
+		}
+	}
+	db := ethdb.NewMemDatabase()
+	genesis := (&core.Genesis{
+		Config:    &config,
+		Timestamp: alienConfig.GenesisTimestamp,
+		ExtraData: make([]byte, 32+65),
+		Alloc:     alloc,
+	}).MustCommit(db)
+
+	now := alienConfig.GenesisTimestamp
+	engine := alien.NewSimulated(&alienConfig, db, func() time.Time { return time.Unix(int64(now), 0) })
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	sealed := []*types.Block{genesis}
+	for number := uint64(1); number <= uint64(blocks); number++ {
+		parent := chain.CurrentBlock()
+
+		now = parent.Time().Uint64() + alienConfig.Period*uint64(1+skip[number])
+		signer, err := engine.SignerAt(parent.Header(), now)
+		if err != nil {
+			t.Fatalf("block %d: failed to resolve signer in turn: %v", number, err)
+		}
+		engine.Authorize(signer, blocksim.SignFn(keys[signer]), blocksim.SignTxFn(keys[signer]))
+
+		block, err := blocksim.Seal(engine, chain, parent, signer, now, nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to seal block: %v", number, err)
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to import sealed block: %v", number, err)
+		}
+		sealed = append(sealed, block)
+	}
+	return chain, engine, sealed
+}
+
+// Tests that the consensus panel carries the signer queue of the head, the
+// signer in turn and the signers missing their slots.
+func TestConsensusState(t *testing.T) {
+	chain, engine, blocks := newAlienTestChain(t, 3, 6, map[uint64]int{4: 1})
+	backend := newTestBackend(chain)
+	defer backend.close()
+
+	db, msgs := newTestDashboard(t, backend)
+	db.updateHead(chain.CurrentBlock())
+
+	var missed []*MissedEntry
+	for _, block := range blocks[1:] {
+		extra, err := engine.HeaderExtra(block.Header())
+		if err != nil {
+			t.Fatalf("block %d: failed to decode header extra: %v", block.NumberU64(), err)
+		}
+		if len(extra.SignerMissing) > 0 {
+			missed = append(missed, &MissedEntry{Number: block.NumberU64(), Signers: extra.SignerMissing})
+		}
+	}
+	if len(missed) == 0 {
+		t.Fatalf("no missed signers recorded by the skipped slot")
+	}
+	if db.consensus == nil {
+		t.Fatalf("consensus state missing")
+	}
+	if !reflect.DeepEqual(db.consensus.Missed, missed) {
+		t.Errorf("missed signers mismatch: have %v, want %v", db.consensus.Missed, missed)
+	}
+	head := chain.CurrentHeader()
+	extra, _ := engine.HeaderExtra(head)
+	if !reflect.DeepEqual(db.consensus.SignerQueue, extra.SignerQueue) || db.consensus.LoopStartTime != extra.LoopStartTime {
+		t.Errorf("signer queue mismatch: have %v at %d, want %v at %d", db.consensus.SignerQueue, db.consensus.LoopStartTime, extra.SignerQueue, extra.LoopStartTime)
+	}
+	want, err := engine.SignerAt(head, db.consensus.NextSlot)
+	if err != nil {
+		t.Fatalf("failed to resolve signer in turn: %v", err)
+	}
+	if db.consensus.NextSigner != want {
+		t.Errorf("next signer mismatch: have %x, want %x", db.consensus.NextSigner, want)
+	}
+	if msg := <-msgs; msg.Consensus == nil || !reflect.DeepEqual(msg.Consensus.Missed, missed) {
+		t.Errorf("consensus update mismatch: have %+v, want missed %v", msg.Consensus, missed)
+	}
+}
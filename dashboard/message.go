@@ -16,16 +16,22 @@
 
 package dashboard
 
-import "time"
+import (
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/p2p"
+)
 
 type Message struct {
-	General *GeneralMessage `json:"general,omitempty"`
-	Home    *HomeMessage    `json:"home,omitempty"`
-	Chain   *ChainMessage   `json:"chain,omitempty"`
-	TxPool  *TxPoolMessage  `json:"txpool,omitempty"`
-	Network *NetworkMessage `json:"network,omitempty"`
-	System  *SystemMessage  `json:"system,omitempty"`
-	Logs    *LogsMessage    `json:"logs,omitempty"`
+	General   *GeneralMessage   `json:"general,omitempty"`
+	Home      *HomeMessage      `json:"home,omitempty"`
+	Chain     *ChainMessage     `json:"chain,omitempty"`
+	TxPool    *TxPoolMessage    `json:"txpool,omitempty"`
+	Network   *NetworkMessage   `json:"network,omitempty"`
+	Consensus *ConsensusMessage `json:"consensus,omitempty"`
+	System    *SystemMessage    `json:"system,omitempty"`
+	Logs      *LogsMessage      `json:"logs,omitempty"`
 }
 
 type ChartEntries []*ChartEntry
@@ -41,19 +47,46 @@ type GeneralMessage struct {
 }
 
 type HomeMessage struct {
-	/* TODO (kurkomisi) */
+	Head    *BlockEntry `json:"head,omitempty"`
+	Peers   int         `json:"peers"`
+	Pending int         `json:"pending"`
+	Queued  int         `json:"queued"`
 }
 
 type ChainMessage struct {
-	/* TODO (kurkomisi) */
+	Blocks []*BlockEntry `json:"blocks,omitempty"`
+}
+
+type BlockEntry struct {
+	Number   uint64         `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Time     uint64         `json:"time"`
+	Coinbase common.Address `json:"coinbase"`
+	Txs      int            `json:"txs"`
+	GasUsed  uint64         `json:"gasUsed"`
+	GasLimit uint64         `json:"gasLimit"`
 }
 
 type TxPoolMessage struct {
-	/* TODO (kurkomisi) */
+	Pending ChartEntries `json:"pending,omitempty"`
+	Queued  ChartEntries `json:"queued,omitempty"`
 }
 
 type NetworkMessage struct {
-	/* TODO (kurkomisi) */
+	Peers []*p2p.PeerInfo `json:"peers"`
+}
+
+type ConsensusMessage struct {
+	SignerQueue   []common.Address `json:"signerQueue,omitempty"`
+	LoopStartTime uint64           `json:"loopStartTime"`
+	NextSigner    common.Address   `json:"nextSigner"`
+	NextSlot      uint64           `json:"nextSlot"`
+	Missed        []*MissedEntry   `json:"missed,omitempty"`
+}
+
+type MissedEntry struct {
+	Number  uint64           `json:"number"`
+	Signers []common.Address `json:"signers"`
 }
 
 type SystemMessage struct {
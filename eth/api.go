@@ -34,6 +34,7 @@ import (
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/miner"
+	"github.com/eeefan/dpeth/p2p/discover"
 	"github.com/eeefan/dpeth/params"
 	"github.com/eeefan/dpeth/rlp"
 	"github.com/eeefan/dpeth/rpc"
@@ -293,6 +294,36 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// PeerScores retrieves the reputation scores of the recently seen peers along
+// with the currently banned ones, lowest score first.
+func (api *PrivateAdminAPI) PeerScores() []*PeerScore {
+	pm := api.eth.protocolManager
+	return pm.reputation.list(func(id discover.NodeID) bool {
+		return pm.peers.Peer(fmt.Sprintf("%x", id[:8])) != nil
+	})
+}
+
+// Unban lifts the ban of a node given by its ID or enode URL, and resets its
+// score. It returns whether the node was banned.
+func (api *PrivateAdminAPI) Unban(node string) (bool, error) {
+	var (
+		id  discover.NodeID
+		err error
+	)
+	if strings.HasPrefix(node, "enode://") {
+		var n *discover.Node
+		if n, err = discover.ParseNode(node); err == nil {
+			id = n.ID
+		}
+	} else {
+		id, err = discover.HexID(node)
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid node: %v", err)
+	}
+	return api.eth.protocolManager.reputation.unban(id), nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
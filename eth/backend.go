@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/external"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
 	"github.com/eeefan/dpeth/consensus"
@@ -361,7 +362,12 @@ func (s *Ethereum) StartMining(local bool) error {
 			log.Error("Etherbase account unavailable locally", "err", err)
 			return fmt.Errorf("signer missing: %v", err)
 		}
-		alien.Authorize(eb, wallet.SignHash, wallet.SignTx)
+		// External signers only seal headers they can check against the signer slots
+		if signer, ok := wallet.(*external.ExternalSigner); ok {
+			alien.AuthorizeHeaderSigner(eb, signer.SignAlienHeader, wallet.SignTx)
+		} else {
+			alien.Authorize(eb, wallet.SignHash, wallet.SignTx)
+		}
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -416,10 +422,17 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 		maxPeers -= s.config.LightPeers
 	}
 	// Start the networking layer and the light server if requested
+	s.protocolManager.reputation.setBanner(srvr)
 	s.protocolManager.Start(maxPeers)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	// Keep the nodes of the alien signer queue connected to each other, and
+	// publish the engine events of the canonical chain
+	if engine, ok := s.engine.(*alien.Alien); ok {
+		go s.signerMeshLoop(&signerMesh{engine: engine, server: srvr})
+		go s.alienEventLoop(engine)
+	}
 	return nil
 }
 
@@ -336,6 +336,23 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	return err
 }
 
+// IsTimeout returns whether a synchronisation error was caused by the remote
+// peer failing to deliver the requested data in time.
+func IsTimeout(err error) bool {
+	return err == errTimeout || err == errStallingPeer
+}
+
+// IsInvalidData returns whether a synchronisation error was caused by the
+// remote peer delivering invalid data.
+func IsInvalidData(err error) bool {
+	switch err {
+	case errBadPeer, errEmptyHeaderSet, errInvalidAncestor, errInvalidChain,
+		errInvalidBlock, errInvalidBody, errInvalidReceipt:
+		return true
+	}
+	return false
+}
+
 // synchronise will select the peer and use it for synchronising. If an empty string is given
 // it will use the best peer possible and synchronize if its TD is higher than our own. If any of the
 // checks fail an error will be returned. This method is synchronous
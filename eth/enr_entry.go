@@ -0,0 +1,56 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/eeefan/dpeth/core/forkid"
+	"github.com/eeefan/dpeth/p2p/enr"
+	"github.com/eeefan/dpeth/rlp"
+)
+
+// ethEntry is the "eth" ENR entry which advertises eth protocol
+// on the discovery network.
+type ethEntry struct {
+	ForkID forkid.ID // Fork identifier per EIP-2124
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e ethEntry) ENRKey() string {
+	return "eth"
+}
+
+// currentENREntry constructs an `eth` ENR entry based on the current state of the chain.
+func currentENREntry(chain forkid.Blockchain) *ethEntry {
+	return &ethEntry{
+		ForkID: forkid.NewIDFromChain(chain),
+	}
+}
+
+// newENRFilter returns a dial filter accepting only the nodes advertising the
+// eth protocol with a fork ID compatible with the local chain.
+func newENRFilter(filter forkid.Filter) func(record *enr.Record) bool {
+	return func(record *enr.Record) bool {
+		var entry ethEntry
+		if err := record.Load(&entry); err != nil {
+			return false
+		}
+		return filter(entry.ForkID) == nil
+	}
+}
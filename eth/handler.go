@@ -29,6 +29,7 @@ import (
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/consensus"
 	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/forkid"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/eth/downloader"
 	"github.com/eeefan/dpeth/eth/fetcher"
@@ -37,6 +38,7 @@ import (
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/p2p"
 	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/p2p/enr"
 	"github.com/eeefan/dpeth/params"
 	"github.com/eeefan/dpeth/rlp"
 )
@@ -72,11 +74,15 @@ type ProtocolManager struct {
 	blockchain  *core.BlockChain
 	chainconfig *params.ChainConfig
 	maxPeers    int
+	forkFilter  forkid.Filter // Fork ID filter, constant across the lifetime of the node
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
 
+	signerNodes func(header *types.Header) map[discover.NodeID]bool // Nodes of the signers in turn after a block, nil outside alien
+	reputation  *reputation                                         // Scores of the peers, banning the misbehaving ones
+
 	SubProtocols []p2p.Protocol
 
 	eventMux      *event.TypeMux
@@ -110,6 +116,9 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
+		signerNodes: upcomingSignerNodes(engine),
+		forkFilter:  forkid.NewFilter(blockchain),
+		reputation:  newReputation(),
 	}
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -152,6 +161,10 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 				}
 				return nil
 			},
+			Attributes: func() []enr.Entry {
+				return []enr.Entry{currentENREntry(manager.blockchain)}
+			},
+			DialFilter: newENRFilter(manager.forkFilter),
 		})
 	}
 	if len(manager.SubProtocols) == 0 {
@@ -173,9 +186,21 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			return 0, nil
 		}
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
-		return manager.blockchain.InsertChain(blocks)
+		n, err := manager.blockchain.InsertChain(blocks)
+
+		// Credit the peers which propagated the imported blocks
+		imported := blocks
+		if err != nil {
+			imported = blocks[:n]
+		}
+		for _, block := range imported {
+			if p, ok := block.ReceivedFrom.(*peer); ok {
+				manager.recordPeerEvent(p, eventUsefulBlock)
+			}
+		}
+		return n, err
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.dropMisbehavingPeer)
 
 	return manager, nil
 }
@@ -248,8 +273,8 @@ func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *p
 // handle is the callback invoked to manage the life cycle of an eth peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	// Ignore maxPeers if this is a trusted peer
-	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
+	// Ignore maxPeers if this is a trusted or prioritized peer
+	if info := p.Peer.Info(); pm.peers.Len() >= pm.maxPeers && !info.Network.Trusted && !info.Network.Prioritized {
 		return p2p.DiscTooManyPeers
 	}
 	p.Log().Debug("dpeth peer connected", "name", p.Name())
@@ -262,7 +287,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), forkid.NewIDFromChain(pm.blockchain), pm.forkFilter); err != nil {
 		p.Log().Debug("dpeth handshake failed", "err", err)
 		return err
 	}
@@ -442,6 +467,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		// A batch of block bodies arrived to one of our previous requests
 		var request blockBodiesData
 		if err := msg.Decode(&request); err != nil {
+			pm.recordPeerEvent(p, eventBadBody)
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver them all to the downloader for queuing
@@ -642,7 +668,10 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			log.Error("Propagating dangling block", "number", block.Number(), "hash", hash)
 			return
 		}
-		// Send the block to a subset of our peers
+		// Send the block to the upcoming signers first, then to a subset of the others
+		if pm.signerNodes != nil {
+			peers = pm.propagateToSigners(block, td, peers)
+		}
 		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block, td)
@@ -17,10 +17,12 @@
 package eth
 
 import (
+	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/core"
@@ -30,6 +32,7 @@ import (
 	"github.com/eeefan/dpeth/eth/downloader"
 	"github.com/eeefan/dpeth/ethdb"
 	"github.com/eeefan/dpeth/p2p"
+	"github.com/eeefan/dpeth/p2p/discover"
 	"github.com/eeefan/dpeth/params"
 )
 
@@ -443,3 +446,37 @@ func testGetReceipt(t *testing.T, protocol int) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that propagated blocks are sent straight to the peers of the upcoming
+// signers, leaving the regular propagation to the rest of the peers.
+func TestBroadcastBlockToSigners(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 1, nil, nil)
+	defer pm.Stop()
+
+	peers := make([]*testPeer, 4)
+	for i := range peers {
+		peers[i], _ = newTestPeer(fmt.Sprintf("peer %d", i), eth63, pm, true)
+		defer peers[i].close()
+	}
+	for pm.peers.Len() < len(peers) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	pm.signerNodes = func(header *types.Header) map[discover.NodeID]bool {
+		return map[discover.NodeID]bool{peers[0].ID(): true, peers[1].ID(): true}
+	}
+	block := pm.blockchain.CurrentBlock()
+	rest := pm.propagateToSigners(block, pm.blockchain.GetTd(block.Hash(), block.NumberU64()), pm.peers.PeersWithoutBlock(block.Hash()))
+	if len(rest) != 2 {
+		t.Fatalf("remaining peer count mismatch: have %d, want 2", len(rest))
+	}
+	for _, p := range rest {
+		if p.ID() == peers[0].ID() || p.ID() == peers[1].ID() {
+			t.Errorf("signer peer %v left to regular propagation", p.ID())
+		}
+	}
+	for i, p := range peers[:2] {
+		if err := p2p.ExpectMsg(p.app, NewBlockMsg, []interface{}{block, pm.blockchain.GetTd(block.Hash(), block.NumberU64())}); err != nil {
+			t.Errorf("signer peer %d: %v", i, err)
+		}
+	}
+}
@@ -54,6 +54,10 @@ var (
 	miscInTrafficMeter        = metrics.NewRegisteredMeter("eth/misc/in/traffic", nil)
 	miscOutPacketsMeter       = metrics.NewRegisteredMeter("eth/misc/out/packets", nil)
 	miscOutTrafficMeter       = metrics.NewRegisteredMeter("eth/misc/out/traffic", nil)
+
+	propSignerBlockOutMeter = metrics.NewRegisteredMeter("eth/prop/signers/blocks/out", nil)
+	propSignerMissMeter     = metrics.NewRegisteredMeter("eth/prop/signers/blocks/miss", nil)
+	propSignerSendTimer     = metrics.NewRegisteredTimer("eth/prop/signers/send", nil)
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
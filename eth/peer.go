@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core/forkid"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/p2p"
 	"github.com/eeefan/dpeth/rlp"
@@ -329,12 +330,25 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 
 // Handshake executes the eth protocol handshake, negotiating version number,
 // network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
-
+	var (
+		status   statusData   // safe to read after two values have been received from errc
+		status64 statusData64 // safe to read after two values have been received from errc
+	)
 	go func() {
+		if p.version >= eth64 {
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData64{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				ForkID:          forkID,
+			})
+			return
+		}
 		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
@@ -344,6 +358,10 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		})
 	}()
 	go func() {
+		if p.version >= eth64 {
+			errc <- p.readStatus64(network, &status64, genesis, forkFilter)
+			return
+		}
 		errc <- p.readStatus(network, &status, genesis)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
@@ -358,11 +376,36 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td, p.head = status.TD, status.CurrentBlock
+	if p.version >= eth64 {
+		p.td, p.head = status64.TD, status64.CurrentBlock
+	} else {
+		p.td, p.head = status.TD, status.CurrentBlock
+	}
 	return nil
 }
 
 func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
+	}
+	return p.checkStatus(network, status.ProtocolVersion, status.NetworkId, status.GenesisBlock, genesis)
+}
+
+func (p *peer) readStatus64(network uint64, status *statusData64, genesis common.Hash, forkFilter forkid.Filter) (err error) {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
+	}
+	if err := p.checkStatus(network, status.ProtocolVersion, status.NetworkId, status.GenesisBlock, genesis); err != nil {
+		return err
+	}
+	if err := forkFilter(status.ForkID); err != nil {
+		return errResp(ErrForkIDRejected, "%v", err)
+	}
+	return nil
+}
+
+// readStatusMsg reads the status message of the remote peer into status.
+func (p *peer) readStatusMsg(status interface{}) error {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -374,17 +417,23 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	if err := msg.Decode(status); err != nil {
 		return errResp(ErrDecode, "msg %v: %v", msg, err)
 	}
-	if status.GenesisBlock != genesis {
-		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
+	return nil
+}
+
+// checkStatus makes sure the remote status fields common to all protocol
+// versions match the local ones.
+func (p *peer) checkStatus(network uint64, version uint32, remoteNetwork uint64, remoteGenesis common.Hash, genesis common.Hash) error {
+	if remoteGenesis != genesis {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", remoteGenesis[:8], genesis[:8])
 	}
-	if status.NetworkId != network {
-		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", status.NetworkId, network)
+	if remoteNetwork != network {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", remoteNetwork, network)
 	}
-	if int(status.ProtocolVersion) != p.version {
-		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
+	if int(version) != p.version {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", version, p.version)
 	}
 	return nil
 }
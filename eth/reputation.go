@@ -0,0 +1,254 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p/discover"
+)
+
+const (
+	reputationHalfLife     = 10 * time.Minute // Time after which half of a peer's score is forgotten
+	reputationBanThreshold = -100             // Score below which a peer gets banned
+	reputationBanDuration  = time.Hour        // Duration of the bans of misbehaving peers
+	reputationMaxScore     = 50               // Cap of the score to prevent banking credit for misbehaving later
+	reputationMinTracked   = 1                // Absolute score below which disconnected peers are forgotten
+)
+
+// peerEvent is an observed behaviour of a remote peer affecting its score.
+type peerEvent int
+
+const (
+	eventInvalidHeader peerEvent = iota // Propagated header failed consensus verification (e.g. bad seal)
+	eventBadBody                        // Delivered invalid block bodies or an invalid chain
+	eventTimeout                        // Failed to deliver the requested data in time
+	eventUsefulBlock                    // Propagated a block that got imported
+)
+
+// eventWeights are the score changes of the individual peer events.
+var eventWeights = map[peerEvent]float64{
+	eventInvalidHeader: -60,
+	eventBadBody:       -40,
+	eventTimeout:       -20,
+	eventUsefulBlock:   2,
+}
+
+func (e peerEvent) String() string {
+	switch e {
+	case eventInvalidHeader:
+		return "invalidHeader"
+	case eventBadBody:
+		return "badBody"
+	case eventTimeout:
+		return "timeout"
+	case eventUsefulBlock:
+		return "usefulBlock"
+	default:
+		return "unknown"
+	}
+}
+
+// banner is the part of the p2p server keeping misbehaving nodes away.
+type banner interface {
+	BanPeer(id discover.NodeID, ip net.IP, duration time.Duration)
+	Unban(id discover.NodeID) bool
+	Bans() []discover.Ban
+}
+
+// peerScore is the decaying reputation of a single peer.
+type peerScore struct {
+	value   float64           // Score as of the last update
+	updated time.Time         // Time of the last update, the score decays from
+	events  map[peerEvent]int // Number of events recorded, for reporting
+}
+
+// decay returns the score of the peer decayed to the given time.
+func (s *peerScore) decay(now time.Time) float64 {
+	elapsed := now.Sub(s.updated)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(elapsed)/float64(reputationHalfLife))
+}
+
+// reputation scores the remote peers based on their behaviour, banning the ones
+// whose score drops below the threshold. Scores decay towards zero over time, so
+// occasional hiccups are forgotten while repeated misbehaviour adds up.
+type reputation struct {
+	lock   sync.Mutex
+	scores map[discover.NodeID]*peerScore
+	banner banner           // Server banning the misbehaving peers, nil until started
+	now    func() time.Time // Source of time, replaceable in tests
+}
+
+// newReputation creates an empty peer reputation tracker.
+func newReputation() *reputation {
+	return &reputation{
+		scores: make(map[discover.NodeID]*peerScore),
+		now:    time.Now,
+	}
+}
+
+// setBanner sets the server used to ban the misbehaving peers.
+func (r *reputation) setBanner(b banner) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.banner = b
+}
+
+// record updates the score of a peer with an observed event, banning the peer
+// if its score drops below the threshold. Trusted peers are scored, but never
+// banned. The returned flag reports whether the peer got banned.
+func (r *reputation) record(id discover.NodeID, ip net.IP, trusted bool, event peerEvent) bool {
+	r.lock.Lock()
+
+	now := r.now()
+	score := r.scores[id]
+	if score == nil {
+		score = &peerScore{events: make(map[peerEvent]int)}
+		r.scores[id] = score
+	}
+	score.value = math.Min(score.decay(now)+eventWeights[event], reputationMaxScore)
+	score.updated = now
+	score.events[event]++
+
+	value, banner := score.value, r.banner
+	r.lock.Unlock()
+
+	if value >= reputationBanThreshold || trusted || banner == nil {
+		return false
+	}
+	// Banning disconnects the peer through the server loop, which may be busy
+	// with a peer recording an event, so don't hold the lock over it
+	log.Debug("Banning misbehaving peer", "id", id, "ip", ip, "score", value, "event", event)
+	banner.BanPeer(id, ip, reputationBanDuration)
+	return true
+}
+
+// forget drops the score of a peer, giving it a clean slate.
+func (r *reputation) forget(id discover.NodeID) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.scores, id)
+}
+
+// score returns the current score of a peer.
+func (r *reputation) score(id discover.NodeID) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if score := r.scores[id]; score != nil {
+		return score.decay(r.now())
+	}
+	return 0
+}
+
+// PeerScore is the reputation of a remote node, as reported by admin_peerScores.
+type PeerScore struct {
+	ID          string         `json:"id"`
+	Score       float64        `json:"score"`
+	Events      map[string]int `json:"events"`
+	BannedUntil *time.Time     `json:"bannedUntil,omitempty"`
+}
+
+// list returns the scores of the tracked peers along with the currently banned
+// ones, lowest score first. Scores decayed to insignificance are dropped, unless
+// the peer is still connected.
+func (r *reputation) list(connected func(id discover.NodeID) bool) []*PeerScore {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		now    = r.now()
+		result = make(map[discover.NodeID]*PeerScore)
+	)
+	for id, score := range r.scores {
+		value := score.decay(now)
+		if math.Abs(value) < reputationMinTracked && !connected(id) {
+			delete(r.scores, id)
+			continue
+		}
+		events := make(map[string]int, len(score.events))
+		for event, count := range score.events {
+			events[event.String()] = count
+		}
+		result[id] = &PeerScore{ID: id.String(), Score: value, Events: events}
+	}
+	if r.banner != nil {
+		for _, ban := range r.banner.Bans() {
+			if result[ban.ID] == nil {
+				result[ban.ID] = &PeerScore{ID: ban.ID.String(), Events: make(map[string]int)}
+			}
+			expiry := ban.Expiry
+			result[ban.ID].BannedUntil = &expiry
+		}
+	}
+	scores := make([]*PeerScore, 0, len(result))
+	for _, score := range result {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score < scores[j].Score
+		}
+		return scores[i].ID < scores[j].ID
+	})
+	return scores
+}
+
+// unban lifts the ban of a node and resets its score, returning whether the
+// node was banned.
+func (r *reputation) unban(id discover.NodeID) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.scores, id)
+	if r.banner == nil {
+		return false
+	}
+	return r.banner.Unban(id)
+}
+
+// recordPeerEvent records an observed event of the peer in its reputation,
+// dropping the peer if it got banned.
+func (pm *ProtocolManager) recordPeerEvent(p *peer, event peerEvent) {
+	var ip net.IP
+	if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok {
+		ip = addr.IP
+	}
+	trusted := p.Peer.Info().Network.Trusted
+	if pm.reputation.record(p.ID(), ip, trusted, event) {
+		pm.removePeer(p.id)
+	}
+}
+
+// dropMisbehavingPeer penalises and drops a peer detected as malicious by the
+// block fetcher, which only happens on invalid propagated headers.
+func (pm *ProtocolManager) dropMisbehavingPeer(id string) {
+	if p := pm.peers.Peer(id); p != nil {
+		pm.recordPeerEvent(p, eventInvalidHeader)
+	}
+	pm.removePeer(id)
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/p2p/discover"
+)
+
+// testBanner is a mock p2p server recording the bans.
+type testBanner struct {
+	bans  map[discover.NodeID]discover.Ban
+	onBan func() // Optional hook run while banning
+}
+
+func (b *testBanner) BanPeer(id discover.NodeID, ip net.IP, duration time.Duration) {
+	if b.onBan != nil {
+		b.onBan()
+	}
+	b.bans[id] = discover.Ban{ID: id, IP: ip, Expiry: time.Now().Add(duration)}
+}
+
+func (b *testBanner) Unban(id discover.NodeID) bool {
+	_, ok := b.bans[id]
+	delete(b.bans, id)
+	return ok
+}
+
+func (b *testBanner) Bans() []discover.Ban {
+	var bans []discover.Ban
+	for _, ban := range b.bans {
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
+// Tests that peer scores decay over time, and that peers are banned once their
+// score drops below the threshold, unless trusted.
+func TestReputation(t *testing.T) {
+	var (
+		now     = time.Unix(1000000, 0)
+		banner  = &testBanner{bans: make(map[discover.NodeID]discover.Ban)}
+		rep     = newReputation()
+		ip      = net.IP{10, 0, 0, 1}
+		good    = discover.NodeID{1}
+		bad     = discover.NodeID{2}
+		trusted = discover.NodeID{3}
+	)
+	rep.now = func() time.Time { return now }
+	rep.setBanner(banner)
+
+	// Useful blocks are credited up to the cap
+	for i := 0; i < 100; i++ {
+		rep.record(good, ip, false, eventUsefulBlock)
+	}
+	if score := rep.score(good); score != reputationMaxScore {
+		t.Errorf("good peer score mismatch: have %v, want %v", score, reputationMaxScore)
+	}
+	// A single timeout is not enough for a ban, and halves after the half-life
+	if rep.record(bad, ip, false, eventTimeout) {
+		t.Fatalf("peer banned after a single timeout")
+	}
+	now = now.Add(reputationHalfLife)
+	if score, want := rep.score(bad), eventWeights[eventTimeout]/2; math.Abs(score-want) > 1e-9 {
+		t.Errorf("decayed score mismatch: have %v, want %v", score, want)
+	}
+	// Repeated invalid headers get the peer banned, without holding up the
+	// other peers while the server disconnects it
+	banner.onBan = func() {
+		done := make(chan struct{})
+		go func() {
+			rep.score(good)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("reputation locked while banning")
+		}
+	}
+	if rep.record(bad, ip, false, eventInvalidHeader) {
+		t.Fatalf("peer banned before reaching the threshold")
+	}
+	if !rep.record(bad, ip, false, eventInvalidHeader) {
+		t.Fatalf("peer not banned below the threshold")
+	}
+	if ban, ok := banner.bans[bad]; !ok || !ban.IP.Equal(ip) {
+		t.Errorf("ban mismatch: have %v, want ip %v", ban, ip)
+	}
+	// Trusted peers are never banned
+	for i := 0; i < 10; i++ {
+		if rep.record(trusted, ip, true, eventBadBody) {
+			t.Fatalf("trusted peer banned")
+		}
+	}
+	// The listing reports the bans and orders by score
+	scores := rep.list(func(discover.NodeID) bool { return false })
+	if len(scores) != 3 || scores[0].ID != trusted.String() || scores[2].ID != good.String() {
+		t.Fatalf("score listing mismatch: have %v", scores)
+	}
+	if scores[1].BannedUntil == nil || scores[1].Events[eventInvalidHeader.String()] != 2 {
+		t.Errorf("banned peer listing mismatch: have %+v", scores[1])
+	}
+	// Unbanning resets the score too
+	if !rep.unban(bad) {
+		t.Errorf("banned peer reported as not banned")
+	}
+	if score := rep.score(bad); score != 0 {
+		t.Errorf("unbanned peer score mismatch: have %v, want 0", score)
+	}
+	// Insignificant scores of disconnected peers are forgotten
+	now = now.Add(20 * reputationHalfLife)
+	if scores := rep.list(func(discover.NodeID) bool { return false }); len(scores) != 0 {
+		t.Errorf("decayed scores not forgotten: %v", scores)
+	}
+}
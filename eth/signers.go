@@ -0,0 +1,191 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/consensus/alien"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p/discover"
+)
+
+// signerPropagationSlots is the number of slots following a block whose signers
+// are sent the full block directly, ahead of the regular propagation.
+const signerPropagationSlots = 3
+
+// prioritizer is the part of the p2p server keeping the nodes of the signers
+// connected.
+type prioritizer interface {
+	SetPrioritizedPeers(nodes []*discover.Node)
+}
+
+// signerMesh keeps the registered nodes of the signers in the current alien
+// signer queue prioritized in the p2p server, so the signers stay connected to
+// each other regardless of the peer limits.
+type signerMesh struct {
+	engine *alien.Alien
+	server prioritizer
+	nodes  []*discover.Node // Nodes currently prioritized in the server
+}
+
+// update prioritizes the nodes of the signer queue carried by the header if
+// they differ from the currently prioritized ones.
+func (m *signerMesh) update(header *types.Header) {
+	nodes, err := m.engine.SignerQueueNodes(header)
+	if err != nil {
+		log.Debug("Failed to retrieve signer nodes", "number", header.Number, "hash", header.Hash(), "err", err)
+		return
+	}
+	if sameNodes(m.nodes, nodes) {
+		return
+	}
+	m.nodes = nodes
+	m.server.SetPrioritizedPeers(nodes)
+	log.Info("Updated signer mesh", "number", header.Number, "nodes", len(nodes))
+}
+
+// sameNodes returns whether both lists contain the same nodes at the same
+// endpoints, regardless of their order.
+func sameNodes(a, b []*discover.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, n := range a {
+		set[n.String()] = true
+	}
+	for _, n := range b {
+		if !set[n.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// signerMeshLoop refreshes the prioritized signer nodes on every new chain head,
+// picking up both the signer queue changes and the updates of the registry.
+func (s *Ethereum) signerMeshLoop(mesh *signerMesh) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	mesh.update(s.blockchain.CurrentHeader())
+	for {
+		select {
+		case ev := <-heads:
+			mesh.update(ev.Block.Header())
+		case <-sub.Err():
+			return
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// alienEventLoop hands every new chain head to the alien engine, which publishes
+// the events of the headers that became canonical with it.
+func (s *Ethereum) alienEventLoop(engine *alien.Alien) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	engine.NewChainHead(s.blockchain, s.blockchain.CurrentHeader())
+	for {
+		select {
+		case ev := <-heads:
+			engine.NewChainHead(s.blockchain, ev.Block.Header())
+		case <-sub.Err():
+			return
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// upcomingSignerNodes returns a function resolving the IDs of the registered
+// nodes of the signers in turn right after a block, or nil if the engine has
+// no notion of upcoming signers.
+func upcomingSignerNodes(engine consensus.Engine) func(header *types.Header) map[discover.NodeID]bool {
+	alien, ok := engine.(*alien.Alien)
+	if !ok {
+		return nil
+	}
+	return func(header *types.Header) map[discover.NodeID]bool {
+		signers, err := alien.UpcomingSigners(header, signerPropagationSlots)
+		if err != nil {
+			log.Debug("Failed to retrieve upcoming signers", "number", header.Number, "hash", header.Hash(), "err", err)
+			return nil
+		}
+		registry := alien.SignerNodes()
+
+		ids := make(map[discover.NodeID]bool)
+		for _, signer := range signers {
+			if node, ok := registry[signer]; ok {
+				ids[node.ID] = true
+			}
+		}
+		return ids
+	}
+}
+
+// propagateToSigners sends the block directly to the given peers run by the
+// upcoming signers, returning the rest of the peers for the regular propagation.
+func (pm *ProtocolManager) propagateToSigners(block *types.Block, td *big.Int, peers []*peer) []*peer {
+	ids := pm.signerNodes(block.Header())
+	if len(ids) == 0 {
+		return peers
+	}
+	// Measure the time until the block is written to the connection of the signer,
+	// from the time it was received, or now if it was sealed locally
+	start := block.ReceivedAt
+	if start.IsZero() {
+		start = time.Now()
+	}
+	var rest []*peer
+	for _, p := range peers {
+		if !ids[p.ID()] {
+			rest = append(rest, p)
+			continue
+		}
+		delete(ids, p.ID())
+
+		// Bypass the broadcast queue of the peer, the block is needed right away
+		propSignerBlockOutMeter.Mark(1)
+		go func(p *peer) {
+			if err := p.SendNewBlock(block, td); err != nil {
+				p.Log().Debug("Failed to propagate block to signer", "number", block.Number(), "hash", block.Hash(), "err", err)
+				return
+			}
+			propSignerSendTimer.UpdateSince(start)
+		}(p)
+	}
+	// Signers not connected are left to the fetcher, the ones already knowing the
+	// block are not missed
+	for id := range ids {
+		if p := pm.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil && p.knownBlocks.Has(block.Hash()) {
+			delete(ids, id)
+		}
+	}
+	propSignerMissMeter.Mark(int64(len(ids)))
+	return rest
+}
@@ -198,6 +198,12 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 
 	// Run the sync cycle, and disable fast sync if we've went past the pivot block
 	if err := pm.downloader.Synchronise(peer.id, pHead, pTd, mode); err != nil {
+		switch {
+		case downloader.IsTimeout(err):
+			pm.recordPeerEvent(peer, eventTimeout)
+		case downloader.IsInvalidData(err):
+			pm.recordPeerEvent(peer, eventBadBody)
+		}
 		return
 	}
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
@@ -0,0 +1,166 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/core/vm"
+)
+
+// ResultTracer is a transaction tracer assembling its findings into a result
+// retrievable after the execution, and supporting the interruption of tracing.
+// Both the JavaScript and the native Go tracers implement it.
+type ResultTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the tracing, along with any
+	// error that occurred during the tracing.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+// natives contains the built in tracers implemented natively in Go, replacing
+// their JavaScript counterparts of the same name.
+var natives = map[string]func() ResultTracer{
+	"callTracer":     func() ResultTracer { return newCallTracer() },
+	"prestateTracer": func() ResultTracer { return newPrestateTracer() },
+}
+
+// NewResultTracer returns the native Go implementation of the named tracer if
+// there is one, or otherwise a JavaScript tracer running the given code or the
+// built in tracer of that name.
+func NewResultTracer(code string) (ResultTracer, error) {
+	if ctor, ok := natives[code]; ok {
+		return ctor(), nil
+	}
+	return New(code)
+}
+
+// txContext gathers the details of the traced transaction, the counterpart of
+// the `ctx` object handed to the result function of the JavaScript tracers.
+type txContext struct {
+	create  bool
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	time    time.Duration
+	execErr error
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (ctx *txContext) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	ctx.create, ctx.from, ctx.to, ctx.input, ctx.gas, ctx.value = create, from, to, input, gas, value
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (ctx *txContext) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	ctx.output, ctx.gasUsed, ctx.time, ctx.execErr = output, gasUsed, t, err
+	return nil
+}
+
+// kind returns the type of the traced transaction.
+func (ctx *txContext) kind() string {
+	if ctx.create {
+		return "CREATE"
+	}
+	return "CALL"
+}
+
+// interrupter implements the interruption of tracing, mirroring the behavior of
+// the JavaScript tracer: once stopped or failed, no further steps are traced.
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+	err       error  // Error, if one has occurred
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (in *interrupter) Stop(err error) {
+	in.reason = err
+	atomic.StoreUint32(&in.interrupt, 1)
+}
+
+// halted reports whether tracing should cease, recording the reason of any
+// pending interruption as the tracing error.
+func (in *interrupter) halted() bool {
+	if in.err != nil {
+		return true
+	}
+	if atomic.LoadUint32(&in.interrupt) > 0 {
+		in.err = in.reason
+		return true
+	}
+	return false
+}
+
+// jsNumber converts a big integer into the closest JavaScript number, as done
+// by bigInt's valueOf.
+func jsNumber(n *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
+
+// jsInt converts a JavaScript number into an integer the way duktape does when
+// passing it to Go, clamping it into the int32 range.
+func jsInt(f float64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f < math.MinInt32:
+		return math.MinInt32
+	case f > math.MaxInt32:
+		return math.MaxInt32
+	}
+	return int64(f)
+}
+
+// jsHex formats an integer as hexadecimal the way the JavaScript tracers do,
+// which is '0x' followed by bigInt's toString(16).
+func jsHex(n int64) string {
+	if n < 0 {
+		return "0x-" + strconv.FormatUint(uint64(-n), 16)
+	}
+	return "0x" + strconv.FormatUint(uint64(n), 16)
+}
+
+// encodeJSON serializes a tracing result in the same compact form as duktape's
+// JSON encoder, leaving HTML characters unescaped.
+func encodeJSON(v interface{}) (json.RawMessage, error) {
+	buf := new(bytes.Buffer)
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
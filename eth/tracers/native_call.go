@@ -0,0 +1,254 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/core/vm"
+)
+
+// callFrame is a single call reported by the call tracer. Fields left empty are
+// the ones the JavaScript tracer leaves undefined, omitted from the output.
+type callFrame struct {
+	Type    string       `json:"type,omitempty"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64  // Gas available before the call opcode
+	gasCost uint64  // Cost of the call opcode
+	gas     uint64  // True allowance of the call, if known
+	hasGas  bool    // Whether the true allowance of the call is known
+	outOff  float64 // Memory offset of the call output
+	outLen  float64 // Length of the call output
+}
+
+// callTracer is a native implementation of the JavaScript callTracer, reporting
+// all the internal calls made by a transaction. Its output is identical to the
+// one of the JavaScript version.
+type callTracer struct {
+	txContext
+	interrupter
+
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer() *callTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// top returns the innermost call being executed.
+func (t *callTracer) top() *callFrame {
+	return t.callstack[len(t.callstack)-1]
+}
+
+// pop removes the innermost call from the call stack.
+func (t *callTracer) pop() *callFrame {
+	call := t.top()
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	return call
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.halted() {
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	var (
+		mem = &memoryWrapper{memory}
+		stk = &stackWrapper{stack}
+	)
+	switch op {
+	case vm.CREATE:
+		// If a new contract is being created, add to the call stack
+		inOff := jsNumber(stk.peek(1))
+		inEnd := inOff + jsNumber(stk.peek(2))
+
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Address().Bytes()),
+			Input:   hexutil.Encode(mem.slice(jsInt(inOff), jsInt(inEnd))),
+			gasIn:   gas,
+			gasCost: cost,
+			Value:   "0x" + stk.peek(0).Text(16),
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		t.top().Calls = append(t.top().Calls, &callFrame{Type: op.String()})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(stk.peek(1))
+		if _, ok := vm.PrecompiledContractsByzantium[to]; ok {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := jsNumber(stk.peek(2 + off))
+		inEnd := inOff + jsNumber(stk.peek(3+off))
+
+		call := &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Address().Bytes()),
+			To:      hexutil.Encode(to.Bytes()),
+			Input:   hexutil.Encode(mem.slice(jsInt(inOff), jsInt(inEnd))),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  jsNumber(stk.peek(4 + off)),
+			outLen:  jsNumber(stk.peek(5 + off)),
+		}
+		if op != vm.DELEGATECALL && op != vm.STATICCALL {
+			call.Value = "0x" + stk.peek(2).Text(16)
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	// Calls made to plain accounts don't reveal it, their gas is left unreported.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.top().gas, t.top().hasGas = gas, true
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.top().Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.pop()
+
+		if call.Type == vm.CREATE.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			call.GasUsed = jsHex(int64(call.gasIn) - int64(call.gasCost) - int64(gas))
+
+			if ret := stk.peek(0); ret.Sign() != 0 {
+				addr := common.BigToAddress(ret)
+				call.To = hexutil.Encode(addr.Bytes())
+				call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.hasGas {
+			// If the call was a contract call, retrieve the gas usage and output
+			call.GasUsed = jsHex(int64(call.gasIn) - int64(call.gasCost) + int64(call.gas) - int64(gas))
+
+			if ret := stk.peek(0); ret.Sign() != 0 {
+				call.Output = hexutil.Encode(mem.slice(jsInt(call.outOff), jsInt(call.outOff+call.outLen)))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		if call.hasGas {
+			call.Gas = "0x" + strconv.FormatUint(call.gas, 16)
+		}
+		// Inject the call into the previous one
+		t.top().Calls = append(t.top().Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err == nil {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault handles the failure of the innermost call.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.top().Error != "" {
+		return
+	}
+	// Pop off the just failed call, consuming all its available gas
+	call := t.pop()
+	call.Error = err.Error()
+
+	if call.hasGas {
+		call.Gas = "0x" + strconv.FormatUint(call.gas, 16)
+		call.GasUsed = call.Gas
+	}
+	// Flatten the failed call into its parent, or leave it in the stack if the
+	// last call failed too
+	if len(t.callstack) > 0 {
+		t.top().Calls = append(t.top().Calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// GetResult returns the JSON encoded call tree of the transaction, along with
+// any error that occurred during the tracing.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	result := &callFrame{
+		Type:    t.kind(),
+		From:    hexutil.Encode(t.from.Bytes()),
+		To:      hexutil.Encode(t.to.Bytes()),
+		Value:   "0x" + t.value.Text(16),
+		Gas:     "0x" + strconv.FormatUint(t.gas, 16),
+		GasUsed: "0x" + strconv.FormatUint(t.gasUsed, 16),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.execErr != nil {
+		result.Error = t.execErr.Error()
+	}
+	if result.Error != "" {
+		result.Output = ""
+	}
+	res, err := encodeJSON(result)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.err
+}
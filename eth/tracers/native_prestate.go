@@ -0,0 +1,181 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// errNoPrestate is returned if the prestate of a transaction is requested but
+// no code was executed, so the state was never accessed.
+var errNoPrestate = errors.New("no state accessed by the transaction")
+
+// prestateAccount is the state of an account prior to a transaction.
+type prestateAccount struct {
+	balance *big.Int
+	nonce   int64
+	code    []byte
+	keys    []common.Hash               // Storage slots in the order they were accessed
+	storage map[common.Hash]common.Hash // Non-empty storage slots
+}
+
+// prestateTracer is a native implementation of the JavaScript prestateTracer,
+// gathering the state accessed by a transaction prior to its execution. Its
+// output is identical to the one of the JavaScript version.
+type prestateTracer struct {
+	txContext
+	interrupter
+
+	db       vm.StateDB                          // State database of the last traced step
+	addrs    []common.Address                    // Accounts in the order they were accessed
+	accounts map[common.Address]*prestateAccount // Accounts accessed by the transaction
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer() *prestateTracer {
+	return new(prestateTracer)
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.accounts[addr]; ok {
+		return
+	}
+	t.addrs = append(t.addrs, addr)
+	t.accounts[addr] = &prestateAccount{
+		balance: new(big.Int).Set(t.db.GetBalance(addr)),
+		nonce:   int64(t.db.GetNonce(addr)),
+		code:    t.db.GetCode(addr),
+		storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate. Empty slots are not recorded, so they are looked up again on
+// every access.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+
+	account := t.accounts[addr]
+	if _, ok := account.storage[key]; ok {
+		return
+	}
+	if value := t.db.GetState(addr, key); value != (common.Hash{}) {
+		account.keys = append(account.keys, key)
+		account.storage[key] = value
+	}
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.halted() {
+		return nil
+	}
+	t.db = env.StateDB
+
+	// Add the current account if we just started tracing. Its balance will
+	// potentially be wrong here, since this will include the value sent along
+	// with the message. We fix that when assembling the result.
+	if t.accounts == nil {
+		t.accounts = make(map[common.Address]*prestateAccount)
+		t.lookupAccount(contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	stk := &stackWrapper{stack}
+
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(stk.peek(0)))
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(stk.peek(1)))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(stk.peek(0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// GetResult returns the JSON encoded prestate of the transaction, along with any
+// error that occurred during the tracing.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.accounts == nil {
+		return nil, errNoPrestate
+	}
+	// At this point, we need to deduct the value from the outer transaction, and
+	// move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	from, to := t.accounts[t.from], t.accounts[t.to]
+	fromBal, toBal := from.balance, to.balance
+
+	to.balance = new(big.Int).Sub(toBal, t.value)
+	from.balance = new(big.Int).Add(fromBal, t.value)
+
+	// Decrement the caller's nonce, and remove empty create targets. We can blindly
+	// delete the contract prestate, as any existing state would have caused the
+	// transaction to be rejected as invalid in the first place.
+	from.nonce--
+	if t.create {
+		delete(t.accounts, t.to)
+	}
+	// Assemble the allocations in the order they were accessed
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for _, addr := range t.addrs {
+		account, ok := t.accounts[addr]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + hexutil.Encode(addr.Bytes()) + `":{`)
+		buf.WriteString(`"balance":"0x` + account.balance.Text(16) + `",`)
+		buf.WriteString(`"nonce":` + strconv.FormatInt(account.nonce, 10) + `,`)
+		buf.WriteString(`"code":"` + hexutil.Encode(account.code) + `",`)
+		buf.WriteString(`"storage":{`)
+		for i, key := range account.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			value := account.storage[key]
+			buf.WriteString(`"` + hexutil.Encode(key.Bytes()) + `":"` + hexutil.Encode(value.Bytes()) + `"`)
+		}
+		buf.WriteString(`}}`)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), t.err
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// timeField matches the execution time reported by the call tracers, the only
+// part of their output expected to differ between runs.
+var timeField = regexp.MustCompile(`"time":"[^"]*",?`)
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the native tracers produce the same output as their JavaScript
+// counterparts.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for name := range natives {
+		for _, file := range files {
+			if !strings.HasPrefix(file.Name(), "call_tracer_") {
+				continue
+			}
+			name, file := name, file // capture range variables
+			t.Run(name+"/"+camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+				t.Parallel()
+
+				blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+				if err != nil {
+					t.Fatalf("failed to read testcase: %v", err)
+				}
+				test := new(callTracerTest)
+				if err := json.Unmarshal(blob, test); err != nil {
+					t.Fatalf("failed to parse testcase: %v", err)
+				}
+				// Trace the transaction with both the JavaScript and the native tracer
+				jst, err := New(name)
+				if err != nil {
+					t.Fatalf("failed to create JavaScript tracer: %v", err)
+				}
+				if err := runTracerTest(test, jst); err != nil {
+					t.Fatalf("failed to execute transaction: %v", err)
+				}
+				want, err := jst.GetResult()
+				if err != nil {
+					t.Fatalf("failed to retrieve JavaScript trace result: %v", err)
+				}
+				tracer, err := NewResultTracer(name)
+				if err != nil {
+					t.Fatalf("failed to create native tracer: %v", err)
+				}
+				if _, ok := tracer.(*Tracer); ok {
+					t.Fatalf("JavaScript tracer selected instead of the native one")
+				}
+				if err := runTracerTest(test, tracer); err != nil {
+					t.Fatalf("failed to execute transaction: %v", err)
+				}
+				have, err := tracer.GetResult()
+				if err != nil {
+					t.Fatalf("failed to retrieve native trace result: %v", err)
+				}
+				// The outputs must be identical, apart from the execution time
+				if h, w := timeField.ReplaceAll(have, nil), timeField.ReplaceAll(want, nil); string(h) != string(w) {
+					t.Fatalf("trace mismatch:\nhave %s\nwant %s", have, want)
+				}
+			})
+		}
+	}
+}
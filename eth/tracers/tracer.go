@@ -52,6 +52,17 @@ func makeSlice(ptr unsafe.Pointer, size uint) []byte {
 	return *(*[]byte)(unsafe.Pointer(&sl))
 }
 
+// fromHex converts a hex string passed in from JavaScript into a byte slice of
+// the given size. Hex strings produced by bigInt's toString(16) carry no prefix,
+// so the custom hex prefixes are only accepted on full length strings, lest the
+// leading digits of an unprefixed value be mistaken for one.
+func fromHex(s string, size int) []byte {
+	if len(s) == 2*size+2 {
+		s = hexutil.CPToHex(s)
+	}
+	return common.FromHex(s)
+}
+
 // popSlice pops a buffer off the JavaScript stack and returns it as a slice.
 func popSlice(ctx *duktape.Context) []byte {
 	blob := common.CopyBytes(makeSlice(ctx.GetBuffer(-1)))
@@ -334,7 +345,7 @@ func New(code string) (*Tracer, error) {
 		if ptr, size := ctx.GetBuffer(-1); ptr != nil {
 			word = common.BytesToHash(makeSlice(ptr, size))
 		} else {
-			word = common.HexToHash(ctx.GetString(-1))
+			word = common.BytesToHash(fromHex(ctx.GetString(-1), common.HashLength))
 		}
 		ctx.Pop()
 		copy(makeSlice(ctx.PushFixedBuffer(32), 32), word[:])
@@ -345,7 +356,7 @@ func New(code string) (*Tracer, error) {
 		if ptr, size := ctx.GetBuffer(-1); ptr != nil {
 			addr = common.BytesToAddress(makeSlice(ptr, size))
 		} else {
-			addr = common.HexToAddress(ctx.GetString(-1))
+			addr = common.BytesToAddress(fromHex(ctx.GetString(-1), common.AddressLength))
 		}
 		ctx.Pop()
 		copy(makeSlice(ctx.PushFixedBuffer(20), 20), addr[:])
@@ -356,7 +367,7 @@ func New(code string) (*Tracer, error) {
 		if ptr, size := ctx.GetBuffer(-2); ptr != nil {
 			from = common.BytesToAddress(makeSlice(ptr, size))
 		} else {
-			from = common.HexToAddress(ctx.GetString(-2))
+			from = common.BytesToAddress(fromHex(ctx.GetString(-2), common.AddressLength))
 		}
 		nonce := uint64(ctx.GetInt(-1))
 		ctx.Pop2()
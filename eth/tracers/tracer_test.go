@@ -21,11 +21,13 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/params"
 )
 
@@ -101,6 +103,42 @@ func TestOpcodes(t *testing.T) {
 	}
 }
 
+// Tests that hex strings passed to the helpers keep their leading digits when
+// they look like a custom hex prefix, as produced by bigInt's toString(16).
+func TestHexConversions(t *testing.T) {
+	addr := "c2" + strings.Repeat("ab", 19)
+	word := "c1" + strings.Repeat("cd", 31)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`toHex(toAddress("` + addr + `"))`, "0x" + addr},
+		{`toHex(toAddress("0x` + addr + `"))`, "0x" + addr},
+		{`toHex(toAddress("c2` + addr + `"))`, "0x" + addr},
+		{`toHex(toWord("` + word + `"))`, "0x" + word},
+		{`toHex(toWord("c0` + word + `"))`, "0x" + word},
+		{`toHex(toWord(bigInt("12648430").toString(16)))`, "0x" + strings.Repeat("00", 29) + "c0ffee"},
+		{`toHex(toContract("` + addr + `", 0))`, crypto.CreateAddress(common.BytesToAddress(common.Hex2Bytes(addr)), 0).Hex()},
+	}
+	for _, tt := range tests {
+		tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return " + tt.expr + "; }}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret, err := runTrace(tracer)
+		if err != nil {
+			t.Fatalf("%s: trace failed: %v", tt.expr, err)
+		}
+		var have string
+		if err := json.Unmarshal(ret, &have); err != nil {
+			t.Fatalf("%s: invalid result %s: %v", tt.expr, ret, err)
+		}
+		if !strings.EqualFold(have, tt.want) {
+			t.Errorf("%s: have %s, want %s", tt.expr, have, tt.want)
+		}
+	}
+}
+
 func TestHalt(t *testing.T) {
 	t.Skip("duktape doesn't support abortion")
 
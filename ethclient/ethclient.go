@@ -27,6 +27,7 @@ import (
 	ethereum "github.com/eeefan/dpeth"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/rlp"
 	"github.com/eeefan/dpeth/rpc"
@@ -50,6 +51,16 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	return NewClient(c), nil
 }
 
+// DialWithAuth connects a client to the given URL, authenticating HTTP requests
+// and websocket handshakes with the credentials produced by auth.
+func DialWithAuth(ctx context.Context, rawurl string, auth rpc.HTTPAuth) (*Client, error) {
+	c, err := rpc.DialContextWithAuth(ctx, rawurl, auth)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
 // NewClient creates a client that uses the given RPC client.
 func NewClient(c *rpc.Client) *Client {
 	return &Client{c}
@@ -307,6 +318,30 @@ func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 	return ec.c.EthSubscribe(ctx, ch, "newHeads")
 }
 
+// SubscribeAlienNewLoop subscribes to notifications about new loops of the alien
+// consensus engine, each carrying the signer queue of the loop.
+func (ec *Client) SubscribeAlienNewLoop(ctx context.Context, ch chan<- alientypes.NewLoopEvent) (ethereum.Subscription, error) {
+	return ec.c.Subscribe(ctx, "alien", ch, "newLoop")
+}
+
+// SubscribeAlienSignerQueueChanged subscribes to notifications about changes of
+// the alien signer queue.
+func (ec *Client) SubscribeAlienSignerQueueChanged(ctx context.Context, ch chan<- alientypes.SignerQueueChangedEvent) (ethereum.Subscription, error) {
+	return ec.c.Subscribe(ctx, "alien", ch, "signerQueueChanged")
+}
+
+// SubscribeAlienAdminAction subscribes to notifications about changes made by
+// the alien signer admin, such as updates of the candidate signers.
+func (ec *Client) SubscribeAlienAdminAction(ctx context.Context, ch chan<- alientypes.AdminActionEvent) (ethereum.Subscription, error) {
+	return ec.c.Subscribe(ctx, "alien", ch, "adminAction")
+}
+
+// SubscribeAlienPunished subscribes to notifications about alien signers being
+// punished for missing their slots.
+func (ec *Client) SubscribeAlienPunished(ctx context.Context, ch chan<- alientypes.PunishedEvent) (ethereum.Subscription, error) {
+	return ec.c.Subscribe(ctx, "alien", ch, "punished")
+}
+
 // State Access
 
 // NetworkID returns the network ID (also known as the chain ID) for this chain.
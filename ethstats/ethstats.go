@@ -33,6 +33,7 @@ import (
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/mclock"
 	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/consensus/alien"
 	"github.com/eeefan/dpeth/core"
 	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/eth"
@@ -40,6 +41,7 @@ import (
 	"github.com/eeefan/dpeth/les"
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/p2p"
+	"github.com/eeefan/dpeth/params"
 	"github.com/eeefan/dpeth/rpc"
 	"golang.org/x/net/websocket"
 )
@@ -358,6 +360,15 @@ type nodeInfo struct {
 	OsVer    string `json:"os_v"`
 	Client   string `json:"client"`
 	History  bool   `json:"canUpdateHistory"`
+
+	Alien *alienNodeInfo `json:"alien,omitempty"` // Consensus details if the chain is sealed by alien
+}
+
+// alienNodeInfo is the delegated-proof-of-stake metainformation about a node.
+type alienNodeInfo struct {
+	Period         uint64         `json:"period"`
+	MaxSignerCount uint64         `json:"maxSignerCount"`
+	Signer         common.Address `json:"signer"`
 }
 
 // authMsg is the authentication infos needed to login to a monitoring server.
@@ -396,6 +407,14 @@ func (s *Service) login(conn *websocket.Conn) error {
 		},
 		Secret: s.pass,
 	}
+	if engine, ok := s.engine.(*alien.Alien); ok {
+		config := s.chainConfig().Alien
+		auth.Info.Alien = &alienNodeInfo{
+			Period:         config.Period,
+			MaxSignerCount: config.MaxSignerCount,
+			Signer:         engine.Signer(),
+		}
+	}
 	login := map[string][]interface{}{
 		"emit": {"hello", auth},
 	}
@@ -481,6 +500,16 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+
+	Alien *alienBlockStats `json:"alien,omitempty"` // Consensus fields if the block is sealed by alien
+}
+
+// alienBlockStats is the delegated-proof-of-stake information to report about
+// individual blocks.
+type alienBlockStats struct {
+	SignerQueue   []common.Address `json:"signerQueue"`
+	LoopStartTime uint64           `json:"loopStartTime"`
+	SignerMissing []common.Address `json:"signerMissing"`
 }
 
 // txStats is the information to report about individual transactions.
@@ -553,6 +582,19 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 	// Assemble and return the block stats
 	author, _ := s.engine.Author(header)
 
+	var alienStats *alienBlockStats
+	if engine, ok := s.engine.(*alien.Alien); ok && header.Number.Sign() > 0 {
+		if extra, err := engine.HeaderExtra(header); err == nil {
+			alienStats = &alienBlockStats{
+				SignerQueue:   extra.SignerQueue,
+				LoopStartTime: extra.LoopStartTime,
+				SignerMissing: extra.SignerMissing,
+			}
+			if alienStats.SignerMissing == nil {
+				alienStats.SignerMissing = []common.Address{}
+			}
+		}
+	}
 	return &blockStats{
 		Number:     header.Number,
 		Hash:       header.Hash(),
@@ -567,6 +609,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		TxHash:     header.TxHash,
 		Root:       header.Root,
 		Uncles:     uncles,
+		Alien:      alienStats,
 	}
 }
 
@@ -670,6 +713,16 @@ type nodeStats struct {
 	Peers    int  `json:"peers"`
 	GasPrice int  `json:"gasPrice"`
 	Uptime   int  `json:"uptime"`
+
+	Alien *alienNodeStats `json:"alien,omitempty"` // Local signer details if the chain is sealed by alien
+}
+
+// alienNodeStats is the information to report about the local signer of a node
+// on a delegated-proof-of-stake chain.
+type alienNodeStats struct {
+	Signer   common.Address `json:"signer"`
+	Punished uint64         `json:"punished"` // Punished credit, only known by full nodes
+	InTurn   bool           `json:"inTurn"`   // Whether the signer is in turn for the next block
 }
 
 // reportPending retrieves various stats about the node at the networking and
@@ -708,6 +761,7 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 			GasPrice: gasprice,
 			Syncing:  syncing,
 			Uptime:   100,
+			Alien:    s.assembleAlienStats(),
 		},
 	}
 	report := map[string][]interface{}{
@@ -715,3 +769,49 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 	}
 	return websocket.JSON.Send(conn, report)
 }
+
+// chainConfig returns the configuration of the monitored chain.
+func (s *Service) chainConfig() *params.ChainConfig {
+	if s.eth != nil {
+		return s.eth.BlockChain().Config()
+	}
+	return s.les.BlockChain().Config()
+}
+
+// assembleAlienStats retrieves the consensus state of the local signer, or nil
+// if the chain is not sealed by the alien engine.
+func (s *Service) assembleAlienStats() *alienNodeStats {
+	engine, ok := s.engine.(*alien.Alien)
+	if !ok {
+		return nil
+	}
+	var head *types.Header
+	if s.eth != nil {
+		head = s.eth.BlockChain().CurrentHeader()
+	} else {
+		head = s.les.BlockChain().CurrentHeader()
+	}
+	stats := &alienNodeStats{Signer: engine.Signer()}
+	if stats.Signer == (common.Address{}) {
+		return stats
+	}
+	// The next block is due one period after the head, or right away if late
+	next := head.Time.Uint64() + s.chainConfig().Alien.Period
+	if now := uint64(time.Now().Unix()); now > next {
+		next = now
+	}
+	if signer, err := engine.SignerAt(head, next); err == nil {
+		stats.InTurn = signer == stats.Signer
+	}
+	// Punishments are tracked in the snapshots, which only full nodes maintain
+	if s.eth != nil {
+		for _, api := range engine.APIs(s.eth.BlockChain()) {
+			if api, ok := api.Service.(*alien.API); ok {
+				if snap, err := api.GetSnapshotAtHash(head.Hash()); err == nil {
+					stats.Punished = snap.Punished[stats.Signer]
+				}
+			}
+		}
+	}
+	return stats
+}
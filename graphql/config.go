@@ -0,0 +1,66 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+// DefaultConfig contains default settings for the GraphQL service.
+var DefaultConfig = Config{
+	Host:         "localhost",
+	Port:         8547,
+	Path:         "/graphql",
+	VirtualHosts: []string{"localhost"},
+	MaxDepth:     16,
+	MaxRange:     1024,
+	MaxCost:      4096,
+}
+
+// Config contains the configuration parameters of the GraphQL service.
+type Config struct {
+	// Host is the host interface on which to start the GraphQL server. If this
+	// field is empty, no GraphQL service will be started.
+	Host string `toml:",omitempty"`
+
+	// Port is the TCP port number on which to start the GraphQL server. The
+	// default zero value is valid and will pick a port number randomly (useful
+	// for ephemeral nodes).
+	Port int `toml:",omitempty"`
+
+	// Path is the HTTP path the GraphQL queries are served on.
+	Path string `toml:",omitempty"`
+
+	// Cors is the Cross-Origin Resource Sharing header to send to requesting
+	// clients. Please be aware that CORS is a browser enforced security, it's fully
+	// useless for custom HTTP clients.
+	Cors []string `toml:",omitempty"`
+
+	// VirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// requests. This is by default {'localhost'}.
+	VirtualHosts []string `toml:",omitempty"`
+
+	// MaxDepth is the maximum field nesting depth accepted in a query, limiting
+	// the amount of work a single request may cause. Zero disables the check.
+	MaxDepth int `toml:",omitempty"`
+
+	// MaxRange is the maximum number of blocks a single blocks or logs query may
+	// span. Zero disables the check.
+	MaxRange uint64 `toml:",omitempty"`
+
+	// MaxCost is the maximum number of blocks, receipt sets, transactions, account
+	// states and alien snapshots a single request may look up across all of its
+	// fields, aliased ones included.
+	// Zero disables the check.
+	MaxCost uint64 `toml:",omitempty"`
+}
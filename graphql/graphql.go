@@ -0,0 +1,1072 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the chain, state and alien
+// consensus data of a node.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/consensus/alien"
+	"github.com/eeefan/dpeth/core/rawdb"
+	"github.com/eeefan/dpeth/core/state"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/internal/ethapi"
+	"github.com/eeefan/dpeth/rpc"
+)
+
+var (
+	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errBlockSelector  = errors.New("only one of number or hash may be specified")
+	errUnknownBlock   = errors.New("unknown block")
+)
+
+// SnapshotBackend retrieves alien consensus snapshots. It is implemented by the
+// API the alien engine exposes.
+type SnapshotBackend interface {
+	GetSnapshotAtHash(hash common.Hash) (*alien.Snapshot, error)
+}
+
+// AlienSnapshots returns the snapshot backend of the given consensus engine, or
+// nil if the chain is not sealed by the alien engine.
+func AlienSnapshots(engine consensus.Engine, chain consensus.ChainReader) SnapshotBackend {
+	for _, api := range engine.APIs(chain) {
+		if snapshots, ok := api.Service.(*alien.API); ok {
+			return snapshots
+		}
+	}
+	return nil
+}
+
+// Account represents an Ethereum account at a particular block.
+type Account struct {
+	backend     ethapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+// getState fetches the StateDB object for an account.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	if err := chargeQuery(ctx); err != nil {
+		return nil, err
+	}
+	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if state == nil && err == nil {
+		err = errUnknownBlock
+	}
+	return state, err
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*state.GetBalance(a.address)), state.Error()
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(state.GetNonce(a.address)), state.Error()
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(state.GetCode(a.address)), state.Error()
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return state.GetState(a.address, args.Slot), state.Error()
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:     l.backend,
+		address:     l.log.Address,
+		blockNumber: args.Number(),
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction represents an Ethereum transaction. The backend and hash are
+// mandatory, all other fields are lazily populated.
+type Transaction struct {
+	backend   ethapi.Backend
+	snapshots SnapshotBackend
+	hash      common.Hash
+	tx        *types.Transaction
+	block     *Block
+	index     uint64
+	lock      sync.Mutex // Protects the lazily resolved fields against concurrent resolvers
+}
+
+// resolve returns the internal transaction object, fetching it if needed.
+func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.tx == nil {
+		if err := chargeQuery(ctx); err != nil {
+			return nil, err
+		}
+		tx, blockHash, _, index := rawdb.ReadTransaction(t.backend.ChainDb(), t.hash)
+		if tx != nil {
+			t.tx = tx
+			t.block = &Block{
+				backend:   t.backend,
+				snapshots: t.snapshots,
+				hash:      blockHash,
+			}
+			t.index = index
+		} else {
+			t.tx = t.backend.GetPoolTransaction(t.hash)
+		}
+	}
+	return t.tx, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.hash
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(tx.Data()), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Gas()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.GasPrice()), nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.Value()), nil
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Nonce()), nil
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	to := tx.To()
+	if to == nil {
+		return nil, nil
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     *to,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     from,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) Index(ctx context.Context) (*int32, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	index := int32(t.index)
+	return &index, nil
+}
+
+// getReceipt returns the receipt associated with this transaction, if any.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(receipts)) <= t.index {
+		return nil, nil
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.Status)
+	return &ret, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.GasUsed)
+	return &ret, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.CumulativeGasUsed)
+	return &ret, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     receipt.ContractAddress,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     t.backend,
+			transaction: t,
+			log:         log,
+		})
+	}
+	return &ret, nil
+}
+
+// Block represents an Ethereum block. The backend and either num or hash are
+// mandatory, all other fields are lazily populated.
+type Block struct {
+	backend   ethapi.Backend
+	snapshots SnapshotBackend
+	num       *rpc.BlockNumber
+	hash      common.Hash
+	block     *types.Block
+	receipts  []*types.Receipt
+	lock      sync.Mutex // Protects the lazily resolved fields against concurrent resolvers
+}
+
+// resolve returns the internal Block object representing this block, fetching
+// it if necessary.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.resolveLocked(ctx)
+}
+
+// resolveLocked is the implementation of resolve, expecting the lock to be held.
+func (b *Block) resolveLocked(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	if err := chargeQuery(ctx); err != nil {
+		return nil, err
+	}
+	var err error
+	switch {
+	case b.hash != (common.Hash{}):
+		b.block, err = b.backend.GetBlock(ctx, b.hash)
+	case b.num != nil:
+		b.block, err = b.backend.BlockByNumber(ctx, *b.num)
+	default:
+		return nil, errBlockInvariant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.block == nil {
+		return nil, errUnknownBlock
+	}
+	b.hash = b.block.Hash()
+	return b.block, nil
+}
+
+// resolveReceipts returns the list of receipts for this block, fetching them
+// if necessary.
+func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.receipts == nil {
+		block, err := b.resolveLocked(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := chargeQuery(ctx); err != nil {
+			return nil, err
+		}
+		receipts, err := b.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = []*types.Receipt(receipts)
+	}
+	return b.receipts, nil
+}
+
+func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.NumberU64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	if _, err := b.resolve(ctx); err != nil {
+		return common.Hash{}, err
+	}
+	return b.hash, nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block.NumberU64() == 0 {
+		return nil, err
+	}
+	return &Block{
+		backend:   b.backend,
+		snapshots: b.snapshots,
+		hash:      block.ParentHash(),
+	}, nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	nonce := block.Header().Nonce
+	return hexutil.Bytes(nonce[:]), nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.TxHash(), nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int32(len(block.Transactions())), nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Root(), nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.ReceiptHash(), nil
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     block.Coinbase(),
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(block.Extra()), nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.GasLimit()), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.GasUsed()), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*block.Time()), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(block.Bloom().Bytes()), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.MixDigest(), nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*block.Difficulty()), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	td := b.backend.GetTd(block.Hash())
+	if td == nil {
+		return hexutil.Big{}, fmt.Errorf("total difficulty not found for block %x", block.Hash())
+	}
+	return hexutil.Big(*td), nil
+}
+
+func (b *Block) Transactions(ctx context.Context) ([]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		ret = append(ret, &Transaction{
+			backend:   b.backend,
+			snapshots: b.snapshots,
+			hash:      tx.Hash(),
+			tx:        tx,
+			block:     b,
+			index:     uint64(i),
+		})
+	}
+	return ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	tx := txs[args.Index]
+	return &Transaction{
+		backend:   b.backend,
+		snapshots: b.snapshots,
+		hash:      tx.Hash(),
+		tx:        tx,
+		block:     b,
+		index:     uint64(args.Index),
+	}, nil
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside
+// a block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+
+	// The Topic list restricts matches to particular event topics. Each event has a list
+	// of topics. Topics matches a prefix of that list. An empty element slice matches any
+	// topic. Non-empty elements represent an alternative that matches any of the
+	// contained topics.
+	//
+	// Examples:
+	// {} or nil          matches any topic list
+	// {{A}}              matches topic A in first position
+	// {{}, {B}}          matches any topic in first position, B in second position
+	// {{A}, {B}}         matches topic A in first position, B in second position
+	// {{A, B}}, {C, D}}  matches topic (A OR B) in first position, (C OR D) in second position
+	Topics *[][]common.Hash
+}
+
+// logs returns the logs of this block matching the given criteria.
+func (b *Block) logs(ctx context.Context, addresses []common.Address, topics [][]common.Hash) ([]*Log, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !bloomFilter(block.Bloom(), addresses, topics) {
+		return []*Log{}, nil
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*Log
+	for i, receipt := range receipts {
+		if i >= len(block.Transactions()) {
+			break
+		}
+		var tx *Transaction
+		for _, log := range receipt.Logs {
+			if !matchLog(log, addresses, topics) {
+				continue
+			}
+			if tx == nil {
+				tx = &Transaction{
+					backend:   b.backend,
+					snapshots: b.snapshots,
+					hash:      block.Transactions()[i].Hash(),
+					tx:        block.Transactions()[i],
+					block:     b,
+					index:     uint64(i),
+				}
+			}
+			ret = append(ret, &Log{
+				backend:     b.backend,
+				transaction: tx,
+				log:         log,
+			})
+		}
+	}
+	if ret == nil {
+		ret = []*Log{}
+	}
+	return ret, nil
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	return b.logs(ctx, addresses, topics)
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     args.Address,
+		blockNumber: rpc.BlockNumber(block.NumberU64()),
+	}, nil
+}
+
+func (b *Block) AlienSnapshot(ctx context.Context) (*AlienSnapshot, error) {
+	if b.snapshots == nil {
+		return nil, nil
+	}
+	if _, err := b.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if err := chargeQuery(ctx); err != nil {
+		return nil, err
+	}
+	snap, err := b.snapshots.GetSnapshotAtHash(b.hash)
+	if err != nil {
+		return nil, err
+	}
+	return &AlienSnapshot{snap: snap}, nil
+}
+
+// PunishedSigner represents a signer along with its punishment credit.
+type PunishedSigner struct {
+	address common.Address
+	credit  uint64
+}
+
+func (p *PunishedSigner) Address(ctx context.Context) common.Address {
+	return p.address
+}
+
+func (p *PunishedSigner) Credit(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(p.credit)
+}
+
+// AlienSnapshot represents the alien consensus snapshot at a particular block.
+type AlienSnapshot struct {
+	snap *alien.Snapshot
+}
+
+func (s *AlienSnapshot) Number(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.Number)
+}
+
+func (s *AlienSnapshot) Hash(ctx context.Context) common.Hash {
+	return s.snap.Hash
+}
+
+func (s *AlienSnapshot) Period(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.Period)
+}
+
+func (s *AlienSnapshot) LoopStartTime(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.LoopStartTime)
+}
+
+func (s *AlienSnapshot) Signers(ctx context.Context) []common.Address {
+	signers := make([]common.Address, 0, len(s.snap.Signers))
+	for _, signer := range s.snap.Signers {
+		signers = append(signers, *signer)
+	}
+	return signers
+}
+
+func (s *AlienSnapshot) CandidateSigners(ctx context.Context) []common.Address {
+	return append([]common.Address{}, s.snap.CandidateSigners...)
+}
+
+func (s *AlienSnapshot) Punished(ctx context.Context) []*PunishedSigner {
+	punished := make([]*PunishedSigner, 0, len(s.snap.Punished))
+	for signer, credit := range s.snap.Punished {
+		punished = append(punished, &PunishedSigner{address: signer, credit: credit})
+	}
+	sort.Slice(punished, func(i, j int) bool {
+		return bytes.Compare(punished[i].address[:], punished[j].address[:]) < 0
+	})
+	return punished
+}
+
+func (s *AlienSnapshot) SignerAdmin(ctx context.Context) common.Address {
+	return s.snap.SignerAdmin
+}
+
+func (s *AlienSnapshot) PerBlockReward(ctx context.Context) hexutil.Big {
+	if s.snap.PerBlockReward == nil {
+		return hexutil.Big{}
+	}
+	return hexutil.Big(*s.snap.PerBlockReward)
+}
+
+func (s *AlienSnapshot) MinerRewardRatio(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.MinerRewardRatio)
+}
+
+func (s *AlienSnapshot) MinVoterBalance(ctx context.Context) *hexutil.Big {
+	if s.snap.MinVB == nil {
+		return nil
+	}
+	balance := hexutil.Big(*s.snap.MinVB)
+	return &balance
+}
+
+// BlockNumberArgs encapsulates arguments to accessors that specify a block number.
+type BlockNumberArgs struct {
+	Block *hexutil.Uint64
+}
+
+// Number returns the provided block number, or rpc.LatestBlockNumber if none
+// was provided.
+func (a BlockNumberArgs) Number() rpc.BlockNumber {
+	if a.Block != nil {
+		return rpc.BlockNumber(*a.Block)
+	}
+	return rpc.LatestBlockNumber
+}
+
+// queryCost tracks the lookups a single request makes against the backend, each
+// block, receipt set, transaction, account state and alien snapshot counting as
+// one, aliased fields included.
+type queryCost struct {
+	used  uint64 // Number of lookups made so far, accessed atomically
+	limit uint64 // Maximum number of lookups the request may make
+}
+
+// queryCostKey is the context key under which the cost of a request is stored.
+type queryCostKey struct{}
+
+// withQueryCost attaches a fresh cost budget of the given limit to the context.
+// Zero disables the check.
+func withQueryCost(ctx context.Context, limit uint64) context.Context {
+	if limit == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryCostKey{}, &queryCost{limit: limit})
+}
+
+// chargeQuery accounts a backend lookup to the request of the context, failing
+// once the request exceeds its cost limit.
+func chargeQuery(ctx context.Context) error {
+	cost, ok := ctx.Value(queryCostKey{}).(*queryCost)
+	if !ok {
+		return nil
+	}
+	if atomic.AddUint64(&cost.used, 1) > cost.limit {
+		return fmt.Errorf("query too expensive (>%d lookups)", cost.limit)
+	}
+	return nil
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend   ethapi.Backend
+	snapshots SnapshotBackend
+	maxRange  uint64
+}
+
+// newBlock creates a lazily resolved block by number, or by hash if set.
+func (r *Resolver) newBlock(number rpc.BlockNumber, hash *common.Hash) *Block {
+	block := &Block{
+		backend:   r.backend,
+		snapshots: r.snapshots,
+	}
+	if hash != nil {
+		block.hash = *hash
+	} else {
+		block.num = &number
+	}
+	return block
+}
+
+// checkRange rejects block ranges exceeding the configured query limit.
+func (r *Resolver) checkRange(from, to uint64) error {
+	if r.maxRange > 0 && to >= from && to-from >= r.maxRange {
+		return fmt.Errorf("block range too large (%d>%d)", to-from+1, r.maxRange)
+	}
+	return nil
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *hexutil.Uint64
+	Hash   *common.Hash
+}) (*Block, error) {
+	if args.Number != nil && args.Hash != nil {
+		return nil, errBlockSelector
+	}
+	number := rpc.LatestBlockNumber
+	if args.Number != nil {
+		number = rpc.BlockNumber(*args.Number)
+	}
+	block := r.newBlock(number, args.Hash)
+
+	// Missing blocks are reported as null instead of as an error
+	if _, err := block.resolve(ctx); err != nil {
+		if err == errUnknownBlock {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From hexutil.Uint64
+	To   *hexutil.Uint64
+}) ([]*Block, error) {
+	from := uint64(args.From)
+
+	var to uint64
+	if args.To != nil {
+		to = uint64(*args.To)
+	} else {
+		to = r.backend.CurrentBlock().NumberU64()
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if err := r.checkRange(from, to); err != nil {
+		return nil, err
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		block := r.newBlock(rpc.BlockNumber(i), nil)
+		if _, err := block.resolve(ctx); err != nil {
+			if err == errUnknownBlock {
+				break
+			}
+			return nil, err
+		}
+		ret = append(ret, block)
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	tx := &Transaction{
+		backend:   r.backend,
+		snapshots: r.snapshots,
+		hash:      args.Hash,
+	}
+	// Unknown transactions are reported as null instead of as an error
+	t, err := tx.resolve(ctx)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver object.
+type FilterCriteria struct {
+	FromBlock *hexutil.Uint64   // beginning of the queried range, nil means latest block
+	ToBlock   *hexutil.Uint64   // end of the range, nil means latest block
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+
+	// The Topic list restricts matches to particular event topics, see
+	// BlockFilterCriteria for the semantics.
+	Topics *[][]common.Hash
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+	head := r.backend.CurrentBlock().NumberU64()
+
+	from, to := head, head
+	if args.Filter.FromBlock != nil {
+		from = uint64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil {
+		to = uint64(*args.Filter.ToBlock)
+	}
+	if to > head {
+		to = head
+	}
+	if err := r.checkRange(from, to); err != nil {
+		return nil, err
+	}
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	ret := []*Log{}
+	for i := from; i <= to && i >= from; i++ {
+		logs, err := r.newBlock(rpc.BlockNumber(i), nil).logs(ctx, addresses, topics)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, logs...)
+	}
+	return ret, nil
+}
+
+func (r *Resolver) AlienSnapshot(ctx context.Context, args BlockNumberArgs) (*AlienSnapshot, error) {
+	if r.snapshots == nil {
+		return nil, nil
+	}
+	block := r.newBlock(args.Number(), nil)
+	if _, err := block.resolve(ctx); err != nil {
+		if err == errUnknownBlock {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return block.AlienSnapshot(ctx)
+}
+
+// bloomFilter reports whether the bloom of a block may contain logs matching
+// the given criteria.
+func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLog reports whether a log matches the given address and topic criteria.
+func matchLog(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if log.Address == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		match := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if log.Topics[i] == topic {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
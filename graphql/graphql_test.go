@@ -0,0 +1,267 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus/alien"
+	"github.com/eeefan/dpeth/consensus/ethash"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/rawdb"
+	"github.com/eeefan/dpeth/core/state"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/internal/ethapi"
+	"github.com/eeefan/dpeth/params"
+	"github.com/eeefan/dpeth/rpc"
+)
+
+var (
+	testBankKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testBank       = crypto.PubkeyToAddress(testBankKey.PublicKey)
+	testRecipient  = common.HexToAddress("0x0000000000000000000000000000000000000123")
+)
+
+// testBackend implements the subset of ethapi.Backend the resolvers use on top
+// of a local blockchain.
+type testBackend struct {
+	ethapi.Backend
+	db    ethdb.Database
+	chain *core.BlockChain
+}
+
+func (b *testBackend) ChainDb() ethdb.Database { return b.db }
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.chain.CurrentBlock() }
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return rawdb.ReadReceipts(b.db, hash, *rawdb.ReadHeaderNumber(b.db, hash)), nil
+}
+
+func (b *testBackend) GetTd(hash common.Hash) *big.Int { return b.chain.GetTdByHash(hash) }
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction { return nil }
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	block, _ := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(block.Root())
+	return statedb, block.Header(), err
+}
+
+// testSnapshots returns a fixed alien snapshot for every block.
+type testSnapshots struct{}
+
+func (testSnapshots) GetSnapshotAtHash(hash common.Hash) (*alien.Snapshot, error) {
+	signer := testBank
+	return &alien.Snapshot{
+		Number:           1,
+		Hash:             hash,
+		Signers:          []*common.Address{&signer},
+		CandidateSigners: []common.Address{testBank, testRecipient},
+		SignerAdmin:      testBank,
+		PerBlockReward:   big.NewInt(1000),
+		Punished:         map[common.Address]uint64{testRecipient: 100},
+	}, nil
+}
+
+// newTestHandler creates a GraphQL handler over a two block chain, the first
+// of which transfers funds from the test bank.
+func newTestHandler(t *testing.T, config Config) http.Handler {
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, block *core.BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), testRecipient, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+			block.AddTx(tx)
+		}
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	handler, err := newHandler(&config, &testBackend{db: db, chain: chain}, testSnapshots{})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	return handler
+}
+
+// query posts a GraphQL query to the handler and returns the response.
+func query(t *testing.T, handler http.Handler, q string) (data map[string]interface{}, errs []interface{}) {
+	body, _ := json.Marshal(map[string]interface{}{"query": q})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+
+	var resp struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []interface{}          `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return resp.Data, resp.Errors
+}
+
+func TestGraphQLBlockQuery(t *testing.T) {
+	handler := newTestHandler(t, DefaultConfig)
+
+	data, errs := query(t, handler, `{
+		block(number: 1) {
+			number
+			transactionCount
+			transactions { from { address } to { balance } value status gasUsed }
+			alienSnapshot { signers candidateSigners signerAdmin perBlockReward punished { address credit } }
+		}
+	}`)
+	if len(errs) > 0 {
+		t.Fatalf("query failed: %v", errs)
+	}
+	block := data["block"].(map[string]interface{})
+	if block["number"] != "0x1" || block["transactionCount"] != float64(1) {
+		t.Errorf("block mismatch: %v", block)
+	}
+	tx := block["transactions"].([]interface{})[0].(map[string]interface{})
+	if from := tx["from"].(map[string]interface{})["address"]; !strings.EqualFold(from.(string), testBank.Hex()) {
+		t.Errorf("sender mismatch: have %v, want %x", from, testBank)
+	}
+	if balance := tx["to"].(map[string]interface{})["balance"]; balance != "0x3e8" {
+		t.Errorf("recipient balance mismatch: have %v, want 0x3e8", balance)
+	}
+	if tx["status"] != "0x1" || tx["gasUsed"] != "0x5208" {
+		t.Errorf("receipt mismatch: %v", tx)
+	}
+	snap := block["alienSnapshot"].(map[string]interface{})
+	if len(snap["candidateSigners"].([]interface{})) != 2 || snap["perBlockReward"] != "0x3e8" {
+		t.Errorf("snapshot mismatch: %v", snap)
+	}
+	punished := snap["punished"].([]interface{})
+	if len(punished) != 1 || punished[0].(map[string]interface{})["credit"] != "0x64" {
+		t.Errorf("punished signers mismatch: %v", punished)
+	}
+}
+
+func TestGraphQLQueryLimits(t *testing.T) {
+	config := DefaultConfig
+	config.MaxDepth = 3
+	config.MaxRange = 2
+	handler := newTestHandler(t, config)
+
+	if _, errs := query(t, handler, `{ blocks(from: 1) { number } }`); len(errs) > 0 {
+		t.Fatalf("query within limits failed: %v", errs)
+	}
+	if _, errs := query(t, handler, `{ logs(filter: {fromBlock: 0, toBlock: 2}) { index } }`); len(errs) == 0 {
+		t.Errorf("query exceeding block range succeeded")
+	}
+	if _, errs := query(t, handler, `{ block { transactions { block { parent { number } } } } }`); len(errs) == 0 {
+		t.Errorf("query exceeding depth succeeded")
+	}
+}
+
+func TestGraphQLQueryCost(t *testing.T) {
+	config := DefaultConfig
+	config.MaxCost = 4
+	handler := newTestHandler(t, config)
+
+	if _, errs := query(t, handler, `{ blocks(from: 0) { number } }`); len(errs) > 0 {
+		t.Fatalf("query within cost limit failed: %v", errs)
+	}
+	// Aliased fields share the budget of the request, each of them within the
+	// block range limit on its own
+	if _, errs := query(t, handler, `{ a: blocks(from: 0) { number } b: blocks(from: 0) { number } }`); len(errs) == 0 {
+		t.Errorf("aliased query exceeding cost limit succeeded")
+	}
+	// Account states and alien snapshots are charged per field, like blocks
+	if _, errs := query(t, handler, `{ block(number: 1) { account(address: "`+testBank.Hex()+`") { a: balance b: transactionCount c: code } } }`); len(errs) > 0 {
+		t.Fatalf("account query within cost limit failed: %v", errs)
+	}
+	if _, errs := query(t, handler, `{ block(number: 1) { account(address: "`+testBank.Hex()+`") { a: balance b: transactionCount c: code d: balance } } }`); len(errs) == 0 {
+		t.Errorf("account query exceeding cost limit succeeded")
+	}
+	if _, errs := query(t, handler, `{ block(number: 1) { a: alienSnapshot { number } b: alienSnapshot { number } c: alienSnapshot { number } d: alienSnapshot { number } } }`); len(errs) == 0 {
+		t.Errorf("snapshot query exceeding cost limit succeeded")
+	}
+	// Every request starts with a fresh budget
+	if _, errs := query(t, handler, `{ blocks(from: 1) { number } }`); len(errs) > 0 {
+		t.Fatalf("query after expensive one failed: %v", errs)
+	}
+}
+
+// Tests that the lazily resolved fields shared between concurrently executed
+// resolvers are resolved safely, to be run with the race detector.
+func TestGraphQLConcurrentResolve(t *testing.T) {
+	handler := newTestHandler(t, DefaultConfig)
+
+	data, errs := query(t, handler, `{
+		block(number: 1) {
+			a: transactions { status gasUsed block { hash } }
+			b: transactions { status gasUsed index }
+			c: logs(filter: {}) { index }
+			hash
+			parent { hash }
+		}
+		t: transaction(hash: "`+testTxHash(t, handler).Hex()+`") {
+			a: block { number }
+			b: index
+			c: status
+		}
+	}`)
+	if len(errs) > 0 {
+		t.Fatalf("query failed: %v", errs)
+	}
+	if tx := data["t"].(map[string]interface{}); tx["b"] != float64(0) || tx["c"] != "0x1" {
+		t.Errorf("transaction mismatch: %v", tx)
+	}
+}
+
+// testTxHash returns the hash of the transaction in the first block of the
+// test chain.
+func testTxHash(t *testing.T, handler http.Handler) common.Hash {
+	data, errs := query(t, handler, `{ block(number: 1) { transactions { hash } } }`)
+	if len(errs) > 0 {
+		t.Fatalf("query failed: %v", errs)
+	}
+	tx := data["block"].(map[string]interface{})["transactions"].([]interface{})[0]
+	return common.HexToHash(tx.(map[string]interface{})["hash"].(string))
+}
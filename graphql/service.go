@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/eeefan/dpeth/internal/ethapi"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p"
+	"github.com/eeefan/dpeth/rpc"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Service encapsulates a GraphQL service.
+type Service struct {
+	config   *Config        // Configuration of the HTTP endpoint and query limits
+	auth     rpc.AuthConfig // Authentication required on the HTTP endpoint
+	handler  http.Handler   // Handler for the GraphQL queries
+	listener net.Listener   // Listening socket of the HTTP endpoint
+}
+
+// New constructs a new GraphQL service instance serving the chain of backend,
+// requiring the same authentication as the RPC endpoints. If snapshots is nil,
+// alien snapshot queries resolve to null.
+func New(config *Config, auth rpc.AuthConfig, backend ethapi.Backend, snapshots SnapshotBackend) (*Service, error) {
+	handler, err := newHandler(config, backend, snapshots)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{config: config, auth: auth, handler: handler}, nil
+}
+
+// newHandler returns an HTTP handler serving GraphQL queries over the backend,
+// limited according to the config.
+func newHandler(config *Config, backend ethapi.Backend, snapshots SnapshotBackend) (http.Handler, error) {
+	resolver := &Resolver{
+		backend:   backend,
+		snapshots: snapshots,
+		maxRange:  config.MaxRange,
+	}
+	s, err := graphql.ParseSchema(schema, resolver, graphql.MaxDepth(config.MaxDepth))
+	if err != nil {
+		return nil, err
+	}
+	handler := &relay.Handler{Schema: s}
+
+	// Give every request a fresh budget of backend lookups
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(withQueryCost(r.Context(), config.MaxCost)))
+	}), nil
+}
+
+// Protocols returns the list of protocols exported by this service.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs returns the list of APIs exported by this service.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start is called after all services have been constructed and the networking
+// layer was also initialized to spawn any goroutines required by the service.
+func (s *Service) Start(server *p2p.Server) error {
+	endpoint := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	mux := http.NewServeMux()
+	mux.Handle(s.config.Path, s.handler)
+	go (&http.Server{Handler: rpc.NewHTTPHandlerStack(mux, "graphql", s.config.Cors, s.config.VirtualHosts, s.auth)}).Serve(listener)
+
+	log.Info("GraphQL endpoint opened", "url", fmt.Sprintf("http://%s%s", listener.Addr(), s.config.Path))
+	return nil
+}
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated.
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+		log.Info("GraphQL endpoint closed", "url", fmt.Sprintf("http://%s:%d%s", s.config.Host, s.config.Port, s.config.Path))
+	}
+	return nil
+}
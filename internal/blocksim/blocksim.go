@@ -0,0 +1,97 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blocksim seals blocks of simulated chains and tests through the real
+// path of a consensus engine.
+package blocksim
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/consensus"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
+)
+
+// SignFn returns a hash signing callback of engines signing with key, which is
+// only acceptable on simulated chains holding their keys in memory.
+func SignFn(key *ecdsa.PrivateKey) func(accounts.Account, []byte) ([]byte, error) {
+	return func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	}
+}
+
+// SignTxFn returns a transaction signing callback of engines signing with key.
+func SignTxFn(key *ecdsa.PrivateKey) func(accounts.Account, *types.Transaction, *big.Int) (*types.Transaction, error) {
+	return func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+	}
+}
+
+// Seal assembles a block of txs mined by coinbase on top of parent at the given
+// time and seals it, running it through the Prepare, Finalize and Seal path of
+// the engine, which has to be authorized to sign for coinbase. The state of the
+// block is committed to the database of the chain, but the block itself is not
+// inserted. The clock of the engine is expected to be set to the time.
+func Seal(engine consensus.Engine, chain *core.BlockChain, parent *types.Block, coinbase common.Address, time uint64, txs types.Transactions) (*types.Block, error) {
+	config := chain.Config()
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent),
+		Coinbase:   coinbase,
+		Time:       new(big.Int).SetUint64(time),
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		return nil, err
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	var (
+		gaspool  = new(core.GasPool).AddGas(header.GasLimit)
+		receipts []*types.Receipt
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		receipt, _, err := core.ApplyTransaction(config, chain, &header.Coinbase, gaspool, statedb, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transaction %d: %v", i, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	block, err := engine.Finalize(chain, header, statedb, txs, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	// Write the state changes so states can be opened on top before the import
+	root, err := statedb.Commit(config.IsEIP158(header.Number))
+	if err != nil {
+		return nil, err
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		return nil, err
+	}
+	return engine.Seal(chain, block, make(chan struct{}))
+}
@@ -22,10 +22,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/eip712"
 	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
@@ -176,6 +178,91 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// RPCPoolTransaction is a pooled transaction along with whether it is executable
+// and, if not, the reason why.
+type RPCPoolTransaction struct {
+	*RPCTransaction
+	Status string `json:"status"`           // Either "pending" or "queued"
+	Reason string `json:"reason,omitempty"` // Why a queued transaction is not executable
+}
+
+// RPCNonceGap is an inclusive range of nonces missing from the pool, blocking
+// the execution of the transactions queued after it.
+type RPCNonceGap struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// RPCPoolAccount describes the state of the transactions an account has in the
+// transaction pool.
+type RPCPoolAccount struct {
+	Address   common.Address        `json:"address"`
+	Nonce     hexutil.Uint64        `json:"nonce"`     // Nonce of the account in the current state
+	Balance   *hexutil.Big          `json:"balance"`   // Balance of the account in the current state
+	Pending   []*RPCPoolTransaction `json:"pending"`   // Executable transactions ordered by nonce
+	Queued    []*RPCPoolTransaction `json:"queued"`    // Non-executable transactions ordered by nonce
+	NonceGaps []RPCNonceGap         `json:"nonceGaps"` // Missing nonces blocking queued transactions
+}
+
+// InspectAccount retrieves the pooled transactions of a single account, reporting
+// the nonce gaps in its queue and why each queued transaction is not executable.
+func (s *PublicTxPoolAPI) InspectAccount(ctx context.Context, address common.Address) (*RPCPoolAccount, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	result := &RPCPoolAccount{
+		Address:   address,
+		Nonce:     hexutil.Uint64(state.GetNonce(address)),
+		Balance:   (*hexutil.Big)(state.GetBalance(address)),
+		Pending:   []*RPCPoolTransaction{},
+		Queued:    []*RPCPoolTransaction{},
+		NonceGaps: []RPCNonceGap{},
+	}
+	pending, queue := s.b.TxPoolContent()
+
+	// Pending transactions are contiguous from the state nonce on
+	next := uint64(result.Nonce)
+	for _, tx := range pending[address] {
+		result.Pending = append(result.Pending, &RPCPoolTransaction{RPCTransaction: newRPCPendingTransaction(tx), Status: "pending"})
+		next = tx.Nonce() + 1
+	}
+	// Queued transactions are blocked by the first gap or unexecutable predecessor
+	queued := queue[address]
+	sort.Sort(types.TxByNonce(queued))
+
+	var (
+		blocker string
+		balance = state.GetBalance(address)
+	)
+	for _, tx := range queued {
+		reason := blocker
+		switch {
+		case tx.Nonce() < uint64(result.Nonce):
+			reason = fmt.Sprintf("nonce too low, state nonce is %d", result.Nonce)
+		case tx.Nonce() > next:
+			result.NonceGaps = append(result.NonceGaps, RPCNonceGap{From: hexutil.Uint64(next), To: hexutil.Uint64(tx.Nonce() - 1)})
+			reason = fmt.Sprintf("nonce gap, missing nonce %d", next)
+		case reason != "":
+		case tx.Cost().Cmp(balance) > 0:
+			// The pool checks every transaction on its own against the balance
+			reason = fmt.Sprintf("insufficient funds for gas * price + value, have %v want %v", balance, tx.Cost())
+		case tx.Gas() > header.GasLimit:
+			reason = fmt.Sprintf("gas limit %d exceeds block gas limit %d", tx.Gas(), header.GasLimit)
+		default:
+			reason = "awaiting promotion"
+		}
+		if tx.Nonce() >= next {
+			if blocker == "" && reason != "awaiting promotion" {
+				blocker = fmt.Sprintf("blocked by non-executable nonce %d", tx.Nonce())
+			}
+			next = tx.Nonce() + 1
+		}
+		result.Queued = append(result.Queued, &RPCPoolTransaction{RPCTransaction: newRPCPendingTransaction(tx), Status: "queued", Reason: reason})
+	}
+	return result, nil
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -441,6 +528,34 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	return signature, nil
 }
 
+// SignTypedData calculates an Ethereum ECDSA signature for EIP-712 typed data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The key used to calculate the signature is decrypted with the given password.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, data eip712.TypedData, addr common.Address, passwd string) (hexutil.Bytes, error) {
+	sighash, err := data.SigningHash()
+	if err != nil {
+		return nil, fmt.Errorf("invalid typed data: %v", err)
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble sign the data with the wallet
+	signature, err := wallet.SignHashWithPassphrase(account, passwd, sighash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the account that was used to create the signature.
 // Note, this function is compatible with eth_sign and personal_sign. As such it recovers
 // the address of:
@@ -1260,6 +1375,33 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an ECDSA signature for EIP-712 typed data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, data eip712.TypedData) (hexutil.Bytes, error) {
+	sighash, err := data.SigningHash()
+	if err != nil {
+		return nil, fmt.Errorf("invalid typed data: %v", err)
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the typed data hash with the wallet
+	signature, err := wallet.SignHash(account, sighash.Bytes())
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -1359,6 +1501,87 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	return common.Hash{}, fmt.Errorf("Transaction %#x not found", matchTx.Hash())
 }
 
+// replacementPrice returns the lowest gas price above old that is raised by at
+// least bump percent, as required by the pool to replace a transaction.
+func replacementPrice(old *big.Int, bump uint64) *big.Int {
+	price := new(big.Int).Mul(old, new(big.Int).SetUint64(100+bump))
+	price.Div(price, big.NewInt(100))
+
+	// Rounding may swallow the bump of tiny prices, the pool wants a strict increase
+	if price.Cmp(old) <= 0 {
+		price.Add(old, common.Big1)
+	}
+	return price
+}
+
+// poolTransaction retrieves a transaction still waiting in the pool along with
+// its sender.
+func (s *PublicTransactionPoolAPI) poolTransaction(hash common.Hash) (*types.Transaction, common.Address, error) {
+	tx := s.b.GetPoolTransaction(hash)
+	if tx == nil {
+		if tx, _, _, _ := rawdb.ReadTransaction(s.b.ChainDb(), hash); tx != nil {
+			return nil, common.Address{}, fmt.Errorf("transaction %#x already mined", hash)
+		}
+		return nil, common.Address{}, fmt.Errorf("transaction %#x not found", hash)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return tx, from, nil
+}
+
+// CancelTransaction replaces a pooled transaction with an empty transfer from its
+// sender to itself, priced just high enough to be accepted by the pool. The sender
+// must be an account managed by this node.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, hash common.Hash) (common.Hash, error) {
+	tx, from, err := s.poolTransaction(hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	price := replacementPrice(tx.GasPrice(), s.b.TxPoolPriceBump())
+
+	signed, err := s.sign(from, types.NewTransaction(tx.Nonce(), from, new(big.Int), params.TxGas, price, nil))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, signed)
+}
+
+// SpeedUpTransaction replaces a pooled transaction with an identical one whose gas
+// price is raised by bump percent. If bump is omitted, the minimum bump enforced
+// by the pool is used. The sender must be an account managed by this node.
+func (s *PublicTransactionPoolAPI) SpeedUpTransaction(ctx context.Context, hash common.Hash, bump *hexutil.Uint64) (common.Hash, error) {
+	tx, from, err := s.poolTransaction(hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	percent := s.b.TxPoolPriceBump()
+	if bump != nil {
+		if uint64(*bump) < percent {
+			return common.Hash{}, fmt.Errorf("price bump %d%% below the pool minimum of %d%%", *bump, percent)
+		}
+		percent = uint64(*bump)
+	}
+	price := replacementPrice(tx.GasPrice(), percent)
+
+	var replacement *types.Transaction
+	if tx.To() == nil {
+		replacement = types.NewContractCreation(tx.Nonce(), tx.Value(), tx.Gas(), price, tx.Data())
+	} else {
+		replacement = types.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), price, tx.Data())
+	}
+	signed, err := s.sign(from, replacement)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, signed)
+}
+
 // PublicDebugAPI is the collection of Ethereum APIs exposed over the public
 // debugging endpoint.
 type PublicDebugAPI struct {
@@ -0,0 +1,290 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/keystore"
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/consensus/ethash"
+	"github.com/eeefan/dpeth/core"
+	"github.com/eeefan/dpeth/core/state"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/core/vm"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/ethdb"
+	"github.com/eeefan/dpeth/params"
+	"github.com/eeefan/dpeth/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testTo      = common.HexToAddress("0x0000000000000000000000000000000000000123")
+)
+
+// testBackend implements the subset of Backend the transaction pool APIs use on
+// top of a local blockchain and a live transaction pool.
+type testBackend struct {
+	Backend
+	db      ethdb.Database
+	chain   *core.BlockChain
+	pool    *core.TxPool
+	manager *accounts.Manager
+}
+
+// newTestBackend creates a backend over a genesis block funding the test
+// account with the given balance, whose key is unlocked in a keystore.
+func newTestBackend(t *testing.T, balance *big.Int) (*testBackend, func()) {
+	var (
+		db    = ethdb.NewMemDatabase()
+		gspec = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testAddress: {Balance: balance}}}
+	)
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	pool := core.NewTxPool(config, gspec.Config, chain)
+
+	dir, err := ioutil.TempDir("", "ethapi-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(testKey, "")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock key: %v", err)
+	}
+	backend := &testBackend{db: db, chain: chain, pool: pool, manager: accounts.NewManager(ks)}
+	return backend, func() {
+		backend.manager.Close()
+		pool.Stop()
+		chain.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func (b *testBackend) ChainDb() ethdb.Database           { return b.db }
+func (b *testBackend) AccountManager() *accounts.Manager { return b.manager }
+func (b *testBackend) ChainConfig() *params.ChainConfig  { return b.chain.Config() }
+func (b *testBackend) CurrentBlock() *types.Block        { return b.chain.CurrentBlock() }
+func (b *testBackend) TxPoolPriceBump() uint64           { return b.pool.PriceBump() }
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return b.pool.Get(hash)
+}
+
+func (b *testBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	return b.pool.AddLocal(tx)
+}
+
+func (b *testBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return b.pool.Content()
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	state, err := b.chain.State()
+	return state, b.chain.CurrentHeader(), err
+}
+
+// contentBackend serves a fixed transaction pool content instead of the one of
+// its live pool.
+type contentBackend struct {
+	*testBackend
+	pending, queued types.Transactions
+}
+
+func (b *contentBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return map[common.Address]types.Transactions{testAddress: b.pending}, map[common.Address]types.Transactions{testAddress: b.queued}
+}
+
+// signTestTx signs a transfer of the test account with the given parameters.
+func signTestTx(t *testing.T, nonce uint64, value int64, price int64) *types.Transaction {
+	tx, err := types.SignTx(types.NewTransaction(nonce, testTo, big.NewInt(value), params.TxGas, big.NewInt(price), nil), types.NewEIP155Signer(params.TestChainConfig.ChainId), testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+func TestReplacementPrice(t *testing.T) {
+	tests := []struct {
+		old  int64
+		bump uint64
+		want int64
+	}{
+		{100, 10, 110},
+		{15, 10, 16}, // rounded down from 16.5
+		{1, 10, 2},   // rounding swallows the bump
+		{0, 10, 1},   // zero priced transactions are bumped too
+		{1000, 0, 1001},
+		{1000, 100, 2000},
+	}
+	for i, tt := range tests {
+		if have := replacementPrice(big.NewInt(tt.old), tt.bump); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: replacement price of %d bumped by %d%% mismatch: have %v, want %d", i, tt.old, tt.bump, have, tt.want)
+		}
+	}
+}
+
+func TestCancelAndSpeedUpTransaction(t *testing.T) {
+	percent := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+
+	tests := []struct {
+		cancel  bool            // Whether to cancel or speed up the pooled transaction
+		bump    *hexutil.Uint64 // Price bump requested when speeding up
+		unknown bool            // Whether to replace a transaction missing from the pool
+		price   int64           // Expected price of the replacement
+		fail    string          // Expected error, if any
+	}{
+		{cancel: true, price: 110},
+		{price: 110},
+		{bump: percent(50), price: 150},
+		{bump: percent(5), fail: "below the pool minimum"},
+		{cancel: true, unknown: true, fail: "not found"},
+		{unknown: true, fail: "not found"},
+	}
+	for i, tt := range tests {
+		backend, teardown := newTestBackend(t, big.NewInt(params.Ether))
+		api := NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+
+		tx := signTestTx(t, 0, 1000, 100)
+		if !tt.unknown {
+			if err := backend.SendTx(context.Background(), tx); err != nil {
+				t.Fatalf("test %d: failed to pool transaction: %v", i, err)
+			}
+		}
+		var (
+			hash common.Hash
+			err  error
+		)
+		if tt.cancel {
+			hash, err = api.CancelTransaction(context.Background(), tx.Hash())
+		} else {
+			hash, err = api.SpeedUpTransaction(context.Background(), tx.Hash(), tt.bump)
+		}
+		switch {
+		case tt.fail != "":
+			if err == nil || !strings.Contains(err.Error(), tt.fail) {
+				t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.fail)
+			}
+		case err != nil:
+			t.Errorf("test %d: failed to replace transaction: %v", i, err)
+		default:
+			if backend.pool.Get(tx.Hash()) != nil {
+				t.Errorf("test %d: replaced transaction still pooled", i)
+			}
+			replacement := backend.pool.Get(hash)
+			if replacement == nil {
+				t.Fatalf("test %d: replacement not pooled", i)
+			}
+			if replacement.Nonce() != tx.Nonce() || replacement.GasPrice().Cmp(big.NewInt(tt.price)) != 0 {
+				t.Errorf("test %d: replacement nonce/price mismatch: have %d/%v, want %d/%d", i, replacement.Nonce(), replacement.GasPrice(), tx.Nonce(), tt.price)
+			}
+			wantTo, wantValue := testTo, tx.Value()
+			if tt.cancel {
+				wantTo, wantValue = testAddress, new(big.Int)
+			}
+			if *replacement.To() != wantTo || replacement.Value().Cmp(wantValue) != 0 {
+				t.Errorf("test %d: replacement transfer mismatch: have %x/%v, want %x/%v", i, *replacement.To(), replacement.Value(), wantTo, wantValue)
+			}
+		}
+		teardown()
+	}
+}
+
+func TestInspectAccount(t *testing.T) {
+	// Every test transfer costs 21000 gas at a price of 1 plus the value
+	const cost = 21000 + 9000
+
+	tests := []struct {
+		balance int64
+		pending []uint64
+		queued  []uint64
+		gaps    []RPCNonceGap
+		reasons []string
+	}{
+		// Queued transactions behind a gap, blocking the ones after them
+		{
+			balance: 10 * cost,
+			pending: []uint64{0},
+			queued:  []uint64{2, 3},
+			gaps:    []RPCNonceGap{{From: 1, To: 1}},
+			reasons: []string{"nonce gap, missing nonce 1", "blocked by non-executable nonce 2"},
+		},
+		// Queued transactions are checked against the balance one by one like the
+		// pool does, even if not affordable along with the pending ones
+		{
+			balance: cost + cost/2,
+			pending: []uint64{0},
+			queued:  []uint64{1, 2},
+			gaps:    []RPCNonceGap{},
+			reasons: []string{"awaiting promotion", "awaiting promotion"},
+		},
+		// Queued transactions costing more than the balance on their own
+		{
+			balance: cost / 2,
+			queued:  []uint64{0, 1},
+			gaps:    []RPCNonceGap{},
+			reasons: []string{"insufficient funds for gas * price + value", "blocked by non-executable nonce 0"},
+		},
+	}
+	for i, tt := range tests {
+		live, teardown := newTestBackend(t, big.NewInt(tt.balance))
+		backend := &contentBackend{testBackend: live}
+		for _, nonce := range tt.pending {
+			backend.pending = append(backend.pending, signTestTx(t, nonce, 9000, 1))
+		}
+		for _, nonce := range tt.queued {
+			backend.queued = append(backend.queued, signTestTx(t, nonce, 9000, 1))
+		}
+		result, err := NewPublicTxPoolAPI(backend).InspectAccount(context.Background(), testAddress)
+		teardown()
+		if err != nil {
+			t.Fatalf("test %d: failed to inspect account: %v", i, err)
+		}
+		if len(result.Pending) != len(tt.pending) || len(result.Queued) != len(tt.queued) {
+			t.Fatalf("test %d: transaction count mismatch: have %d/%d, want %d/%d", i, len(result.Pending), len(result.Queued), len(tt.pending), len(tt.queued))
+		}
+		if len(result.NonceGaps) != len(tt.gaps) {
+			t.Errorf("test %d: nonce gaps mismatch: have %v, want %v", i, result.NonceGaps, tt.gaps)
+		}
+		for j, gap := range tt.gaps {
+			if j < len(result.NonceGaps) && result.NonceGaps[j] != gap {
+				t.Errorf("test %d: nonce gap %d mismatch: have %v, want %v", i, j, result.NonceGaps[j], gap)
+			}
+		}
+		for j, reason := range tt.reasons {
+			if !strings.HasPrefix(result.Queued[j].Reason, reason) {
+				t.Errorf("test %d: queued transaction %d reason mismatch: have %q, want %q", i, j, result.Queued[j].Reason, reason)
+			}
+		}
+	}
+}
@@ -136,6 +136,12 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.txPool.Content()
 }
 
+// TxPoolPriceBump returns the default price bump, as replacements are validated
+// by the pools of the serving full nodes.
+func (b *LesApiBackend) TxPoolPriceBump() uint64 {
+	return core.DefaultTxPoolConfig.PriceBump
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
@@ -0,0 +1,139 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eeefan/dpeth/metrics"
+)
+
+var (
+	typeGaugeTpl           = "# TYPE %s gauge\n"
+	typeCounterTpl         = "# TYPE %s counter\n"
+	typeSummaryTpl         = "# TYPE %s summary\n"
+	keyValueTpl            = "%s %v\n"
+	keyQuantileTagValueTpl = "%s{quantile=\"%s\"} %v\n"
+)
+
+// quantiles are the percentiles reported for histograms and timers.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+// collector is a collection of byte buffers that aggregate Prometheus reports
+// for different metric types.
+type collector struct {
+	buff *bytes.Buffer
+}
+
+// newCollector creates a new Prometheus metric aggregator.
+func newCollector() *collector {
+	return &collector{
+		buff: &bytes.Buffer{},
+	}
+}
+
+func (c *collector) addCounter(name string, m metrics.Counter) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeCounterTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, m.Count()))
+}
+
+func (c *collector) addGauge(name string, m metrics.Gauge) {
+	c.writeGauge(name, m.Value())
+}
+
+func (c *collector) addGaugeFloat64(name string, m metrics.GaugeFloat64) {
+	c.writeGauge(name, m.Value())
+}
+
+func (c *collector) addMeter(name string, m metrics.Meter) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeCounterTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, m.Count()))
+}
+
+func (c *collector) addHistogram(name string, m metrics.Histogram) {
+	c.writeSummary(name, m.Percentiles(quantiles), m.Count(), m.Sum())
+}
+
+func (c *collector) addTimer(name string, m metrics.Timer) {
+	c.writeSummary(name, m.Percentiles(quantiles), m.Count(), m.Sum())
+}
+
+// addResettingTimer reports the values gathered by the timer since it was last
+// flushed. The live timer is only read, as taking a snapshot would reset it and
+// drop the values from the other reporters flushing it.
+func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer) {
+	values := m.Values()
+	if len(values) == 0 {
+		return
+	}
+	sort.Sort(metrics.Int64Slice(values))
+
+	var sum int64
+	for _, v := range values {
+		sum += v
+	}
+	qs := []float64{0.5, 0.95, 0.99}
+	ps := make([]float64, len(qs))
+	for i, q := range qs {
+		// Nearest rank, the same boundaries a timer snapshot reports
+		rank := int(math.Floor(q*float64(len(values))+0.5)) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		ps[i] = float64(values[rank])
+	}
+	c.writeSummary(name, ps, int64(len(values)), sum, qs...)
+}
+
+func (c *collector) writeGauge(name string, value interface{}) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, value))
+}
+
+// writeSummary reports the given percentile values as a summary, labeled with
+// the reported quantiles, or the default ones if none are given.
+func (c *collector) writeSummary(name string, values []float64, count int64, sum int64, qs ...float64) {
+	if len(qs) == 0 {
+		qs = quantiles
+	}
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeSummaryTpl, name))
+	for i, q := range qs {
+		c.buff.WriteString(fmt.Sprintf(keyQuantileTagValueTpl, name, strconv.FormatFloat(q, 'f', -1, 64), values[i]))
+	}
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_sum", sum))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_count", count))
+}
+
+// mutateKey converts a registry metric name into a valid Prometheus one.
+func mutateKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, key)
+}
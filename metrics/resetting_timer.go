@@ -86,9 +86,11 @@ type StandardResettingTimer struct {
 	mutex  sync.Mutex
 }
 
-// Values returns a slice with all measurements.
+// Values returns a copy of all measurements since the last snapshot.
 func (t *StandardResettingTimer) Values() []int64 {
-	return t.values
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]int64(nil), t.values...)
 }
 
 // Snapshot resets the timer and returns a read-only copy of its contents.
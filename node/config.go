@@ -18,6 +18,7 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -26,6 +27,8 @@ import (
 	"strings"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/external"
+	"github.com/eeefan/dpeth/accounts/hdwallet"
 	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/accounts/usbwallet"
 	"github.com/eeefan/dpeth/common"
@@ -33,6 +36,7 @@ import (
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/p2p"
 	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/rpc"
 )
 
 const (
@@ -85,6 +89,10 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the endpoint (IPC path or HTTP/WS URL) of an external
+	// signer, such as clef, holding keys outside of the node.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -145,6 +153,43 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCBatchItemLimit is the maximum number of requests accepted in a single
+	// batch over the HTTP and websocket RPC interfaces. Zero means no limit.
+	RPCBatchItemLimit int `toml:",omitempty"`
+
+	// RPCBatchResponseMaxSize is the maximum size in bytes of a response sent over
+	// the HTTP and websocket RPC interfaces. Zero means no limit.
+	RPCBatchResponseMaxSize int `toml:",omitempty"`
+
+	// RPCRateLimit is the number of requests per second a single remote IP may
+	// issue to any one RPC method. Zero disables rate limiting.
+	RPCRateLimit float64 `toml:",omitempty"`
+
+	// RPCRateBurst is the number of requests a remote IP may issue to a method in
+	// a burst above RPCRateLimit.
+	RPCRateBurst int `toml:",omitempty"`
+
+	// RPCJWTSecret is the path of a file holding the hex encoded HS256 secret used
+	// to verify JWT bearer tokens on the HTTP and websocket RPC interfaces. JWTs
+	// may limit the namespaces they grant access to in a space separated "scope"
+	// claim.
+	RPCJWTSecret string `toml:",omitempty"`
+
+	// RPCAuthTokens is a list of static bearer tokens accepted on the HTTP and
+	// websocket RPC interfaces, each scoped to a set of API namespaces. If either
+	// this or RPCJWTSecret is set, requests without credentials may only call the
+	// namespaces in RPCPublicModules.
+	RPCAuthTokens []rpc.AuthToken `toml:",omitempty"`
+
+	// RPCAuthTokenFile is the path of a file holding further static bearer tokens,
+	// one per line, each followed by the comma separated API namespaces it may
+	// call. Empty lines and lines starting with '#' are ignored.
+	RPCAuthTokenFile string `toml:",omitempty"`
+
+	// RPCPublicModules is the list of API namespaces callable without credentials
+	// when RPC authentication is enabled.
+	RPCPublicModules []string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -218,6 +263,76 @@ func (c *Config) WSEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
 }
 
+// RPCLimits assembles the request limits enforced on the HTTP and websocket RPC
+// interfaces from the configured values.
+func (c *Config) RPCLimits() rpc.ServerLimits {
+	return rpc.ServerLimits{
+		BatchItemLimit:       c.RPCBatchItemLimit,
+		BatchResponseMaxSize: c.RPCBatchResponseMaxSize,
+		RateLimit:            c.RPCRateLimit,
+		RateBurst:            c.RPCRateBurst,
+	}
+}
+
+// RPCAuth assembles the authentication required on the HTTP and websocket RPC
+// interfaces, loading the JWT secret and static tokens from disk if configured.
+func (c *Config) RPCAuth() (rpc.AuthConfig, error) {
+	auth := rpc.AuthConfig{
+		Tokens:        c.RPCAuthTokens,
+		PublicModules: c.RPCPublicModules,
+	}
+	if c.RPCAuthTokenFile != "" {
+		tokens, err := loadAuthTokens(c.RPCAuthTokenFile)
+		if err != nil {
+			return auth, err
+		}
+		auth.Tokens = append(append([]rpc.AuthToken{}, c.RPCAuthTokens...), tokens...)
+	}
+	if c.RPCJWTSecret != "" {
+		blob, err := ioutil.ReadFile(c.RPCJWTSecret)
+		if err != nil {
+			return auth, fmt.Errorf("failed to read JWT secret: %v", err)
+		}
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+		if err != nil {
+			return auth, fmt.Errorf("invalid JWT secret: %v", err)
+		}
+		if len(secret) < 32 {
+			return auth, fmt.Errorf("JWT secret too short: have %d bytes, want at least 32", len(secret))
+		}
+		auth.JWTSecret = secret
+	}
+	return auth, nil
+}
+
+// loadAuthTokens reads the static bearer tokens and their API namespaces from a
+// token file.
+func loadAuthTokens(path string) ([]rpc.AuthToken, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RPC auth tokens: %v", err)
+	}
+	var tokens []rpc.AuthToken
+	for i, line := range strings.Split(string(blob), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid RPC auth token on line %d: want \"<token> <api>[,<api>...]\"", i+1)
+		}
+		var modules []string
+		for _, module := range strings.Split(fields[1], ",") {
+			if module = strings.TrimSpace(module); module != "" {
+				modules = append(modules, module)
+			}
+		}
+		tokens = append(tokens, rpc.AuthToken{Token: fields[0], Modules: modules})
+	}
+	return tokens, nil
+}
+
 // DefaultWSEndpoint returns the websocket endpoint used by default.
 func DefaultWSEndpoint() string {
 	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
@@ -418,6 +533,15 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	// Assemble the account manager and supported backends
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		hdwallet.NewBackend(filepath.Join(keydir, hdwallet.DirName), scryptN, scryptP),
+	}
+	if len(conf.ExternalSigner) > 0 {
+		log.Info("Using external signer", "url", conf.ExternalSigner)
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		backends = append(backends, extapi)
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
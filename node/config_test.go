@@ -21,11 +21,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
 	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/p2p"
+	"github.com/eeefan/dpeth/rpc"
 )
 
 // Tests that datadirs can be successfully created, be them manually configured
@@ -148,3 +150,38 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that static RPC bearer tokens are loaded from the token file alongside
+// the configured ones.
+func TestRPCAuthTokenFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create token file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("# comment\n\nuser-token eth,alien\nadmin-token *\n")
+	file.Close()
+
+	config := &Config{
+		RPCAuthTokens:    []rpc.AuthToken{{Token: "static-token", Modules: []string{"net"}}},
+		RPCAuthTokenFile: file.Name(),
+	}
+	auth, err := config.RPCAuth()
+	if err != nil {
+		t.Fatalf("failed to assemble RPC auth: %v", err)
+	}
+	want := []rpc.AuthToken{
+		{Token: "static-token", Modules: []string{"net"}},
+		{Token: "user-token", Modules: []string{"eth", "alien"}},
+		{Token: "admin-token", Modules: []string{"*"}},
+	}
+	if !reflect.DeepEqual(auth.Tokens, want) {
+		t.Fatalf("tokens mismatch: have %v, want %v", auth.Tokens, want)
+	}
+	// Malformed lines must be rejected instead of granting unscoped access
+	ioutil.WriteFile(file.Name(), []byte("lonely-token\n"), 0600)
+	if _, err := config.RPCAuth(); err == nil {
+		t.Fatalf("malformed token file accepted")
+	}
+}
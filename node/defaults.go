@@ -41,6 +41,10 @@ var DefaultConfig = Config{
 	HTTPVirtualHosts: []string{"localhost"},
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
+
+	RPCBatchItemLimit:       1000,
+	RPCBatchResponseMaxSize: 25 * 1024 * 1024,
+	RPCRateBurst:            100,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   25,
@@ -57,6 +57,12 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.resolvePath(path)
 }
 
+// RPCAuth returns the authentication the node requires on its HTTP and websocket
+// RPC endpoints, for services exposing endpoints of their own.
+func (ctx *ServiceContext) RPCAuth() (rpc.AuthConfig, error) {
+	return ctx.config.RPCAuth()
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
@@ -0,0 +1,179 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p/discover"
+)
+
+// banStore is the persistent storage of the node bans, implemented on top of the
+// node database by the discovery table, or by the ban database without discovery.
+type banStore interface {
+	Bans() []discover.Ban
+	SetBan(ban discover.Ban) error
+	DeleteBan(id discover.NodeID) error
+}
+
+// banList tracks the nodes and IP addresses temporarily barred from connecting.
+// All methods are safe to call on a nil list, which bans nothing.
+type banList struct {
+	lock  sync.RWMutex
+	bans  map[discover.NodeID]discover.Ban
+	store banStore // Persistent storage of the bans, nil if there's no node database
+}
+
+// newBanList creates a ban list, loading the still active bans from the store.
+func newBanList(store banStore) *banList {
+	list := &banList{
+		bans:  make(map[discover.NodeID]discover.Ban),
+		store: store,
+	}
+	if store != nil {
+		now := time.Now()
+		for _, ban := range store.Bans() {
+			if ban.Expiry.After(now) {
+				list.bans[ban.ID] = ban
+			}
+		}
+	}
+	return list
+}
+
+// add bans the node and IP address until the given expiry, extending any ban
+// already in place.
+func (l *banList) add(ban discover.Ban) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if old, ok := l.bans[ban.ID]; ok && old.Expiry.After(ban.Expiry) {
+		ban.Expiry = old.Expiry
+	}
+	l.bans[ban.ID] = ban
+	if l.store != nil {
+		if err := l.store.SetBan(ban); err != nil {
+			log.Warn("Failed to persist node ban", "id", ban.ID, "err", err)
+		}
+	}
+}
+
+// remove lifts the ban of a node, returning whether it was banned.
+func (l *banList) remove(id discover.NodeID) bool {
+	if l == nil {
+		return false
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	ban, ok := l.bans[id]
+	delete(l.bans, id)
+	if l.store != nil {
+		if err := l.store.DeleteBan(id); err != nil {
+			log.Warn("Failed to delete node ban", "id", id, "err", err)
+		}
+	}
+	return ok && ban.Expiry.After(time.Now())
+}
+
+// bannedID returns whether the node is currently banned.
+func (l *banList) bannedID(id discover.NodeID) bool {
+	if l == nil {
+		return false
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	ban, ok := l.bans[id]
+	return ok && ban.Expiry.After(time.Now())
+}
+
+// bannedIP returns whether any of the currently banned nodes was connecting
+// from the given IP address.
+func (l *banList) bannedIP(ip net.IP) bool {
+	if l == nil || ip == nil {
+		return false
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	now := time.Now()
+	for _, ban := range l.bans {
+		if ban.IP.Equal(ip) && ban.Expiry.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// list returns the currently active bans, ordered by expiry.
+func (l *banList) list() []discover.Ban {
+	if l == nil {
+		return nil
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var (
+		now  = time.Now()
+		bans []discover.Ban
+	)
+	for _, ban := range l.bans {
+		if ban.Expiry.After(now) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Expiry.Before(bans[j].Expiry) })
+	return bans
+}
+
+// BanPeer bars the node and the IP address it is connecting from from connecting
+// for the given duration, disconnecting it if currently connected. Bans are kept
+// in the node database and survive restarts, unless it's in memory.
+//
+// Trusted nodes are still accepted, unless connecting from a banned IP address.
+func (srv *Server) BanPeer(id discover.NodeID, ip net.IP, duration time.Duration) {
+	srv.bans.add(discover.Ban{ID: id, IP: ip, Expiry: time.Now().Add(duration)})
+	srv.log.Debug("Banned node", "id", id, "ip", ip, "duration", duration)
+
+	select {
+	case srv.peerOp <- func(peers map[discover.NodeID]*Peer) {
+		if p := peers[id]; p != nil {
+			p.Disconnect(DiscUselessPeer)
+		}
+	}:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+}
+
+// Unban lifts the ban of a node, returning whether it was banned.
+func (srv *Server) Unban(id discover.NodeID) bool {
+	return srv.bans.remove(id)
+}
+
+// Bans returns the currently active node bans.
+func (srv *Server) Bans() []discover.Ban {
+	return srv.bans.list()
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/p2p/enr"
 	"github.com/eeefan/dpeth/p2p/netutil"
 )
 
@@ -34,6 +35,10 @@ const (
 	// redialing a certain node.
 	dialHistoryExpiration = 30 * time.Second
 
+	// Prioritized nodes are redialed much sooner, as losing them for long
+	// is costlier than the redundant dials.
+	prioritizedDialHistoryExpiration = 3 * time.Second
+
 	// Discovery lookups are throttled and can only run
 	// once every few seconds.
 	lookupInterval = 4 * time.Second
@@ -78,6 +83,7 @@ type dialstate struct {
 	lookupBuf     []*discover.Node // current discovery lookup results
 	randomNodes   []*discover.Node // filled from Table
 	static        map[discover.NodeID]*dialTask
+	prioritized   map[discover.NodeID]*dialTask
 	hist          *dialHistory
 
 	start     time.Time        // time when the dialer was first used
@@ -90,6 +96,7 @@ type discoverTable interface {
 	Resolve(target discover.NodeID) *discover.Node
 	Lookup(target discover.NodeID) []*discover.Node
 	ReadRandomNodes([]*discover.Node) int
+	RequestENR(*discover.Node) (*enr.Record, error)
 }
 
 // the dial history remembers recent dials.
@@ -133,6 +140,7 @@ func newDialState(static []*discover.Node, bootnodes []*discover.Node, ntab disc
 		ntab:        ntab,
 		netrestrict: netrestrict,
 		static:      make(map[discover.NodeID]*dialTask),
+		prioritized: make(map[discover.NodeID]*dialTask),
 		dialing:     make(map[discover.NodeID]connFlag),
 		bootnodes:   make([]*discover.Node, len(bootnodes)),
 		randomNodes: make([]*discover.Node, maxdyn/2),
@@ -159,6 +167,21 @@ func (s *dialstate) removeStatic(n *discover.Node) {
 	s.hist.remove(n.ID)
 }
 
+func (s *dialstate) setPrioritized(nodes []*discover.Node) {
+	prioritized := make(map[discover.NodeID]*dialTask, len(nodes))
+	for _, n := range nodes {
+		if t, ok := s.prioritized[n.ID]; ok && t.dest.IP.Equal(n.IP) && t.dest.TCP == n.TCP {
+			// Keep the task of nodes already in the set to retain their resolve state.
+			prioritized[n.ID] = t
+			continue
+		}
+		prioritized[n.ID] = &dialTask{flags: prioritizedConn, dest: n}
+		// Newly prioritized nodes shouldn't wait for an earlier dynamic dial to expire.
+		s.hist.remove(n.ID)
+	}
+	s.prioritized = prioritized
+}
+
 func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now time.Time) []task {
 	if s.start.IsZero() {
 		s.start = now
@@ -191,6 +214,19 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 	// Expire the dial history on every invocation.
 	s.hist.expire(now)
 
+	// Create dials for prioritized nodes if they are not connected. They come
+	// first and don't take up any of the dynamic dial slots.
+	for id, t := range s.prioritized {
+		err := s.checkDial(t.dest, peers)
+		switch err {
+		case errNotWhitelisted, errSelf:
+			log.Warn("Removing prioritized dial candidate", "id", t.dest.ID, "addr", &net.TCPAddr{IP: t.dest.IP, Port: int(t.dest.TCP)}, "err", err)
+			delete(s.prioritized, t.dest.ID)
+		case nil:
+			s.dialing[id] = t.flags
+			newtasks = append(newtasks, t)
+		}
+	}
 	// Create dials for static nodes if they are not connected.
 	for id, t := range s.static {
 		err := s.checkDial(t.dest, peers)
@@ -280,7 +316,11 @@ func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer)
 func (s *dialstate) taskDone(t task, now time.Time) {
 	switch t := t.(type) {
 	case *dialTask:
-		s.hist.add(t.dest.ID, now.Add(dialHistoryExpiration))
+		if t.flags&prioritizedConn != 0 {
+			s.hist.add(t.dest.ID, now.Add(prioritizedDialHistoryExpiration))
+		} else {
+			s.hist.add(t.dest.ID, now.Add(dialHistoryExpiration))
+		}
 		delete(s.dialing, t.dest.ID)
 	case *discoverTask:
 		s.lookupRunning = false
@@ -294,11 +334,18 @@ func (t *dialTask) Do(srv *Server) {
 			return
 		}
 	}
+	// Skip discovered nodes that are banned or advertise an incompatible node record
+	if t.flags&dynDialedConn != 0 && (srv.bans.bannedID(t.dest.ID) || srv.bans.bannedIP(t.dest.IP)) {
+		return
+	}
+	if t.flags&dynDialedConn != 0 && !srv.acceptsNode(t.dest) {
+		return
+	}
 	err := t.dial(srv, t.dest)
 	if err != nil {
 		log.Trace("Dial error", "task", t, "err", err)
-		// Try resolving the ID of static nodes if dialing failed.
-		if _, ok := err.(*dialError); ok && t.flags&staticDialedConn != 0 {
+		// Try resolving the ID of static and prioritized nodes if dialing failed.
+		if _, ok := err.(*dialError); ok && t.flags&(staticDialedConn|prioritizedConn) != 0 {
 			if t.resolve(srv) {
 				t.dial(srv, t.dest)
 			}
@@ -18,12 +18,14 @@ package p2p
 
 import (
 	"encoding/binary"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/p2p/enr"
 	"github.com/eeefan/dpeth/p2p/netutil"
 	"github.com/davecgh/go-spew/spew"
 )
@@ -83,6 +85,9 @@ func (t fakeTable) Close()                                   {}
 func (t fakeTable) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t fakeTable) Resolve(discover.NodeID) *discover.Node   { return nil }
 func (t fakeTable) ReadRandomNodes(buf []*discover.Node) int { return copy(buf, t) }
+func (t fakeTable) RequestENR(*discover.Node) (*enr.Record, error) {
+	return nil, errors.New("not supported")
+}
 
 // This test checks that dynamic dials are launched from discovery results.
 func TestDialStateDynDial(t *testing.T) {
@@ -559,6 +564,53 @@ func TestDialStaticAfterReset(t *testing.T) {
 	runDialTest(t, dTest)
 }
 
+// This test checks that prioritized nodes are dialed without taking up dynamic
+// dial slots and are redialed soon after losing the connection.
+func TestDialStatePrioritized(t *testing.T) {
+	state := newDialState(nil, nil, fakeTable{}, 0, nil)
+	state.setPrioritized([]*discover.Node{
+		{ID: uintID(1)},
+		{ID: uintID(2)},
+	})
+	runDialTest(t, dialtest{
+		init: state,
+		rounds: []round{
+			// Dials are launched for all prioritized nodes.
+			{
+				new: []task{
+					&dialTask{flags: prioritizedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: prioritizedConn, dest: &discover.Node{ID: uintID(2)}},
+				},
+			},
+			// Node 2 failed to connect, its history entry expires shortly.
+			{
+				peers: []*Peer{
+					{rw: &conn{flags: prioritizedConn, id: uintID(1)}},
+				},
+				done: []task{
+					&dialTask{flags: prioritizedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: prioritizedConn, dest: &discover.Node{ID: uintID(2)}},
+				},
+				new: []task{
+					&waitExpireTask{Duration: prioritizedDialHistoryExpiration},
+				},
+			},
+			// Node 2 is redialed once its entry expired.
+			{
+				peers: []*Peer{
+					{rw: &conn{flags: prioritizedConn, id: uintID(1)}},
+				},
+				done: []task{
+					&waitExpireTask{Duration: prioritizedDialHistoryExpiration},
+				},
+				new: []task{
+					&dialTask{flags: prioritizedConn, dest: &discover.Node{ID: uintID(2)}},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that past dials are not retried for some time.
 func TestDialStateCache(t *testing.T) {
 	wantStatic := []*discover.Node{
@@ -694,3 +746,6 @@ func (t *resolveMock) Close()                                   {}
 func (t *resolveMock) Bootstrap([]*discover.Node)               {}
 func (t *resolveMock) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*discover.Node) int { return 0 }
+func (t *resolveMock) RequestENR(*discover.Node) (*enr.Record, error) {
+	return nil, errors.New("not supported")
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"net"
 	"os"
 	"sync"
 	"time"
@@ -56,6 +57,7 @@ type nodeDB struct {
 var (
 	nodeDBVersionKey = []byte("version") // Version of the database to flush if changes
 	nodeDBItemPrefix = []byte("n:")      // Identifier to prefix node entries with
+	nodeDBBanPrefix  = []byte("b:")      // Identifier to prefix ban entries with (outlive the node entries)
 
 	nodeDBDiscoverRoot      = ":discover"
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
@@ -210,6 +212,99 @@ func (db *nodeDB) deleteNode(id NodeID) error {
 	return nil
 }
 
+// Ban is a temporary ban of a node and the IP address it was connecting from.
+type Ban struct {
+	ID     NodeID
+	IP     net.IP
+	Expiry time.Time
+}
+
+// banRLP is the database encoding of a ban.
+type banRLP struct {
+	IP     net.IP
+	Expiry uint64
+}
+
+// bans retrieves all the bans stored in the database, including the expired
+// ones not yet cleaned up.
+func (db *nodeDB) bans() []Ban {
+	it := db.lvl.NewIterator(util.BytesPrefix(nodeDBBanPrefix), nil)
+	defer it.Release()
+
+	var bans []Ban
+	for it.Next() {
+		var ban Ban
+		if len(it.Key()) != len(nodeDBBanPrefix)+len(ban.ID) {
+			continue
+		}
+		var enc banRLP
+		if err := rlp.DecodeBytes(it.Value(), &enc); err != nil {
+			log.Error("Failed to decode ban RLP", "err", err)
+			continue
+		}
+		copy(ban.ID[:], it.Key()[len(nodeDBBanPrefix):])
+		ban.IP, ban.Expiry = enc.IP, time.Unix(int64(enc.Expiry), 0)
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
+// updateBan inserts - potentially overwriting - a ban into the database.
+func (db *nodeDB) updateBan(ban Ban) error {
+	blob, err := rlp.EncodeToBytes(&banRLP{IP: ban.IP, Expiry: uint64(ban.Expiry.Unix())})
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(append(nodeDBBanPrefix, ban.ID[:]...), blob, nil)
+}
+
+// deleteBan removes the ban of a node from the database.
+func (db *nodeDB) deleteBan(id NodeID) error {
+	return db.lvl.Delete(append(nodeDBBanPrefix, id[:]...), nil)
+}
+
+// BanDB is the node database opened for the node bans only, for nodes running
+// without discovery, whose table would own the database otherwise.
+type BanDB struct {
+	db *nodeDB
+}
+
+// OpenBanDB opens the node database at the given path for the node bans,
+// dropping the bans that already ran out.
+func OpenBanDB(path string) (*BanDB, error) {
+	db, err := newNodeDB(path, Version, NodeID{})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, ban := range db.bans() {
+		if ban.Expiry.Before(now) {
+			db.deleteBan(ban.ID)
+		}
+	}
+	return &BanDB{db: db}, nil
+}
+
+// Bans returns the node bans stored in the database.
+func (b *BanDB) Bans() []Ban {
+	return b.db.bans()
+}
+
+// SetBan stores a node ban in the database.
+func (b *BanDB) SetBan(ban Ban) error {
+	return b.db.updateBan(ban)
+}
+
+// DeleteBan removes the ban of a node from the database.
+func (b *BanDB) DeleteBan(id NodeID) error {
+	return b.db.deleteBan(id)
+}
+
+// Close closes the database.
+func (b *BanDB) Close() {
+	b.db.close()
+}
+
 // ensureExpirer is a small helper method ensuring that the data expiration
 // mechanism is running. If the expiration goroutine is already running, this
 // method simply returns.
@@ -264,6 +359,13 @@ func (db *nodeDB) expireNodes() error {
 		// Otherwise delete all associated information
 		db.deleteNode(id)
 	}
+	// Drop the bans that already ran out too
+	now := time.Now()
+	for _, ban := range db.bans() {
+		if ban.Expiry.Before(now) {
+			db.deleteBan(ban.ID)
+		}
+	}
 	return nil
 }
 
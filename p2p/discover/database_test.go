@@ -378,3 +378,39 @@ func TestNodeDBSelfExpiration(t *testing.T) {
 		t.Errorf("self not evacuated")
 	}
 }
+
+func TestNodeDBBans(t *testing.T) {
+	db, _ := newNodeDB("", Version, NodeID{})
+	defer db.close()
+
+	var (
+		node    = nodeDBExpirationNodes[1].node
+		active  = Ban{ID: node.ID, IP: node.IP, Expiry: time.Unix(time.Now().Add(time.Hour).Unix(), 0)}
+		expired = Ban{ID: nodeDBExpirationNodes[0].node.ID, IP: net.IP{127, 0, 0, 1}, Expiry: time.Unix(time.Now().Add(-time.Hour).Unix(), 0)}
+	)
+	for i, ban := range []Ban{active, expired} {
+		if err := db.updateBan(ban); err != nil {
+			t.Fatalf("ban %d: failed to insert: %v", i, err)
+		}
+	}
+	// Bans must outlive the discovery data of the node, but not their own expiry
+	if err := db.updateNode(node); err != nil {
+		t.Fatalf("failed to insert node: %v", err)
+	}
+	if err := db.expireNodes(); err != nil {
+		t.Fatalf("failed to expire nodes: %v", err)
+	}
+	if db.node(node.ID) != nil {
+		t.Errorf("unbonded node not expired")
+	}
+	bans := db.bans()
+	if len(bans) != 1 || bans[0].ID != active.ID || !bans[0].IP.Equal(active.IP) || !bans[0].Expiry.Equal(active.Expiry) {
+		t.Fatalf("ban mismatch: have %v, want [%v]", bans, active)
+	}
+	if err := db.deleteBan(active.ID); err != nil {
+		t.Fatalf("failed to delete ban: %v", err)
+	}
+	if bans := db.bans(); len(bans) != 0 {
+		t.Errorf("bans remained after deletion: %v", bans)
+	}
+}
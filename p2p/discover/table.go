@@ -36,6 +36,7 @@ import (
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p/enr"
 	"github.com/eeefan/dpeth/p2p/netutil"
 )
 
@@ -101,6 +102,7 @@ type transport interface {
 	ping(NodeID, *net.UDPAddr) error
 	waitping(NodeID) error
 	findnode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error)
+	requestENR(toid NodeID, addr *net.UDPAddr) (*enr.Record, error)
 	close()
 }
 
@@ -270,6 +272,26 @@ func (tab *Table) Resolve(targetID NodeID) *Node {
 	return nil
 }
 
+// RequestENR retrieves the current node record of the given node.
+func (tab *Table) RequestENR(n *Node) (*enr.Record, error) {
+	return tab.net.requestENR(n.ID, n.addr())
+}
+
+// Bans returns the node bans stored in the node database.
+func (tab *Table) Bans() []Ban {
+	return tab.db.bans()
+}
+
+// SetBan stores a node ban in the node database, so it survives restarts.
+func (tab *Table) SetBan(ban Ban) error {
+	return tab.db.updateBan(ban)
+}
+
+// DeleteBan removes the ban of a node from the node database.
+func (tab *Table) DeleteBan(id NodeID) error {
+	return tab.db.deleteBan(id)
+}
+
 // Lookup performs a network search for nodes close
 // to the given target. It approaches the target by querying
 // nodes that are closer to it on each iteration.
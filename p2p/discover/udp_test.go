@@ -35,6 +35,7 @@ import (
 
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/p2p/enr"
 	"github.com/eeefan/dpeth/rlp"
 	"github.com/davecgh/go-spew/spew"
 )
@@ -321,6 +322,67 @@ func TestUDP_findnodeMultiReply(t *testing.T) {
 	}
 }
 
+func TestUDP_enrRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	var record enr.Record
+	record.Set(enr.TCP(30303))
+	if err := enr.SignV4(&record, test.localkey); err != nil {
+		t.Fatalf("can't sign record: %v", err)
+	}
+	test.udp.record = func() *enr.Record { return &record }
+
+	// ENR requests are refused without a bond.
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+
+	// ensure there's a bond with the test node.
+	test.table.db.updateBondTime(PubkeyID(&test.remotekey.PublicKey), time.Now())
+
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	test.waitPacketOut(func(p *enrResponse) {
+		if !bytes.Equal(p.ReplyTok, test.sent[len(test.sent)-1][:macSize]) {
+			t.Errorf("wrong reply token: %x", p.ReplyTok)
+		}
+		var tcp enr.TCP
+		if err := p.Record.Load(&tcp); err != nil || tcp != 30303 {
+			t.Errorf("wrong record returned: tcp %d, err %v", tcp, err)
+		}
+	})
+}
+
+func TestUDP_requestENR(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	sign := func(key *ecdsa.PrivateKey) enr.Record {
+		var record enr.Record
+		record.Set(enr.TCP(30303))
+		if err := enr.SignV4(&record, key); err != nil {
+			t.Fatalf("can't sign record: %v", err)
+		}
+		return record
+	}
+	remoteID := PubkeyID(&test.remotekey.PublicKey)
+	for i, key := range []*ecdsa.PrivateKey{test.remotekey, newkey()} {
+		done := make(chan error, 1)
+		go func() {
+			_, err := test.udp.requestENR(remoteID, test.remoteaddr)
+			done <- err
+		}()
+		hash, _ := test.waitPacketOut(func(p *enrRequest) {})
+		test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: sign(key)})
+
+		want := error(nil)
+		if i > 0 {
+			want = errRecordMismatch
+		}
+		if err := <-done; err != want {
+			t.Errorf("test %d: error mismatch: got %v, want %v", i, err, want)
+		}
+	}
+}
+
 func TestUDP_successfulPing(t *testing.T) {
 	test := newUDPTest(t)
 	added := make(chan *Node, 1)
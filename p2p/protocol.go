@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/p2p/enr"
 )
 
 // Protocol represents a P2P subprotocol implementation.
@@ -52,6 +53,15 @@ type Protocol struct {
 	// about a certain peer in the network. If an info retrieval function is set,
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id discover.NodeID) interface{}
+
+	// Attributes is an optional helper method returning the node record entries
+	// advertising the protocol, such as the chain served. It is queried every
+	// time the local record is needed, so the entries may change over time.
+	Attributes func() []enr.Entry
+
+	// DialFilter is an optional helper method deciding whether a discovered node
+	// is worth dialing for the protocol, based on the node record it advertises.
+	DialFilter func(record *enr.Record) bool
 }
 
 func (p Protocol) cap() Cap {
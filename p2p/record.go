@@ -0,0 +1,180 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of the dpeth library.
+//
+// The dpeth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The dpeth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dpeth library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"encoding/base64"
+	"time"
+
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/p2p/enr"
+	"github.com/eeefan/dpeth/rlp"
+)
+
+const (
+	nodeRecordTTL        = 30 * time.Minute // Time a retrieved dial candidate record is reused for
+	nodeRecordFailureTTL = 5 * time.Minute  // Time a dial candidate failing to serve its record is skipped for
+	maxNodeRecords       = 1024             // Maximum number of dial candidate records cached
+)
+
+// LocalRecord returns the signed node record of the local node, advertising
+// its endpoint along with the attributes of the running protocols. The record
+// is re-signed with a higher sequence number whenever its content changes.
+func (srv *Server) LocalRecord() *enr.Record {
+	if srv.PrivateKey == nil {
+		return nil
+	}
+	self := srv.Self()
+
+	var record enr.Record
+	record.Set(enr.ID("v4"))
+	record.Set(enr.Secp256k1(srv.PrivateKey.PublicKey))
+	record.Set(enr.IP(self.IP))
+	record.Set(enr.UDP(self.UDP))
+	record.Set(enr.TCP(self.TCP))
+	for _, proto := range srv.Protocols {
+		if proto.Attributes == nil {
+			continue
+		}
+		for _, entry := range proto.Attributes() {
+			record.Set(entry)
+		}
+	}
+	srv.recordLock.Lock()
+	defer srv.recordLock.Unlock()
+
+	if srv.record != nil {
+		// Keep the current record if none of the entries changed
+		record.SetSeq(srv.record.Seq())
+		if sameRecord(&record, srv.record) {
+			return srv.record
+		}
+		record.SetSeq(srv.record.Seq() + 1)
+	} else {
+		// Start from the current time so records created after a restart
+		// supersede the ones remote nodes may have seen before.
+		record.SetSeq(uint64(time.Now().Unix()))
+	}
+	if err := enr.SignV4(&record, srv.PrivateKey); err != nil {
+		log.Error("Failed to sign local node record", "err", err)
+		return srv.record
+	}
+	srv.record = &record
+	return srv.record
+}
+
+// sameRecord returns whether two records carry the same sequence number and
+// entries, disregarding their signatures.
+func sameRecord(a, b *enr.Record) bool {
+	blobA, errA := rlp.EncodeToBytes(a.AppendElements(nil))
+	blobB, errB := rlp.EncodeToBytes(b.AppendElements(nil))
+	return errA == nil && errB == nil && bytes.Equal(blobA, blobB)
+}
+
+// encodeRecord returns the textual form of a node record.
+func encodeRecord(r *enr.Record) string {
+	if r == nil {
+		return ""
+	}
+	blob, err := rlp.EncodeToBytes(r)
+	if err != nil {
+		return ""
+	}
+	return "enr:" + base64.RawURLEncoding.EncodeToString(blob)
+}
+
+// acceptsNode returns whether a discovered node is worth dialing, based on the
+// node record it advertises and the dial filters of the protocols. Nodes are
+// accepted if any of the protocols accepts them. Nodes not serving their record,
+// such as ones running an older version, are dialed regardless and left to the
+// protocol handshakes to reject if incompatible.
+func (srv *Server) acceptsNode(n *discover.Node) bool {
+	var filters []func(*enr.Record) bool
+	for _, proto := range srv.Protocols {
+		if proto.DialFilter != nil {
+			filters = append(filters, proto.DialFilter)
+		}
+	}
+	if len(filters) == 0 || srv.ntab == nil {
+		return true
+	}
+	record := srv.nodeRecord(n)
+	if record == nil {
+		srv.log.Trace("Dialing candidate without node record", "id", n.ID, "addr", n.IP)
+		return true
+	}
+	for _, filter := range filters {
+		if filter(record) {
+			return true
+		}
+	}
+	srv.log.Debug("Skipping incompatible dial candidate", "id", n.ID, "addr", n.IP)
+	return false
+}
+
+// nodeRecord is the cached result of a node record request.
+type nodeRecord struct {
+	record  *enr.Record // Retrieved record, nil if the request failed
+	expires time.Time   // Time after which the record is requested again
+}
+
+// nodeRecord returns the node record of a dial candidate, or nil if it could not
+// be retrieved. The results, failures included, are cached for a while so that
+// redials don't wait for a discovery round trip each time.
+func (srv *Server) nodeRecord(n *discover.Node) *enr.Record {
+	now := time.Now()
+
+	srv.nodeRecordsLock.Lock()
+	if cached, ok := srv.nodeRecords[n.ID]; ok && now.Before(cached.expires) {
+		srv.nodeRecordsLock.Unlock()
+		return cached.record
+	}
+	srv.nodeRecordsLock.Unlock()
+
+	cached := &nodeRecord{expires: now.Add(nodeRecordTTL)}
+	record, err := srv.ntab.RequestENR(n)
+	if err != nil {
+		srv.log.Trace("Failed to retrieve node record", "id", n.ID, "err", err)
+		cached.expires = now.Add(nodeRecordFailureTTL)
+	} else {
+		cached.record = record
+	}
+	srv.nodeRecordsLock.Lock()
+	defer srv.nodeRecordsLock.Unlock()
+
+	if srv.nodeRecords == nil {
+		srv.nodeRecords = make(map[discover.NodeID]*nodeRecord)
+	}
+	if len(srv.nodeRecords) >= maxNodeRecords {
+		for id, old := range srv.nodeRecords {
+			if !now.Before(old.expires) {
+				delete(srv.nodeRecords, id)
+			}
+		}
+		// Evict a random entry if none expired yet
+		for id := range srv.nodeRecords {
+			if len(srv.nodeRecords) < maxNodeRecords {
+				break
+			}
+			delete(srv.nodeRecords, id)
+		}
+	}
+	srv.nodeRecords[n.ID] = cached
+	return cached.record
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/p2p/discover"
 	"github.com/eeefan/dpeth/p2p/discv5"
+	"github.com/eeefan/dpeth/p2p/enr"
 	"github.com/eeefan/dpeth/p2p/nat"
 	"github.com/eeefan/dpeth/p2p/netutil"
 )
@@ -169,12 +170,22 @@ type Server struct {
 	quit          chan struct{}
 	addstatic     chan *discover.Node
 	removestatic  chan *discover.Node
+	prioritize    chan []*discover.Node
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
 	loopWG        sync.WaitGroup // loop, listenLoop
 	peerFeed      event.Feed
 	log           log.Logger
+
+	record     *enr.Record // Last signed local node record, see LocalRecord
+	recordLock sync.Mutex
+
+	nodeRecords     map[discover.NodeID]*nodeRecord // Records retrieved from dial candidates, see acceptsNode
+	nodeRecordsLock sync.Mutex
+
+	bans  *banList        // Nodes and IPs temporarily barred from connecting
+	banDB *discover.BanDB // Node database keeping the bans if discovery is disabled
 }
 
 type peerOpFunc func(map[discover.NodeID]*Peer)
@@ -192,6 +203,7 @@ const (
 	staticDialedConn
 	inboundConn
 	trustedConn
+	prioritizedConn
 )
 
 // conn wraps a network connection with information gathered
@@ -234,6 +246,9 @@ func (f connFlag) String() string {
 	if f&trustedConn != 0 {
 		s += "-trusted"
 	}
+	if f&prioritizedConn != 0 {
+		s += "-prioritized"
+	}
 	if f&dynDialedConn != 0 {
 		s += "-dyndial"
 	}
@@ -300,6 +315,20 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// SetPrioritizedPeers replaces the set of prioritized nodes, such as the signers
+// in the current consensus queue. Prioritized nodes are dialed ahead of any other
+// node and redialed quickly after losing the connection. Their connections are
+// accepted even if the server is at the MaxPeers limit.
+//
+// Nodes dropped from the set are not disconnected, they merely lose their
+// priority once their current connection ends.
+func (srv *Server) SetPrioritizedPeers(nodes []*discover.Node) {
+	select {
+	case srv.prioritize <- nodes:
+	case <-srv.quit:
+	}
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -410,6 +439,7 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.prioritize = make(chan []*discover.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
@@ -455,6 +485,7 @@ func (srv *Server) Start() (err error) {
 			NetRestrict:  srv.NetRestrict,
 			Bootnodes:    srv.BootstrapNodes,
 			Unhandled:    unhandled,
+			Record:       srv.LocalRecord,
 		}
 		ntab, err := discover.ListenUDP(conn, cfg)
 		if err != nil {
@@ -462,6 +493,16 @@ func (srv *Server) Start() (err error) {
 		}
 		srv.ntab = ntab
 	}
+	store, _ := srv.ntab.(banStore)
+	if store == nil && srv.NodeDatabase != "" {
+		// Keep the bans across restarts even if there's no table owning the database
+		db, err := discover.OpenBanDB(srv.NodeDatabase)
+		if err != nil {
+			return err
+		}
+		srv.banDB, store = db, db
+	}
+	srv.bans = newBanList(store)
 
 	if srv.DiscoveryV5 {
 		var (
@@ -533,6 +574,7 @@ type dialer interface {
 	taskDone(task, time.Time)
 	addStatic(*discover.Node)
 	removeStatic(*discover.Node)
+	setPrioritized([]*discover.Node)
 }
 
 func (srv *Server) run(dialstate dialer) {
@@ -541,6 +583,7 @@ func (srv *Server) run(dialstate dialer) {
 		peers        = make(map[discover.NodeID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[discover.NodeID]bool, len(srv.TrustedNodes))
+		prioritized  = make(map[discover.NodeID]bool)
 		taskdone     = make(chan task, maxActiveDialTasks)
 		runningTasks []task
 		queuedTasks  []task // tasks that can't run yet
@@ -605,6 +648,15 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case nodes := <-srv.prioritize:
+			// This channel is used by SetPrioritizedPeers to replace
+			// the prioritized node set. The dialer keeps them connected.
+			srv.log.Debug("Updating prioritized nodes", "count", len(nodes))
+			prioritized = make(map[discover.NodeID]bool, len(nodes))
+			for _, n := range nodes {
+				prioritized[n.ID] = true
+			}
+			dialstate.setPrioritized(nodes)
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -623,6 +675,11 @@ running:
 				// Ensure that the trusted flag is set before checking against MaxPeers.
 				c.flags |= trustedConn
 			}
+			if prioritized[c.id] {
+				// Prioritized peers bypass the peer limits just like trusted ones,
+				// no matter which side initiated the connection.
+				c.flags |= prioritizedConn
+			}
 			// TODO: track in-progress inbound node IDs (pre-Peer) to avoid dialing them.
 			select {
 			case c.cont <- srv.encHandshakeChecks(peers, inboundCount, c):
@@ -674,6 +731,9 @@ running:
 	if srv.ntab != nil {
 		srv.ntab.Close()
 	}
+	if srv.banDB != nil {
+		srv.banDB.Close()
+	}
 	if srv.DiscV5 != nil {
 		srv.DiscV5.Close()
 	}
@@ -703,14 +763,16 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, inbound
 
 func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
+	case !c.is(trustedConn|staticDialedConn|prioritizedConn) && len(peers) >= srv.MaxPeers:
 		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
+	case !c.is(trustedConn|prioritizedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
 		return DiscTooManyPeers
 	case peers[c.id] != nil:
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !c.is(trustedConn) && srv.bans.bannedID(c.id):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -779,6 +841,13 @@ func (srv *Server) listenLoop() {
 				continue
 			}
 		}
+		// Reject connections from the addresses of banned nodes.
+		if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok && srv.bans.bannedIP(tcp.IP) {
+			srv.log.Debug("Rejected conn (banned address)", "addr", fd.RemoteAddr())
+			fd.Close()
+			slots <- struct{}{}
+			continue
+		}
 
 		fd = newMeteredConn(fd, true)
 		srv.log.Trace("Accepted connection", "addr", fd.RemoteAddr())
@@ -910,6 +979,7 @@ type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
 	Name  string `json:"name"`  // Name of the node, including client type, version, OS, custom data
 	Enode string `json:"enode"` // Enode URL for adding this peer from remote peers
+	ENR   string `json:"enr"`   // Node record advertising the endpoint and protocol attributes
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
 		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
@@ -927,6 +997,7 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	info := &NodeInfo{
 		Name:       srv.Name,
 		Enode:      node.String(),
+		ENR:        encodeRecord(srv.LocalRecord()),
 		ID:         node.ID.String(),
 		IP:         node.IP.String(),
 		ListenAddr: srv.ListenAddr,
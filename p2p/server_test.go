@@ -17,10 +17,13 @@
 package p2p
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
+	"io/ioutil"
 	"math/rand"
 	"net"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -29,6 +32,7 @@ import (
 	"github.com/eeefan/dpeth/crypto/sha3"
 	"github.com/eeefan/dpeth/log"
 	"github.com/eeefan/dpeth/p2p/discover"
+	"github.com/eeefan/dpeth/p2p/enr"
 )
 
 func init() {
@@ -309,6 +313,8 @@ func (tg taskgen) addStatic(*discover.Node) {
 }
 func (tg taskgen) removeStatic(*discover.Node) {
 }
+func (tg taskgen) setPrioritized([]*discover.Node) {
+}
 
 type testTask struct {
 	index  int
@@ -321,9 +327,9 @@ func (t *testTask) Do(srv *Server) {
 
 // This test checks that connections are disconnected
 // just after the encryption handshake when the server is
-// at capacity. Trusted connections should still be accepted.
+// at capacity. Trusted and prioritized connections should still be accepted.
 func TestServerAtCap(t *testing.T) {
-	trustedID := randomID()
+	trustedID, prioritizedID := randomID(), randomID()
 	srv := &Server{
 		Config: Config{
 			PrivateKey:   newkey(),
@@ -363,7 +369,192 @@ func TestServerAtCap(t *testing.T) {
 	if !c.is(trustedConn) {
 		t.Error("Server did not set trusted flag")
 	}
+	// Try inserting a prioritized connection.
+	srv.SetPrioritizedPeers([]*discover.Node{{ID: prioritizedID}})
+	c = newconn(prioritizedID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Error("unexpected error for prioritized conn @posthandshake:", err)
+	}
+	if !c.is(prioritizedConn) {
+		t.Error("Server did not set prioritized flag")
+	}
+}
+
+// This test checks that banned nodes are rejected until their ban is lifted,
+// unless they are trusted.
+func TestServerBans(t *testing.T) {
+	trustedID, bannedID := randomID(), randomID()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:   newkey(),
+			MaxPeers:     10,
+			NoDial:       true,
+			TrustedNodes: []*discover.Node{{ID: trustedID}},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id discover.NodeID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(id, fd)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, id: id, cont: make(chan error)}
+	}
+	ip := net.IP{10, 0, 0, 1}
+	srv.BanPeer(bannedID, ip, time.Hour)
+	srv.BanPeer(trustedID, ip, time.Hour)
+
+	if bans := srv.Bans(); len(bans) != 2 {
+		t.Fatalf("ban count mismatch: have %d, want 2", len(bans))
+	}
+	if !srv.bans.bannedIP(ip) {
+		t.Error("address of banned node not banned")
+	}
+	if err := srv.checkpoint(newconn(bannedID), srv.posthandshake); err != DiscUselessPeer {
+		t.Error("wrong error for banned conn:", err)
+	}
+	if err := srv.checkpoint(newconn(trustedID), srv.posthandshake); err != nil {
+		t.Error("unexpected error for banned trusted conn:", err)
+	}
+	// Lift the ban and retry
+	if !srv.Unban(bannedID) {
+		t.Error("banned node reported as not banned")
+	}
+	if srv.Unban(bannedID) {
+		t.Error("unbanned node reported as banned")
+	}
+	if err := srv.checkpoint(newconn(bannedID), srv.posthandshake); err != nil {
+		t.Error("unexpected error for unbanned conn:", err)
+	}
+}
+
+// This test checks that bans survive restarts even if discovery is disabled.
+func TestServerBansPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p-bans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := Config{
+		PrivateKey:   newkey(),
+		MaxPeers:     10,
+		NoDial:       true,
+		NoDiscovery:  true,
+		NodeDatabase: dir,
+	}
+	id := randomID()
+
+	srv := &Server{Config: config}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	srv.BanPeer(id, net.IP{10, 0, 0, 1}, time.Hour)
+	srv.Stop()
+
+	srv = &Server{Config: config}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not restart: %v", err)
+	}
+	defer srv.Stop()
+
+	if bans := srv.Bans(); len(bans) != 1 || bans[0].ID != id {
+		t.Fatalf("bans mismatch after restart: have %v, want ban of %v", bans, id)
+	}
+}
+
+// This test checks that the local node record carries the protocol attributes
+// and is only re-signed when they change.
+func TestServerLocalRecord(t *testing.T) {
+	chain := uint(1)
+	srv := &Server{
+		Config: Config{
+			PrivateKey: newkey(),
+			Protocols: []Protocol{{
+				Name:       "test",
+				Attributes: func() []enr.Entry { return []enr.Entry{enr.WithEntry("test", chain)} },
+			}},
+		},
+	}
+	record := srv.LocalRecord()
+	if id := discover.PubkeyID(&srv.PrivateKey.PublicKey); !bytes.Equal(record.NodeAddr(), crypto.Keccak256(id[:])) {
+		t.Fatalf("record signed by the wrong key")
+	}
+	var have uint
+	if err := record.Load(enr.WithEntry("test", &have)); err != nil || have != chain {
+		t.Fatalf("protocol attribute mismatch: have %d, want %d, err %v", have, chain, err)
+	}
+	if again := srv.LocalRecord(); again.Seq() != record.Seq() {
+		t.Errorf("unchanged record re-signed: seq %d -> %d", record.Seq(), again.Seq())
+	}
+	chain = 2
+	if changed := srv.LocalRecord(); changed.Seq() <= record.Seq() {
+		t.Errorf("changed record seq not increased: %d -> %d", record.Seq(), changed.Seq())
+	}
+}
 
+// This test checks that discovered nodes are not dialed if their node record
+// fails the dial filters of the protocols, while nodes not serving a record are.
+func TestServerAcceptsNode(t *testing.T) {
+	compatible, incompatible := newkey(), newkey()
+	records := &recordTable{records: make(map[discover.NodeID]*enr.Record)}
+	for key, chain := range map[*ecdsa.PrivateKey]uint{compatible: 1, incompatible: 2} {
+		var record enr.Record
+		record.Set(enr.WithEntry("test", chain))
+		enr.SignV4(&record, key)
+		records.records[discover.PubkeyID(&key.PublicKey)] = &record
+	}
+	srv := &Server{
+		Config: Config{
+			Protocols: []Protocol{{
+				Name: "test",
+				DialFilter: func(record *enr.Record) bool {
+					var chain uint
+					return record.Load(enr.WithEntry("test", &chain)) == nil && chain == 1
+				},
+			}},
+		},
+		ntab: records,
+		log:  log.Root(),
+	}
+	if !srv.acceptsNode(&discover.Node{ID: discover.PubkeyID(&compatible.PublicKey)}) {
+		t.Errorf("compatible node rejected")
+	}
+	if srv.acceptsNode(&discover.Node{ID: discover.PubkeyID(&incompatible.PublicKey)}) {
+		t.Errorf("incompatible node accepted")
+	}
+	unknown := &discover.Node{ID: randomID()}
+	if !srv.acceptsNode(unknown) {
+		t.Errorf("node without record rejected")
+	}
+	// Redials are decided from the cached records and failures
+	requests := records.requests
+	srv.acceptsNode(&discover.Node{ID: discover.PubkeyID(&compatible.PublicKey)})
+	srv.acceptsNode(unknown)
+	if records.requests != requests {
+		t.Errorf("node records requested again: %d requests, want %d", records.requests, requests)
+	}
+}
+
+// recordTable is a discovery table serving the node records it contains.
+type recordTable struct {
+	records  map[discover.NodeID]*enr.Record
+	requests int // Number of node record requests served
+}
+
+func (t *recordTable) Self() *discover.Node                     { return new(discover.Node) }
+func (t *recordTable) Close()                                   {}
+func (t *recordTable) Lookup(discover.NodeID) []*discover.Node  { return nil }
+func (t *recordTable) Resolve(discover.NodeID) *discover.Node   { return nil }
+func (t *recordTable) ReadRandomNodes(buf []*discover.Node) int { return 0 }
+func (t *recordTable) RequestENR(n *discover.Node) (*enr.Record, error) {
+	t.requests++
+	if record, ok := t.records[n.ID]; ok {
+		return record, nil
+	}
+	return nil, errors.New("timeout")
 }
 
 func TestServerSetupConn(t *testing.T) {
@@ -0,0 +1,279 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/eeefan/dpeth/log"
+)
+
+const (
+	// authModulesAll is the module wildcard granting access to every namespace.
+	authModulesAll = "*"
+
+	// jwtScopeClaim is the JWT claim listing the space separated namespaces the
+	// token may call. Tokens without the claim may call every namespace.
+	jwtScopeClaim = "scope"
+
+	// jwtIssuedAtWindow is the maximum distance between the mandatory "iat" claim
+	// of a JWT and the local time, limiting how long a leaked token can be replayed.
+	jwtIssuedAtWindow = 60 * time.Second
+)
+
+var (
+	errAuthMissing = errors.New("missing bearer token")
+	errAuthInvalid = errors.New("invalid bearer token")
+	errAuthStale   = errors.New("stale or expired bearer token")
+)
+
+// AuthToken is a static bearer token along with the API namespaces it may call.
+type AuthToken struct {
+	Token   string   // Opaque secret the client sends as "Authorization: Bearer <token>"
+	Modules []string // API namespaces the token may call, "*" grants all of them
+}
+
+// AuthConfig configures the authentication of the HTTP and websocket RPC
+// endpoints. Authentication is only enforced if a JWT secret or at least one
+// static token is set.
+type AuthConfig struct {
+	JWTSecret     []byte      // HS256 secret accepted for JWT bearer tokens, nil disables JWT
+	Tokens        []AuthToken // Static bearer tokens with their namespace scopes
+	PublicModules []string    // API namespaces callable without any credentials
+}
+
+// Enabled reports whether the configuration requires clients to authenticate.
+func (c AuthConfig) Enabled() bool {
+	return len(c.JWTSecret) > 0 || len(c.Tokens) > 0
+}
+
+// authScope is the set of API namespaces an authenticated request may call.
+type authScope struct {
+	all     bool
+	modules map[string]bool
+}
+
+// newAuthScope creates a scope granting access to the given namespaces.
+func newAuthScope(modules []string) *authScope {
+	scope := &authScope{modules: make(map[string]bool)}
+	for _, module := range modules {
+		if module == authModulesAll {
+			scope.all = true
+		}
+		scope.modules[module] = true
+	}
+	return scope
+}
+
+// allows reports whether the scope permits calls into the given namespace. The
+// metadata namespace is always accessible.
+func (s *authScope) allows(namespace string) bool {
+	return s.all || namespace == MetadataApi || s.modules[namespace]
+}
+
+// authScopeKey is the context key under which the scope of a request is stored.
+type authScopeKey struct{}
+
+// scopeAllows reports whether the authentication scope carried by the context
+// permits calls into the given namespace. Contexts without a scope belong to
+// unauthenticated transports and are allowed everything.
+func scopeAllows(ctx context.Context, namespace string) bool {
+	scope, ok := ctx.Value(authScopeKey{}).(*authScope)
+	if !ok {
+		return true
+	}
+	return scope.allows(namespace)
+}
+
+// withAuthScope copies the authentication scope of an HTTP request, if any,
+// into the given context.
+func withAuthScope(ctx context.Context, r *http.Request) context.Context {
+	if scope, ok := r.Context().Value(authScopeKey{}).(*authScope); ok {
+		return context.WithValue(ctx, authScopeKey{}, scope)
+	}
+	return ctx
+}
+
+// scopeHandler is a handler which rejects requests whose authentication scope
+// does not grant access to the API namespace it serves.
+type scopeHandler struct {
+	namespace string
+	next      http.Handler
+}
+
+// newScopeHandler wraps next into a handler only serving requests allowed to
+// call into the given namespace.
+func newScopeHandler(namespace string, next http.Handler) http.Handler {
+	return &scopeHandler{namespace: namespace, next: next}
+}
+
+// ServeHTTP checks the scope of the request and passes it on, implements http.Handler.
+func (h *scopeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !scopeAllows(r.Context(), h.namespace) {
+		http.Error(w, fmt.Sprintf("the %s API is not permitted", h.namespace), http.StatusForbidden)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// authHandler is a handler which authenticates bearer tokens on incoming HTTP
+// requests and websocket upgrades, attaching the granted scope to the request.
+type authHandler struct {
+	jwtSecret []byte
+	tokens    map[string]*authScope
+	public    *authScope
+	next      http.Handler
+}
+
+// newAuthHandler wraps next with bearer token authentication. If the config does
+// not enable authentication, next is returned as is.
+func newAuthHandler(config AuthConfig, next http.Handler) http.Handler {
+	if !config.Enabled() {
+		return next
+	}
+	h := &authHandler{
+		jwtSecret: config.JWTSecret,
+		tokens:    make(map[string]*authScope),
+		next:      next,
+	}
+	for _, token := range config.Tokens {
+		h.tokens[token.Token] = newAuthScope(token.Modules)
+	}
+	if len(config.PublicModules) > 0 {
+		h.public = newAuthScope(config.PublicModules)
+	}
+	return h
+}
+
+// ServeHTTP authenticates the request and passes it on, implements http.Handler.
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scope, err := h.authenticate(r.Header.Get("Authorization"))
+	if err != nil {
+		log.Debug("Rejected unauthenticated RPC request", "remote", r.RemoteAddr, "err", err)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authScopeKey{}, scope)))
+}
+
+// authenticate resolves the scope granted by an Authorization header.
+func (h *authHandler) authenticate(header string) (*authScope, error) {
+	if header == "" {
+		if h.public == nil {
+			return nil, errAuthMissing
+		}
+		return h.public, nil
+	}
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return nil, errAuthInvalid
+	}
+	token := strings.TrimSpace(header[7:])
+
+	// Static tokens are compared in constant time against every configured one
+	var scope *authScope
+	for secret, s := range h.tokens {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1 {
+			scope = s
+		}
+	}
+	if scope != nil {
+		return scope, nil
+	}
+	if len(h.jwtSecret) > 0 {
+		return h.authenticateJWT(token)
+	}
+	return nil, errAuthInvalid
+}
+
+// authenticateJWT validates an HS256 signed JWT and returns the scope it claims.
+func (h *authHandler) authenticateJWT(token string) (*authScope, error) {
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}, SkipClaimsValidation: true}
+	parsed, err := parser.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return h.jwtSecret, nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, errAuthInvalid
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+	if err := verifyJWTTime(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	scope, ok := claims[jwtScopeClaim]
+	if !ok {
+		return newAuthScope([]string{authModulesAll}), nil
+	}
+	modules, ok := scope.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid %q claim", jwtScopeClaim)
+	}
+	return newAuthScope(strings.Fields(modules)), nil
+}
+
+// verifyJWTTime checks the time claims of a JWT. Tokens must carry an "iat"
+// claim close to the local time, as the optional "exp" claim alone would leave
+// tokens without it valid forever. If present, "exp" and "nbf" are enforced too.
+func verifyJWTTime(claims jwt.MapClaims, now time.Time) error {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return errAuthStale
+	}
+	if issued := time.Unix(int64(iat), 0); issued.Before(now.Add(-jwtIssuedAtWindow)) || issued.After(now.Add(jwtIssuedAtWindow)) {
+		return errAuthStale
+	}
+	if !claims.VerifyExpiresAt(now.Unix(), false) || !claims.VerifyNotBefore(now.Unix(), false) {
+		return errAuthStale
+	}
+	return nil
+}
+
+// HTTPAuth adds credentials to the headers of an outgoing HTTP request or
+// websocket handshake. It is invoked anew for every request and reconnection.
+type HTTPAuth func(header http.Header) error
+
+// NewBearerAuth creates an HTTPAuth sending a static bearer token.
+func NewBearerAuth(token string) HTTPAuth {
+	return func(header http.Header) error {
+		header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// NewJWTAuth creates an HTTPAuth signing a fresh HS256 JWT with the given secret
+// for every request. If modules is non-empty, the token is scoped to those API
+// namespaces.
+func NewJWTAuth(secret []byte, modules []string) HTTPAuth {
+	return func(header http.Header) error {
+		claims := jwt.MapClaims{"iat": time.Now().Unix()}
+		if len(modules) > 0 {
+			claims[jwtScopeClaim] = strings.Join(modules, " ")
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			return err
+		}
+		header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// newAuthTestServer starts an HTTP RPC server exposing the test service under a
+// public and a privileged namespace, protected by the given auth config.
+func newAuthTestServer(t *testing.T, auth AuthConfig) *httptest.Server {
+	server := NewServer()
+	if err := server.RegisterName("public", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("admin", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(newHTTPHandler(nil, []string{"*"}, auth, server))
+}
+
+func callRets(t *testing.T, url string, auth HTTPAuth, method string) error {
+	client, err := DialHTTPWithAuth(url, new(http.Client), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var result string
+	return client.Call(&result, method)
+}
+
+func TestHTTPStaticTokenAuth(t *testing.T) {
+	httpsrv := newAuthTestServer(t, AuthConfig{
+		Tokens: []AuthToken{
+			{Token: "user-token", Modules: []string{"public"}},
+			{Token: "admin-token", Modules: []string{"*"}},
+		},
+		PublicModules: []string{"public"},
+	})
+	defer httpsrv.Close()
+
+	tests := []struct {
+		auth   HTTPAuth
+		method string
+		fail   string
+	}{
+		{nil, "public_rets", ""},
+		{nil, "admin_rets", "not authorized"},
+		{NewBearerAuth("user-token"), "public_rets", ""},
+		{NewBearerAuth("user-token"), "admin_rets", "not authorized"},
+		{NewBearerAuth("admin-token"), "admin_rets", ""},
+		{NewBearerAuth("bogus-token"), "public_rets", "401"},
+	}
+	for i, tt := range tests {
+		err := callRets(t, httpsrv.URL, tt.auth, tt.method)
+		switch {
+		case tt.fail == "" && err != nil:
+			t.Errorf("test %d: call to %s failed: %v", i, tt.method, err)
+		case tt.fail != "" && (err == nil || !strings.Contains(err.Error(), tt.fail)):
+			t.Errorf("test %d: call to %s error mismatch: have %v, want %q", i, tt.method, err, tt.fail)
+		}
+	}
+}
+
+func TestHTTPJWTAuth(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	httpsrv := newAuthTestServer(t, AuthConfig{JWTSecret: secret})
+	defer httpsrv.Close()
+
+	// Without public modules, unauthenticated requests are refused entirely
+	if err := callRets(t, httpsrv.URL, nil, "public_rets"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("unauthenticated call error mismatch: %v", err)
+	}
+	if err := callRets(t, httpsrv.URL, NewJWTAuth(secret, nil), "admin_rets"); err != nil {
+		t.Fatalf("unscoped JWT call failed: %v", err)
+	}
+	if err := callRets(t, httpsrv.URL, NewJWTAuth(secret, []string{"public"}), "public_rets"); err != nil {
+		t.Fatalf("scoped JWT call failed: %v", err)
+	}
+	if err := callRets(t, httpsrv.URL, NewJWTAuth(secret, []string{"public"}), "admin_rets"); err == nil {
+		t.Fatalf("scoped JWT call outside of scope succeeded")
+	}
+	if err := callRets(t, httpsrv.URL, NewJWTAuth([]byte("wrong secret"), nil), "public_rets"); err == nil {
+		t.Fatalf("JWT signed with wrong secret accepted")
+	}
+}
+
+// Tests that handlers wrapped into the HTTP handler stack, such as the GraphQL
+// endpoint, enforce the authentication and namespace scopes of the RPC endpoints.
+func TestHTTPHandlerStackAuth(t *testing.T) {
+	served := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := NewHTTPHandlerStack(served, "graphql", nil, []string{"*"}, AuthConfig{
+		Tokens: []AuthToken{
+			{Token: "graphql-token", Modules: []string{"graphql"}},
+			{Token: "eth-token", Modules: []string{"eth"}},
+		},
+	})
+	tests := []struct {
+		token string
+		code  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"bogus-token", http.StatusUnauthorized},
+		{"eth-token", http.StatusForbidden},
+		{"graphql-token", http.StatusOK},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestJWTTimeClaims(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	handler := newAuthHandler(AuthConfig{JWTSecret: secret}, nil).(*authHandler)
+
+	now := time.Now()
+	tests := []struct {
+		claims jwt.MapClaims
+		valid  bool
+	}{
+		{jwt.MapClaims{}, false},
+		{jwt.MapClaims{"exp": now.Add(time.Hour).Unix()}, false},
+		{jwt.MapClaims{"iat": now.Unix()}, true},
+		{jwt.MapClaims{"iat": now.Add(30 * time.Second).Unix()}, true},
+		{jwt.MapClaims{"iat": now.Add(-30 * time.Second).Unix()}, true},
+		{jwt.MapClaims{"iat": now.Add(-2 * time.Minute).Unix()}, false},
+		{jwt.MapClaims{"iat": now.Add(2 * time.Minute).Unix()}, false},
+		{jwt.MapClaims{"iat": now.Unix(), "exp": now.Add(-time.Second).Unix()}, false},
+		{jwt.MapClaims{"iat": now.Unix(), "nbf": now.Add(time.Minute).Unix()}, false},
+	}
+	for i, tt := range tests {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString(secret)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign token: %v", i, err)
+		}
+		_, err = handler.authenticate("Bearer " + token)
+		if tt.valid && err != nil {
+			t.Errorf("test %d: valid token rejected: %v", i, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("test %d: invalid token accepted", i)
+		}
+	}
+}
+
+func TestWebsocketAuth(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("public", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("admin", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	httpsrv := httptest.NewServer(newAuthHandler(AuthConfig{
+		JWTSecret:     secret,
+		Tokens:        []AuthToken{{Token: "user-token", Modules: []string{"public"}}},
+		PublicModules: []string{"public"},
+	}, server.WebsocketHandler([]string{"*"})))
+	defer httpsrv.Close()
+
+	wsURL := "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+	call := func(auth HTTPAuth, method string) error {
+		client, err := DialWebsocketWithAuth(context.Background(), wsURL, "", auth)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		var result string
+		return client.Call(&result, method)
+	}
+	tests := []struct {
+		auth   HTTPAuth
+		method string
+		fail   bool
+	}{
+		{nil, "public_rets", false},
+		{nil, "admin_rets", true},
+		{NewBearerAuth("user-token"), "public_rets", false},
+		{NewBearerAuth("user-token"), "admin_rets", true},
+		{NewBearerAuth("bogus-token"), "public_rets", true},
+		{NewJWTAuth(secret, nil), "admin_rets", false},
+		{NewJWTAuth(secret, []string{"public"}), "admin_rets", true},
+		{NewJWTAuth([]byte("wrong secret"), nil), "public_rets", true},
+	}
+	for i, tt := range tests {
+		if err := call(tt.auth, tt.method); (err != nil) != tt.fail {
+			t.Errorf("test %d: call to %s error mismatch: have %v, want failure %v", i, tt.method, err, tt.fail)
+		}
+	}
+}
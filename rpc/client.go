@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
@@ -181,6 +182,24 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	}
 }
 
+// DialContextWithAuth creates a new RPC client just like DialContext, sending the
+// credentials produced by auth on HTTP and websocket connections. IPC and stdio
+// connections are local and never authenticated.
+func DialContextWithAuth(ctx context.Context, rawurl string, auth HTTPAuth) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return DialHTTPWithAuth(rawurl, new(http.Client), auth)
+	case "ws", "wss":
+		return DialWebsocketWithAuth(ctx, rawurl, "", auth)
+	default:
+		return DialContext(ctx, rawurl)
+	}
+}
+
 type StdIOConn struct{}
 
 func (io StdIOConn) Read(b []byte) (n int, err error) {
@@ -44,6 +44,7 @@ var nullAddr, _ = net.ResolveTCPAddr("tcp", "127.0.0.1:0")
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
+	auth      HTTPAuth
 	closeOnce sync.Once
 	closed    chan struct{}
 }
@@ -69,6 +70,13 @@ func (hc *httpConn) Close() error {
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return DialHTTPWithAuth(endpoint, client, nil)
+}
+
+// DialHTTPWithAuth creates a new RPC client that connects to an RPC server over HTTP
+// using the provided HTTP Client, authenticating every request with auth. A nil
+// auth sends no credentials.
+func DialHTTPWithAuth(endpoint string, client *http.Client, auth HTTPAuth) (*Client, error) {
 	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -78,7 +86,7 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 
 	initctx := context.Background()
 	return newClient(initctx, func(context.Context) (net.Conn, error) {
-		return &httpConn{client: client, req: req, closed: make(chan struct{})}, nil
+		return &httpConn{client: client, req: req, auth: auth, closed: make(chan struct{})}, nil
 	})
 }
 
@@ -137,6 +145,18 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	req.ContentLength = int64(len(body))
 
+	if hc.auth != nil {
+		// The header map is shared with the template request, copy before modifying
+		header := make(http.Header, len(hc.req.Header)+1)
+		for key, values := range hc.req.Header {
+			header[key] = values
+		}
+		if err := hc.auth(header); err != nil {
+			return nil, err
+		}
+		req.Header = header
+	}
+
 	resp, err := hc.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -162,10 +182,27 @@ func (t *httpReadWriteNopCloser) Close() error {
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
-	// Wrap the CORS-handler within a host-handler
-	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
-	return &http.Server{Handler: handler}
+	return &http.Server{Handler: newHTTPHandler(cors, vhosts, AuthConfig{}, srv)}
+}
+
+// newHTTPHandler wraps the server into the authentication, CORS and virtual host
+// handlers, in that order from the inside out.
+func newHTTPHandler(cors []string, vhosts []string, auth AuthConfig, srv *Server) http.Handler {
+	// Wrap the auth-handler within a CORS-handler within a host-handler. CORS
+	// preflight requests carry no credentials, so they are answered before
+	// authentication takes place.
+	handler := newAuthHandler(auth, srv)
+	handler = newCorsHandler(handler, cors)
+	return newVHostHandler(vhosts, handler)
+}
+
+// NewHTTPHandlerStack wraps an arbitrary HTTP handler serving the given API
+// namespace into the same authentication, CORS and virtual host handlers the
+// RPC endpoint uses. Authenticated requests must be granted the namespace.
+func NewHTTPHandlerStack(handler http.Handler, namespace string, cors []string, vhosts []string, auth AuthConfig) http.Handler {
+	handler = newAuthHandler(auth, newScopeHandler(namespace, handler))
+	handler = newCorsHandler(handler, cors)
+	return newVHostHandler(vhosts, handler)
 }
 
 // ServeHTTP serves JSON-RPC requests over HTTP.
@@ -185,6 +222,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
+	ctx = withAuthScope(ctx, r)
 
 	body := io.LimitReader(r.Body, maxRequestContentLength)
 	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
@@ -212,7 +250,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -0,0 +1,134 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// rateLimiterMaxBuckets is the number of (method, IP) buckets kept in memory
+	// before idle ones are evicted.
+	rateLimiterMaxBuckets = 16384
+)
+
+// ServerLimits bounds the amount of work a single client can make the server do.
+// A zero value for any of the fields disables the corresponding limit.
+type ServerLimits struct {
+	BatchItemLimit       int     // Maximum number of requests in a single batch
+	BatchResponseMaxSize int     // Maximum size in bytes of an encoded response
+	RateLimit            float64 // Sustained requests per second allowed per method and remote IP
+	RateBurst            int     // Number of requests a client may burst above the sustained rate
+}
+
+// SetLimits configures the request limits enforced by the server. It should be
+// called before the server starts serving requests.
+func (s *Server) SetLimits(limits ServerLimits) {
+	s.limits = limits
+	if limits.RateLimit > 0 {
+		s.limiter = newRateLimiter(limits.RateLimit, limits.RateBurst)
+	} else {
+		s.limiter = nil
+	}
+}
+
+// tokenBucket is a single token bucket tracking the allowance of one client for
+// one method.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket rate limiter keyed by method name and remote IP.
+type rateLimiter struct {
+	rate  float64 // Tokens added to every bucket per second
+	burst float64 // Capacity of every bucket
+
+	buckets map[string]*tokenBucket
+	lock    sync.Mutex
+}
+
+// newRateLimiter creates a limiter allowing rate requests per second with bursts
+// of up to burst requests. A burst below one is raised to one.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow reports whether a request for method originating from ip may proceed,
+// consuming a token from the matching bucket if so.
+func (l *rateLimiter) allow(method, ip string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key := method + "@" + ip
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= rateLimiterMaxBuckets {
+			l.evict(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	// Refill the bucket for the time elapsed since the last request
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens += elapsed * l.rate
+		if bucket.tokens > l.burst {
+			bucket.tokens = l.burst
+		}
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// evict drops all buckets which would have been refilled completely by now, as
+// they carry no state that differs from a freshly created bucket.
+func (l *rateLimiter) evict(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// remoteIP extracts the IP address of the remote peer from the request context,
+// returning an empty string for in-process and IPC connections.
+func remoteIP(ctx context.Context) string {
+	remote, _ := ctx.Value("remote").(string)
+	if remote == "" {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		return remote
+	}
+	return host
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2, 3)
+	now := time.Unix(1000, 0)
+
+	// The burst should be served right away, after which requests are refused
+	for i := 0; i < 3; i++ {
+		if !limiter.allow("test_echo", "10.0.0.1", now) {
+			t.Fatalf("request %d within burst refused", i)
+		}
+	}
+	if limiter.allow("test_echo", "10.0.0.1", now) {
+		t.Fatalf("request above burst allowed")
+	}
+	// Other methods and other clients have their own buckets
+	if !limiter.allow("test_rets", "10.0.0.1", now) {
+		t.Fatalf("request to different method refused")
+	}
+	if !limiter.allow("test_echo", "10.0.0.2", now) {
+		t.Fatalf("request from different client refused")
+	}
+	// Half a second at two requests per second refills a single token
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.allow("test_echo", "10.0.0.1", now) {
+		t.Fatalf("request after refill refused")
+	}
+	if limiter.allow("test_echo", "10.0.0.1", now) {
+		t.Fatalf("request above refill allowed")
+	}
+}
+
+func TestServerBatchLimit(t *testing.T) {
+	server := NewServer()
+	server.SetLimits(ServerLimits{BatchItemLimit: 2})
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	out := json.NewEncoder(clientConn)
+	in := json.NewDecoder(clientConn)
+
+	batch := []map[string]interface{}{
+		{"jsonrpc": "2.0", "id": 1, "method": "test_rets"},
+		{"jsonrpc": "2.0", "id": 2, "method": "test_rets"},
+		{"jsonrpc": "2.0", "id": 3, "method": "test_rets"},
+	}
+	if err := out.Encode(batch); err != nil {
+		t.Fatal(err)
+	}
+	var resp jsonErrResponse
+	if err := in.Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Code != -32600 || !strings.Contains(resp.Error.Message, "batch too large") {
+		t.Fatalf("unexpected error response: %+v", resp.Error)
+	}
+	// A batch within the limit must still be served
+	if err := out.Encode(batch[:2]); err != nil {
+		t.Fatal(err)
+	}
+	var resps []jsonSuccessResponse
+	if err := in.Decode(&resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(resps))
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	server := NewServer()
+	server.SetLimits(ServerLimits{RateLimit: 0.001, RateBurst: 1})
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	remote := context.WithValue(context.Background(), "remote", "10.0.0.1:30303")
+
+	call := func(ctx context.Context) error {
+		client := DialInProc(server)
+		defer client.Close()
+
+		var result string
+		return client.CallContext(ctx, &result, "test_rets")
+	}
+	// Calls through the in-process client carry no remote address
+	for i := 0; i < 3; i++ {
+		if err := call(context.Background()); err != nil {
+			t.Fatalf("local call %d failed: %v", i, err)
+		}
+	}
+	req := &serverRequest{method: "test_rets"}
+	if !server.allowRequest(remote, req) {
+		t.Fatalf("first remote request refused")
+	}
+	if server.allowRequest(remote, req) {
+		t.Fatalf("second remote request allowed")
+	}
+}
+
+func TestServerResponseSizeLimit(t *testing.T) {
+	server := NewServer()
+	server.SetLimits(ServerLimits{BatchResponseMaxSize: 80})
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	out := json.NewEncoder(clientConn)
+	in := json.NewDecoder(clientConn)
+
+	// A single response within the limit must be delivered intact
+	if err := out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "test_rets"}); err != nil {
+		t.Fatal(err)
+	}
+	var resp jsonSuccessResponse
+	if err := in.Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Id == nil || resp.Result != "" {
+		t.Fatalf("unexpected result: %v", resp.Result)
+	}
+	// A single response above the limit must be replaced by an error
+	large := map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "test_echo", "params": []interface{}{strings.Repeat("x", 200), 1, Args{}}}
+	if err := out.Encode(large); err != nil {
+		t.Fatal(err)
+	}
+	var errResp jsonErrResponse
+	if err := in.Decode(&errResp); err != nil {
+		t.Fatal(err)
+	}
+	if errResp.Error.Code != -32003 {
+		t.Fatalf("unexpected error response: %+v", errResp.Error)
+	}
+	// Batch responses are accumulated, failing the ones beyond the limit
+	batch := []map[string]interface{}{
+		{"jsonrpc": "2.0", "id": 3, "method": "test_rets"},
+		{"jsonrpc": "2.0", "id": 4, "method": "test_rets"},
+		{"jsonrpc": "2.0", "id": 5, "method": "test_rets"},
+	}
+	if err := out.Encode(batch); err != nil {
+		t.Fatal(err)
+	}
+	var resps []map[string]interface{}
+	if err := in.Decode(&resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(resps))
+	}
+	for i, resp := range resps {
+		_, failed := resp["error"]
+		if failed != (i == 2) {
+			t.Errorf("response %d: unexpected outcome %v", i, resp)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"time"
+
+	"github.com/eeefan/dpeth/metrics"
+)
+
+var (
+	rpcRequestMeter     = metrics.NewRegisteredMeter("rpc/requests", nil)
+	rpcErrorMeter       = metrics.NewRegisteredMeter("rpc/errors", nil)
+	rpcRateLimitedMeter = metrics.NewRegisteredMeter("rpc/ratelimited", nil)
+	rpcBatchDropMeter   = metrics.NewRegisteredMeter("rpc/batch/dropped", nil)
+	rpcServingTimer     = metrics.NewRegisteredTimer("rpc/duration", nil)
+)
+
+// updateServeMetrics records the outcome and latency of a served RPC method call,
+// both in the global aggregates and in the per-method series.
+func updateServeMetrics(method string, failed bool, elapsed time.Duration) {
+	if !metrics.Enabled {
+		return
+	}
+	rpcRequestMeter.Mark(1)
+	rpcServingTimer.Update(elapsed)
+	metrics.GetOrRegisterCounter("rpc/requests/"+method, nil).Inc(1)
+	if failed {
+		rpcErrorMeter.Mark(1)
+		metrics.GetOrRegisterCounter("rpc/errors/"+method, nil).Inc(1)
+	}
+	metrics.GetOrRegisterTimer("rpc/duration/"+method, nil).Update(elapsed)
+}
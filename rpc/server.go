@@ -18,12 +18,14 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/eeefan/dpeth/log"
 	"gopkg.in/fatih/set.v0"
@@ -187,6 +189,18 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 			}
 			return nil
 		}
+		// Reject batches exceeding the configured limit without executing any of them
+		if limit := s.limits.BatchItemLimit; batch && limit > 0 && len(reqs) > limit {
+			rpcBatchDropMeter.Mark(1)
+			if err := codec.Write(codec.CreateErrorResponse(nil, &batchTooLargeError{limit})); err != nil {
+				log.Debug("Failed to write batch rejection", "err", err)
+				codec.Close()
+			}
+			if singleShot {
+				return nil
+			}
+			continue
+		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
 			if batch {
@@ -277,6 +291,14 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+	if !scopeAllows(ctx, req.svcname) {
+		return codec.CreateErrorResponse(&req.id, &unauthorizedError{req.method}), nil
+	}
+	if !s.allowRequest(ctx, req) {
+		rpcRateLimitedMeter.Mark(1)
+		return codec.CreateErrorResponse(&req.id, &rateLimitedError{req.method}), nil
+	}
+
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
@@ -309,21 +331,50 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
+	start := time.Now()
 	reply := req.callb.method.Func.Call(arguments)
 	if len(reply) == 0 {
+		updateServeMetrics(req.method, false, time.Since(start))
 		return codec.CreateResponse(req.id, nil), nil
 	}
 
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
+			updateServeMetrics(req.method, true, time.Since(start))
 			e := reply[req.callb.errPos].Interface().(error)
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
 	}
+	updateServeMetrics(req.method, false, time.Since(start))
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// allowRequest checks the request against the rate limiter of the server. Calls
+// without a known remote address (in-process and IPC) are never limited.
+func (s *Server) allowRequest(ctx context.Context, req *serverRequest) bool {
+	if s.limiter == nil {
+		return true
+	}
+	ip := remoteIP(ctx)
+	if ip == "" {
+		return true
+	}
+	return s.limiter.allow(req.method, ip, time.Now())
+}
+
+// encodeResponse encodes a response ahead of writing it, so that its size can be
+// checked against the configured limit. The returned raw message is written by
+// the codec as is, without encoding the response a second time. Responses which
+// cannot be encoded are returned untouched, for the codec to report the failure.
+func encodeResponse(res interface{}) (interface{}, int) {
+	blob, err := json.Marshal(res)
+	if err != nil {
+		return res, 0
+	}
+	return json.RawMessage(blob), len(blob)
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
@@ -333,6 +384,12 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 	} else {
 		response, callback = s.handle(ctx, codec, req)
 	}
+	if limit := s.limits.BatchResponseMaxSize; limit > 0 {
+		var size int
+		if response, size = encodeResponse(response); size > limit {
+			response, callback = codec.CreateErrorResponse(&req.id, &responseTooLargeError{}), nil
+		}
+	}
 
 	if err := codec.Write(response); err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
@@ -346,18 +403,38 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 }
 
 // execBatch executes the given requests and writes the result back using the codec.
-// It will only write the response back when the last request is processed.
+// It will only write the response back when the last request is processed. If the
+// accumulated response grows beyond the configured size limit, the remaining
+// requests are not executed and are answered with an error instead.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
-	responses := make([]interface{}, len(requests))
-	var callbacks []func()
+	var (
+		responses = make([]interface{}, len(requests))
+		callbacks []func()
+		limit     = s.limits.BatchResponseMaxSize
+		size      int
+		exceeded  bool
+	)
 	for i, req := range requests {
+		if exceeded {
+			responses[i] = codec.CreateErrorResponse(&req.id, &responseTooLargeError{})
+			continue
+		}
+		var callback func()
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
 		} else {
-			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
-				callbacks = append(callbacks, callback)
+			responses[i], callback = s.handle(ctx, codec, req)
+		}
+		if limit > 0 {
+			var n int
+			if responses[i], n = encodeResponse(responses[i]); size+n > limit {
+				responses[i], callback = codec.CreateErrorResponse(&req.id, &responseTooLargeError{}), nil
+				exceeded = true
 			}
+			size += n
+		}
+		if callback != nil {
+			callbacks = append(callbacks, callback)
 		}
 	}
 
@@ -411,7 +488,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + subscribeMethodSuffix, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -428,7 +505,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + serviceMethodSeparator + r.method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
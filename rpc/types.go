@@ -57,6 +57,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // fully qualified method name used for limits and metrics
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -74,6 +75,9 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	limits  ServerLimits // Batch and response size limits enforced on requests
+	limiter *rateLimiter // Per method and remote IP rate limiter, nil if disabled
 }
 
 // rpcRequest represents a raw incoming RPC request
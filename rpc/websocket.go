@@ -68,7 +68,13 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			// Expose the remote address so per-client limits can be applied
+			ctx := context.WithValue(context.Background(), "remote", conn.Request().RemoteAddr)
+			ctx = withAuthScope(ctx, conn.Request())
+
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -124,6 +130,13 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
 func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
+	return DialWebsocketWithAuth(ctx, endpoint, origin, nil)
+}
+
+// DialWebsocketWithAuth creates a new RPC client that communicates with a JSON-RPC
+// server listening on the given endpoint, authenticating the websocket handshake
+// of every (re)connection with auth. A nil auth sends no credentials.
+func DialWebsocketWithAuth(ctx context.Context, endpoint, origin string, auth HTTPAuth) (*Client, error) {
 	if origin == "" {
 		var err error
 		if origin, err = os.Hostname(); err != nil {
@@ -141,7 +154,18 @@ func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error
 	}
 
 	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
-		return wsDialContext(ctx, config)
+		if auth == nil {
+			return wsDialContext(ctx, config)
+		}
+		authed := *config
+		authed.Header = make(http.Header)
+		for key, values := range config.Header {
+			authed.Header[key] = values
+		}
+		if err := auth(authed.Header); err != nil {
+			return nil, err
+		}
+		return wsDialContext(ctx, &authed)
 	})
 }
 
@@ -26,10 +26,13 @@ import (
 	"reflect"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/eip712"
 	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/accounts/usbwallet"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
+	"github.com/eeefan/dpeth/core/types"
 	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/internal/ethapi"
 	"github.com/eeefan/dpeth/log"
@@ -46,6 +49,10 @@ type ExternalAPI interface {
 	SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error)
 	// Sign - request to sign the given data (plus prefix)
 	Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error)
+	// SignAlienHeader - request to seal the given RLP encoded alien block header, on top of its parent
+	SignAlienHeader(ctx context.Context, addr common.MixedcaseAddress, header hexutil.Bytes, parent hexutil.Bytes) (hexutil.Bytes, error)
+	// SignTypedData - request to sign the given EIP-712 typed data
+	SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data eip712.TypedData) (hexutil.Bytes, error)
 	// EcRecover - request to perform ecrecover
 	EcRecover(ctx context.Context, data, sig hexutil.Bytes) (common.Address, error)
 	// Export - request to export an account
@@ -81,6 +88,14 @@ type SignerUI interface {
 	OnSignerStartup(info StartupInfo)
 }
 
+// TxFailureHandler is implemented by UIs which need to learn about transactions
+// they approved, but which could not be signed, e.g. to release the value they
+// reserved for them.
+type TxFailureHandler interface {
+	// OnFailedTx notifies the UI about an approved transaction failing to be signed.
+	OnFailedTx(tx SendTxArgs, err error)
+}
+
 // SignerAPI defines the actual implementation of ExternalAPI
 type SignerAPI struct {
 	chainID   *big.Int
@@ -94,11 +109,13 @@ type Metadata struct {
 	Remote string `json:"remote"`
 	Local  string `json:"local"`
 	Scheme string `json:"scheme"`
+
+	trace *auditTrace // Outcome of the UI interactions, if the request is audited
 }
 
 // MetadataFromContext extracts Metadata from a given context.Context
 func MetadataFromContext(ctx context.Context) Metadata {
-	m := Metadata{"NA", "NA", "NA"} // batman
+	m := Metadata{Remote: "NA", Local: "NA", Scheme: "NA"} // batman
 
 	if v := ctx.Value("remote"); v != nil {
 		m.Remote = v.(string)
@@ -109,6 +126,9 @@ func MetadataFromContext(ctx context.Context) Metadata {
 	if v := ctx.Value("local"); v != nil {
 		m.Local = v.(string)
 	}
+	if v := ctx.Value(auditTraceKey{}); v != nil {
+		m.trace = v.(*auditTrace)
+	}
 	return m
 }
 
@@ -155,11 +175,26 @@ type (
 		NewPassword string `json:"new_password"`
 	}
 	SignDataRequest struct {
-		Address common.MixedcaseAddress `json:"address"`
-		Rawdata hexutil.Bytes           `json:"raw_data"`
-		Message string                  `json:"message"`
-		Hash    hexutil.Bytes           `json:"hash"`
-		Meta    Metadata                `json:"meta"`
+		ContentType string                  `json:"content_type"`
+		Address     common.MixedcaseAddress `json:"address"`
+		Rawdata     hexutil.Bytes           `json:"raw_data"`
+		Message     string                  `json:"message"`
+		Hash        hexutil.Bytes           `json:"hash"`
+		Header      *AlienHeaderInfo        `json:"header,omitempty"`
+		TypedData   *eip712.TypedData       `json:"typed_data,omitempty"`
+		Meta        Metadata                `json:"meta"`
+	}
+	// AlienHeaderInfo contains the fields of an alien header to be sealed, which
+	// are needed to decide whether the header is in the signer's slot. The slots
+	// are those of the loop start time and signer queue of the parent header, as
+	// a header starting a new loop already carries the queue of that next loop.
+	AlienHeaderInfo struct {
+		Number        uint64           `json:"number"`
+		Time          uint64           `json:"time"`
+		ParentHash    common.Hash      `json:"parent_hash"`
+		Coinbase      common.Address   `json:"coinbase"`
+		LoopStartTime uint64           `json:"loop_start_time"`
+		SignerQueue   []common.Address `json:"signer_queue"`
 	}
 	SignDataResponse struct {
 		Approved bool `json:"approved"`
@@ -187,6 +222,13 @@ type (
 	}
 )
 
+// Content types of the data to sign
+const (
+	TextPlain   = "text/plain"                 // Arbitrary data, signed with the Ethereum message prefix
+	AlienHeader = "application/x-alien-header" // Alien block header, sealed by signing its seal hash
+	TypedData   = "data/typed"                 // EIP-712 typed structured data
+)
+
 var ErrRequestDenied = errors.New("Request denied")
 
 type errorWrapper struct {
@@ -353,6 +395,7 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 	acc = accounts.Account{Address: result.Transaction.From.Address()}
 	wallet, err = api.am.Find(acc)
 	if err != nil {
+		api.failedTx(result.Transaction, err)
 		return nil, err
 	}
 	// Convert fields into a real transaction
@@ -362,6 +405,7 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 	signedTx, err := wallet.SignTxWithPassphrase(acc, result.Password, unsignedTx, api.chainID)
 	if err != nil {
 		api.UI.ShowError(err.Error())
+		api.failedTx(result.Transaction, err)
 		return nil, err
 	}
 
@@ -375,6 +419,14 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 
 }
 
+// failedTx notifies the UI, if interested, about an approved transaction which
+// could not be signed.
+func (api *SignerAPI) failedTx(tx SendTxArgs, err error) {
+	if handler, ok := api.UI.(TxFailureHandler); ok {
+		handler.OnFailedTx(tx, err)
+	}
+}
+
 // Sign calculates an Ethereum ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message))
 //
@@ -388,7 +440,7 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	sighash, msg := SignHash(data)
 	// We make the request prior to looking up if we actually have the account, to prevent
 	// account-enumeration via the API
-	req := &SignDataRequest{Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
+	req := &SignDataRequest{ContentType: TextPlain, Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
 	res, err := api.UI.ApproveSignData(req)
 
 	if err != nil {
@@ -413,6 +465,118 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	return signature, nil
 }
 
+// SignAlienHeader seals an RLP encoded alien block header, returning the 65 byte
+// seal to be placed at the end of its extra-data.
+//
+// The header is decoded and its seal hash calculated by the signer itself, so the
+// approval (possibly by rules) is based on the number and time of the header
+// actually signed rather than on an opaque hash. The slots are given by the loop
+// start time and signer queue of the RLP encoded parent, which has to be the one
+// the header builds on. The genesis block carries no queue, so the children of
+// the genesis are requested with an empty one.
+func (api *SignerAPI) SignAlienHeader(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes, parentData hexutil.Bytes) (hexutil.Bytes, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	sealhash, err := alientypes.SealHash(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if _, err := alientypes.ParseHeaderExtra(header); err != nil {
+		return nil, fmt.Errorf("invalid header extra-data: %v", err)
+	}
+	parent := new(types.Header)
+	if err := rlp.DecodeBytes(parentData, parent); err != nil {
+		return nil, fmt.Errorf("invalid parent header: %v", err)
+	}
+	if parent.Hash() != header.ParentHash || parent.Number.Uint64()+1 != header.Number.Uint64() {
+		return nil, fmt.Errorf("parent header %d [0x%x] is not the parent of the header", parent.Number, parent.Hash())
+	}
+	info := &AlienHeaderInfo{
+		Number:     header.Number.Uint64(),
+		Time:       header.Time.Uint64(),
+		ParentHash: header.ParentHash,
+		Coinbase:   header.Coinbase,
+	}
+	if parent.Number.Sign() > 0 {
+		extra, err := alientypes.ParseHeaderExtra(parent)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parent header extra-data: %v", err)
+		}
+		info.LoopStartTime, info.SignerQueue = extra.LoopStartTime, extra.SignerQueue
+	}
+	msg := fmt.Sprintf("alien header %d [0x%x]", info.Number, sealhash)
+
+	// We make the request prior to looking up if we actually have the account, to prevent
+	// account-enumeration via the API
+	req := &SignDataRequest{ContentType: AlienHeader, Address: addr, Rawdata: data, Message: msg, Hash: sealhash.Bytes(), Header: info, Meta: MetadataFromContext(ctx)}
+	res, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Approved {
+		return nil, ErrRequestDenied
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr.Address()}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Seals are plain signatures of the seal hash, V stays 0/1
+	signature, err := wallet.SignHashWithPassphrase(account, res.Password, sealhash.Bytes())
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	return signature, nil
+}
+
+// SignTypedData calculates an Ethereum ECDSA signature for EIP-712 typed data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// The typed data is validated and formatted by the signer itself, so the UI shows
+// and the rules check the actual fields of the message instead of an opaque hash.
+// As with Sign, the V value of the signature will be 27 or 28.
+func (api *SignerAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data eip712.TypedData) (hexutil.Bytes, error) {
+	sighash, err := data.SigningHash()
+	if err != nil {
+		return nil, fmt.Errorf("invalid typed data: %v", err)
+	}
+	domainSeparator, _ := data.DomainSeparator()
+	messageHash, _ := data.HashStruct(data.PrimaryType, data.Message)
+	rawdata := append(append([]byte{0x19, 0x01}, domainSeparator.Bytes()...), messageHash.Bytes()...)
+
+	fields, err := data.Format()
+	if err != nil {
+		return nil, fmt.Errorf("invalid typed data: %v", err)
+	}
+	// We make the request prior to looking up if we actually have the account, to prevent
+	// account-enumeration via the API
+	req := &SignDataRequest{ContentType: TypedData, Address: addr, Rawdata: rawdata, Message: eip712.Pprint(fields), Hash: sighash.Bytes(), TypedData: &data, Meta: MetadataFromContext(ctx)}
+	res, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Approved {
+		return nil, ErrRequestDenied
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr.Address()}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignHashWithPassphrase(account, res.Password, sighash.Bytes())
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the Account that was used to create the signature.
 // Note, this function is compatible with eth_sign and personal_sign. As such it recovers
 // the address of:
@@ -27,11 +27,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eeefan/dpeth/accounts/eip712"
 	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/cmd/utils"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/common/math"
+	"github.com/eeefan/dpeth/consensus/alien/alientypes"
 	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/internal/ethapi"
 	"github.com/eeefan/dpeth/rlp"
 )
@@ -254,6 +258,201 @@ func TestSignData(t *testing.T) {
 		t.Errorf("Expected 65 byte signature (got %d bytes)", len(h))
 	}
 }
+// mkTestAlienHeader creates an unsealed alien header with the given number and
+// time, carrying the given loop start time and signer queue.
+func mkTestAlienHeader(t *testing.T, parent *types.Header, time uint64, loopStartTime uint64, queue []common.Address) *types.Header {
+	empty := rlp.RawValue(rlp.EmptyList)
+	extra, err := rlp.EncodeToBytes(&alientypes.HeaderExtra{
+		CurrentBlockConfirmations: empty,
+		CurrentBlockVotes:         empty,
+		CurrentBlockProposals:     empty,
+		CurrentBlockDeclares:      empty,
+		ModifyPredecessorVotes:    empty,
+		LoopStartTime:             loopStartTime,
+		SignerQueue:               queue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{
+		Number:     big.NewInt(0),
+		Time:       new(big.Int).SetUint64(time),
+		Difficulty: big.NewInt(1),
+		Extra:      append(append(make([]byte, 32), extra...), make([]byte, 65)...),
+	}
+	if parent != nil {
+		header.ParentHash = parent.Hash()
+		header.Number.Add(parent.Number, big.NewInt(1))
+	}
+	return header
+}
+
+// mkTestAlienHeaders creates an RLP encoded alien header along with its parent.
+func mkTestAlienHeaders(t *testing.T, parent, header *types.Header) (hexutil.Bytes, hexutil.Bytes) {
+	blob, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentBlob, err := rlp.EncodeToBytes(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob, parentBlob
+}
+
+func TestSignAlienHeader(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0].Address)
+
+	var (
+		queue  = []common.Address{list[0].Address}
+		parent = mkTestAlienHeader(t, mkTestAlienHeader(t, nil, 0, 0, nil), 1018, 1000, queue)
+		header = mkTestAlienHeader(t, parent, 1021, 1000, queue)
+	)
+	blob, parentBlob := mkTestAlienHeaders(t, parent, header)
+
+	control <- "No way"
+	if _, err := api.SignAlienHeader(context.Background(), a, blob, parentBlob); err != ErrRequestDenied {
+		t.Errorf("Expected ErrRequestDenied! %v", err)
+	}
+	if _, err := api.SignAlienHeader(context.Background(), a, []byte("not a header"), parentBlob); err == nil {
+		t.Errorf("Expected error for invalid header")
+	}
+	if _, err := api.SignAlienHeader(context.Background(), a, blob, blob); err == nil {
+		t.Errorf("Expected error for mismatching parent")
+	}
+	control <- "Y"
+	control <- "apassword"
+	seal, err := api.SignAlienHeader(context.Background(), a, blob, parentBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The seal must be a plain signature of the seal hash by the signer
+	hash, err := alientypes.SealHash(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, err := crypto.SigToPub(hash.Bytes(), seal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != list[0].Address {
+		t.Errorf("Seal signer mismatch: have %x, want %x", signer, list[0].Address)
+	}
+}
+
+// recordingUI is a HeadlessUI recording the sign data requests it is asked to
+// approve.
+type recordingUI struct {
+	*HeadlessUI
+	requests []*SignDataRequest
+}
+
+func (ui *recordingUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	ui.requests = append(ui.requests, request)
+	return ui.HeadlessUI.ApproveSignData(request)
+}
+
+// Tests that a header starting a new loop, which already carries the queue of
+// that loop, is presented with the slots of its parent's loop it is sealed in.
+func TestSignAlienHeaderLoopBoundary(t *testing.T) {
+	api, control := setup(t)
+	ui := &recordingUI{HeadlessUI: api.UI.(*HeadlessUI)}
+	api.UI = ui
+
+	var (
+		signer = common.HexToAddress("0x694267f14675d7e1b9494fd8d72fefe1755710fa")
+		other  = common.HexToAddress("0x1337")
+		a      = common.NewMixedcaseAddress(signer)
+
+		genesis = mkTestAlienHeader(t, nil, 0, 0, nil)
+		parent  = mkTestAlienHeader(t, genesis, 1006, 1000, []common.Address{other, signer, other})
+		header  = mkTestAlienHeader(t, parent, 1012, 1012, []common.Address{other, other, signer})
+	)
+	blob, parentBlob := mkTestAlienHeaders(t, parent, header)
+	control <- "No"
+	if _, err := api.SignAlienHeader(context.Background(), a, blob, parentBlob); err != ErrRequestDenied {
+		t.Fatalf("Expected ErrRequestDenied! %v", err)
+	}
+	info := ui.requests[0].Header
+	if info.LoopStartTime != 1000 || len(info.SignerQueue) != 3 || info.SignerQueue[(info.Time-info.LoopStartTime)/3%3] != signer {
+		t.Errorf("Slot info mismatch: have loop start %d, queue %x", info.LoopStartTime, info.SignerQueue)
+	}
+	// Children of the genesis block are presented without a queue
+	blob, parentBlob = mkTestAlienHeaders(t, genesis, parent)
+	control <- "No"
+	if _, err := api.SignAlienHeader(context.Background(), a, blob, parentBlob); err != ErrRequestDenied {
+		t.Fatalf("Expected ErrRequestDenied! %v", err)
+	}
+	if info := ui.requests[1].Header; info.LoopStartTime != 0 || len(info.SignerQueue) != 0 {
+		t.Errorf("Genesis child slot info mismatch: have loop start %d, queue %x", info.LoopStartTime, info.SignerQueue)
+	}
+}
+
+func mkTestTypedData() eip712.TypedData {
+	return eip712.TypedData{
+		Types: eip712.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+			"Order":        {{Name: "maker", Type: "address"}, {Name: "amount", Type: "uint256"}},
+		},
+		PrimaryType: "Order",
+		Domain:      eip712.Domain{Name: "Exchange", ChainId: (*math.HexOrDecimal256)(big.NewInt(1))},
+		Message: map[string]interface{}{
+			"maker":  "0x1337000000000000000000000000000000001337",
+			"amount": "1000000000000000000",
+		},
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0].Address)
+
+	data := mkTestTypedData()
+	control <- "No way"
+	if _, err := api.SignTypedData(context.Background(), a, data); err != ErrRequestDenied {
+		t.Errorf("Expected ErrRequestDenied! %v", err)
+	}
+	invalid := mkTestTypedData()
+	invalid.Message["amount"] = "-1"
+	if _, err := api.SignTypedData(context.Background(), a, invalid); err == nil {
+		t.Errorf("Expected error for invalid typed data")
+	}
+	control <- "Y"
+	control <- "apassword"
+	sig, err := api.SignTypedData(context.Background(), a, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		t.Fatalf("Invalid signature V value %d", sig[64])
+	}
+	sig[64] -= 27
+	hash, err := data.SigningHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != list[0].Address {
+		t.Errorf("Signer mismatch: have %x, want %x", signer, list[0].Address)
+	}
+}
+
 func mkTestTx(from common.MixedcaseAddress) SendTxArgs {
 	to := common.NewMixedcaseAddress(common.HexToAddress("0x1337"))
 	gas := hexutil.Uint64(21000)
@@ -17,94 +17,647 @@
 package core
 
 import (
+	"bufio"
+	"bytes"
 	"context"
-
+	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/eip712"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/crypto"
 	"github.com/eeefan/dpeth/internal/ethapi"
 	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/signer/storage"
+)
+
+// Decisions of the UI and verdicts of the rule engine recorded in the audit log.
+const (
+	AuditApproved = "approved" // Request approved by the UI or the rules
+	AuditRejected = "rejected" // Request rejected by the UI or the rules
+	AuditDeferred = "deferred" // Rules left the decision to the UI
 )
 
+// AuditRotated is the method recorded by the entry starting a new chain in place
+// of an audit log which had to be moved aside. It commits to the head of the old
+// chain, as far as known, instead of to an empty hash.
+const AuditRotated = "AuditLogRotated"
+
+// AuditEntry is a single entry of the audit log, describing an API call along
+// with its outcome. Entries are hash-chained, every entry committing to the
+// hash of the previous one.
+type AuditEntry struct {
+	Index    uint64          `json:"index"`
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Metadata Metadata        `json:"metadata"`
+	Account  *common.Address `json:"account,omitempty"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Verdict  string          `json:"verdict,omitempty"`  // Verdict of the rule engine, if enabled
+	Decision string          `json:"decision,omitempty"` // Decision of the UI, if consulted
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Prev     common.Hash     `json:"prev"`
+}
+
+// auditRecord is the line format of the audit log. The entry is kept as raw json
+// so that its hash can be checked against the exact bytes written.
+type auditRecord struct {
+	Entry json.RawMessage `json:"entry"`
+	Hash  common.Hash     `json:"hash"`
+	Seal  hexutil.Bytes   `json:"seal,omitempty"`
+}
+
+// AuditRecord is an entry of the audit log as read back, along with its
+// integrity data.
+type AuditRecord struct {
+	AuditEntry
+	Hash   common.Hash     // Hash of the entry, committed to by the next one
+	Sealer *common.Address // Account having signed the hash, if the entry is sealed
+}
+
+// AuditLogError is returned when the audit log fails the integrity checks.
+type AuditLogError struct {
+	Line int   // Line of the first entry failing the checks
+	Err  error // Reason of the failure
+}
+
+func (err *AuditLogError) Error() string {
+	return fmt.Sprintf("audit log corrupted at line %d: %v", err.Line, err.Err)
+}
+
+// AuditChainError is returned when the audit log can't be continued, as it
+// failed the integrity checks, lost entries off its end or went missing.
+type AuditChainError struct {
+	Err error // Reason the chain can't be continued
+}
+
+func (err *AuditChainError) Error() string {
+	return fmt.Sprintf("audit log can't be continued: %v", err.Err)
+}
+
+// AuditRotation is the request recorded by the entry starting a new chain.
+type AuditRotation struct {
+	Moved  string     `json:"moved,omitempty"` // Path the old log was moved to, empty if it was missing
+	Reason string     `json:"reason"`          // Reason the old log couldn't be continued
+	Head   *AuditHead `json:"head,omitempty"`  // Head of the old chain, if known
+}
+
+// auditTrace collects the outcome of the UI interactions of a single request. It
+// travels along with the request metadata to the UI, so that the decisions can
+// be recorded without changing the UI interface.
+type auditTrace struct {
+	verdict  string
+	decision string
+}
+
+type auditTraceKey struct{}
+
+// AuditLogger is an ExternalAPI wrapping another one, recording every call in an
+// append-only, hash-chained log file. Calls whose entry can't be written fail,
+// so no result is ever handed out without being recorded.
 type AuditLogger struct {
-	log log.Logger
 	api ExternalAPI
+
+	lock   sync.Mutex
+	path   string
+	file   *os.File
+	offset int64       // Size of the log up to the last entry written in full
+	index  uint64      // Index of the next entry
+	head   common.Hash // Hash of the last entry
+	failed error       // Set if the log couldn't be restored after a failed write
+
+	heads   storage.Storage // Storage of the chain head, nil if disabled
+	headKey string          // Key of the chain head in the storage
+
+	sealKey      *ecdsa.PrivateKey // Key signing the log, nil if disabled
+	sealInterval uint64            // Number of entries between seals
+}
+
+// AuditHead is the last entry of the audit log, kept in encrypted storage on
+// every append so that entries cut off the end of the log can be detected.
+type AuditHead struct {
+	Index uint64      `json:"index"`
+	Hash  common.Hash `json:"hash"`
+}
+
+// auditHeadKey returns the storage key of the head of the audit log at path.
+func auditHeadKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "auditlog_head:" + path
+}
+
+// StoredAuditHead returns the head of the audit log at path as last stored in
+// the given storage, or nil if none was stored.
+func StoredAuditHead(heads storage.Storage, path string) *AuditHead {
+	blob := heads.Get(auditHeadKey(path))
+	if blob == "" {
+		return nil
+	}
+	head := new(AuditHead)
+	if err := json.Unmarshal([]byte(blob), head); err != nil {
+		return nil
+	}
+	return head
+}
+
+// NewAuditLogger opens the audit log at path and wraps the given API to append
+// to it. The entries already present are checked for integrity and, if a head
+// storage is given, for the head stored there, which must be part of the chain.
+//
+// A log failing the checks, such as one of the legacy logfmt format or one with
+// entries cut off, or missing while a head was stored, is refused with an
+// *AuditChainError. If rotate is set, it is moved aside instead and a new chain
+// is started, whose first entry records the rotation and commits to the head of
+// the old chain.
+func NewAuditLogger(path string, api ExternalAPI, heads storage.Storage, rotate bool) (*AuditLogger, error) {
+	l := &AuditLogger{api: api, path: path, heads: heads, headKey: auditHeadKey(path)}
+
+	var stored, last *AuditHead
+	if heads != nil {
+		stored = StoredAuditHead(heads, path)
+	}
+	found := false
+	err := ReadAuditLog(path, func(rec *AuditRecord) error {
+		l.index, l.head = rec.Index+1, rec.Hash
+		last = &AuditHead{rec.Index, rec.Hash}
+		if stored != nil && *stored == *last {
+			found = true
+		}
+		return nil
+	})
+	var (
+		reason error
+		exists = true
+	)
+	switch {
+	case os.IsNotExist(err):
+		exists = false
+		if stored != nil {
+			reason = fmt.Errorf("log missing, stored head #%d [%x]", stored.Index, stored.Hash)
+		}
+	case err != nil:
+		if _, ok := err.(*AuditLogError); !ok {
+			return nil, err
+		}
+		reason = err
+	case stored != nil && !found:
+		reason = fmt.Errorf("stored head #%d [%x] missing, entries cut off", stored.Index, stored.Hash)
+	}
+	var rotation *AuditRotation
+	if reason != nil {
+		if !rotate {
+			return nil, &AuditChainError{reason}
+		}
+		// Commit to the stored head if known, the last intact entry otherwise
+		rotation = &AuditRotation{Reason: reason.Error(), Head: stored}
+		if rotation.Head == nil {
+			rotation.Head = last
+		}
+		if exists {
+			rotation.Moved = fmt.Sprintf("%s.%d.bak", path, time.Now().Unix())
+			if err := os.Rename(path, rotation.Moved); err != nil {
+				return nil, err
+			}
+		}
+		log.Warn("Audit log can't be continued, starting a new chain", "path", path, "moved", rotation.Moved, "err", reason)
+		l.index, l.head = 0, common.Hash{}
+		if rotation.Head != nil {
+			l.head = rotation.Head.Hash
+		}
+	}
+	if l.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
+		return nil, err
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		l.file.Close()
+		return nil, err
+	}
+	l.offset = info.Size()
+	if rotation != nil {
+		request, _ := json.Marshal(rotation)
+		entry := &AuditEntry{Time: time.Now().UTC(), Method: AuditRotated, Request: request}
+		if err := l.append(entry); err != nil {
+			l.file.Close()
+			return nil, err
+		}
+	} else if l.index > 0 {
+		l.storeHead()
+	}
+	return l, nil
+}
+
+// EnableSealing makes the logger sign the hash of every interval-th entry with
+// the given key, preventing anyone without it from rewriting the log.
+func (l *AuditLogger) EnableSealing(key *ecdsa.PrivateKey, interval uint64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.sealKey, l.sealInterval = key, interval
+}
+
+// Close closes the audit log file.
+func (l *AuditLogger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.file.Close()
+}
+
+// begin starts the audit of a request, returning the context to pass on to the
+// wrapped API.
+func (l *AuditLogger) begin(ctx context.Context, method string, account *common.Address, request interface{}) (context.Context, *AuditEntry) {
+	trace := new(auditTrace)
+	ctx = context.WithValue(ctx, auditTraceKey{}, trace)
+
+	entry := &AuditEntry{
+		Time:     time.Now().UTC(),
+		Method:   method,
+		Metadata: MetadataFromContext(ctx),
+		Account:  account,
+	}
+	if request != nil {
+		entry.Request, _ = json.Marshal(request)
+	}
+	return ctx, entry
+}
+
+// end completes the audit of a request with its outcome and appends it to the log.
+// If the entry can't be written, an error is returned which the caller has to
+// hand out instead of the result.
+func (l *AuditLogger) end(ctx context.Context, entry *AuditEntry, result interface{}, err error) error {
+	if trace, ok := ctx.Value(auditTraceKey{}).(*auditTrace); ok {
+		entry.Verdict, entry.Decision = trace.verdict, trace.decision
+	}
+	if result != nil {
+		entry.Result, _ = json.Marshal(result)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := l.append(entry); err != nil {
+		log.Error("Failed to write audit log, withholding the result", "method", entry.Method, "err", err)
+		return fmt.Errorf("audit log unavailable: %v", err)
+	}
+	return nil
+}
+
+// append chains the entry to the log, sealing it if due, and writes it out. A
+// failed write is cut off the log again, keeping the chain intact.
+func (l *AuditLogger) append(entry *AuditEntry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.failed != nil {
+		return l.failed
+	}
+	entry.Index, entry.Prev = l.index, l.head
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	rec := auditRecord{Entry: body, Hash: crypto.Keccak256Hash(body)}
+	if l.sealKey != nil && l.sealInterval > 0 && (entry.Index+1)%l.sealInterval == 0 {
+		if rec.Seal, err = crypto.Sign(rec.Hash[:], l.sealKey); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := l.file.Write(line); err != nil {
+		return l.rollback(err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return l.rollback(err)
+	}
+	l.offset += int64(len(line))
+	l.index, l.head = entry.Index+1, rec.Hash
+	l.storeHead()
+	return nil
+}
+
+// rollback cuts a partially written entry off the log after a failed write. If
+// that fails too, the log is unusable and all further entries are refused.
+func (l *AuditLogger) rollback(err error) error {
+	if terr := l.file.Truncate(l.offset); terr != nil {
+		l.failed = fmt.Errorf("audit log %s left inconsistent: %v", l.path, terr)
+		return l.failed
+	}
+	return err
+}
+
+// storeHead saves the head of the chain in the head storage, if enabled.
+func (l *AuditLogger) storeHead() {
+	if l.heads == nil {
+		return
+	}
+	blob, _ := json.Marshal(&AuditHead{Index: l.index - 1, Hash: l.head})
+	l.heads.Put(l.headKey, string(blob))
+}
+
+// ReadAuditLog reads the audit log at path, checking the hash chain and the seals,
+// and calls fn for every entry in order. A first entry recording a rotation may
+// commit to the head of the old chain. Reading stops at the first entry failing
+// the checks, returning an *AuditLogError.
+func ReadAuditLog(path string, fn func(*AuditRecord) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		reader = bufio.NewReader(f)
+		head   common.Hash
+	)
+	for line, index := 1, uint64(0); ; line, index = line+1, index+1 {
+		raw, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(raw) == 0 {
+			return nil
+		}
+		if err == io.EOF {
+			return &AuditLogError{line, errors.New("incomplete entry")}
+		}
+		if err != nil {
+			return err
+		}
+		rec, err := decodeAuditRecord(raw)
+		if err != nil {
+			return &AuditLogError{line, err}
+		}
+		switch {
+		case rec.Index != index:
+			return &AuditLogError{line, fmt.Errorf("index mismatch: have %d, want %d", rec.Index, index)}
+		case rec.Prev != head && !(index == 0 && rec.Method == AuditRotated):
+			return &AuditLogError{line, fmt.Errorf("chain broken: previous hash %x, want %x", rec.Prev, head)}
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+		head = rec.Hash
+	}
+}
+
+// decodeAuditRecord decodes a line of the audit log, checking the hash and the
+// seal of the entry.
+func decodeAuditRecord(line []byte) (*AuditRecord, error) {
+	var raw auditRecord
+	if err := json.Unmarshal(bytes.TrimSpace(line), &raw); err != nil {
+		return nil, err
+	}
+	if hash := crypto.Keccak256Hash(raw.Entry); hash != raw.Hash {
+		return nil, fmt.Errorf("hash mismatch: have %x, want %x", hash, raw.Hash)
+	}
+	rec := &AuditRecord{Hash: raw.Hash}
+	if err := json.Unmarshal(raw.Entry, &rec.AuditEntry); err != nil {
+		return nil, err
+	}
+	if len(raw.Seal) > 0 {
+		pub, err := crypto.SigToPub(raw.Hash[:], raw.Seal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seal: %v", err)
+		}
+		sealer := crypto.PubkeyToAddress(*pub)
+		rec.Sealer = &sealer
+	}
+	return rec, nil
 }
 
 func (l *AuditLogger) List(ctx context.Context) (Accounts, error) {
-	l.log.Info("List", "type", "request", "metadata", MetadataFromContext(ctx).String())
+	ctx, entry := l.begin(ctx, "List", nil, nil)
 	res, e := l.api.List(ctx)
 
-	l.log.Info("List", "type", "response", "data", res.String())
-
+	var addrs interface{}
+	if e == nil {
+		list := make([]common.Address, 0, len(res))
+		for _, acc := range res {
+			list = append(list, acc.Address)
+		}
+		addrs = list
+	}
+	if err := l.end(ctx, entry, addrs, e); err != nil {
+		return nil, err
+	}
 	return res, e
 }
 
 func (l *AuditLogger) New(ctx context.Context) (accounts.Account, error) {
-	return l.api.New(ctx)
-}
+	ctx, entry := l.begin(ctx, "New", nil, nil)
+	acc, e := l.api.New(ctx)
 
-func (l *AuditLogger) SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error) {
-	sel := "<nil>"
-	if methodSelector != nil {
-		sel = *methodSelector
+	var res interface{}
+	if e == nil {
+		res = acc.Address
+	}
+	if err := l.end(ctx, entry, res, e); err != nil {
+		return accounts.Account{}, err
 	}
-	l.log.Info("SignTransaction", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"tx", args.String(),
-		"methodSelector", sel)
+	return acc, e
+}
 
+func (l *AuditLogger) SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error) {
+	from := args.From.Address()
+	ctx, entry := l.begin(ctx, "SignTransaction", &from, map[string]interface{}{
+		"tx":             args,
+		"methodSelector": methodSelector,
+	})
 	res, e := l.api.SignTransaction(ctx, args, methodSelector)
+
+	var raw interface{}
 	if res != nil {
-		l.log.Info("SignTransaction", "type", "response", "data", common.Bytes2Hex(res.Raw), "error", e)
-	} else {
-		l.log.Info("SignTransaction", "type", "response", "data", res, "error", e)
+		raw = res.Raw
+	}
+	if err := l.end(ctx, entry, raw, e); err != nil {
+		return nil, err
 	}
 	return res, e
 }
 
 func (l *AuditLogger) Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
-	l.log.Info("Sign", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"addr", addr.String(), "data", common.Bytes2Hex(data))
+	account := addr.Address()
+	ctx, entry := l.begin(ctx, "Sign", &account, map[string]interface{}{"data": data})
 	b, e := l.api.Sign(ctx, addr, data)
-	l.log.Info("Sign", "type", "response", "data", common.Bytes2Hex(b), "error", e)
+	if err := l.end(ctx, entry, signatureResult(b), e); err != nil {
+		return nil, err
+	}
+	return b, e
+}
+
+func (l *AuditLogger) SignAlienHeader(ctx context.Context, addr common.MixedcaseAddress, header hexutil.Bytes, parent hexutil.Bytes) (hexutil.Bytes, error) {
+	account := addr.Address()
+	ctx, entry := l.begin(ctx, "SignAlienHeader", &account, map[string]interface{}{"header": header, "parent": parent})
+	b, e := l.api.SignAlienHeader(ctx, addr, header, parent)
+	if err := l.end(ctx, entry, signatureResult(b), e); err != nil {
+		return nil, err
+	}
+	return b, e
+}
+
+func (l *AuditLogger) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data eip712.TypedData) (hexutil.Bytes, error) {
+	account := addr.Address()
+	ctx, entry := l.begin(ctx, "SignTypedData", &account, data)
+	b, e := l.api.SignTypedData(ctx, addr, data)
+	if err := l.end(ctx, entry, signatureResult(b), e); err != nil {
+		return nil, err
+	}
 	return b, e
 }
 
 func (l *AuditLogger) EcRecover(ctx context.Context, data, sig hexutil.Bytes) (common.Address, error) {
-	l.log.Info("EcRecover", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"data", common.Bytes2Hex(data))
+	ctx, entry := l.begin(ctx, "EcRecover", nil, map[string]interface{}{"data": data, "sig": sig})
 	a, e := l.api.EcRecover(ctx, data, sig)
-	l.log.Info("EcRecover", "type", "response", "addr", a.String(), "error", e)
+
+	var res interface{}
+	if e == nil {
+		res = a
+	}
+	if err := l.end(ctx, entry, res, e); err != nil {
+		return common.Address{}, err
+	}
 	return a, e
 }
 
 func (l *AuditLogger) Export(ctx context.Context, addr common.Address) (json.RawMessage, error) {
-	l.log.Info("Export", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"addr", addr.Hex())
+	ctx, entry := l.begin(ctx, "Export", &addr, nil)
 	j, e := l.api.Export(ctx, addr)
 	// In this case, we don't actually log the json-response, which may be extra sensitive
-	l.log.Info("Export", "type", "response", "json response size", len(j), "error", e)
+	if err := l.end(ctx, entry, map[string]int{"size": len(j)}, e); err != nil {
+		return nil, err
+	}
 	return j, e
 }
 
 func (l *AuditLogger) Import(ctx context.Context, keyJSON json.RawMessage) (Account, error) {
 	// Don't actually log the json contents
-	l.log.Info("Import", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"keyJSON size", len(keyJSON))
+	ctx, entry := l.begin(ctx, "Import", nil, map[string]int{"size": len(keyJSON)})
 	a, e := l.api.Import(ctx, keyJSON)
-	l.log.Info("Import", "type", "response", "addr", a.String(), "error", e)
+
+	var res interface{}
+	if e == nil {
+		res = a.Address
+	}
+	if err := l.end(ctx, entry, res, e); err != nil {
+		return Account{}, err
+	}
 	return a, e
 }
 
-func NewAuditLogger(path string, api ExternalAPI) (*AuditLogger, error) {
-	l := log.New("api", "signer")
-	handler, err := log.FileHandler(path, log.LogfmtFormat())
-	if err != nil {
-		return nil, err
+// signatureResult returns the signature to record, or nil if none was made.
+func signatureResult(sig hexutil.Bytes) interface{} {
+	if sig == nil {
+		return nil
 	}
-	l.SetHandler(handler)
-	l.Info("Configured", "audit log", path)
-	return &AuditLogger{l, api}, nil
+	return sig
+}
+
+// auditUI is a SignerUI recording the decisions of the wrapped UI in the audit
+// trace of the requests. Wrapping the rule engine, it records its verdict,
+// deferred if the rules passed the request on to the UI.
+type auditUI struct {
+	next  SignerUI
+	rules bool
+}
+
+// NewAuditUI wraps the user interface to record its decisions in the audit log.
+func NewAuditUI(next SignerUI) SignerUI {
+	return &auditUI{next: next}
+}
+
+// NewAuditRulesUI wraps the rule engine to record its verdicts in the audit log.
+// The UI the rules defer to should be wrapped with NewAuditUI.
+func NewAuditRulesUI(next SignerUI) SignerUI {
+	return &auditUI{next: next, rules: true}
+}
+
+// record stores the outcome of a request in its audit trace, if audited.
+func (ui *auditUI) record(meta Metadata, approved bool) {
+	trace := meta.trace
+	if trace == nil {
+		return
+	}
+	outcome := AuditRejected
+	if approved {
+		outcome = AuditApproved
+	}
+	switch {
+	case !ui.rules:
+		trace.decision = outcome
+	case trace.decision != "":
+		trace.verdict = AuditDeferred
+	default:
+		trace.verdict = outcome
+	}
+}
+
+func (ui *auditUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	res, err := ui.next.ApproveTx(request)
+	ui.record(request.Meta, err == nil && res.Approved)
+	return res, err
+}
+
+func (ui *auditUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	res, err := ui.next.ApproveSignData(request)
+	ui.record(request.Meta, err == nil && res.Approved)
+	return res, err
+}
+
+func (ui *auditUI) ApproveExport(request *ExportRequest) (ExportResponse, error) {
+	res, err := ui.next.ApproveExport(request)
+	ui.record(request.Meta, err == nil && res.Approved)
+	return res, err
+}
+
+func (ui *auditUI) ApproveImport(request *ImportRequest) (ImportResponse, error) {
+	res, err := ui.next.ApproveImport(request)
+	ui.record(request.Meta, err == nil && res.Approved)
+	return res, err
+}
+
+func (ui *auditUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	res, err := ui.next.ApproveListing(request)
+	ui.record(request.Meta, err == nil && res.Accounts != nil)
+	return res, err
+}
+
+func (ui *auditUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	res, err := ui.next.ApproveNewAccount(request)
+	ui.record(request.Meta, err == nil && res.Approved)
+	return res, err
+}
+
+func (ui *auditUI) ShowError(message string) {
+	ui.next.ShowError(message)
+}
+
+func (ui *auditUI) ShowInfo(message string) {
+	ui.next.ShowInfo(message)
+}
+
+func (ui *auditUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	ui.next.OnApprovedTx(tx)
+}
+
+func (ui *auditUI) OnFailedTx(tx SendTxArgs, err error) {
+	if handler, ok := ui.next.(TxFailureHandler); ok {
+		handler.OnFailedTx(tx, err)
+	}
+}
+
+func (ui *auditUI) OnSignerStartup(info StartupInfo) {
+	ui.next.OnSignerStartup(info)
 }
@@ -0,0 +1,325 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/signer/storage"
+)
+
+func readAuditLog(t *testing.T, path string) ([]*AuditRecord, error) {
+	var recs []*AuditRecord
+	err := ReadAuditLog(path, func(rec *AuditRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})
+	return recs, err
+}
+
+// Tests that API calls are recorded with the decisions taken on them, chained
+// and sealed, and that the chain continues across restarts.
+func TestAuditLog(t *testing.T) {
+	api, control := setup(t)
+	api.UI = NewAuditRulesUI(NewAuditUI(api.UI))
+
+	path := filepath.Join(tmpDirName(t), "audit.log")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	heads := storage.NewEphemeralStorage()
+	logger, err := NewAuditLogger(path, api, heads, false)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	logger.EnableSealing(key, 2)
+
+	control <- "Y"
+	control <- "apassword"
+	acc, err := logger.New(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the account manager to pick up the new account
+	for i := 0; i < 100 && len(api.am.Wallets()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	control <- "A"
+	if _, err := logger.List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	addr := common.NewMixedcaseAddress(acc.Address)
+	control <- "N"
+	if _, err := logger.Sign(context.Background(), addr, []byte("EHLO world")); err != ErrRequestDenied {
+		t.Fatalf("denied signing error mismatch: have %v, want %v", err, ErrRequestDenied)
+	}
+	control <- "Y"
+	control <- "apassword"
+	sig, err := logger.Sign(context.Background(), addr, []byte("EHLO world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigHex := sig.String()
+	logger.Close()
+
+	// Reopen the log and append to the chain
+	if logger, err = NewAuditLogger(path, api, heads, false); err != nil {
+		t.Fatalf("failed to reopen audit log: %v", err)
+	}
+	if _, err := logger.EcRecover(context.Background(), []byte("EHLO world"), sig); err != nil {
+		t.Fatal(err)
+	}
+	logger.Close()
+
+	recs, err := readAuditLog(t, path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	want := []struct {
+		method   string
+		verdict  string
+		decision string
+		err      string
+	}{
+		{"New", AuditDeferred, AuditApproved, ""},
+		{"List", AuditDeferred, AuditApproved, ""},
+		{"Sign", AuditDeferred, AuditRejected, ErrRequestDenied.Error()},
+		{"Sign", AuditDeferred, AuditApproved, ""},
+		{"EcRecover", "", "", ""},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("entry count mismatch: have %d, want %d", len(recs), len(want))
+	}
+	for i, rec := range recs {
+		if rec.Method != want[i].method || rec.Verdict != want[i].verdict || rec.Decision != want[i].decision || rec.Error != want[i].err {
+			t.Errorf("entry %d mismatch: have %s/%s/%s/%q, want %s/%s/%s/%q", i,
+				rec.Method, rec.Verdict, rec.Decision, rec.Error, want[i].method, want[i].verdict, want[i].decision, want[i].err)
+		}
+	}
+	if recs[3].Account == nil || *recs[3].Account != acc.Address {
+		t.Errorf("signing account mismatch: have %v, want %x", recs[3].Account, acc.Address)
+	}
+	if !bytes.Contains(recs[3].Result, []byte(sigHex)) {
+		t.Errorf("signature not recorded: %s", recs[3].Result)
+	}
+	sealer := crypto.PubkeyToAddress(key.PublicKey)
+	for i, sealed := range []bool{false, true, false, true} {
+		if have := recs[i].Sealer != nil && *recs[i].Sealer == sealer; have != sealed {
+			t.Errorf("entry %d seal mismatch: have %v, want %v", i, have, sealed)
+		}
+	}
+	if recs[4].Sealer != nil {
+		t.Errorf("unexpected seal after reopening without sealing")
+	}
+	if head := StoredAuditHead(heads, path); head == nil || *head != (AuditHead{recs[4].Index, recs[4].Hash}) {
+		t.Errorf("stored head mismatch: have %v, want #%d [%x]", head, recs[4].Index, recs[4].Hash)
+	}
+}
+
+// writeAuditEntries opens the audit log at path and records n rejected account
+// creations.
+func writeAuditEntries(t *testing.T, path string, api *SignerAPI, control chan string, heads storage.Storage, rotate bool, n int) {
+	logger, err := NewAuditLogger(path, api, heads, rotate)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		control <- "N"
+		logger.New(context.Background())
+	}
+	logger.Close()
+}
+
+// checkAuditRefused checks that the audit log at path is refused without the
+// rotation override, and left in place.
+func checkAuditRefused(t *testing.T, name, path string, api *SignerAPI, heads storage.Storage) {
+	if _, err := NewAuditLogger(path, api, heads, false); err == nil {
+		t.Fatalf("%s: broken audit log continued", name)
+	} else if _, ok := err.(*AuditChainError); !ok {
+		t.Errorf("%s: error mismatch: have %v, want chain error", name, err)
+	}
+	if aside, _ := filepath.Glob(path + ".*.bak"); len(aside) != 0 {
+		t.Errorf("%s: audit log moved aside without override: %v", name, aside)
+	}
+}
+
+// checkAuditRotated checks that a new chain was started at path, committing to
+// the given head of the old one, and that the old log was moved aside if moved.
+func checkAuditRotated(t *testing.T, name, path string, prev common.Hash, moved bool) {
+	aside, _ := filepath.Glob(path + ".*.bak")
+	if moved && len(aside) != 1 || !moved && len(aside) != 0 {
+		t.Errorf("%s: moved audit log mismatch: have %v, want moved %v", name, aside, moved)
+	}
+	for _, file := range aside {
+		os.Remove(file)
+	}
+	recs, err := readAuditLog(t, path)
+	if err != nil {
+		t.Fatalf("%s: failed to read new audit log: %v", name, err)
+	}
+	if len(recs) != 2 || recs[0].Method != AuditRotated || recs[1].Prev != recs[0].Hash {
+		t.Fatalf("%s: new chain mismatch: have %d entries", name, len(recs))
+	}
+	if recs[0].Prev != prev {
+		t.Errorf("%s: rotation commits to %x, want %x", name, recs[0].Prev, prev)
+	}
+	var rotation AuditRotation
+	if err := json.Unmarshal(recs[0].Request, &rotation); err != nil {
+		t.Fatalf("%s: invalid rotation entry: %v", name, err)
+	}
+	if rotation.Reason == "" || (rotation.Moved != "") != moved || (len(aside) == 1 && rotation.Moved != aside[0]) {
+		t.Errorf("%s: rotation entry mismatch: have %+v, moved to %v", name, rotation, aside)
+	}
+}
+
+// Tests that modified, dropped and truncated entries are detected, and that such
+// logs, as well as ones of the legacy format, are refused unless rotated.
+func TestAuditLogTampering(t *testing.T) {
+	api, control := setup(t)
+	path := filepath.Join(tmpDirName(t), "audit.log")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	writeAuditEntries(t, path, api, control, nil, false, 3)
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := readAuditLog(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(blob, []byte("\n"))
+	tests := []struct {
+		name   string
+		tamper func() []byte
+		line   int
+	}{
+		{"modified", func() []byte { return bytes.Replace(blob, []byte(`"New"`), []byte(`"Nop"`), 1) }, 1},
+		{"dropped", func() []byte { return append(append([]byte{}, lines[0]...), lines[2]...) }, 2},
+		{"cut", func() []byte { return blob[:len(blob)-10] }, 3},
+		{"legacy", func() []byte { return []byte("t=2019-01-01T00:00:00+0000 lvl=info msg=New api=signer type=request\n") }, 1},
+	}
+	for _, tt := range tests {
+		if err := ioutil.WriteFile(path, tt.tamper(), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readAuditLog(t, path)
+		if lerr, ok := err.(*AuditLogError); !ok || lerr.Line != tt.line {
+			t.Errorf("%s: error mismatch: have %v, want corruption at line %d", tt.name, err, tt.line)
+		}
+		checkAuditRefused(t, tt.name, path, api, nil)
+
+		// Rotating commits to the last intact entry, lacking a stored head
+		var prev common.Hash
+		if tt.line > 1 && tt.name != "legacy" {
+			prev = recs[tt.line-2].Hash
+		}
+		writeAuditEntries(t, path, api, control, nil, true, 1)
+		checkAuditRotated(t, tt.name, path, prev, true)
+	}
+}
+
+// Tests that whole entries cut off the end of the log, or the whole log going
+// missing, are detected through the stored head of the chain.
+func TestAuditLogTruncation(t *testing.T) {
+	api, control := setup(t)
+	path := filepath.Join(tmpDirName(t), "audit.log")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	heads := storage.NewEphemeralStorage()
+	writeAuditEntries(t, path, api, control, heads, false, 3)
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(blob, []byte("\n"))
+	if err := ioutil.WriteFile(path, bytes.Join(lines[:2], nil), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// The remaining chain is intact, only the stored head reveals the cut
+	if recs, err := readAuditLog(t, path); err != nil || len(recs) != 2 {
+		t.Fatalf("truncated audit log mismatch: have %d entries, %v", len(recs), err)
+	}
+	head := StoredAuditHead(heads, path)
+	if head == nil || head.Index != 2 {
+		t.Fatalf("stored head mismatch: have %v, want #2", head)
+	}
+	checkAuditRefused(t, "truncated", path, api, heads)
+	writeAuditEntries(t, path, api, control, heads, true, 1)
+	checkAuditRotated(t, "truncated", path, head.Hash, true)
+
+	if stored := StoredAuditHead(heads, path); stored == nil || stored.Index != 1 {
+		t.Errorf("stored head mismatch: have %v, want #1", stored)
+	}
+	// A missing log is refused as well, the new chain continuing the stored head
+	head = StoredAuditHead(heads, path)
+	os.Remove(path)
+	checkAuditRefused(t, "missing", path, api, heads)
+	writeAuditEntries(t, path, api, control, heads, true, 1)
+	checkAuditRotated(t, "missing", path, head.Hash, false)
+}
+
+// Tests that results are withheld if their entry can't be written, and that the
+// log is left at the last entry written in full.
+func TestAuditLogFailClosed(t *testing.T) {
+	api, _ := setup(t)
+	path := filepath.Join(tmpDirName(t), "audit.log")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	logger, err := NewAuditLogger(path, api, nil, false)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer logger.Close()
+
+	key, _ := crypto.GenerateKey()
+	data := []byte("EHLO world")
+	hash, _ := SignHash(data)
+	sig, _ := crypto.Sign(hash, key)
+	sig[64] += 27
+
+	// EcRecover transforms the signature in place, hand out copies
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	if addr, err := logger.EcRecover(context.Background(), data, common.CopyBytes(sig)); err != nil || addr != want {
+		t.Fatalf("recovered address mismatch: have %x, %v, want %x", addr, err, want)
+	}
+	// Swap in a read-only handle, failing all writes
+	logger.file.Close()
+	if logger.file, err = os.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := logger.EcRecover(context.Background(), data, common.CopyBytes(sig))
+	if err == nil {
+		t.Fatalf("result handed out without audit entry")
+	}
+	if addr != (common.Address{}) {
+		t.Errorf("address handed out without audit entry: %x", addr)
+	}
+	recs, err := readAuditLog(t, path)
+	if err != nil || len(recs) != 1 {
+		t.Errorf("audit log mismatch after failed write: have %d entries, %v", len(recs), err)
+	}
+}
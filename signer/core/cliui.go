@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"sync"
 
@@ -143,7 +144,18 @@ func (ui *CommandlineUI) ApproveSignData(request *SignDataRequest) (SignDataResp
 
 	fmt.Printf("-------- Sign data request--------------\n")
 	fmt.Printf("Account:  %s\n", request.Address.String())
-	fmt.Printf("message:  \n%q\n", request.Message)
+	if header := request.Header; header != nil {
+		fmt.Printf("alien header:\n")
+		fmt.Printf("  number:          %d\n", header.Number)
+		fmt.Printf("  time:            %d (%v)\n", header.Time, time.Unix(int64(header.Time), 0))
+		fmt.Printf("  parent:          %s\n", header.ParentHash.Hex())
+		fmt.Printf("  loop start time: %d\n", header.LoopStartTime)
+		fmt.Printf("  signer queue:    %d signers\n", len(header.SignerQueue))
+	} else if request.ContentType == TypedData {
+		fmt.Printf("typed data:\n%s", request.Message)
+	} else {
+		fmt.Printf("message:  \n%q\n", request.Message)
+	}
 	fmt.Printf("raw data: \n%v\n", request.Rawdata)
 	fmt.Printf("message hash:  %v\n", request.Hash)
 	fmt.Printf("-------------------------------------------\n")
@@ -0,0 +1,171 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/naoina/toml"
+)
+
+// Config is the set of policies enforced on the transactions signed by clef.
+// The default policy applies to every account, and the account policies on top
+// of it: a transaction must satisfy both.
+type Config struct {
+	Default  *Policy                    `json:"default,omitempty"`
+	Accounts map[common.Address]*Policy `json:"accounts,omitempty"`
+}
+
+// Policy restricts the transactions sent from an account. Unset fields don't
+// restrict anything, while empty lists deny everything they list.
+type Policy struct {
+	MaxTxValue    *Amount                    `json:"maxTxValue,omitempty"`    // Cap on the value of a single transaction
+	MaxDailyValue *Amount                    `json:"maxDailyValue,omitempty"` // Cap on the value sent per UTC day
+	MaxGasPrice   *Amount                    `json:"maxGasPrice,omitempty"`   // Ceiling on the gas price
+	Allowlist     []common.Address           `json:"allowlist,omitempty"`     // Allowed destinations
+	DposTypes     []string                   `json:"dposTypes,omitempty"`     // Allowed dpos: custom transactions, as category or category:action
+	Recipients    map[common.Address]*Limits `json:"recipients,omitempty"`    // Caps on the value sent to specific recipients
+}
+
+// Limits caps the value sent to a recipient.
+type Limits struct {
+	MaxTxValue    *Amount `json:"maxTxValue,omitempty"`    // Cap on the value of a single transaction
+	MaxDailyValue *Amount `json:"maxDailyValue,omitempty"` // Cap on the value sent per UTC day
+}
+
+// LoadConfig reads the policies from a TOML file if its extension is .toml, or
+// from a JSON file otherwise.
+func LoadConfig(file string) (*Config, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := new(Config)
+	if strings.ToLower(filepath.Ext(file)) == ".toml" {
+		err = toml.Unmarshal(blob, config)
+	} else {
+		err = json.Unmarshal(blob, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return config, nil
+}
+
+// validate checks the custom transaction types of the policies.
+func (c *Config) validate() error {
+	policies := []*Policy{c.Default}
+	for _, policy := range c.Accounts {
+		policies = append(policies, policy)
+	}
+	for _, policy := range policies {
+		if policy == nil {
+			continue
+		}
+		for _, typ := range policy.DposTypes {
+			if parts := strings.Split(typ, ":"); typ == "" || len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+				return fmt.Errorf("invalid dpos transaction type %q, want category or category:action", typ)
+			}
+		}
+	}
+	return nil
+}
+
+// units are the decimals of the units amounts can be given in.
+var units = map[string]int{
+	"wei":   0,
+	"gwei":  9,
+	"ether": 18,
+}
+
+// Amount is an amount of wei, which can be configured in wei, gwei or ether,
+// e.g. "1.5 ether". Amounts without unit are in wei.
+type Amount big.Int
+
+// ParseAmount parses an amount with an optional unit.
+func ParseAmount(s string) (*Amount, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	unit := "wei"
+	if len(fields) == 2 {
+		unit = strings.ToLower(fields[1])
+	}
+	decimals, ok := units[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", fields[1])
+	}
+	whole, frac := fields[0], ""
+	if i := strings.IndexByte(whole, '.'); i >= 0 {
+		whole, frac = whole[:i], whole[i+1:]
+	}
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("amount %q is not a whole number of wei", s)
+	}
+	digits := whole + frac + strings.Repeat("0", decimals-len(frac))
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok || value.Sign() < 0 || strings.ContainsAny(digits, "+-") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return (*Amount)(value), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Amount) UnmarshalText(input []byte) error {
+	amount, err := ParseAmount(string(input))
+	if err != nil {
+		return err
+	}
+	*a = *amount
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a *Amount) MarshalText() ([]byte, error) {
+	return []byte((*big.Int)(a).String()), nil
+}
+
+// ToInt returns the amount in wei.
+func (a *Amount) ToInt() *big.Int {
+	return (*big.Int)(a)
+}
+
+// formatEther formats an amount of wei in ether for display.
+func formatEther(wei *big.Int) string {
+	digits := new(big.Int).Abs(wei).String()
+	if len(digits) <= 18 {
+		digits = strings.Repeat("0", 19-len(digits)) + digits
+	}
+	whole, frac := digits[:len(digits)-18], strings.TrimRight(digits[len(digits)-18:], "0")
+	if wei.Sign() < 0 {
+		whole = "-" + whole
+	}
+	if frac == "" {
+		return whole + " ether"
+	}
+	return whole + "." + frac + " ether"
+}
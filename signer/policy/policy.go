@@ -0,0 +1,350 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+// Package policy implements declarative limits on the transactions signed by
+// clef, enforced before the rules and the user are consulted.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/internal/ethapi"
+	"github.com/eeefan/dpeth/log"
+	"github.com/eeefan/dpeth/signer/core"
+	"github.com/eeefan/dpeth/signer/storage"
+)
+
+// Denial is the error returned for transactions violating a policy.
+type Denial struct {
+	Scope  string // Policy denying the transaction, default or the account
+	Reason string // Limit violated by the transaction
+}
+
+func (d *Denial) Error() string {
+	return fmt.Sprintf("denied by %s policy: %s", d.Scope, d.Reason)
+}
+
+// spending is the value sent by an account on a day, as persisted in storage.
+type spending struct {
+	Day        string                      `json:"day"`
+	Total      *big.Int                    `json:"total"`
+	Recipients map[common.Address]*big.Int `json:"recipients"`
+}
+
+// policyUI is a SignerUI denying the transactions violating the policies, and
+// forwarding everything else to the next handler.
+type policyUI struct {
+	next    core.SignerUI
+	config  *Config
+	storage storage.Storage
+
+	now  func() time.Time // Clock deciding the day spendings are accounted to
+	lock sync.Mutex       // Serializes the reservations of spendings in storage
+}
+
+// NewPolicyEvaluator creates a SignerUI enforcing the policies of config on the
+// transactions, before handing them to next. The value sent by each account is
+// accounted in the given storage.
+func NewPolicyEvaluator(next core.SignerUI, config *Config, storage storage.Storage) core.SignerUI {
+	return &policyUI{
+		next:    next,
+		config:  config,
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+func (p *policyUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	// Reserve the value up front, so concurrent requests can't exceed the caps
+	day, err := p.reserve(&request.Transaction)
+	if err != nil {
+		return p.deny(err)
+	}
+	res, err := p.next.ApproveTx(request)
+	if err != nil || !res.Approved {
+		p.release(&request.Transaction, day)
+		return res, err
+	}
+	// The transaction may have been modified, reserve what is going to be signed
+	if err := p.replace(&request.Transaction, day, &res.Transaction); err != nil {
+		return p.deny(err)
+	}
+	return res, nil
+}
+
+// deny reports a policy violation.
+func (p *policyUI) deny(err error) (core.SignTxResponse, error) {
+	log.Info("Transaction denied by policy", "reason", err)
+	p.next.ShowError(err.Error())
+	return core.SignTxResponse{Approved: false}, err
+}
+
+// check verifies a transaction against the default and the account policy.
+func (p *policyUI) check(args *core.SendTxArgs, spent *spending) error {
+	from := args.From.Address()
+
+	if p.config.Default != nil {
+		if reason := p.config.Default.check(args, spent); reason != "" {
+			return &Denial{Scope: "default", Reason: reason}
+		}
+	}
+	if policy := p.config.Accounts[from]; policy != nil {
+		if reason := policy.check(args, spent); reason != "" {
+			return &Denial{Scope: "account " + from.Hex(), Reason: reason}
+		}
+	}
+	return nil
+}
+
+// reserve verifies a transaction against the policies and, if allowed, accounts
+// its value to the spendings of the sender in one step, returning the day it is
+// accounted to. The value stays reserved until released, or for good once the
+// transaction is signed.
+func (p *policyUI) reserve(args *core.SendTxArgs) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.reserveLocked(args)
+}
+
+// release returns the value reserved for a transaction on the given day to the
+// spendings of the sender. Values reserved on a previous day expired with it.
+func (p *policyUI) release(args *core.SendTxArgs, day string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.releaseLocked(args, day)
+}
+
+// replace releases the value reserved for a transaction and reserves the value
+// of the one replacing it, without other reservations in between.
+func (p *policyUI) replace(old *core.SendTxArgs, day string, args *core.SendTxArgs) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.releaseLocked(old, day)
+	_, err := p.reserveLocked(args)
+	return err
+}
+
+func (p *policyUI) reserveLocked(args *core.SendTxArgs) (string, error) {
+	from := args.From.Address()
+	spent := p.spending(from)
+	if err := p.check(args, spent); err != nil {
+		return "", err
+	}
+	p.account(from, recipient(args), spent, args.Value.ToInt())
+	return spent.Day, nil
+}
+
+func (p *policyUI) releaseLocked(args *core.SendTxArgs, day string) {
+	from := args.From.Address()
+	if spent := p.spending(from); spent.Day == day {
+		p.account(from, recipient(args), spent, new(big.Int).Neg(args.Value.ToInt()))
+	}
+}
+
+// recipient returns the destination of a transaction, nil for contract creations.
+func recipient(args *core.SendTxArgs) *common.Address {
+	if args.To == nil {
+		return nil
+	}
+	to := args.To.Address()
+	return &to
+}
+
+// account adds a value to the spendings of an account towards a recipient, and
+// persists them. The totals are kept from going below zero.
+func (p *policyUI) account(from common.Address, to *common.Address, spent *spending, value *big.Int) {
+	if value.Sign() == 0 {
+		return
+	}
+	add := func(total *big.Int) {
+		if total.Add(total, value).Sign() < 0 {
+			total.SetUint64(0)
+		}
+	}
+	add(spent.Total)
+	if to != nil {
+		if spent.Recipients[*to] == nil {
+			spent.Recipients[*to] = new(big.Int)
+		}
+		add(spent.Recipients[*to])
+	}
+	blob, _ := json.Marshal(spent)
+	p.storage.Put(spendingKey(from), string(blob))
+}
+
+// spending retrieves the value sent by an account today.
+func (p *policyUI) spending(account common.Address) *spending {
+	day := p.today()
+	spent := new(spending)
+	if blob := p.storage.Get(spendingKey(account)); blob != "" {
+		if err := json.Unmarshal([]byte(blob), spent); err != nil {
+			log.Error("Corrupted policy spendings, resetting", "account", account, "err", err)
+		}
+	}
+	if spent.Day != day || spent.Total == nil {
+		spent = &spending{Day: day, Total: new(big.Int)}
+	}
+	if spent.Recipients == nil {
+		spent.Recipients = make(map[common.Address]*big.Int)
+	}
+	return spent
+}
+
+// today returns the UTC day spendings are currently accounted to.
+func (p *policyUI) today() string {
+	return p.now().UTC().Format("2006-01-02")
+}
+
+func spendingKey(account common.Address) string {
+	return "policy:spent:" + strings.ToLower(account.Hex())
+}
+
+// check verifies a transaction against the policy, returning the reason of its
+// denial, if any.
+func (policy *Policy) check(args *core.SendTxArgs, spent *spending) string {
+	value := args.Value.ToInt()
+
+	if policy.MaxGasPrice != nil && args.GasPrice.ToInt().Cmp(policy.MaxGasPrice.ToInt()) > 0 {
+		return fmt.Sprintf("gas price %v wei above ceiling of %v wei", args.GasPrice.ToInt(), policy.MaxGasPrice.ToInt())
+	}
+	if policy.Allowlist != nil {
+		if args.To == nil {
+			return "contract creation not allowed"
+		}
+		allowed := false
+		for _, addr := range policy.Allowlist {
+			if addr == args.To.Address() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("destination %s not allowed", args.To.Address().Hex())
+		}
+	}
+	if policy.DposTypes != nil {
+		if reason := policy.checkDpos(args); reason != "" {
+			return reason
+		}
+	}
+	if reason := checkLimits(value, spent.Total, policy.MaxTxValue, policy.MaxDailyValue); reason != "" {
+		return reason
+	}
+	if args.To != nil {
+		to := args.To.Address()
+		if limits := policy.Recipients[to]; limits != nil {
+			spentTo := spent.Recipients[to]
+			if spentTo == nil {
+				spentTo = new(big.Int)
+			}
+			if reason := checkLimits(value, spentTo, limits.MaxTxValue, limits.MaxDailyValue); reason != "" {
+				return reason + " to " + to.Hex()
+			}
+		}
+	}
+	return ""
+}
+
+// checkDpos verifies that a dpos: custom transaction is of an allowed type.
+func (policy *Policy) checkDpos(args *core.SendTxArgs) string {
+	var data []byte
+	if args.Data != nil {
+		data = *args.Data
+	} else if args.Input != nil {
+		data = *args.Input
+	}
+	// Custom transactions are formatted as dpos:version:category:action/data
+	parts := strings.Split(string(data), ":")
+	if parts[0] != "dpos" {
+		return ""
+	}
+	if len(parts) < 4 {
+		return fmt.Sprintf("malformed dpos transaction %q", data)
+	}
+	for _, typ := range policy.DposTypes {
+		if typ == parts[2] || typ == parts[2]+":"+parts[3] {
+			return ""
+		}
+	}
+	return fmt.Sprintf("dpos transaction type %s:%s not allowed", parts[2], parts[3])
+}
+
+// checkLimits verifies a value against the per transaction and daily caps.
+func checkLimits(value, spent *big.Int, maxTx, maxDaily *Amount) string {
+	if maxTx != nil && value.Cmp(maxTx.ToInt()) > 0 {
+		return fmt.Sprintf("value %s above per transaction cap of %s", formatEther(value), formatEther(maxTx.ToInt()))
+	}
+	if maxDaily != nil {
+		if total := new(big.Int).Add(spent, value); total.Cmp(maxDaily.ToInt()) > 0 {
+			return fmt.Sprintf("value %s above daily cap of %s, %s already sent today", formatEther(value), formatEther(maxDaily.ToInt()), formatEther(spent))
+		}
+	}
+	return ""
+}
+
+func (p *policyUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	// The value was reserved when approving the transaction, keep it accounted
+	p.next.OnApprovedTx(tx)
+}
+
+func (p *policyUI) OnFailedTx(tx core.SendTxArgs, err error) {
+	// Signing follows right after the approval, release from the current day
+	p.release(&tx, p.today())
+	if handler, ok := p.next.(core.TxFailureHandler); ok {
+		handler.OnFailedTx(tx, err)
+	}
+}
+
+func (p *policyUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return p.next.ApproveSignData(request)
+}
+
+func (p *policyUI) ApproveExport(request *core.ExportRequest) (core.ExportResponse, error) {
+	return p.next.ApproveExport(request)
+}
+
+func (p *policyUI) ApproveImport(request *core.ImportRequest) (core.ImportResponse, error) {
+	return p.next.ApproveImport(request)
+}
+
+func (p *policyUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return p.next.ApproveListing(request)
+}
+
+func (p *policyUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return p.next.ApproveNewAccount(request)
+}
+
+func (p *policyUI) ShowError(message string) {
+	p.next.ShowError(message)
+}
+
+func (p *policyUI) ShowInfo(message string) {
+	p.next.ShowInfo(message)
+}
+
+func (p *policyUI) OnSignerStartup(info core.StartupInfo) {
+	p.next.OnSignerStartup(info)
+}
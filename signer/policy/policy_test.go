@@ -0,0 +1,379 @@
+// Copyright 2019 The dpeth Authors
+// This file is part of dpeth.
+//
+// dpeth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// dpeth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with dpeth. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eeefan/dpeth/common"
+	"github.com/eeefan/dpeth/common/hexutil"
+	"github.com/eeefan/dpeth/core/types"
+	"github.com/eeefan/dpeth/crypto"
+	"github.com/eeefan/dpeth/internal/ethapi"
+	"github.com/eeefan/dpeth/signer/core"
+	"github.com/eeefan/dpeth/signer/storage"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAccount = crypto.PubkeyToAddress(testKey.PublicKey)
+
+	alice = common.HexToAddress("0x000000000000000000000000000000000000a11c")
+	bob   = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+)
+
+const testConfigJSON = `{
+	"default": {
+		"maxGasPrice": "50 gwei"
+	},
+	"accounts": {
+		"0x71562b71999873DB5b286dF957af199Ec94617F7": {
+			"maxTxValue": "1 ether",
+			"maxDailyValue": "2.5 ether",
+			"allowlist": ["0x000000000000000000000000000000000000a11c", "0x0000000000000000000000000000000000000b0b", "0x71562b71999873DB5b286dF957af199Ec94617F7"],
+			"dposTypes": ["event:vote", "oplog"],
+			"recipients": {
+				"0x0000000000000000000000000000000000000b0b": {"maxDailyValue": "1.5 ether"}
+			}
+		}
+	}
+}`
+
+const testConfigTOML = `
+[Default]
+MaxGasPrice = "50 gwei"
+
+[Accounts.0x71562b71999873DB5b286dF957af199Ec94617F7]
+MaxTxValue = "1 ether"
+MaxDailyValue = "2.5 ether"
+Allowlist = ["0x000000000000000000000000000000000000a11c", "0x0000000000000000000000000000000000000b0b", "0x71562b71999873DB5b286dF957af199Ec94617F7"]
+DposTypes = ["event:vote", "oplog"]
+
+[Accounts.0x71562b71999873DB5b286dF957af199Ec94617F7.Recipients.0x0000000000000000000000000000000000000b0b]
+MaxDailyValue = "1.5 ether"
+`
+
+// approvingUI approves every request, recording the signed transactions.
+type approvingUI struct {
+	errors   []string
+	approved int
+}
+
+func (ui *approvingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+}
+func (ui *approvingUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return core.SignDataResponse{Approved: true}, nil
+}
+func (ui *approvingUI) ApproveExport(request *core.ExportRequest) (core.ExportResponse, error) {
+	return core.ExportResponse{Approved: true}, nil
+}
+func (ui *approvingUI) ApproveImport(request *core.ImportRequest) (core.ImportResponse, error) {
+	return core.ImportResponse{Approved: true}, nil
+}
+func (ui *approvingUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return core.ListResponse{Accounts: request.Accounts}, nil
+}
+func (ui *approvingUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return core.NewAccountResponse{Approved: true}, nil
+}
+func (ui *approvingUI) ShowError(message string)                  { ui.errors = append(ui.errors, message) }
+func (ui *approvingUI) ShowInfo(message string)                   {}
+func (ui *approvingUI) OnApprovedTx(ethapi.SignTransactionResult) { ui.approved++ }
+func (ui *approvingUI) OnSignerStartup(info core.StartupInfo)     {}
+
+func writeConfig(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func ether(n string) *big.Int {
+	amount, err := ParseAmount(n + " ether")
+	if err != nil {
+		panic(err)
+	}
+	return amount.ToInt()
+}
+
+func txArgs(to *common.Address, value *big.Int, gasPrice int64, data string) core.SendTxArgs {
+	args := core.SendTxArgs{
+		From:     common.NewMixedcaseAddress(testAccount),
+		Value:    hexutil.Big(*value),
+		GasPrice: hexutil.Big(*big.NewInt(gasPrice)),
+		Gas:      21000,
+	}
+	if to != nil {
+		addr := common.NewMixedcaseAddress(*to)
+		args.To = &addr
+	}
+	if data != "" {
+		input := hexutil.Bytes(data)
+		args.Data = &input
+	}
+	return args
+}
+
+// sign approves and signs the transaction, reporting it as signed like the
+// SignerAPI.
+func sign(t *testing.T, ui core.SignerUI, args core.SendTxArgs) {
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: args}); err != nil {
+		t.Fatalf("unexpected denial: %v", err)
+	}
+	tx := types.NewTransaction(uint64(args.Nonce), args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), nil)
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(1)), testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui.OnApprovedTx(ethapi.SignTransactionResult{Tx: signed})
+}
+
+// fail reports an approved transaction as failed to be signed, like the SignerAPI.
+func fail(ui core.SignerUI, args core.SendTxArgs) {
+	ui.(core.TxFailureHandler).OnFailedTx(args, errors.New("signing failed"))
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1000", "1000"},
+		{"1000 wei", "1000"},
+		{"20 gwei", "20000000000"},
+		{"1.5 ether", "1500000000000000000"},
+		{"0.000000000000000001 Ether", "1"},
+		{"1.5", ""},
+		{"0.1 gwei ether", ""},
+		{"-1 ether", ""},
+		{"1 finney", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		amount, err := ParseAmount(tt.input)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("%q: expected error, got %v", tt.input, amount.ToInt())
+		case tt.want != "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+		case tt.want != "" && amount.ToInt().String() != tt.want:
+			t.Errorf("%q: amount mismatch: have %v, want %s", tt.input, amount.ToInt(), tt.want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	for _, name := range []string{"policy.json", "policy.toml"} {
+		content := testConfigJSON
+		if strings.HasSuffix(name, ".toml") {
+			content = testConfigTOML
+		}
+		path := writeConfig(t, name, content)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: failed to load: %v", name, err)
+		}
+		if config.Default == nil || config.Default.MaxGasPrice.ToInt().Cmp(big.NewInt(50e9)) != 0 {
+			t.Errorf("%s: default gas price ceiling mismatch", name)
+		}
+		policy := config.Accounts[testAccount]
+		if policy == nil {
+			t.Fatalf("%s: account policy missing", name)
+		}
+		if policy.MaxDailyValue.ToInt().Cmp(ether("2.5")) != 0 {
+			t.Errorf("%s: daily cap mismatch: have %v", name, policy.MaxDailyValue.ToInt())
+		}
+		if len(policy.Allowlist) != 3 || policy.Allowlist[0] != alice {
+			t.Errorf("%s: allowlist mismatch: have %v", name, policy.Allowlist)
+		}
+		if limits := policy.Recipients[bob]; limits == nil || limits.MaxDailyValue.ToInt().Cmp(ether("1.5")) != 0 {
+			t.Errorf("%s: recipient caps mismatch: have %v", name, limits)
+		}
+	}
+	path := writeConfig(t, "policy.json", `{"default": {"dposTypes": ["event:vote:yes"]}}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	if _, err := LoadConfig(path); err == nil {
+		t.Errorf("invalid dpos transaction type accepted")
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	path := writeConfig(t, "policy.json", testConfigJSON)
+	defer os.RemoveAll(filepath.Dir(path))
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := new(approvingUI)
+	ui := NewPolicyEvaluator(next, config, storage.NewEphemeralStorage())
+
+	now := time.Date(2019, 3, 8, 23, 0, 0, 0, time.UTC)
+	ui.(*policyUI).now = func() time.Time { return now }
+
+	carol := common.HexToAddress("0x00000000000000000000000000000000000ca201")
+	tests := []struct {
+		args   core.SendTxArgs
+		denied string // Expected denial reason, empty if approved
+	}{
+		{txArgs(&alice, ether("1"), 1e9, ""), ""},
+		{txArgs(&alice, ether("1"), 51e9, ""), "denied by default policy: gas price 51000000000 wei above ceiling"},
+		{txArgs(&alice, ether("1.1"), 1e9, ""), "value 1.1 ether above per transaction cap of 1 ether"},
+		{txArgs(&carol, ether("0.1"), 1e9, ""), "destination " + carol.Hex() + " not allowed"},
+		{txArgs(nil, ether("0"), 1e9, "code"), "contract creation not allowed"},
+		{txArgs(&testAccount, ether("0"), 1e9, "dpos:1:event:vote"), ""},
+		{txArgs(&testAccount, ether("0"), 1e9, "dpos:1:oplog:record"), ""},
+		{txArgs(&testAccount, ether("0"), 1e9, "dpos:1:admin:dels"), "dpos transaction type admin:dels not allowed"},
+		{txArgs(&testAccount, ether("0"), 1e9, "dpos:1"), "malformed dpos transaction"},
+	}
+	for i, tt := range tests {
+		res, err := ui.ApproveTx(&core.SignTxRequest{Transaction: tt.args})
+		if tt.denied == "" {
+			if err != nil || !res.Approved {
+				t.Errorf("test %d: unexpected denial: %v", i, err)
+			}
+			fail(ui, res.Transaction)
+			continue
+		}
+		if _, ok := err.(*Denial); !ok || res.Approved || !strings.Contains(err.Error(), tt.denied) {
+			t.Errorf("test %d: denial mismatch: have %v, want %q", i, err, tt.denied)
+		}
+	}
+	if len(next.errors) != 6 {
+		t.Errorf("denials not reported to the UI: have %d, want 6", len(next.errors))
+	}
+
+	// Check the daily caps, for the recipient and overall
+	for _, value := range []string{"1", "0.5"} {
+		sign(t, ui, txArgs(&bob, ether(value), 1e9, ""))
+	}
+	if next.approved != 2 {
+		t.Errorf("signed transactions not forwarded: have %d, want 2", next.approved)
+	}
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&bob, big.NewInt(1), 1e9, "")}); err == nil || !strings.Contains(err.Error(), "daily cap of 1.5 ether, 1.5 ether already sent today to "+bob.Hex()) {
+		t.Errorf("recipient daily cap not enforced: %v", err)
+	}
+	sign(t, ui, txArgs(&alice, ether("1"), 1e9, ""))
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, ether("0.1"), 1e9, "")}); err == nil || !strings.Contains(err.Error(), "daily cap of 2.5 ether") {
+		t.Errorf("daily cap not enforced: %v", err)
+	}
+	// The caps reset on the next UTC day
+	now = now.Add(2 * time.Hour)
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, ether("1"), 1e9, "")}); err != nil {
+		t.Errorf("daily cap not reset: %v", err)
+	}
+}
+
+// blockingUI approves or rejects requests as decided by the test, simulating a
+// user deciding on concurrent requests.
+type blockingUI struct {
+	approvingUI
+	decide chan bool
+}
+
+func (ui *blockingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	if !<-ui.decide {
+		return core.SignTxResponse{Approved: false}, nil
+	}
+	return ui.approvingUI.ApproveTx(request)
+}
+func (ui *blockingUI) ShowError(message string) {}
+
+// Tests that concurrently pending requests can't exceed the daily caps together,
+// and that rejected or failed requests release the value reserved for them.
+func TestPolicyConcurrentReservations(t *testing.T) {
+	path := writeConfig(t, "policy.json", testConfigJSON)
+	defer os.RemoveAll(filepath.Dir(path))
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := &blockingUI{decide: make(chan bool)}
+	ui := NewPolicyEvaluator(next, config, storage.NewEphemeralStorage())
+
+	// Request 1 ether four times at once, only two fit in the daily cap of 2.5
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		approved []core.SendTxArgs
+		denied   int
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, ether("1"), 1e9, "")})
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				denied++
+			} else if res.Approved {
+				approved = append(approved, res.Transaction)
+			}
+		}()
+	}
+	// Wait for the denials, then let the user approve the pending requests
+	for i := 0; i < 100; i++ {
+		lock.Lock()
+		done := denied == 2
+		lock.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	next.decide <- true
+	next.decide <- true
+	wg.Wait()
+	if len(approved) != 2 || denied != 2 {
+		t.Fatalf("concurrent requests mismatch: have %d approved, %d denied, want 2 and 2", len(approved), denied)
+	}
+	// Failing to sign one releases its value for another request
+	fail(ui, approved[0])
+	go func() { next.decide <- true }()
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, ether("0.5"), 1e9, "")}); err != nil {
+		t.Fatalf("released value not available: %v", err)
+	}
+	// Rejecting a request releases its value right away
+	go func() { next.decide <- false }()
+	if res, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, ether("0.5"), 1e9, "")}); err != nil || res.Approved {
+		t.Fatalf("rejection mismatch: have %v, %v", res.Approved, err)
+	}
+	go func() { next.decide <- true }()
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, ether("1"), 1e9, "")}); err != nil {
+		t.Fatalf("rejected value not released: %v", err)
+	}
+	if _, err := ui.ApproveTx(&core.SignTxRequest{Transaction: txArgs(&alice, big.NewInt(1), 1e9, "")}); err == nil {
+		t.Errorf("daily cap exceeded after releases")
+	}
+}
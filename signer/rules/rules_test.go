@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"github.com/eeefan/dpeth/accounts"
+	"github.com/eeefan/dpeth/accounts/eip712"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/common/hexutil"
 	"github.com/eeefan/dpeth/core/types"
@@ -629,3 +630,134 @@ function ApproveSignData(r){
 		t.Fatalf("Expected approved")
 	}
 }
+
+// alienSlotRules approves the sealing of alien headers only in the slots of the
+// signer, as given by the loop of the parent header, and never twice at the same
+// height, with a fixed period of 3 seconds.
+const alienSlotRules = `
+function ApproveSignData(r){
+    if (r.content_type != "application/x-alien-header") {
+        return
+    }
+    var h = r.header
+    if (h.signer_queue.length == 0) {
+        return // Children of the genesis, which carries no queue, go to manual processing
+    }
+    if (h.time < h.loop_start_time) {
+        return "Reject"
+    }
+    var slot = Math.floor((h.time - h.loop_start_time) / 3) % h.signer_queue.length
+    if (h.signer_queue[slot].toLowerCase() != r.address.toLowerCase()) {
+        return "Reject"
+    }
+    var last = storage.Get("lastSealed")
+    if (last != "" && h.number <= parseInt(last)) {
+        return "Reject"
+    }
+    storage.Put("lastSealed", "" + h.number)
+    return "Approve"
+}`
+
+func TestSignAlienHeader(t *testing.T) {
+	r, err := initRuleEngine(alienSlotRules)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	var (
+		signer = common.HexToAddress("0x694267f14675d7e1b9494fd8d72fefe1755710fa")
+		other  = common.HexToAddress("0x1337")
+		addr   = common.NewMixedcaseAddress(signer)
+	)
+	request := func(number, time uint64, queue []common.Address) *core.SignDataRequest {
+		return &core.SignDataRequest{
+			ContentType: core.AlienHeader,
+			Address:     addr,
+			Header: &core.AlienHeaderInfo{
+				Number:        number,
+				Time:          time,
+				LoopStartTime: 1000,
+				SignerQueue:   queue,
+			},
+			Meta: core.Metadata{Remote: "remoteip", Local: "localip", Scheme: "inproc"},
+		}
+	}
+	queue := []common.Address{other, signer, other}
+	tests := []struct {
+		number, time uint64
+		queue        []common.Address
+		approved     bool
+	}{
+		{10, 1003, queue, true},  // Signer's slot
+		{11, 1006, queue, false}, // Another signer's slot
+		{10, 1012, queue, false}, // Signer's slot, but the height was already sealed
+		{12, 1012, queue, true},  // Signer's slot of the next loop
+		{13, 999, queue, false},  // Before the loop started
+		{1, 1003, nil, false},    // Child of the genesis, left to manual processing
+	}
+	for i, tt := range tests {
+		resp, err := r.ApproveSignData(request(tt.number, tt.time, tt.queue))
+		if err != nil {
+			t.Fatalf("test %d: unexpected error %v", i, err)
+		}
+		if resp.Approved != tt.approved {
+			t.Errorf("test %d: approval mismatch: have %v, want %v", i, resp.Approved, tt.approved)
+		}
+	}
+}
+
+// typedOrderRules approves signing typed orders of a single exchange, as long as
+// the order amount stays below 10 ether.
+const typedOrderRules = `
+function ApproveSignData(r){
+    if (r.content_type != "data/typed") {
+        return
+    }
+    var td = r.typed_data
+    if (td.domain.name != "Exchange" || td.primaryType != "Order") {
+        return "Reject"
+    }
+    if (new BigNumber(td.message.amount).gte(new BigNumber("10e18"))) {
+        return "Reject"
+    }
+    return "Approve"
+}`
+
+func TestSignTypedData(t *testing.T) {
+	r, err := initRuleEngine(typedOrderRules)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	request := func(domain string, amount string) *core.SignDataRequest {
+		return &core.SignDataRequest{
+			ContentType: core.TypedData,
+			Address:     common.NewMixedcaseAddress(common.HexToAddress("0x694267f14675d7e1b9494fd8d72fefe1755710fa")),
+			TypedData: &eip712.TypedData{
+				Types: eip712.Types{
+					"EIP712Domain": {{Name: "name", Type: "string"}},
+					"Order":        {{Name: "amount", Type: "uint256"}},
+				},
+				PrimaryType: "Order",
+				Domain:      eip712.Domain{Name: domain},
+				Message:     map[string]interface{}{"amount": amount},
+			},
+			Meta: core.Metadata{Remote: "remoteip", Local: "localip", Scheme: "inproc"},
+		}
+	}
+	tests := []struct {
+		domain, amount string
+		approved       bool
+	}{
+		{"Exchange", "1000000000000000000", true},
+		{"Exchange", "10000000000000000000", false},
+		{"Phishing", "1", false},
+	}
+	for i, tt := range tests {
+		resp, err := r.ApproveSignData(request(tt.domain, tt.amount))
+		if err != nil {
+			t.Fatalf("test %d: unexpected error %v", i, err)
+		}
+		if resp.Approved != tt.approved {
+			t.Errorf("test %d: approval mismatch: have %v, want %v", i, resp.Approved, tt.approved)
+		}
+	}
+}
@@ -18,14 +18,11 @@
 package storage
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/json"
-	"io"
 	"io/ioutil"
 	"os"
 
+	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/log"
 )
 
@@ -63,7 +60,7 @@ func (s *AESEncryptedStorage) Put(key, value string) {
 		log.Warn("Failed to read encrypted storage", "err", err, "file", s.filename)
 		return
 	}
-	ciphertext, iv, err := encrypt(s.key, []byte(value))
+	ciphertext, iv, err := keystore.AESGCMEncrypt(s.key, []byte(value))
 	if err != nil {
 		log.Warn("Failed to encrypt entry", "err", err)
 		return
@@ -90,7 +87,7 @@ func (s *AESEncryptedStorage) Get(key string) string {
 		log.Warn("Key does not exist", "key", key)
 		return ""
 	}
-	entry, err := decrypt(s.key, encrypted.Iv, encrypted.CipherText)
+	entry, err := keystore.AESGCMDecrypt(s.key, encrypted.Iv, encrypted.CipherText)
 	if err != nil {
 		log.Warn("Failed to decrypt key", "key", key)
 		return ""
@@ -128,36 +125,3 @@ func (s *AESEncryptedStorage) writeEncryptedStorage(creds map[string]storedCrede
 	}
 	return nil
 }
-
-func encrypt(key []byte, plaintext []byte) ([]byte, []byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, nil, err
-	}
-	aesgcm, err := cipher.NewGCM(block)
-	nonce := make([]byte, aesgcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, nil, err
-	}
-	if err != nil {
-		return nil, nil, err
-	}
-	ciphertext := aesgcm.Seal(nil, nonce, plaintext, nil)
-	return ciphertext, nonce, nil
-}
-
-func decrypt(key []byte, nonce []byte, ciphertext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	aesgcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return nil, err
-	}
-	return plaintext, nil
-}
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/eeefan/dpeth/accounts/keystore"
 	"github.com/eeefan/dpeth/common"
 	"github.com/eeefan/dpeth/log"
 	"github.com/mattn/go-colorable"
@@ -33,13 +34,13 @@ func TestEncryption(t *testing.T) {
 	key := []byte("AES256Key-32Characters1234567890")
 	plaintext := []byte("exampleplaintext")
 
-	c, iv, err := encrypt(key, plaintext)
+	c, iv, err := keystore.AESGCMEncrypt(key, plaintext)
 	if err != nil {
 		t.Fatal(err)
 	}
 	fmt.Printf("Ciphertext %x, nonce %x\n", c, iv)
 
-	p, err := decrypt(key, iv, c)
+	p, err := keystore.AESGCMDecrypt(key, iv, c)
 	if err != nil {
 		t.Fatal(err)
 	}